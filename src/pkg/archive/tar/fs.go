@@ -0,0 +1,329 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tar
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInsecurePath = errors.New("archive/tar: insecure file path")
+	ErrTooLarge     = errors.New("archive/tar: extraction limit exceeded")
+)
+
+// AddDir adds the directory tree rooted at dir to the archive.
+// Files are named by their slash-separated path relative to dir.
+// Regular files, directories, symbolic links, named pipes and devices
+// are archived with their modes and modification times; symbolic links
+// are not followed and sockets are skipped. dir itself is not archived.
+func (tw *Writer) AddDir(dir string) error {
+	return filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if rel == "." || fi.Mode()&os.ModeSocket != 0 {
+			return nil
+		}
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		hdr, err := FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != TypeReg {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+}
+
+// ExtractOptions limits the resources used by Reader.Extract.
+// A zero value means no limit.
+type ExtractOptions struct {
+	MaxFiles     int   // number of entries
+	MaxFileSize  int64 // size of each file
+	MaxTotalSize int64 // total size of all files
+}
+
+// Extract extracts the remaining entries of the archive into the
+// directory dir, which is created if needed.
+//
+// Entries are only ever created inside dir: Extract returns
+// ErrInsecurePath for names that are absolute or contain "..",
+// for names reaching through a symbolic link, and for symbolic or hard
+// links pointing outside dir. It returns ErrTooLarge as soon as the
+// archive exceeds one of the limits in opts, which may be nil.
+//
+// Regular files, directories, symbolic links and hard links are
+// extracted with their permission bits and modification times; the
+// holes of sparse files are not written. Other entries, setuid, setgid
+// and sticky bits, owners and extended attributes are ignored.
+// Existing files are replaced, existing directories are kept.
+func (tr *Reader) Extract(dir string, opts *ExtractOptions) error {
+	if opts == nil {
+		opts = new(ExtractOptions)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	var (
+		dirs  []*Header // set modes and times once they are filled
+		files int
+		total int64
+	)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name, err := localPath(dir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if files++; opts.MaxFiles > 0 && files > opts.MaxFiles {
+			return ErrTooLarge
+		}
+		switch hdr.Typeflag {
+		case TypeDir:
+			if fi, err := os.Lstat(name); err == nil && !fi.IsDir() {
+				if err := os.Remove(name); err != nil {
+					return err
+				}
+			}
+			if err := os.MkdirAll(name, 0777); err != nil {
+				return err
+			}
+			dirs = append(dirs, hdr)
+			continue
+		case TypeReg, TypeRegA, TypeGNUSparse:
+			if err := prepareFile(name); err != nil {
+				return err
+			}
+			n, err := tr.extractFile(name, hdr, opts, total)
+			total += n
+			if err != nil {
+				return err
+			}
+		case TypeSymlink:
+			if !localLink(hdr.Name, hdr.Linkname) {
+				return ErrInsecurePath
+			}
+			if err := prepareFile(name); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, name); err != nil {
+				return err
+			}
+			continue
+		case TypeLink:
+			target, err := localPath(dir, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := prepareFile(name); err != nil {
+				return err
+			}
+			if err := os.Link(target, name); err != nil {
+				return err
+			}
+			continue
+		default:
+			continue
+		}
+		if err := setAttrs(name, hdr); err != nil {
+			return err
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		name, _ := localPath(dir, dirs[i].Name)
+		if err := setAttrs(name, dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the contents of the current entry to a new file
+// called name. total is the size of the files already extracted.
+// It returns the number of bytes written.
+func (tr *Reader) extractFile(name string, hdr *Header, opts *ExtractOptions, total int64) (int64, error) {
+	// Only the data fragments of sparse files count towards the limits.
+	data := []SparseEntry{{0, hdr.Size}}
+	if hdr.SparseHoles != nil {
+		var err error
+		if data, err = sparseData(hdr.SparseHoles, hdr.Size); err != nil {
+			return 0, err
+		}
+	}
+	var size int64
+	for _, d := range data {
+		size += d.Length
+	}
+	if opts.MaxFileSize > 0 && size > opts.MaxFileSize ||
+		opts.MaxTotalSize > 0 && total+size > opts.MaxTotalSize {
+		return 0, ErrTooLarge
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return 0, err
+	}
+	var n, pos int64
+	for _, d := range data {
+		if d.Offset > pos {
+			if _, err = io.CopyN(ioutil.Discard, tr, d.Offset-pos); err != nil {
+				break
+			}
+			if _, err = f.Seek(d.Offset, os.SEEK_SET); err != nil {
+				break
+			}
+		}
+		var nw int64
+		nw, err = io.CopyN(f, tr, d.Length)
+		n += nw
+		pos = d.Offset + nw
+		if err != nil {
+			break
+		}
+	}
+	if err == nil && hdr.SparseHoles != nil {
+		// Seeking over a final hole does not extend the file.
+		err = f.Truncate(hdr.Size)
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// setAttrs sets the permission bits and times of the file name from hdr.
+// Entries without permission bits keep the default ones, and entries
+// without a modification time keep their times.
+func setAttrs(name string, hdr *Header) error {
+	if perm := os.FileMode(hdr.Mode).Perm(); perm != 0 {
+		if err := os.Chmod(name, perm); err != nil {
+			return err
+		}
+	}
+	if hdr.ModTime.IsZero() {
+		return nil
+	}
+	atime := hdr.AccessTime
+	if atime.IsZero() {
+		atime = hdr.ModTime
+	}
+	return os.Chtimes(name, atime, hdr.ModTime)
+}
+
+// localPath returns the path under dir of the archived file name.
+// It returns ErrInsecurePath if name is not a relative path inside dir
+// or if one of its parent directories is a symbolic link.
+func localPath(dir, name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || path.IsAbs(name) || os.PathSeparator == '\\' && strings.ContainsAny(name, `\:`) {
+		return "", ErrInsecurePath
+	}
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", ErrInsecurePath
+	}
+	elems := strings.Split(name, "/")
+	p := dir
+	for _, e := range elems[:len(elems)-1] {
+		p = filepath.Join(p, e)
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", ErrInsecurePath
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// localLink reports whether the target of the symbolic link name stays
+// inside the extraction directory. The target must be relative and
+// may only use ".." as leading elements, so that they are resolved
+// from the directory containing the link and not from the target of
+// another link.
+func localLink(name, target string) bool {
+	if target == "" || path.IsAbs(target) || os.PathSeparator == '\\' && strings.ContainsAny(target, `\:`) {
+		return false
+	}
+	depth := strings.Count(path.Clean(strings.TrimSuffix(name, "/")), "/")
+	up := true
+	for _, e := range strings.Split(target, "/") {
+		switch e {
+		case "", ".":
+		case "..":
+			if !up || depth == 0 {
+				return false
+			}
+			depth--
+		default:
+			up = false
+		}
+	}
+	return true
+}
+
+// prepareFile makes way for a new file called name, returned by
+// localPath. It creates the missing parent directories, which archives
+// need not list, and removes the file if it exists, so that the new
+// file can be created without following a symbolic link left in its
+// place.
+func prepareFile(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.PathError{Op: "extract", Path: name, Err: errors.New("is a directory")}
+	}
+	return os.Remove(name)
+}
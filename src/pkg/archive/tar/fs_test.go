@@ -0,0 +1,265 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tar
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// makeTree creates a small directory tree under dir.
+func makeTree(t *testing.T, dir string) {
+	mtime := time.Unix(1360000000, 0)
+	files := []struct {
+		name string
+		mode os.FileMode
+		data string
+	}{
+		{"sub", os.ModeDir | 0750, ""},
+		{"sub/b.txt", 0600, "bbb\n"},
+		{"a.txt", 0644, "Hello, world.\n"},
+		{"ro", os.ModeDir | 0555, ""},
+	}
+	for _, f := range files {
+		name := filepath.Join(dir, f.name)
+		var err error
+		if f.mode.IsDir() {
+			err = os.Mkdir(name, 0777)
+		} else {
+			err = ioutil.WriteFile(name, []byte(f.data), 0666)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func(name string, mode os.FileMode) {
+			if err := os.Chmod(name, mode.Perm()); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(name, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}(name, f.mode)
+	}
+	if err := os.Symlink("sub/b.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTree checks that dir2 has the same files as dir1.
+func checkTree(t *testing.T, dir1, dir2 string) {
+	err := filepath.Walk(dir1, func(file string, fi1 os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir1, file)
+		fi2, err := os.Lstat(filepath.Join(dir2, rel))
+		if err != nil {
+			return err
+		}
+		if fi1.Mode() != fi2.Mode() {
+			t.Errorf("%s: mode = %v, want %v", rel, fi2.Mode(), fi1.Mode())
+		}
+		switch {
+		case fi1.Mode()&os.ModeSymlink != 0:
+			l1, _ := os.Readlink(file)
+			l2, _ := os.Readlink(filepath.Join(dir2, rel))
+			if l1 != l2 {
+				t.Errorf("%s: link = %q, want %q", rel, l2, l1)
+			}
+			return nil
+		case fi1.Mode().IsRegular():
+			b1, _ := ioutil.ReadFile(file)
+			b2, _ := ioutil.ReadFile(filepath.Join(dir2, rel))
+			if !bytes.Equal(b1, b2) {
+				t.Errorf("%s: contents = %q, want %q", rel, b2, b1)
+			}
+		}
+		if rel != "." && !fi1.ModTime().Equal(fi2.ModTime()) {
+			t.Errorf("%s: mtime = %v, want %v", rel, fi2.ModTime(), fi1.ModTime())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAddDirExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	if err := os.Mkdir(src, 0777); err != nil {
+		t.Fatal(err)
+	}
+	makeTree(t, src)
+
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	if err := tw.AddDir(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewReader(bytes.NewReader(buf.Bytes())).Extract(dst, nil); err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, src, dst)
+}
+
+func TestExtractSparse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Open("testdata/sparse-formats.tar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := NewReader(f).Extract(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.Seek(0, os.SEEK_SET)
+	tr := NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		want, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(filepath.Join(dir, hdr.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: extracted contents differ", hdr.Name)
+		}
+	}
+}
+
+type extractTestEntry struct {
+	hdr  Header
+	data string
+}
+
+var insecureTests = [][]extractTestEntry{
+	{{hdr: Header{Name: "../evil", Typeflag: TypeReg}}},
+	{{hdr: Header{Name: "a/../../evil", Typeflag: TypeReg}}},
+	{{hdr: Header{Name: "/evil", Typeflag: TypeReg}}},
+	{{hdr: Header{Name: "link", Typeflag: TypeSymlink, Linkname: "../evil"}}},
+	{{hdr: Header{Name: "link", Typeflag: TypeSymlink, Linkname: "/etc"}}},
+	{{hdr: Header{Name: "link", Typeflag: TypeLink, Linkname: "../evil"}}},
+	{
+		{hdr: Header{Name: "dot", Typeflag: TypeSymlink, Linkname: "."}},
+		{hdr: Header{Name: "link", Typeflag: TypeSymlink, Linkname: "dot/.."}},
+	},
+	{
+		{hdr: Header{Name: "sub", Typeflag: TypeSymlink, Linkname: "."}},
+		{hdr: Header{Name: "sub/evil", Typeflag: TypeReg, Size: 1}, data: "x"},
+	},
+}
+
+func writeEntries(t *testing.T, entries []extractTestEntry) *bytes.Reader {
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	for _, e := range entries {
+		hdr := e.hdr
+		hdr.Mode = 0644
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExtractInsecure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, entries := range insecureTests {
+		dst := filepath.Join(dir, "dst", "x")
+		err := NewReader(writeEntries(t, entries)).Extract(dst, nil)
+		if err != ErrInsecurePath {
+			t.Errorf("test %d: err = %v, want %v", i, err, ErrInsecurePath)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "dst", "evil")); err == nil {
+			t.Errorf("test %d: file created outside of the directory", i)
+		}
+		os.RemoveAll(filepath.Join(dir, "dst"))
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	entries := []extractTestEntry{
+		{hdr: Header{Name: "a", Typeflag: TypeReg, Size: 3}, data: "aaa"},
+		{hdr: Header{Name: "b", Typeflag: TypeReg, Size: 3}, data: "bbb"},
+	}
+	tests := []struct {
+		opts ExtractOptions
+		err  error
+	}{
+		{ExtractOptions{}, nil},
+		{ExtractOptions{MaxFiles: 2, MaxFileSize: 3, MaxTotalSize: 6}, nil},
+		{ExtractOptions{MaxFiles: 1}, ErrTooLarge},
+		{ExtractOptions{MaxFileSize: 2}, ErrTooLarge},
+		{ExtractOptions{MaxTotalSize: 5}, ErrTooLarge},
+	}
+	for i, test := range tests {
+		err := NewReader(writeEntries(t, entries)).Extract(filepath.Join(dir, "dst"), &test.opts)
+		if err != test.err {
+			t.Errorf("test %d: err = %v, want %v", i, err, test.err)
+		}
+		os.RemoveAll(filepath.Join(dir, "dst"))
+	}
+}
+
+// TestExtractNoDirEntries checks that Extract creates the parent
+// directories of entries when the archive does not list them.
+func TestExtractNoDirEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	entries := []extractTestEntry{
+		{hdr: Header{Name: "a/b/c.txt", Typeflag: TypeReg, Size: 3}, data: "ccc"},
+		{hdr: Header{Name: "d/link", Typeflag: TypeSymlink, Linkname: "../a/b/c.txt"}},
+		{hdr: Header{Name: "e/hard", Typeflag: TypeLink, Linkname: "a/b/c.txt"}},
+	}
+	if err := NewReader(writeEntries(t, entries)).Extract(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b/c.txt", "d/link", "e/hard"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(b) != "ccc" {
+			t.Errorf("%s: got %q, %v; want %q", name, b, err, "ccc")
+		}
+	}
+}
@@ -29,10 +29,11 @@ const maxNanoSecondIntSize = 9
 // The Next method advances to the next file in the archive (including the first),
 // and then it can be treated as an io.Reader to access the file's data.
 type Reader struct {
-	r   io.Reader
-	err error
-	nb  int64 // number of unread bytes for current file entry
-	pad int64 // amount of padding (ignored) after current file entry
+	r      io.Reader
+	err    error
+	nb     int64       // number of unread bytes for current file entry
+	pad    int64       // amount of padding (ignored) after current file entry
+	sparse *sparseFile // if non-nil, the current file entry is sparse
 }
 
 // NewReader creates a new Reader reading from r.
@@ -63,7 +64,16 @@ func (tr *Reader) Next() (*Header, error) {
 		// but this skips alignment padding
 		tr.skipUnread()
 		hdr = tr.readHeader()
-		mergePAX(hdr, headers)
+		if hdr == nil {
+			return nil, tr.err
+		}
+		if err := mergePAX(hdr, headers); err != nil {
+			return nil, err
+		}
+		// Check for a PAX format sparse file.
+		if err := tr.readPAXSparse(hdr, headers); err != nil {
+			return nil, err
+		}
 		return hdr, nil
 	case TypeGNULongName:
 		// We have a GNU long name header. Its contents are the real file name.
@@ -139,6 +149,21 @@ func mergePAX(hdr *Header, headers map[string]string) error {
 				return err
 			}
 			hdr.Size = int64(size)
+		case paxGNUSparseName:
+			hdr.Name = v
+		case paxGNUSparseSize, paxGNUSparseRealSize:
+			size, err := strconv.ParseInt(v, 10, 0)
+			if err != nil {
+				return err
+			}
+			hdr.Size = int64(size)
+		default:
+			if strings.HasPrefix(k, paxXattr) {
+				if hdr.Xattrs == nil {
+					hdr.Xattrs = make(map[string]string)
+				}
+				hdr.Xattrs[k[len(paxXattr):]] = v
+			}
 		}
 
 	}
@@ -189,6 +214,10 @@ func parsePAX(r io.Reader) (map[string]string, error) {
 		return nil, err
 	}
 	headers := make(map[string]string)
+	// The GNU 0.0 sparse format repeats the offset and numbytes
+	// records; they are collected into a GNU.sparse.map record
+	// as in the 0.1 format.
+	var sparseMap []string
 	// Each record is constructed as
 	//     "%d %s=%s\n", length, keyword, value
 	for len(buf) > 0 {
@@ -214,8 +243,18 @@ func parsePAX(r io.Reader) (map[string]string, error) {
 		if eq == -1 {
 			return nil, ErrHeader
 		}
-		key, value := record[:eq], record[eq+1:]
-		headers[string(key)] = string(value)
+		key, value := string(record[:eq]), string(record[eq+1:])
+		switch key {
+		case paxGNUSparseOffset, paxGNUSparseNumBytes:
+			// Offsets and sizes must alternate.
+			if (key == paxGNUSparseOffset) != (len(sparseMap)%2 == 0) {
+				return nil, ErrHeader
+			}
+			sparseMap = append(sparseMap, value)
+			headers[paxGNUSparseMap] = strings.Join(sparseMap, ",")
+		default:
+			headers[key] = value
+		}
 	}
 	return headers, nil
 }
@@ -264,6 +303,7 @@ func (tr *Reader) octal(b []byte) int64 {
 func (tr *Reader) skipUnread() {
 	nr := tr.nb + tr.pad // number of bytes to skip
 	tr.nb, tr.pad = 0, 0
+	tr.sparse = nil
 	if sr, ok := tr.r.(io.Seeker); ok {
 		if _, err := sr.Seek(nr, os.SEEK_CUR); err == nil {
 			return
@@ -370,13 +410,34 @@ func (tr *Reader) readHeader() *Header {
 	tr.nb = int64(hdr.Size)
 	tr.pad = -tr.nb & (blockSize - 1) // blockSize is a power of two
 
+	if hdr.Typeflag == TypeGNUSparse && format == "gnu" {
+		data := tr.readOldGNUSparseMap(header)
+		hdr.Size = tr.octal(header[483:495]) // real size of the file
+		if tr.err != nil {
+			return nil
+		}
+		if err := tr.setSparse(hdr, data); err != nil {
+			tr.err = err
+			return nil
+		}
+	}
+
 	return hdr
 }
 
 // Read reads from the current entry in the tar archive.
 // It returns 0, io.EOF when it reaches the end of that entry,
 // until Next is called to advance to the next entry.
+// The holes of a sparse file read as zeros.
 func (tr *Reader) Read(b []byte) (n int, err error) {
+	if tr.sparse != nil {
+		return tr.readSparse(b)
+	}
+	return tr.read(b)
+}
+
+// read reads the entry's data as stored in the archive.
+func (tr *Reader) read(b []byte) (n int, err error) {
 	if tr.nb == 0 {
 		// file consumed
 		return 0, io.EOF
@@ -54,6 +54,52 @@ var gnuTarTest = &untarTest{
 	},
 }
 
+var sparseTarTest = &untarTest{
+	file: "testdata/sparse-formats.tar", // one file in each GNU sparse format
+	headers: []*Header{
+		{
+			Name:        "sparse-gnu",
+			Mode:        0644,
+			Size:        200000,
+			ModTime:     time.Unix(1380000000, 0),
+			Typeflag:    TypeGNUSparse,
+			SparseHoles: sparseFormatsHoles,
+		},
+		{
+			Name:        "sparse-00",
+			Mode:        0644,
+			Size:        200000,
+			ModTime:     time.Unix(1380000000, 0),
+			Typeflag:    TypeGNUSparse,
+			SparseHoles: sparseFormatsHoles,
+		},
+		{
+			Name:        "sparse-01",
+			Mode:        0644,
+			Size:        200000,
+			ModTime:     time.Unix(1380000000, 0),
+			Typeflag:    TypeGNUSparse,
+			SparseHoles: sparseFormatsHoles,
+		},
+		{
+			Name:        "sparse-10",
+			Mode:        0644,
+			Size:        200000,
+			ModTime:     time.Unix(1380000000, 0),
+			Typeflag:    TypeGNUSparse,
+			SparseHoles: sparseFormatsHoles,
+		},
+	},
+	cksums: []string{
+		"917fb7e4a5d033abf050c5382e588170",
+		"917fb7e4a5d033abf050c5382e588170",
+		"917fb7e4a5d033abf050c5382e588170",
+		"917fb7e4a5d033abf050c5382e588170",
+	},
+}
+
+var sparseFormatsHoles = []SparseEntry{{4096, 61440}, {69632, 130368}}
+
 var untarTests = []*untarTest{
 	gnuTarTest,
 	{
@@ -161,6 +207,23 @@ var untarTests = []*untarTest{
 			},
 		},
 	},
+	sparseTarTest,
+	{
+		file: "testdata/xattrs.tar",
+		headers: []*Header{
+			{
+				Name:     "small.txt",
+				Mode:     0644,
+				Size:     7,
+				ModTime:  time.Unix(1380000000, 0),
+				Typeflag: TypeReg,
+				Xattrs: map[string]string{
+					"user.key":  "value",
+					"user.key2": "value2",
+				},
+			},
+		},
+	},
 }
 
 func TestReader(t *testing.T) {
@@ -180,7 +243,7 @@ testLoop:
 				f.Close()
 				continue testLoop
 			}
-			if *hdr != *header {
+			if !reflect.DeepEqual(*hdr, *header) {
 				t.Errorf("test %d, entry %d: Incorrect header:\nhave %+v\nwant %+v",
 					i, j, *hdr, *header)
 			}
@@ -232,7 +295,11 @@ func TestPartialRead(t *testing.T) {
 }
 
 func TestIncrementalRead(t *testing.T) {
-	test := gnuTarTest
+	testIncrementalRead(t, gnuTarTest)
+	testIncrementalRead(t, sparseTarTest)
+}
+
+func testIncrementalRead(t *testing.T, test *untarTest) {
 	f, err := os.Open(test.file)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -253,7 +320,7 @@ func TestIncrementalRead(t *testing.T) {
 		}
 
 		// check the header
-		if *hdr != *headers[nread] {
+		if !reflect.DeepEqual(*hdr, *headers[nread]) {
 			t.Errorf("Incorrect header:\nhave %+v\nwant %+v",
 				*hdr, headers[nread])
 		}
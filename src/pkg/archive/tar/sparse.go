@@ -0,0 +1,353 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tar
+
+// Sparse files are read in any of the formats written by GNU tar:
+// the old GNU format, where the sparse map is stored in the header
+// block and extension blocks following it, and the PAX formats 0.0,
+// 0.1 and 1.0. Sparse files are written in the PAX 1.0 format.
+//
+// References:
+//   http://www.gnu.org/software/tar/manual/html_node/Sparse-Formats.html
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var (
+	errSparseMap  = errors.New("archive/tar: invalid sparse map")
+	errSparseType = errors.New("archive/tar: sparse holes in a non-regular file")
+	errSparseHole = errors.New("archive/tar: non-zero data written to a sparse file hole")
+)
+
+const (
+	oldGNUSparseOffset     = 386 // offset of the sparse map in an old GNU header
+	oldGNUSparseEntries    = 4   // sparse map entries in an old GNU header
+	oldGNUSparseExtEntries = 21  // sparse map entries in an extension block
+	oldGNUSparseEntryLen   = 24  // two 12 byte octal fields
+)
+
+// sparseData returns the data fragments of a file of the given size
+// with the given holes. It returns errSparseMap if the holes are not
+// in increasing order or do not fit in the file.
+func sparseData(holes []SparseEntry, size int64) ([]SparseEntry, error) {
+	var data []SparseEntry
+	var pos int64
+	for _, h := range holes {
+		if h.Offset < pos || h.Length < 0 || h.Offset+h.Length > size {
+			return nil, errSparseMap
+		}
+		if h.Offset > pos {
+			data = append(data, SparseEntry{pos, h.Offset - pos})
+		}
+		pos = h.Offset + h.Length
+	}
+	// GNU tar always records the end of the file, even when it
+	// is a hole.
+	data = append(data, SparseEntry{pos, size - pos})
+	return data, nil
+}
+
+// sparseHoles returns the holes of a file of the given size with the
+// given data fragments. It returns errSparseMap if the fragments are
+// not in increasing order or do not fit in the file.
+func sparseHoles(data []SparseEntry, size int64) ([]SparseEntry, error) {
+	var holes []SparseEntry
+	var pos int64
+	for _, d := range data {
+		if d.Offset < pos || d.Length < 0 || d.Offset+d.Length > size {
+			return nil, errSparseMap
+		}
+		if d.Offset > pos {
+			holes = append(holes, SparseEntry{pos, d.Offset - pos})
+		}
+		pos = d.Offset + d.Length
+	}
+	if pos < size {
+		holes = append(holes, SparseEntry{pos, size - pos})
+	}
+	return holes, nil
+}
+
+// sparseFile tracks the position in a sparse file being read or written.
+type sparseFile struct {
+	data []SparseEntry // data fragments not yet fully read or written
+	pos  int64         // logical position in the file
+	size int64         // logical size of the file
+}
+
+// next returns the number of bytes, at most max, that can be read or
+// written from the current position before crossing a boundary between
+// data and a hole, and whether these bytes are in a hole.
+func (sp *sparseFile) next(max int) (n int, hole bool) {
+	for len(sp.data) > 0 && sp.data[0].Offset+sp.data[0].Length <= sp.pos {
+		sp.data = sp.data[1:]
+	}
+	end := sp.size
+	hole = true
+	if len(sp.data) > 0 {
+		d := sp.data[0]
+		if sp.pos >= d.Offset {
+			end = d.Offset + d.Length
+			hole = false
+		} else {
+			end = d.Offset
+		}
+	}
+	if int64(max) > end-sp.pos {
+		max = int(end - sp.pos)
+	}
+	return max, hole
+}
+
+// setSparse records that hdr is a sparse file made of the given data
+// fragments, which must add up to the unread bytes of the entry.
+func (tr *Reader) setSparse(hdr *Header, data []SparseEntry) error {
+	var n int64
+	for _, d := range data {
+		n += d.Length
+	}
+	holes, err := sparseHoles(data, hdr.Size)
+	if err != nil || n != tr.nb {
+		return ErrHeader
+	}
+	hdr.SparseHoles = holes
+	tr.sparse = &sparseFile{data: data, size: hdr.Size}
+	return nil
+}
+
+// readSparse reads from the current sparse file, filling holes with zeros.
+func (tr *Reader) readSparse(b []byte) (n int, err error) {
+	sp := tr.sparse
+	for n < len(b) && sp.pos < sp.size {
+		m, hole := sp.next(len(b) - n)
+		if hole {
+			for i := range b[n : n+m] {
+				b[n+i] = 0
+			}
+			n += m
+			sp.pos += int64(m)
+			continue
+		}
+		nr, err := tr.read(b[n : n+m])
+		n += nr
+		sp.pos += int64(nr)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			tr.err = err
+			return n, err
+		}
+	}
+	if n == 0 && len(b) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// readOldGNUSparseMap reads the sparse map of an old GNU format sparse
+// file from its header block and from the extension blocks following it.
+func (tr *Reader) readOldGNUSparseMap(header []byte) []SparseEntry {
+	var data []SparseEntry
+	parse := func(b []byte, n int) {
+		for i := 0; i < n; i++ {
+			s := slicer(b[i*oldGNUSparseEntryLen:])
+			if s[0] == 0 {
+				break // unused entry
+			}
+			offset := tr.octal(s.next(12))
+			length := tr.octal(s.next(12))
+			data = append(data, SparseEntry{offset, length})
+		}
+	}
+	parse(header[oldGNUSparseOffset:], oldGNUSparseEntries)
+	extended := header[oldGNUSparseOffset+oldGNUSparseEntries*oldGNUSparseEntryLen] != 0
+	for extended && tr.err == nil {
+		ext := make([]byte, blockSize)
+		if _, tr.err = io.ReadFull(tr.r, ext); tr.err != nil {
+			break
+		}
+		parse(ext, oldGNUSparseExtEntries)
+		extended = ext[oldGNUSparseExtEntries*oldGNUSparseEntryLen] != 0
+	}
+	return data
+}
+
+// readPAXSparse checks the PAX headers of hdr for a sparse file in the
+// GNU formats 0.0, 0.1 or 1.0 and prepares to read it.
+func (tr *Reader) readPAXSparse(hdr *Header, headers map[string]string) error {
+	var data []SparseEntry
+	var err error
+	if headers[paxGNUSparseMajor] == "1" && headers[paxGNUSparseMinor] == "0" {
+		data, err = tr.readGNUSparseMap1x0()
+	} else if m, ok := headers[paxGNUSparseMap]; ok {
+		data, err = parseGNUSparseMap(m)
+		if n, ok := headers[paxGNUSparseNumBlocks]; ok && err == nil {
+			if n != strconv.Itoa(len(data)) {
+				err = ErrHeader
+			}
+		}
+	} else {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tr.setSparse(hdr, data)
+}
+
+// parseGNUSparseMap parses the comma separated offset and length
+// pairs of a GNU.sparse.map PAX record.
+func parseGNUSparseMap(m string) ([]SparseEntry, error) {
+	if m == "" {
+		return nil, nil
+	}
+	f := strings.Split(m, ",")
+	if len(f)%2 != 0 {
+		return nil, ErrHeader
+	}
+	data := make([]SparseEntry, len(f)/2)
+	for i := range data {
+		offset, err1 := strconv.ParseInt(f[2*i], 10, 64)
+		length, err2 := strconv.ParseInt(f[2*i+1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, ErrHeader
+		}
+		data[i] = SparseEntry{offset, length}
+	}
+	return data, nil
+}
+
+// readGNUSparseMap1x0 reads the sparse map of a GNU 1.0 format sparse
+// file, which is stored in decimal, one number per line, at the start
+// of the entry's data and padded to a whole number of blocks.
+func (tr *Reader) readGNUSparseMap1x0() ([]SparseEntry, error) {
+	var buf []byte
+	block := make([]byte, blockSize)
+	nextField := func() (int64, error) {
+		for {
+			if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+				v, err := strconv.ParseInt(string(buf[:i]), 10, 64)
+				buf = buf[i+1:]
+				if err != nil {
+					return 0, ErrHeader
+				}
+				return v, nil
+			}
+			if _, err := io.ReadFull(tr, block); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			buf = append(buf, block...)
+		}
+	}
+	n, err := nextField()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > tr.nb {
+		return nil, ErrHeader
+	}
+	var data []SparseEntry
+	for i := int64(0); i < n; i++ {
+		offset, err := nextField()
+		if err != nil {
+			return nil, err
+		}
+		length, err := nextField()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, SparseEntry{offset, length})
+	}
+	return data, nil
+}
+
+// writeSparseHeader writes hdr, which has SparseHoles, in the GNU 1.0
+// sparse format and prepares to accept the file's contents.
+func (tw *Writer) writeSparseHeader(hdr *Header) error {
+	switch hdr.Typeflag {
+	case TypeReg, TypeRegA, TypeGNUSparse:
+	default:
+		return errSparseType
+	}
+	data, err := sparseData(hdr.SparseHoles, hdr.Size)
+	if err != nil {
+		return err
+	}
+
+	// The sparse map is stored at the start of the entry's data.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d\n", len(data))
+	size := int64(0)
+	for _, d := range data {
+		fmt.Fprintf(&buf, "%d\n%d\n", d.Offset, d.Length)
+		size += d.Length
+	}
+	buf.Write(zeroBlock[:-buf.Len()&(blockSize-1)])
+	size += int64(buf.Len())
+
+	// The real name and size are recorded in the PAX header, GNU tar
+	// style, so that other tars extract the file under a different
+	// name instead of writing the sparse map into it.
+	sh := *hdr
+	dir, file := path.Split(hdr.Name)
+	sh.Name = path.Join(dir, "GNUSparseFile.0", file)
+	sh.Size = size
+	sh.Typeflag = TypeReg
+	sh.SparseHoles = nil
+	paxHeaders := map[string]string{
+		paxGNUSparseMajor:    "1",
+		paxGNUSparseMinor:    "0",
+		paxGNUSparseName:     hdr.Name,
+		paxGNUSparseRealSize: strconv.FormatInt(hdr.Size, 10),
+	}
+	if err := tw.writeHeader(&sh, true, paxHeaders); err != nil {
+		return err
+	}
+	if _, err := tw.write(buf.Bytes()); err != nil {
+		return err
+	}
+	tw.sparse = &sparseFile{data: data, size: hdr.Size}
+	return nil
+}
+
+// writeSparse writes to the current sparse file. Bytes falling in holes
+// must be zero and are not stored.
+func (tw *Writer) writeSparse(b []byte) (n int, err error) {
+	sp := tw.sparse
+	for n < len(b) {
+		if sp.pos >= sp.size {
+			return n, ErrWriteTooLong
+		}
+		m, hole := sp.next(len(b) - n)
+		if hole {
+			for _, c := range b[n : n+m] {
+				if c != 0 {
+					return n, errSparseHole
+				}
+			}
+			n += m
+			sp.pos += int64(m)
+			continue
+		}
+		nw, err := tw.write(b[n : n+m])
+		n += nw
+		sp.pos += int64(nw)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
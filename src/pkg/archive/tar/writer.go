@@ -37,8 +37,9 @@ type Writer struct {
 	nb         int64 // number of unwritten bytes for current file entry
 	pad        int64 // amount of padding to write after current file entry
 	closed     bool
-	usedBinary bool // whether the binary numeric field extension was used
-	preferPax  bool // use pax header instead of binary numeric header
+	usedBinary bool        // whether the binary numeric field extension was used
+	preferPax  bool        // use pax header instead of binary numeric header
+	sparse     *sparseFile // if non-nil, the current file entry is sparse
 }
 
 // NewWriter creates a new Writer writing to w.
@@ -63,6 +64,7 @@ func (tw *Writer) Flush() error {
 	}
 	tw.nb = 0
 	tw.pad = 0
+	tw.sparse = nil
 	return tw.err
 }
 
@@ -133,8 +135,12 @@ var (
 // WriteHeader writes hdr and prepares to accept the file's contents.
 // WriteHeader calls Flush if it is not the first header.
 // Calling after a Close will return ErrWriteAfterClose.
+// If hdr has SparseHoles, the file is written in the GNU 1.0 sparse format.
 func (tw *Writer) WriteHeader(hdr *Header) error {
-	return tw.writeHeader(hdr, true)
+	if len(hdr.SparseHoles) > 0 {
+		return tw.writeSparseHeader(hdr)
+	}
+	return tw.writeHeader(hdr, true, nil)
 }
 
 // WriteHeader writes hdr and prepares to accept the file's contents.
@@ -142,7 +148,8 @@ func (tw *Writer) WriteHeader(hdr *Header) error {
 // Calling after a Close will return ErrWriteAfterClose.
 // As this method is called internally by writePax header to allow it to
 // suppress writing the pax header.
-func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
+// paxHeaders, if non-nil, holds additional pax header records to write.
+func (tw *Writer) writeHeader(hdr *Header, allowPax bool, paxHeaders map[string]string) error {
 	if tw.closed {
 		return ErrWriteAfterClose
 	}
@@ -154,7 +161,12 @@ func (tw *Writer) writeHeader(hdr *Header, allowPax bool) error {
 	}
 
 	// a map to hold pax header records, if any are needed
-	paxHeaders := make(map[string]string)
+	if paxHeaders == nil {
+		paxHeaders = make(map[string]string)
+	}
+	for k, v := range hdr.Xattrs {
+		paxHeaders[paxXattr+k] = v
+	}
 
 	// TODO(shanemhansen): we might want to use PAX headers for
 	// subsecond time resolution, but for now let's just capture
@@ -304,7 +316,7 @@ func (tw *Writer) writePAXHeader(hdr *Header, paxHeaders map[string]string) erro
 	}
 
 	ext.Size = int64(len(buf.Bytes()))
-	if err := tw.writeHeader(ext, false); err != nil {
+	if err := tw.writeHeader(ext, false, nil); err != nil {
 		return err
 	}
 	if _, err := tw.Write(buf.Bytes()); err != nil {
@@ -334,7 +346,16 @@ func paxHeader(msg string) string {
 // Write writes to the current entry in the tar archive.
 // Write returns the error ErrWriteTooLong if more than
 // hdr.Size bytes are written after WriteHeader.
+// The bytes of a sparse file falling in its holes must be zero.
 func (tw *Writer) Write(b []byte) (n int, err error) {
+	if tw.sparse != nil {
+		return tw.writeSparse(b)
+	}
+	return tw.write(b)
+}
+
+// write writes the entry's data as stored in the archive.
+func (tw *Writer) write(b []byte) (n int, err error) {
 	if tw.closed {
 		err = ErrWriteTooLong
 		return
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -391,3 +392,94 @@ func TestUSTARLongName(t *testing.T) {
 		t.Fatal("Couldn't recover long name")
 	}
 }
+
+func TestSparseWriter(t *testing.T) {
+	data := make([]byte, 20000)
+	copy(data[1000:], "sparse data")
+	copy(data[15000:], "more sparse data")
+	hdr := &Header{
+		Name:        "sparse",
+		Mode:        0644,
+		Size:        int64(len(data)),
+		Typeflag:    TypeReg,
+		SparseHoles: []SparseEntry{{0, 1000}, {1011, 13989}, {15016, 4984}},
+	}
+
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if buf.Len() >= len(data) {
+		t.Errorf("archive is %d bytes, want less than %d", buf.Len(), len(data))
+	}
+
+	tr := NewReader(&buf)
+	rhdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if rhdr.Name != hdr.Name || rhdr.Size != hdr.Size {
+		t.Errorf("got name %q, size %d; want %q, %d", rhdr.Name, rhdr.Size, hdr.Name, hdr.Size)
+	}
+	if !reflect.DeepEqual(rhdr.SparseHoles, hdr.SparseHoles) {
+		t.Errorf("SparseHoles = %v, want %v", rhdr.SparseHoles, hdr.SparseHoles)
+	}
+	rdata, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !bytes.Equal(rdata, data) {
+		t.Errorf("contents differ")
+	}
+}
+
+func TestSparseWriterHoleData(t *testing.T) {
+	tw := NewWriter(ioutil.Discard)
+	hdr := &Header{
+		Name:        "sparse",
+		Size:        10,
+		Typeflag:    TypeReg,
+		SparseHoles: []SparseEntry{{5, 5}},
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if _, err := tw.Write([]byte("0123456789")); err != errSparseHole {
+		t.Errorf("Write: got %v, want %v", err, errSparseHole)
+	}
+}
+
+func TestXattrs(t *testing.T) {
+	hdr := &Header{
+		Name:     "file",
+		Mode:     0644,
+		Typeflag: TypeReg,
+		ModTime:  time.Unix(1380000000, 0),
+		Xattrs: map[string]string{
+			"user.key":         "value",
+			"security.selinux": "unconfined_u:object_r:default_t:s0\x00",
+		},
+	}
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	rhdr, err := NewReader(&buf).Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if !reflect.DeepEqual(rhdr, hdr) {
+		t.Errorf("got %+v, want %+v", rhdr, hdr)
+	}
+}
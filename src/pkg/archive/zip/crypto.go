@@ -0,0 +1,332 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zip
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ErrPassword is returned when opening an encrypted file without a
+// password or with a wrong one.
+var ErrPassword = errors.New("zip: missing or invalid password")
+
+// An EncryptionMethod selects how Writer.CreateEncrypted encrypts a file.
+type EncryptionMethod int
+
+const (
+	// ZipCrypto is the traditional PKWARE encryption. It is weak and
+	// should only be used for compatibility with old tools.
+	ZipCrypto EncryptionMethod = iota + 1
+
+	// AES128, AES192 and AES256 are the WinZip AES encryption methods
+	// (AE-2), with a key of 128, 192 or 256 bits.
+	AES128
+	AES192
+	AES256
+)
+
+const (
+	encryptedFlag = 0x1 // general purpose flag: file is encrypted
+
+	winzipAESMethod   = 99     // Method of WinZip AES encrypted files
+	winzipAESExtraId  = 0x9901 // WinZip AES extra field
+	winzipAESExtraLen = 7      // extra field data size
+
+	zipCryptoHeaderLen = 12 // encryption header of ZipCrypto files
+	aesVerifierLen     = 2  // password verification value
+	aesMACLen          = 10 // truncated HMAC-SHA1 authentication code
+	aesIterations      = 1000
+)
+
+// IsEncrypted reports whether the file is encrypted.
+func (h *FileHeader) IsEncrypted() bool {
+	return h.Flags&encryptedFlag != 0
+}
+
+// aesKeyLen returns the key length in bytes for a WinZip AES strength
+// value, or 0 if the strength is unknown.
+func aesKeyLen(strength byte) int {
+	switch strength {
+	case 1:
+		return 16
+	case 2:
+		return 24
+	case 3:
+		return 32
+	}
+	return 0
+}
+
+// aesSaltLen returns the salt length for the given key length.
+func aesSaltLen(keyLen int) int {
+	return keyLen / 2
+}
+
+// aesKeys derives the encryption key, the authentication key and the
+// password verification value from password and salt.
+func aesKeys(password, salt []byte, keyLen int) (encKey, authKey, verifier []byte) {
+	k := pbkdf2.Key(password, salt, aesIterations, 2*keyLen+aesVerifierLen, sha1.New)
+	return k[:keyLen], k[keyLen : 2*keyLen], k[2*keyLen:]
+}
+
+// aesCTR is the counter mode used by WinZip AES: the counter is a
+// little-endian integer starting at 1.
+type aesCTR struct {
+	b    cipher.Block
+	ctr  [aes.BlockSize]byte
+	out  [aes.BlockSize]byte
+	used int
+}
+
+func newAESCTR(key []byte) (*aesCTR, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesCTR{b: b, used: aes.BlockSize}, nil
+}
+
+func (x *aesCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if x.used == len(x.out) {
+			for j := range x.ctr {
+				x.ctr[j]++
+				if x.ctr[j] != 0 {
+					break
+				}
+			}
+			x.b.Encrypt(x.out[:], x.ctr[:])
+			x.used = 0
+		}
+		dst[i] = src[i] ^ x.out[x.used]
+		x.used++
+	}
+}
+
+// aesReader decrypts the data of a WinZip AES file and verifies its
+// authentication code when the data is exhausted.
+type aesReader struct {
+	r      io.Reader
+	stream cipher.Stream
+	mac    hash.Hash
+	macr   io.Reader // where to read the authentication code
+	err    error     // sticky error
+}
+
+// newAESReader returns a Reader decrypting the size bytes of r, which
+// hold the salt, the password verifier, the encrypted data and the
+// authentication code of a file.
+func newAESReader(r io.ReaderAt, size int64, password []byte, strength byte) (io.Reader, error) {
+	keyLen := aesKeyLen(strength)
+	if keyLen == 0 {
+		return nil, ErrAlgorithm
+	}
+	saltLen := aesSaltLen(keyLen)
+	dataLen := size - int64(saltLen+aesVerifierLen+aesMACLen)
+	if dataLen < 0 {
+		return nil, ErrFormat
+	}
+	buf := make([]byte, saltLen+aesVerifierLen)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+	encKey, authKey, verifier := aesKeys(password, buf[:saltLen], keyLen)
+	if !hmac.Equal(verifier, buf[saltLen:]) {
+		return nil, ErrPassword
+	}
+	stream, err := newAESCTR(encKey)
+	if err != nil {
+		return nil, err
+	}
+	off := int64(len(buf))
+	return &aesReader{
+		r:      io.NewSectionReader(r, off, dataLen),
+		stream: stream,
+		mac:    hmac.New(sha1.New, authKey),
+		macr:   io.NewSectionReader(r, off+dataLen, aesMACLen),
+	}, nil
+}
+
+func (r *aesReader) Read(b []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err = r.r.Read(b)
+	r.mac.Write(b[:n])
+	r.stream.XORKeyStream(b[:n], b[:n])
+	if err == io.EOF {
+		var code [aesMACLen]byte
+		if _, err1 := io.ReadFull(r.macr, code[:]); err1 != nil {
+			err = err1
+		} else if !hmac.Equal(r.mac.Sum(nil)[:aesMACLen], code[:]) {
+			err = ErrChecksum
+		}
+	}
+	r.err = err
+	return
+}
+
+// aesWriter encrypts the data of a WinZip AES file and appends its
+// authentication code on Close.
+type aesWriter struct {
+	w      io.Writer
+	stream cipher.Stream
+	mac    hash.Hash
+	buf    []byte
+}
+
+// newAESWriter writes the salt and password verifier for a new file to
+// w and returns a WriteCloser encrypting the file's data.
+func newAESWriter(w io.Writer, password []byte, strength byte) (io.WriteCloser, error) {
+	keyLen := aesKeyLen(strength)
+	salt := make([]byte, aesSaltLen(keyLen))
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	encKey, authKey, verifier := aesKeys(password, salt, keyLen)
+	stream, err := newAESCTR(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(salt); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(verifier); err != nil {
+		return nil, err
+	}
+	return &aesWriter{w: w, stream: stream, mac: hmac.New(sha1.New, authKey)}, nil
+}
+
+func (w *aesWriter) Write(p []byte) (int, error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+	buf := w.buf[:len(p)]
+	w.stream.XORKeyStream(buf, p)
+	w.mac.Write(buf)
+	return w.w.Write(buf)
+}
+
+func (w *aesWriter) Close() error {
+	_, err := w.w.Write(w.mac.Sum(nil)[:aesMACLen])
+	return err
+}
+
+// zipCryptoKeys holds the state of the traditional PKWARE cipher.
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password []byte) *zipCryptoKeys {
+	k := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for _, c := range password {
+		k.update(c)
+	}
+	return k
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+func (k *zipCryptoKeys) update(c byte) {
+	k[0] = crc32Update(k[0], c)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) streamByte() byte {
+	t := uint16(k[2] | 2)
+	return byte(t * (t ^ 1) >> 8)
+}
+
+func (k *zipCryptoKeys) decrypt(b []byte) {
+	for i, c := range b {
+		c ^= k.streamByte()
+		k.update(c)
+		b[i] = c
+	}
+}
+
+func (k *zipCryptoKeys) encrypt(dst, src []byte) {
+	for i, c := range src {
+		t := k.streamByte()
+		k.update(c)
+		dst[i] = c ^ t
+	}
+}
+
+// zipCryptoReader decrypts the data of a ZipCrypto file.
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+// newZipCryptoReader reads the encryption header from r and returns a
+// Reader decrypting the rest of the file's data. check is the expected
+// value of the last byte of the decrypted header.
+func newZipCryptoReader(r io.Reader, password []byte, check byte) (io.Reader, error) {
+	var hdr [zipCryptoHeaderLen]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	keys := newZipCryptoKeys(password)
+	keys.decrypt(hdr[:])
+	if hdr[zipCryptoHeaderLen-1] != check {
+		return nil, ErrPassword
+	}
+	return &zipCryptoReader{r, keys}, nil
+}
+
+func (r *zipCryptoReader) Read(b []byte) (n int, err error) {
+	n, err = r.r.Read(b)
+	r.keys.decrypt(b[:n])
+	return
+}
+
+// zipCryptoWriter encrypts the data of a ZipCrypto file.
+type zipCryptoWriter struct {
+	w    io.Writer
+	keys *zipCryptoKeys
+	buf  []byte
+}
+
+// newZipCryptoWriter writes a random encryption header ending with
+// check to w and returns a WriteCloser encrypting the file's data.
+func newZipCryptoWriter(w io.Writer, password []byte, check byte) (io.WriteCloser, error) {
+	var hdr [zipCryptoHeaderLen]byte
+	if _, err := io.ReadFull(rand.Reader, hdr[:zipCryptoHeaderLen-1]); err != nil {
+		return nil, err
+	}
+	hdr[zipCryptoHeaderLen-1] = check
+	zw := &zipCryptoWriter{w: w, keys: newZipCryptoKeys(password)}
+	if _, err := zw.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return zw, nil
+}
+
+func (w *zipCryptoWriter) Write(p []byte) (int, error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+	buf := w.buf[:len(p)]
+	w.keys.encrypt(buf, p)
+	return w.w.Write(buf)
+}
+
+func (w *zipCryptoWriter) Close() error {
+	return nil
+}
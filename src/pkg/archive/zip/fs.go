@@ -0,0 +1,328 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zip
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInsecurePath = errors.New("zip: insecure file path")
+	ErrTooLarge     = errors.New("zip: extraction limit exceeded")
+)
+
+// maxLinkLen is the longest symbolic link target Extract accepts.
+const maxLinkLen = 4096
+
+// AddDir adds the directory tree rooted at dir to the archive.
+// Files are named by their slash-separated path relative to dir.
+// Regular files, directories and symbolic links are archived with
+// their modes and modification times; symbolic links are not followed
+// and store their target as the file's contents, as Info-ZIP does.
+// Other files are skipped and dir itself is not archived.
+func (w *Writer) AddDir(dir string) error {
+	return filepath.Walk(dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		mode := fi.Mode()
+		if rel == "." || !mode.IsRegular() && !mode.IsDir() && mode&os.ModeSymlink == 0 {
+			return nil
+		}
+		fh, err := FileInfoHeader(fi)
+		if err != nil {
+			return err
+		}
+		fh.Name = filepath.ToSlash(rel)
+		if mode.IsRegular() {
+			fh.Method = Deflate
+		} else {
+			fh.UncompressedSize, fh.UncompressedSize64 = 0, 0
+		}
+		if mode.IsDir() {
+			fh.Name += "/"
+		}
+		fw, err := w.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		switch {
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(fw, filepath.ToSlash(link))
+			return err
+		case mode.IsRegular():
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.CopyN(fw, f, fi.Size())
+			return err
+		}
+		return nil
+	})
+}
+
+// ExtractOptions limits the resources used by Reader.Extract.
+// A zero value means no limit.
+type ExtractOptions struct {
+	MaxFiles     int   // number of entries
+	MaxFileSize  int64 // size of each file
+	MaxTotalSize int64 // total size of all files
+}
+
+// Extract extracts the files of the archive into the directory dir,
+// which is created if needed.
+//
+// Files are only ever created inside dir: Extract returns
+// ErrInsecurePath for names that are absolute or contain "..",
+// for names reaching through a symbolic link, and for symbolic links
+// pointing outside dir. It returns ErrTooLarge if the archive exceeds
+// one of the limits in opts, which may be nil. The limits apply to the
+// decompressed data, whatever sizes the archive records.
+//
+// Regular files, directories and symbolic links are extracted with
+// their permission bits and modification times. Other files and the
+// setuid, setgid and sticky bits are ignored. Existing files are
+// replaced, existing directories are kept.
+func (r *Reader) Extract(dir string, opts *ExtractOptions) error {
+	if opts == nil {
+		opts = new(ExtractOptions)
+	}
+	if opts.MaxFiles > 0 && len(r.File) > opts.MaxFiles {
+		return ErrTooLarge
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	var (
+		dirs  []*File // set modes and times once they are filled
+		total int64
+	)
+	for _, f := range r.File {
+		name, err := localPath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if fi, err := os.Lstat(name); err == nil && !fi.IsDir() {
+				if err := os.Remove(name); err != nil {
+					return err
+				}
+			}
+			if err := os.MkdirAll(name, 0777); err != nil {
+				return err
+			}
+			dirs = append(dirs, f)
+			continue
+		case mode&os.ModeSymlink != 0:
+			link, err := readLink(f)
+			if err != nil {
+				return err
+			}
+			if !localLink(f.Name, link) {
+				return ErrInsecurePath
+			}
+			if err := prepareFile(name); err != nil {
+				return err
+			}
+			if err := os.Symlink(filepath.FromSlash(link), name); err != nil {
+				return err
+			}
+			continue
+		case mode&os.ModeType == 0:
+			if err := prepareFile(name); err != nil {
+				return err
+			}
+			n, err := extractFile(name, f, opts, total)
+			total += n
+			if err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		if err := setAttrs(name, f); err != nil {
+			return err
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		name, _ := localPath(dir, dirs[i].Name)
+		if err := setAttrs(name, dirs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes the contents of f to a new file called name.
+// total is the size of the files already extracted. It returns the
+// number of bytes written.
+func extractFile(name string, f *File, opts *ExtractOptions, total int64) (int64, error) {
+	// The recorded size cannot be trusted, so the limit is enforced
+	// on the data actually decompressed.
+	limit := int64(-1)
+	if opts.MaxFileSize > 0 {
+		limit = opts.MaxFileSize
+	}
+	if opts.MaxTotalSize > 0 && (limit < 0 || opts.MaxTotalSize-total < limit) {
+		limit = opts.MaxTotalSize - total
+	}
+	if limit >= 0 && f.UncompressedSize64 > uint64(limit) {
+		return 0, ErrTooLarge
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	var r io.Reader = rc
+	if limit >= 0 {
+		r = io.LimitReader(rc, limit+1)
+	}
+	w, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, r)
+	if err1 := w.Close(); err == nil {
+		err = err1
+	}
+	if err == nil && limit >= 0 && n > limit {
+		err = ErrTooLarge
+	}
+	return n, err
+}
+
+// readLink returns the target of the symbolic link f.
+func readLink(f *File) (string, error) {
+	if f.UncompressedSize64 > maxLinkLen {
+		return "", ErrInsecurePath
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(rc, maxLinkLen+1))
+	if err != nil {
+		return "", err
+	}
+	if len(b) > maxLinkLen {
+		return "", ErrInsecurePath
+	}
+	return string(b), nil
+}
+
+// setAttrs sets the permission bits and modification time of the file
+// name from f. Entries without permission bits keep the default ones,
+// and entries without a modification time keep their times.
+func setAttrs(name string, f *File) error {
+	if perm := f.Mode().Perm(); perm != 0 {
+		if err := os.Chmod(name, perm); err != nil {
+			return err
+		}
+	}
+	t := f.ModTime()
+	if t.IsZero() {
+		return nil
+	}
+	return os.Chtimes(name, t, t)
+}
+
+// localPath returns the path under dir of the archived file name.
+// It returns ErrInsecurePath if name is not a relative path inside dir
+// or if one of its parent directories is a symbolic link.
+func localPath(dir, name string) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" || path.IsAbs(name) || os.PathSeparator == '\\' && strings.ContainsAny(name, `\:`) {
+		return "", ErrInsecurePath
+	}
+	name = path.Clean(name)
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", ErrInsecurePath
+	}
+	elems := strings.Split(name, "/")
+	p := dir
+	for _, e := range elems[:len(elems)-1] {
+		p = filepath.Join(p, e)
+		fi, err := os.Lstat(p)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", ErrInsecurePath
+		}
+	}
+	return filepath.Join(dir, filepath.FromSlash(name)), nil
+}
+
+// localLink reports whether the target of the symbolic link name stays
+// inside the extraction directory. The target must be relative and
+// may only use ".." as leading elements, so that they are resolved
+// from the directory containing the link and not from the target of
+// another link.
+func localLink(name, target string) bool {
+	if target == "" || path.IsAbs(target) || os.PathSeparator == '\\' && strings.ContainsAny(target, `\:`) {
+		return false
+	}
+	depth := strings.Count(path.Clean(strings.TrimSuffix(name, "/")), "/")
+	up := true
+	for _, e := range strings.Split(target, "/") {
+		switch e {
+		case "", ".":
+		case "..":
+			if !up || depth == 0 {
+				return false
+			}
+			depth--
+		default:
+			up = false
+		}
+	}
+	return true
+}
+
+// prepareFile makes way for a new file called name, returned by
+// localPath. It creates the missing parent directories, which archives
+// need not list, and removes the file if it exists, so that the new
+// file can be created without following a symbolic link left in its
+// place.
+func prepareFile(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	fi, err := os.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return &os.PathError{Op: "extract", Path: name, Err: errors.New("is a directory")}
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,259 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zip
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddDirExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	mtime := time.Date(2013, 2, 4, 17, 46, 40, 0, time.UTC)
+	files := []struct {
+		name string
+		mode os.FileMode
+		data string
+	}{
+		{"", os.ModeDir | 0777, ""},
+		{"sub", os.ModeDir | 0750, ""},
+		{"sub/b.txt", 0600, "bbb\n"},
+		{"a.txt", 0644, "Hello, world.\n"},
+		{"ro", os.ModeDir | 0555, ""},
+		{"link", os.ModeSymlink | 0777, "sub/b.txt"},
+	}
+	for _, f := range files {
+		name := filepath.Join(src, f.name)
+		switch {
+		case f.mode.IsDir():
+			err = os.Mkdir(name, 0777)
+		case f.mode&os.ModeSymlink != 0:
+			err = os.Symlink(f.data, name)
+		default:
+			err = ioutil.WriteFile(name, []byte(f.data), 0666)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := len(files) - 1; i > 0; i-- {
+		if f := files[i]; f.mode&os.ModeSymlink == 0 {
+			name := filepath.Join(src, f.name)
+			os.Chmod(name, f.mode.Perm())
+			os.Chtimes(name, mtime, mtime)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.AddDir(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != len(files)-1 {
+		t.Fatalf("archived %d files, want %d", len(r.File), len(files)-1)
+	}
+	if err := r.Extract(dst, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files[1:] {
+		name := filepath.Join(dst, f.name)
+		fi, err := os.Lstat(name)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if fi.Mode() != f.mode {
+			t.Errorf("%s: mode = %v, want %v", f.name, fi.Mode(), f.mode)
+		}
+		switch {
+		case f.mode&os.ModeSymlink != 0:
+			if link, _ := os.Readlink(name); link != f.data {
+				t.Errorf("%s: link = %q, want %q", f.name, link, f.data)
+			}
+			continue
+		case f.mode.IsRegular():
+			if b, _ := ioutil.ReadFile(name); string(b) != f.data {
+				t.Errorf("%s: contents = %q, want %q", f.name, b, f.data)
+			}
+		}
+		if !fi.ModTime().Equal(mtime) {
+			t.Errorf("%s: mtime = %v, want %v", f.name, fi.ModTime(), mtime)
+		}
+	}
+}
+
+type extractTestFile struct {
+	name string
+	mode os.FileMode
+	data string
+}
+
+func writeFiles(t *testing.T, files []extractTestFile) *Reader {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, f := range files {
+		fh := &FileHeader{Name: f.name}
+		fh.SetMode(f.mode)
+		fw, err := w.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+var insecureTests = [][]extractTestFile{
+	{{"../evil", 0644, "x"}},
+	{{"a/../../evil", 0644, "x"}},
+	{{"/evil", 0644, "x"}},
+	{{"link", os.ModeSymlink | 0777, "../evil"}},
+	{{"link", os.ModeSymlink | 0777, "/etc"}},
+	{
+		{"dot", os.ModeSymlink | 0777, "."},
+		{"link", os.ModeSymlink | 0777, "dot/.."},
+	},
+	{
+		{"sub", os.ModeSymlink | 0777, "."},
+		{"sub/evil", 0644, "x"},
+	},
+}
+
+func TestExtractInsecure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for i, files := range insecureTests {
+		dst := filepath.Join(dir, "dst", "x")
+		err := writeFiles(t, files).Extract(dst, nil)
+		if err != ErrInsecurePath {
+			t.Errorf("test %d: err = %v, want %v", i, err, ErrInsecurePath)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "dst", "evil")); err == nil {
+			t.Errorf("test %d: file created outside of the directory", i)
+		}
+		os.RemoveAll(filepath.Join(dir, "dst"))
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []extractTestFile{
+		{"a", 0644, "aaa"},
+		{"b", 0644, "bbb"},
+	}
+	tests := []struct {
+		opts ExtractOptions
+		err  error
+	}{
+		{ExtractOptions{}, nil},
+		{ExtractOptions{MaxFiles: 2, MaxFileSize: 3, MaxTotalSize: 6}, nil},
+		{ExtractOptions{MaxFiles: 1}, ErrTooLarge},
+		{ExtractOptions{MaxFileSize: 2}, ErrTooLarge},
+		{ExtractOptions{MaxTotalSize: 5}, ErrTooLarge},
+	}
+	for i, test := range tests {
+		err := writeFiles(t, files).Extract(filepath.Join(dir, "dst"), &test.opts)
+		if err != test.err {
+			t.Errorf("test %d: err = %v, want %v", i, err, test.err)
+		}
+		os.RemoveAll(filepath.Join(dir, "dst"))
+	}
+}
+
+// TestExtractBomb checks that the limits apply to the decompressed data
+// when the archive lies about the size of a file.
+func TestExtractBomb(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var data bytes.Buffer
+	fw, _ := flate.NewWriter(&data, flate.BestCompression)
+	fw.Write(make([]byte, 1<<20))
+	fw.Close()
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	fh := &FileHeader{
+		Name:               "bomb",
+		Method:             Deflate,
+		CompressedSize64:   uint64(data.Len()),
+		UncompressedSize64: 10,
+	}
+	raw, err := w.CreateRaw(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Write(data.Bytes())
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Extract(dir, &ExtractOptions{MaxFileSize: 1000})
+	if err != ErrTooLarge {
+		t.Errorf("err = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+// TestExtractNoDirEntries checks that Extract creates the parent
+// directories of files when the archive does not list them.
+func TestExtractNoDirEntries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zip-fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := []extractTestFile{
+		{"a/b/c.txt", 0644, "ccc"},
+		{"d/link", os.ModeSymlink | 0777, "../a/b/c.txt"},
+	}
+	if err := writeFiles(t, files).Extract(dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b/c.txt", "d/link"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(b) != "ccc" {
+			t.Errorf("%s: got %q, %v; want %q", name, b, err, "ccc")
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"hash"
 	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
 )
 
@@ -21,9 +22,14 @@ var (
 )
 
 type Reader struct {
-	r       io.ReaderAt
-	File    []*File
-	Comment string
+	r         io.ReaderAt
+	File      []*File
+	Comment   string
+	dirOffset int64 // offset of the central directory
+
+	// Password, if non-nil, is called by File.Open to obtain the
+	// password of an encrypted file.
+	Password func(f *File) (string, error)
 }
 
 type ReadCloser struct {
@@ -33,9 +39,15 @@ type ReadCloser struct {
 
 type File struct {
 	FileHeader
+	zip          *Reader
 	zipr         io.ReaderAt
 	zipsize      int64
 	headerOffset int64
+
+	// WinZip AES encryption parameters, from the extra field.
+	aesVersion  uint16
+	aesStrength byte
+	aesMethod   uint16
 }
 
 func (f *File) hasDataDescriptor() bool {
@@ -78,6 +90,7 @@ func (z *Reader) init(r io.ReaderAt, size int64) error {
 		return err
 	}
 	z.r = r
+	z.dirOffset = int64(end.directoryOffset)
 	z.File = make([]*File, 0, end.directoryRecords)
 	z.Comment = end.comment
 	rs := io.NewSectionReader(r, 0, size)
@@ -91,7 +104,7 @@ func (z *Reader) init(r io.ReaderAt, size int64) error {
 	// a bad one, and then only report a ErrFormat or UnexpectedEOF if
 	// the file count modulo 65536 is incorrect.
 	for {
-		f := &File{zipr: r, zipsize: size}
+		f := &File{zip: z, zipr: r, zipsize: size}
 		err = readDirectoryHeader(f, buf)
 		if err == ErrFormat || err == io.ErrUnexpectedEOF {
 			break
@@ -127,6 +140,18 @@ func (f *File) DataOffset() (offset int64, err error) {
 	return f.headerOffset + bodyOffset, nil
 }
 
+// OpenRaw returns a Reader that provides access to the File's
+// contents without decompressing them or verifying their checksum.
+// The returned data is exactly CompressedSize64 bytes long and may be
+// passed, together with the FileHeader, to Writer.CreateRaw.
+func (f *File) OpenRaw() (io.Reader, error) {
+	offset, err := f.DataOffset()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(f.zipr, offset, int64(f.CompressedSize64)), nil
+}
+
 // Open returns a ReadCloser that provides access to the File's contents.
 // Multiple files may be read concurrently.
 func (f *File) Open() (rc io.ReadCloser, err error) {
@@ -135,27 +160,69 @@ func (f *File) Open() (rc io.ReadCloser, err error) {
 		return
 	}
 	size := int64(f.CompressedSize64)
-	r := io.NewSectionReader(f.zipr, f.headerOffset+bodyOffset, size)
-	dcomp := decompressor(f.Method)
+	sr := io.NewSectionReader(f.zipr, f.headerOffset+bodyOffset, size)
+	var r io.Reader = sr
+	method := f.Method
+	if method == winzipAESMethod {
+		method = f.aesMethod
+	}
+	dcomp := decompressor(method)
 	if dcomp == nil {
 		err = ErrAlgorithm
 		return
 	}
+	var auth io.Reader
+	if f.IsEncrypted() {
+		if r, err = f.decrypt(sr, size); err != nil {
+			return
+		}
+		if f.Method == winzipAESMethod {
+			auth = r
+		}
+	}
 	rc = dcomp(r)
 	var desr io.Reader
 	if f.hasDataDescriptor() {
 		desr = io.NewSectionReader(f.zipr, f.headerOffset+bodyOffset+size, dataDescriptorLen)
 	}
-	rc = &checksumReader{rc, crc32.NewIEEE(), f, desr, nil}
+	// AE-2 files store no CRC-32; their authentication code is
+	// verified by the decrypting reader instead.
+	noCRC := f.Method == winzipAESMethod && f.aesVersion == 2
+	rc = &checksumReader{rc, crc32.NewIEEE(), f, desr, auth, noCRC, nil}
 	return
 }
 
+// decrypt returns a Reader decrypting the size bytes of encrypted
+// file data read from r.
+func (f *File) decrypt(r *io.SectionReader, size int64) (io.Reader, error) {
+	if f.zip == nil || f.zip.Password == nil {
+		return nil, ErrPassword
+	}
+	password, err := f.zip.Password(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Method == winzipAESMethod {
+		return newAESReader(r, size, []byte(password), f.aesStrength)
+	}
+	// The last byte of the encryption header is the high byte of the
+	// CRC-32, or of the modification time if the CRC-32 is stored in a
+	// data descriptor following the file.
+	check := byte(f.CRC32 >> 24)
+	if f.hasDataDescriptor() {
+		check = byte(f.ModifiedTime >> 8)
+	}
+	return newZipCryptoReader(r, []byte(password), check)
+}
+
 type checksumReader struct {
-	rc   io.ReadCloser
-	hash hash.Hash32
-	f    *File
-	desr io.Reader // if non-nil, where to read the data descriptor
-	err  error     // sticky error
+	rc    io.ReadCloser
+	hash  hash.Hash32
+	f     *File
+	desr  io.Reader // if non-nil, where to read the data descriptor
+	auth  io.Reader // if non-nil, the reader authenticating the data
+	noCRC bool      // the file has no CRC-32 to verify
+	err   error     // sticky error
 }
 
 func (r *checksumReader) Read(b []byte) (n int, err error) {
@@ -167,7 +234,15 @@ func (r *checksumReader) Read(b []byte) (n int, err error) {
 	if err == nil {
 		return
 	}
-	if err == io.EOF {
+	if err == io.EOF && r.auth != nil {
+		// The decompressor may stop short of the end of the
+		// encrypted data, whose authentication code is only
+		// verified once all of it has been read.
+		if _, err1 := io.Copy(ioutil.Discard, r.auth); err1 != nil {
+			err = err1
+		}
+	}
+	if err == io.EOF && !r.noCRC {
 		if r.desr != nil {
 			if err1 := readDataDescriptor(r.desr, r.f); err1 != nil {
 				err = err1
@@ -251,7 +326,8 @@ func readDirectoryHeader(f *File, r io.Reader) error {
 			if int(size) > len(b) {
 				return ErrFormat
 			}
-			if tag == zip64ExtraId {
+			switch tag {
+			case zip64ExtraId:
 				// update directory values from the zip64 extra block
 				eb := readBuf(b)
 				if len(eb) >= 8 {
@@ -263,6 +339,16 @@ func readDirectoryHeader(f *File, r io.Reader) error {
 				if len(eb) >= 8 {
 					f.headerOffset = int64(eb.uint64())
 				}
+			case winzipAESExtraId:
+				if size < winzipAESExtraLen {
+					return ErrFormat
+				}
+				eb := readBuf(b[:size])
+				f.aesVersion = eb.uint16()
+				eb = eb[2:] // skip vendor ID
+				f.aesStrength = eb[0]
+				eb = eb[1:]
+				f.aesMethod = eb.uint16()
 			}
 			b = b[size:]
 		}
@@ -386,6 +386,107 @@ func readTestFile(t *testing.T, zt ZipTest, ft ZipTestFile, f *File) {
 	}
 }
 
+func TestStreamReader(t *testing.T) {
+	for _, zt := range tests {
+		if zt.Source != nil || zt.Error != nil || zt.File == nil {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join("testdata", zt.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// hide the ReaderAt implementation of bytes.Reader
+		r := NewStreamReader(struct{ io.Reader }{bytes.NewReader(b)})
+		var headers []*FileHeader
+		for _, ft := range zt.File {
+			fh, err := r.Next()
+			if err != nil {
+				t.Fatalf("%s: Next: %v", zt.Name, err)
+			}
+			headers = append(headers, fh)
+			if fh.Name != ft.Name {
+				t.Errorf("%s: name=%q, want %q", zt.Name, fh.Name, ft.Name)
+			}
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Errorf("%s: %s: reading: %v", zt.Name, fh.Name, err)
+				continue
+			}
+			want := ft.Content
+			if want == nil {
+				if want, err = ioutil.ReadFile("testdata/" + ft.File); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: %s: contents differ", zt.Name, fh.Name)
+			}
+		}
+		if fh, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: Next at end = %v, %v; want io.EOF", zt.Name, fh, err)
+			continue
+		}
+		for i, ft := range zt.File {
+			if mode := headers[i].Mode(); mode != ft.Mode {
+				t.Errorf("%s: %s mode: want %v, got %v", zt.Name, ft.Name, ft.Mode, mode)
+			}
+		}
+	}
+}
+
+func TestStreamReaderSkip(t *testing.T) {
+	// write files of all kinds without reading them
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	for _, method := range []uint16{Store, Deflate} {
+		fw, err := w.CreateHeader(&FileHeader{Name: "skipped", Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, "PK\x07\x08 looks like a data descriptor")
+	}
+	fw, err := w.Create("last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(fw, "last file")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewStreamReader(buf)
+	for i := 0; i < 3; i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+	}
+	if b, err := ioutil.ReadAll(r); err != nil || string(b) != "last file" {
+		t.Errorf("reading last file: got %q, %v", b, err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next at end: got %v, want io.EOF", err)
+	}
+}
+
+func TestStreamReaderCorruptDirectory(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/go-with-datadesc-sig.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename the first file in the central directory only
+	i := bytes.LastIndex(b, []byte("foo.txt"))
+	b[i] = 'g'
+	r := NewStreamReader(bytes.NewBuffer(b))
+	for {
+		if _, err = r.Next(); err != nil {
+			break
+		}
+	}
+	if err != ErrFormat {
+		t.Errorf("got %v, want %v", err, ErrFormat)
+	}
+}
+
 func testFileMode(t *testing.T, zipName string, f *File, want os.FileMode) {
 	mode := f.Mode()
 	if want == 0 {
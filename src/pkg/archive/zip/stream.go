@@ -0,0 +1,443 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+var errStreamSize = errors.New("zip: cannot find the end of a streamed file of unknown size")
+
+// A StreamReader provides sequential access to the files of a zip
+// archive read from a non-seekable source, such as a pipe or a network
+// connection. Unlike Reader, which starts from the central directory at
+// the end of the archive, it walks the local file headers that precede
+// each file's data.
+//
+// The Next method advances to the next file in the archive (including
+// the first), and then the StreamReader can be treated as an io.Reader
+// to access the file's contents. Once the last file has been read, Next
+// checks the local file headers against the central directory and
+// returns io.EOF.
+//
+// Local file headers lack some of the metadata held in the central
+// directory, notably the file mode. The headers returned by Next are
+// completed with that metadata when Next returns io.EOF.
+//
+// Encrypted files, and files compressed with a method that has no
+// registered decompressor, cannot be read: Read returns ErrAlgorithm.
+// Next skips them if their size is recorded in their local header.
+type StreamReader struct {
+	r     *countReader
+	files []*streamFile
+	cur   *streamFile // current file, if its data has not been consumed
+	err   error       // sticky error
+}
+
+// NewStreamReader returns a new StreamReader reading from r.
+func NewStreamReader(r io.Reader) *StreamReader {
+	return &StreamReader{r: &countReader{r: bufio.NewReader(r)}}
+}
+
+// streamFile is a file read by a StreamReader.
+type streamFile struct {
+	*FileHeader
+	offset     int64         // offset of the local file header
+	dataOffset int64         // offset of the file's data
+	zip64      bool          // the local file header has a zip64 extra field
+	rc         io.ReadCloser // decompressed data
+	rerr       error         // if non-nil, why the data cannot be read
+	hash       hash.Hash32
+	n          int64 // number of decompressed bytes read
+	done       bool  // data and data descriptor have been consumed
+}
+
+// Next advances to the next file in the archive and returns its header.
+// At the end of the archive, Next returns io.EOF, or ErrFormat if the
+// central directory does not match the files read.
+func (z *StreamReader) Next() (*FileHeader, error) {
+	if z.err != nil {
+		return nil, z.err
+	}
+	if z.cur != nil {
+		if z.err = z.skip(z.cur); z.err != nil {
+			return nil, z.err
+		}
+		z.cur = nil
+	}
+	sig, err := z.r.peekUint32()
+	if err != nil {
+		z.err = unexpectedEOF(err)
+		return nil, z.err
+	}
+	switch sig {
+	case fileHeaderSignature:
+		f, err := z.readFileHeader()
+		if err != nil {
+			z.err = err
+			return nil, err
+		}
+		z.files = append(z.files, f)
+		z.cur = f
+		return f.FileHeader, nil
+	case directoryHeaderSignature, directory64EndSignature, directoryEndSignature:
+		z.err = z.readDirectory()
+		if z.err == nil {
+			z.err = io.EOF
+		}
+		return nil, z.err
+	}
+	z.err = ErrFormat
+	return nil, z.err
+}
+
+// Read reads from the current file in the archive. It returns 0, io.EOF
+// when it reaches the end of that file, until Next is called to advance
+// to the next file.
+func (z *StreamReader) Read(b []byte) (n int, err error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	f := z.cur
+	if f == nil || f.done {
+		return 0, io.EOF
+	}
+	if f.rerr != nil {
+		return 0, f.rerr
+	}
+	n, err = f.rc.Read(b)
+	f.hash.Write(b[:n])
+	f.n += int64(n)
+	if err == io.EOF {
+		if err1 := z.finish(f); err1 != nil {
+			z.err = err1
+			err = err1
+		}
+	} else if err != nil {
+		z.err = unexpectedEOF(err)
+		err = z.err
+	}
+	return
+}
+
+// readFileHeader reads a local file header and prepares to read the
+// file's data.
+func (z *StreamReader) readFileHeader() (*streamFile, error) {
+	offset := z.r.n
+	var buf [fileHeaderLen]byte
+	if _, err := io.ReadFull(z.r, buf[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	b := readBuf(buf[4:]) // skip signature
+	fh := new(FileHeader)
+	fh.ReaderVersion = b.uint16()
+	fh.Flags = b.uint16()
+	fh.Method = b.uint16()
+	fh.ModifiedTime = b.uint16()
+	fh.ModifiedDate = b.uint16()
+	fh.CRC32 = b.uint32()
+	fh.CompressedSize = b.uint32()
+	fh.UncompressedSize = b.uint32()
+	fh.CompressedSize64 = uint64(fh.CompressedSize)
+	fh.UncompressedSize64 = uint64(fh.UncompressedSize)
+	filenameLen := int(b.uint16())
+	extraLen := int(b.uint16())
+	d := make([]byte, filenameLen+extraLen)
+	if _, err := io.ReadFull(z.r, d); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	fh.Name = string(d[:filenameLen])
+	fh.Extra = d[filenameLen:]
+
+	f := &streamFile{FileHeader: fh, offset: offset, dataOffset: z.r.n}
+	for b := readBuf(fh.Extra); len(b) >= 4; {
+		tag := b.uint16()
+		size := int(b.uint16())
+		if size > len(b) {
+			return nil, ErrFormat
+		}
+		if tag == zip64ExtraId {
+			// The local zip64 extra block holds both sizes.
+			f.zip64 = true
+			eb := readBuf(b)
+			if len(eb) >= 16 {
+				fh.UncompressedSize64 = eb.uint64()
+				fh.CompressedSize64 = eb.uint64()
+			}
+		}
+		b = b[size:]
+	}
+
+	dcomp := decompressor(fh.Method)
+	if dcomp == nil || fh.IsEncrypted() {
+		f.rerr = ErrAlgorithm
+		return f, nil
+	}
+	var r io.Reader
+	switch {
+	case !f.hasDataDescriptor() || fh.CompressedSize64 != 0:
+		r = io.LimitReader(z.r, int64(fh.CompressedSize64))
+	case fh.Method == Deflate:
+		// The deflate stream marks its own end. The decompressor
+		// reads the archive byte by byte, so as to leave the data
+		// descriptor unread.
+		r = z.r
+	case fh.Method == Store:
+		r = &storedReader{r: z.r, hash: crc32.NewIEEE()}
+	default:
+		f.rerr = errStreamSize
+		return f, nil
+	}
+	f.rc = dcomp(r)
+	f.hash = crc32.NewIEEE()
+	return f, nil
+}
+
+// hasDataDescriptor reports whether the file's CRC-32 and sizes follow
+// its data.
+func (f *streamFile) hasDataDescriptor() bool {
+	return f.Flags&0x8 != 0
+}
+
+// finish consumes the rest of f's data and its data descriptor, if any,
+// and checks them against the data read.
+func (z *StreamReader) finish(f *streamFile) error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.rc.Close()
+	if f.hasDataDescriptor() && f.CompressedSize64 == 0 {
+		// Data of unknown size has been consumed exactly.
+		f.CompressedSize64 = uint64(z.r.n - f.dataOffset)
+	} else if err := z.discard(f.dataOffset + int64(f.CompressedSize64) - z.r.n); err != nil {
+		return err
+	}
+	size := uint64(z.r.n - f.dataOffset)
+	if f.hasDataDescriptor() {
+		if err := z.readDataDescriptor(f); err != nil {
+			return err
+		}
+	}
+	if f.CompressedSize64 != size || f.UncompressedSize64 != uint64(f.n) || f.CRC32 != f.hash.Sum32() {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// skip consumes the rest of f's data and its data descriptor, if any.
+func (z *StreamReader) skip(f *streamFile) error {
+	if f.done {
+		return nil
+	}
+	if f.rerr == nil {
+		_, err := io.Copy(ioutil.Discard, z)
+		return err
+	}
+	// The data cannot be read, and thus cannot be checked.
+	if f.hasDataDescriptor() && f.CompressedSize64 == 0 {
+		return errStreamSize
+	}
+	f.done = true
+	if err := z.discard(int64(f.CompressedSize64)); err != nil {
+		return err
+	}
+	if f.hasDataDescriptor() {
+		return z.readDataDescriptor(f)
+	}
+	return nil
+}
+
+// readDataDescriptor reads the data descriptor following f's data and
+// records its values in f. The sizes are eight bytes long if f has a
+// zip64 extra field or if they do not fit in four bytes.
+func (z *StreamReader) readDataDescriptor(f *streamFile) error {
+	if sig, err := z.r.peekUint32(); err != nil {
+		return unexpectedEOF(err)
+	} else if sig == dataDescriptorSignature {
+		z.discard(4)
+	}
+	n := dataDescriptorLen - 4
+	zip64 := f.zip64 || z.r.n-f.dataOffset > uint32max || f.n > uint32max
+	if zip64 {
+		n = dataDescriptor64Len - 4
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(z.r, buf); err != nil {
+		return unexpectedEOF(err)
+	}
+	b := readBuf(buf)
+	f.CRC32 = b.uint32()
+	if zip64 {
+		f.CompressedSize64 = b.uint64()
+		f.UncompressedSize64 = b.uint64()
+	} else {
+		f.CompressedSize64 = uint64(b.uint32())
+		f.UncompressedSize64 = uint64(b.uint32())
+	}
+	f.setSizes32()
+	return nil
+}
+
+// setSizes32 sets the deprecated 32 bit size fields from the 64 bit ones.
+func (fh *FileHeader) setSizes32() {
+	if fh.isZip64() {
+		fh.CompressedSize = uint32max
+		fh.UncompressedSize = uint32max
+	} else {
+		fh.CompressedSize = uint32(fh.CompressedSize64)
+		fh.UncompressedSize = uint32(fh.UncompressedSize64)
+	}
+}
+
+// readDirectory reads the central directory and checks it against the
+// local file headers read.
+func (z *StreamReader) readDirectory() error {
+	for i := 0; ; i++ {
+		sig, err := z.r.peekUint32()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if sig != directoryHeaderSignature {
+			if i != len(z.files) {
+				return ErrFormat
+			}
+			if sig != directoryEndSignature && sig != directory64EndSignature {
+				return ErrFormat
+			}
+			return nil
+		}
+		d := new(File)
+		if err := readDirectoryHeader(d, z.r); err != nil {
+			return err
+		}
+		if i >= len(z.files) {
+			return ErrFormat
+		}
+		f := z.files[i]
+		if d.Name != f.Name || d.headerOffset != f.offset || d.CRC32 != f.CRC32 ||
+			d.CompressedSize64 != f.CompressedSize64 || d.UncompressedSize64 != f.UncompressedSize64 {
+			return ErrFormat
+		}
+		f.CreatorVersion = d.CreatorVersion
+		f.ExternalAttrs = d.ExternalAttrs
+		f.Comment = d.Comment
+	}
+}
+
+// discard consumes n bytes of the archive.
+func (z *StreamReader) discard(n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(ioutil.Discard, z.r, n)
+	return unexpectedEOF(err)
+}
+
+// storedReader reads the data of a stored file of unknown size, which
+// ends where a data descriptor matching the data read is found.
+type storedReader struct {
+	r    *countReader
+	hash hash.Hash32
+	n    int64
+	done bool
+}
+
+var dataDescriptorSig = []byte{'P', 'K', 0x07, 0x08}
+
+func (s *storedReader) Read(b []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	p, err := s.r.r.Peek(dataDescriptor64Len)
+	if n := s.r.r.Buffered(); n > len(p) {
+		p, err = s.r.r.Peek(n)
+	}
+	if s.isDescriptor(p) {
+		s.done = true
+		return 0, io.EOF
+	}
+	// Return the data before the next possible descriptor.
+	n := len(p) - (len(dataDescriptorSig) - 1)
+	if i := bytes.Index(p[1:], dataDescriptorSig); i >= 0 {
+		n = i + 1
+	}
+	if n <= 0 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	if n > len(b) {
+		n = len(b)
+	}
+	n, err = io.ReadFull(s.r, b[:n])
+	s.hash.Write(b[:n])
+	s.n += int64(n)
+	return n, err
+}
+
+// isDescriptor reports whether p starts with a data descriptor for
+// the data read so far, with either four or eight byte sizes.
+func (s *storedReader) isDescriptor(p []byte) bool {
+	if len(p) < dataDescriptorLen || !bytes.HasPrefix(p, dataDescriptorSig) {
+		return false
+	}
+	b := readBuf(p[4:])
+	if b.uint32() != s.hash.Sum32() {
+		return false
+	}
+	if len(b) >= 16 {
+		eb := b
+		if eb.uint64() == uint64(s.n) && eb.uint64() == uint64(s.n) {
+			return true
+		}
+	}
+	return b.uint32() == uint32(s.n) && b.uint32() == uint32(s.n) && s.n <= uint32max
+}
+
+// countReader counts the bytes read from a bufio.Reader. It implements
+// io.ByteReader so that decompressors do not read ahead of their data.
+type countReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (r *countReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countReader) ReadByte() (byte, error) {
+	c, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return c, err
+}
+
+// peekUint32 returns the next four bytes without consuming them.
+func (r *countReader) peekUint32() (uint32, error) {
+	b, err := r.r.Peek(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
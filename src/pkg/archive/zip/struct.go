@@ -56,6 +56,7 @@ const (
 	// version numbers
 	zipVersion20 = 20 // 2.0
 	zipVersion45 = 45 // 4.5 (reads and writes zip64 archives)
+	zipVersion51 = 51 // 5.1 (reads and writes WinZip AES encrypted files)
 
 	// limits for non zip64 files
 	uint16max = (1 << 16) - 1
@@ -11,6 +11,7 @@ import (
 	"hash"
 	"hash/crc32"
 	"io"
+	"os"
 )
 
 // TODO(adg): support zip file comments
@@ -34,6 +35,74 @@ func NewWriter(w io.Writer) *Writer {
 	return &Writer{cw: &countWriter{w: bufio.NewWriter(w)}}
 }
 
+// NewAppendWriter returns a Writer that adds files to the existing
+// zip file read by r. The storage written by w must be the same as
+// the one read by r.
+//
+// New files are written over the existing central directory, which is
+// rewritten, including the files already in r, when the Writer is closed.
+// If the resulting zip file is shorter than the original one, the caller
+// must truncate it after Close; OpenAppend does this for files on disk.
+func NewAppendWriter(w io.WriteSeeker, r *Reader) (*Writer, error) {
+	if _, err := w.Seek(r.dirOffset, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	zw := &Writer{cw: &countWriter{w: bufio.NewWriter(w), count: r.dirOffset}}
+	for _, f := range r.File {
+		fh := f.FileHeader
+		fh.Extra = stripZip64Extra(fh.Extra)
+		zw.dir = append(zw.dir, &header{FileHeader: &fh, offset: uint64(f.headerOffset)})
+	}
+	return zw, nil
+}
+
+// A WriteCloser is a Writer appending to a zip file opened with
+// OpenAppend.
+type WriteCloser struct {
+	f *os.File
+	Writer
+}
+
+// OpenAppend opens the Zip file specified by name for appending and
+// returns a WriteCloser. Files added to it are written after the
+// existing ones.
+func OpenAppend(name string) (*WriteCloser, error) {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewReader(f, fi.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w, err := NewAppendWriter(f, r)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &WriteCloser{f: f, Writer: *w}, nil
+}
+
+// Close finishes writing the zip file by writing the central directory,
+// truncates the file after it and closes the file.
+func (wc *WriteCloser) Close() error {
+	if err := wc.Writer.Close(); err != nil {
+		wc.f.Close()
+		return err
+	}
+	if err := wc.f.Truncate(wc.cw.count); err != nil {
+		wc.f.Close()
+		return err
+	}
+	return wc.f.Close()
+}
+
 // Close finishes writing the zip file by writing the central directory.
 // It does not (and can not) close the underlying writer.
 func (w *Writer) Close() error {
@@ -166,7 +235,7 @@ func (w *Writer) Close() error {
 // letter (e.g. C:) or leading slash, and only forward slashes are
 // allowed.
 // The file's contents must be written to the io.Writer before the next
-// call to Create, CreateHeader, or Close.
+// call to Create, CreateHeader, CreateRaw, Copy, or Close.
 func (w *Writer) Create(name string) (io.Writer, error) {
 	header := &FileHeader{
 		Name:   name,
@@ -179,8 +248,25 @@ func (w *Writer) Create(name string) (io.Writer, error) {
 // for the file metadata.
 // It returns a Writer to which the file contents should be written.
 // The file's contents must be written to the io.Writer before the next
-// call to Create, CreateHeader, or Close.
+// call to Create, CreateHeader, CreateRaw, Copy, or Close.
 func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
+	return w.createHeader(fh, nil, 0)
+}
+
+// CreateEncrypted adds a file to the zip file using the provided
+// FileHeader for the file metadata, and encrypts its contents with
+// password using the given encryption method.
+// It returns a Writer to which the file contents should be written.
+// The file's contents must be written to the io.Writer before the next
+// call to Create, CreateHeader, CreateRaw, Copy, or Close.
+func (w *Writer) CreateEncrypted(fh *FileHeader, password string, method EncryptionMethod) (io.Writer, error) {
+	if method < ZipCrypto || method > AES256 {
+		return nil, errors.New("zip: unknown encryption method")
+	}
+	return w.createHeader(fh, []byte(password), method)
+}
+
+func (w *Writer) createHeader(fh *FileHeader, password []byte, enc EncryptionMethod) (io.Writer, error) {
 	if w.last != nil && !w.last.closed {
 		if err := w.last.close(); err != nil {
 			return nil, err
@@ -192,22 +278,103 @@ func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
 	fh.CreatorVersion = fh.CreatorVersion&0xff00 | zipVersion20 // preserve compatibility byte
 	fh.ReaderVersion = zipVersion20
 
+	comp := compressor(fh.Method)
+	if comp == nil {
+		return nil, ErrAlgorithm
+	}
+
+	var aesStrength byte
+	if enc != 0 {
+		fh.Flags |= encryptedFlag
+	}
+	if enc >= AES128 {
+		// The real compression method is recorded in the
+		// WinZip AES extra field.
+		aesStrength = byte(enc-AES128) + 1
+		var buf [4 + winzipAESExtraLen]byte
+		eb := writeBuf(buf[:])
+		eb.uint16(winzipAESExtraId)
+		eb.uint16(winzipAESExtraLen)
+		eb.uint16(2)      // AE-2
+		eb.uint16(0x4541) // vendor ID "AE"
+		eb[0] = aesStrength
+		eb = eb[1:]
+		eb.uint16(fh.Method)
+		fh.Extra = append(fh.Extra, buf[:]...)
+		fh.Method = winzipAESMethod
+		fh.ReaderVersion = zipVersion51
+	}
+
 	fw := &fileWriter{
 		zipw:      w.cw,
 		compCount: &countWriter{w: w.cw},
 		crc32:     crc32.NewIEEE(),
+		noCRC:     aesStrength != 0,
 	}
-	comp := compressor(fh.Method)
-	if comp == nil {
-		return nil, ErrAlgorithm
+
+	h := &header{
+		FileHeader: fh,
+		offset:     uint64(w.cw.count),
 	}
+	w.dir = append(w.dir, h)
+	fw.header = h
+
+	if err := writeHeader(w.cw, fh); err != nil {
+		return nil, err
+	}
+
+	var cw io.Writer = fw.compCount
 	var err error
-	fw.comp, err = comp(fw.compCount)
+	switch {
+	case enc == ZipCrypto:
+		// The data descriptor holds the CRC-32, so the encryption
+		// header is checked against the modification time instead.
+		fw.enc, err = newZipCryptoWriter(cw, password, byte(fh.ModifiedTime>>8))
+	case aesStrength != 0:
+		fw.enc, err = newAESWriter(cw, password, aesStrength)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fw.enc != nil {
+		cw = fw.enc
+	}
+	fw.comp, err = comp(cw)
 	if err != nil {
 		return nil, err
 	}
 	fw.rawCount = &countWriter{w: fw.comp}
 
+	w.last = fw
+	return fw, nil
+}
+
+// CreateRaw adds a file to the zip file using the provided FileHeader
+// and returns a Writer to which the file's already compressed contents
+// should be written. The data is stored as is; the header's CRC32,
+// CompressedSize64 and UncompressedSize64 fields must describe it.
+// The file's contents must be written to the io.Writer before the next
+// call to Create, CreateHeader, CreateRaw, Copy, or Close.
+func (w *Writer) CreateRaw(fh *FileHeader) (io.Writer, error) {
+	if w.last != nil && !w.last.closed {
+		if err := w.last.close(); err != nil {
+			return nil, err
+		}
+	}
+
+	fh.Flags |= 0x8 // we will write a data descriptor
+
+	fh.CreatorVersion = fh.CreatorVersion&0xff00 | zipVersion20 // preserve compatibility byte
+	fh.ReaderVersion = zipVersion20
+	if fh.Method == winzipAESMethod {
+		fh.ReaderVersion = zipVersion51
+	}
+
+	fw := &fileWriter{
+		zipw:      w.cw,
+		compCount: &countWriter{w: w.cw},
+		raw:       true,
+	}
 	h := &header{
 		FileHeader: fh,
 		offset:     uint64(w.cw.count),
@@ -223,6 +390,23 @@ func (w *Writer) CreateHeader(fh *FileHeader) (io.Writer, error) {
 	return fw, nil
 }
 
+// Copy copies the file f, typically read from a Reader, into w
+// without decompressing and recompressing its contents.
+func (w *Writer) Copy(f *File) error {
+	r, err := f.OpenRaw()
+	if err != nil {
+		return err
+	}
+	fh := f.FileHeader
+	fh.Extra = stripZip64Extra(fh.Extra)
+	fw, err := w.CreateRaw(&fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
 func writeHeader(w io.Writer, h *FileHeader) error {
 	var buf [fileHeaderLen]byte
 	b := writeBuf(buf[:])
@@ -253,7 +437,10 @@ type fileWriter struct {
 	rawCount  *countWriter
 	comp      io.WriteCloser
 	compCount *countWriter
+	enc       io.WriteCloser // if non-nil, encrypts the compressed data
 	crc32     hash.Hash32
+	noCRC     bool // store a zero CRC-32, as WinZip AES (AE-2) requires
+	raw       bool // contents are written as is, see CreateRaw
 	closed    bool
 }
 
@@ -261,6 +448,9 @@ func (w *fileWriter) Write(p []byte) (int, error) {
 	if w.closed {
 		return 0, errors.New("zip: write to closed file")
 	}
+	if w.raw {
+		return w.compCount.Write(p)
+	}
 	w.crc32.Write(p)
 	return w.rawCount.Write(p)
 }
@@ -270,20 +460,36 @@ func (w *fileWriter) close() error {
 		return errors.New("zip: file closed twice")
 	}
 	w.closed = true
-	if err := w.comp.Close(); err != nil {
-		return err
-	}
 
 	// update FileHeader
 	fh := w.header.FileHeader
-	fh.CRC32 = w.crc32.Sum32()
-	fh.CompressedSize64 = uint64(w.compCount.count)
-	fh.UncompressedSize64 = uint64(w.rawCount.count)
+	if w.raw {
+		if uint64(w.compCount.count) != fh.CompressedSize64 {
+			return errors.New("zip: raw file size does not match CompressedSize64")
+		}
+	} else {
+		if err := w.comp.Close(); err != nil {
+			return err
+		}
+		if w.enc != nil {
+			if err := w.enc.Close(); err != nil {
+				return err
+			}
+		}
+		fh.CRC32 = w.crc32.Sum32()
+		if w.noCRC {
+			fh.CRC32 = 0
+		}
+		fh.CompressedSize64 = uint64(w.compCount.count)
+		fh.UncompressedSize64 = uint64(w.rawCount.count)
+	}
 
 	if fh.isZip64() {
 		fh.CompressedSize = uint32max
 		fh.UncompressedSize = uint32max
-		fh.ReaderVersion = zipVersion45 // requires 4.5 - File uses ZIP64 format extensions
+		if fh.ReaderVersion < zipVersion45 {
+			fh.ReaderVersion = zipVersion45 // requires 4.5 - File uses ZIP64 format extensions
+		}
 	} else {
 		fh.CompressedSize = uint32(fh.CompressedSize64)
 		fh.UncompressedSize = uint32(fh.UncompressedSize64)
@@ -314,6 +520,24 @@ func (w *fileWriter) close() error {
 	return err
 }
 
+// stripZip64Extra returns a copy of extra without any zip64 extra
+// block, which the Writer adds itself where needed.
+func stripZip64Extra(extra []byte) []byte {
+	var out []byte
+	for b := readBuf(extra); len(b) >= 4; {
+		tag := b.uint16()
+		size := int(b.uint16())
+		if size > len(b) {
+			break
+		}
+		if tag != zip64ExtraId {
+			out = append(out, extra[len(extra)-len(b)-4:len(extra)-len(b)+size]...)
+		}
+		b = b[size:]
+	}
+	return out
+}
+
 type countWriter struct {
 	w     io.Writer
 	count int64
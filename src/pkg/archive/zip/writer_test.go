@@ -125,3 +125,101 @@ func testReadFile(t *testing.T, f *File, wt *WriteTest) {
 		t.Errorf("File contents %q, want %q", b, wt.Data)
 	}
 }
+
+func TestWriterCopy(t *testing.T) {
+	// write a zip file
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	for _, wt := range writeTests {
+		testCreate(t, w, &wt)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// copy all of its files into a new one
+	buf2 := new(bytes.Buffer)
+	w = NewWriter(buf2)
+	for _, f := range r.File {
+		if err := w.Copy(f); err != nil {
+			t.Fatalf("Copy(%s): %v", f.Name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r2, err := NewReader(bytes.NewReader(buf2.Bytes()), int64(buf2.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r2.File) != len(writeTests) {
+		t.Fatalf("got %d files, want %d", len(r2.File), len(writeTests))
+	}
+	for i, wt := range writeTests {
+		testReadFile(t, r2.File[i], &wt)
+		if got, want := r2.File[i].CompressedSize64, r.File[i].CompressedSize64; got != want {
+			t.Errorf("%s: CompressedSize64 = %d, want %d", wt.Name, got, want)
+		}
+	}
+}
+
+func TestWriterCreateRawSizeMismatch(t *testing.T) {
+	w := NewWriter(ioutil.Discard)
+	fw, err := w.CreateRaw(&FileHeader{Name: "raw", Method: Store, CompressedSize64: 10, UncompressedSize64: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("short")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err == nil {
+		t.Error("Close succeeded after writing too few raw bytes")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "zip-append")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	w := NewWriter(f)
+	for _, wt := range writeTests[:2] {
+		testCreate(t, w, &wt)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wc, err := OpenAppend(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wt := range writeTests[2:] {
+		testCreate(t, &wc.Writer, &wt)
+	}
+	if err := wc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if len(r.File) != len(writeTests) {
+		t.Fatalf("got %d files, want %d", len(r.File), len(writeTests))
+	}
+	for i, wt := range writeTests {
+		testReadFile(t, r.File[i], &wt)
+	}
+}
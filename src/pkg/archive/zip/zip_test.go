@@ -393,3 +393,142 @@ func BenchmarkZip64Test(b *testing.B) {
 		testZip64(b, 1<<26)
 	}
 }
+
+func TestZipCryptoReader(t *testing.T) {
+	// zipcrypto.zip was created with "zip -P golang".
+	r, err := OpenReader("testdata/zipcrypto.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	f := r.File[0]
+	if !f.IsEncrypted() {
+		t.Fatalf("%s is not encrypted", f.Name)
+	}
+	if _, err := f.Open(); err != ErrPassword {
+		t.Errorf("Open without password: got %v, want %v", err, ErrPassword)
+	}
+	r.Password = func(*File) (string, error) { return "golang", nil }
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "This is a ZipCrypto encrypted file.\n"; string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("Rabbits, guinea pigs, gophers, marsupial rats, and quolls. "), 100)
+	methods := []EncryptionMethod{ZipCrypto, AES128, AES192, AES256}
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	for _, enc := range methods {
+		for _, method := range []uint16{Store, Deflate} {
+			fh := &FileHeader{
+				Name:   fmt.Sprintf("%d-%d", enc, method),
+				Method: method,
+			}
+			fh.SetModTime(time.Date(2013, time.August, 1, 12, 34, 56, 0, time.UTC))
+			fw, err := w.CreateEncrypted(fh, "secret", enc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fw.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := "wrong"
+	r.Password = func(*File) (string, error) { return password, nil }
+	for _, f := range r.File {
+		if !f.IsEncrypted() {
+			t.Errorf("%s is not encrypted", f.Name)
+		}
+		if _, err := f.Open(); err != ErrPassword {
+			t.Errorf("%s: Open with wrong password: got %v, want %v", f.Name, err, ErrPassword)
+		}
+	}
+	password = "secret"
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Errorf("%s: Open: %v", f.Name, err)
+			continue
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Errorf("%s: reading: %v", f.Name, err)
+			continue
+		}
+		if !bytes.Equal(b, data) {
+			t.Errorf("%s: contents differ", f.Name)
+		}
+	}
+}
+
+func TestAESAuthentication(t *testing.T) {
+	tests := []struct {
+		method uint16
+		mac    bool // corrupt the authentication code, not the data
+	}{
+		{Store, false},
+		{Store, true},
+		{Deflate, true},
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		w := NewWriter(buf)
+		fw, err := w.CreateEncrypted(&FileHeader{Name: "aes", Method: tt.method}, "secret", AES256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(fw, "authenticated contents"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b := buf.Bytes()
+
+		r, err := NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := r.File[0]
+		off, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.mac {
+			b[off+int64(f.CompressedSize64)-1] ^= 1
+		} else {
+			b[off+16+aesVerifierLen] ^= 1
+		}
+
+		r.Password = func(*File) (string, error) { return "secret", nil }
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ioutil.ReadAll(rc); err != ErrChecksum {
+			t.Errorf("method %d, corrupt mac %v: got %v, want %v", tt.method, tt.mac, err, ErrChecksum)
+		}
+		rc.Close()
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package curve25519 implements the X25519 Diffie-Hellman function over
+// Curve25519, as specified in RFC 7748.
+//
+// All operations on secret values take time that is independent of those
+// values.
+package curve25519
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	// ScalarSize is the size of the scalar input to X25519.
+	ScalarSize = 32
+	// PointSize is the size of the point input to X25519.
+	PointSize = 32
+)
+
+// Basepoint is the canonical Curve25519 generator.
+var Basepoint []byte
+
+var basePoint = [32]byte{9}
+
+func init() { Basepoint = basePoint[:] }
+
+// ScalarMult sets dst to the product scalar * point.
+func ScalarMult(dst, scalar, point *[32]byte) {
+	scalarMult(dst, scalar, point)
+}
+
+// ScalarBaseMult sets dst to the product scalar * base where base is the
+// standard generator.
+func ScalarBaseMult(dst, scalar *[32]byte) {
+	scalarMult(dst, scalar, &basePoint)
+}
+
+// X25519 returns the result of the scalar multiplication (scalar * point),
+// according to RFC 7748, section 5. scalar, point and the return value are
+// slices of 32 bytes.
+//
+// An error is returned if either input has the wrong length or if the
+// result is the all-zero value, which happens when point has a small order.
+// Callers performing Diffie-Hellman must treat that as a failure.
+func X25519(scalar, point []byte) ([]byte, error) {
+	if l := len(scalar); l != ScalarSize {
+		return nil, errors.New("curve25519: bad scalar length")
+	}
+	if l := len(point); l != PointSize {
+		return nil, errors.New("curve25519: bad point length")
+	}
+
+	var dst, in, base [32]byte
+	copy(in[:], scalar)
+	copy(base[:], point)
+	scalarMult(&dst, &in, &base)
+
+	var zero [32]byte
+	if subtle.ConstantTimeCompare(dst[:], zero[:]) == 1 {
+		return nil, errors.New("curve25519: bad input point: low order point")
+	}
+	return dst[:], nil
+}
+
+// scalarMult computes the x coordinate of scalar * point using the
+// Montgomery ladder of RFC 7748, section 5.
+func scalarMult(out, scalar, point *[32]byte) {
+	var e [32]byte
+	copy(e[:], scalar[:])
+	e[0] &= 248
+	e[31] &= 127
+	e[31] |= 64
+
+	var x1, x2, z2, x3, z3, tmp0, tmp1 fieldElement
+	feFromBytes(&x1, point)
+	x2[0] = 1
+	x3 = x1
+	z3[0] = 1
+
+	swap := int64(0)
+	for pos := 254; pos >= 0; pos-- {
+		b := int64(e[pos/8]>>uint(pos&7)) & 1
+		swap ^= b
+		feCSwap(&x2, &x3, swap)
+		feCSwap(&z2, &z3, swap)
+		swap = b
+
+		feSub(&tmp0, &x3, &z3)
+		feSub(&tmp1, &x2, &z2)
+		feAdd(&x2, &x2, &z2)
+		feAdd(&z2, &x3, &z3)
+		feMul(&z3, &tmp0, &x2)
+		feMul(&z2, &z2, &tmp1)
+		feSquare(&tmp0, &tmp1)
+		feSquare(&tmp1, &x2)
+		feAdd(&x3, &z3, &z2)
+		feSub(&z2, &z3, &z2)
+		feMul(&x2, &tmp1, &tmp0)
+		feSub(&tmp1, &tmp1, &tmp0)
+		feSquare(&z2, &z2)
+		feMul(&z3, &tmp1, &a24)
+		feSquare(&x3, &x3)
+		feAdd(&tmp0, &tmp0, &z3)
+		feMul(&z3, &x1, &z2)
+		feMul(&z2, &tmp1, &tmp0)
+	}
+	feCSwap(&x2, &x3, swap)
+	feCSwap(&z2, &z3, swap)
+
+	feInvert(&z2, &z2)
+	feMul(&x2, &x2, &z2)
+	feToBytes(out, &x2)
+}
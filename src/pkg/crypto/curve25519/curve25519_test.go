@@ -0,0 +1,116 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// x25519Tests are the test vectors from RFC 7748, section 5.2.
+var x25519Tests = []struct {
+	scalar, point, out string
+}{
+	{
+		"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+		"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+		"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552",
+	},
+	{
+		"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+		"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+		"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957",
+	},
+}
+
+func TestX25519(t *testing.T) {
+	for i, test := range x25519Tests {
+		out, err := X25519(fromHex(test.scalar), fromHex(test.point))
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %s", i, err)
+			continue
+		}
+		if got := hex.EncodeToString(out); got != test.out {
+			t.Errorf("#%d: got %s, want %s", i, got, test.out)
+		}
+	}
+}
+
+func TestX25519Iterated(t *testing.T) {
+	// RFC 7748, section 5.2: the result after 1 and 1000 iterations.
+	want := map[int]string{
+		1:    "422c8e7a6227d7bca1350b3e2bb7279f7897b87bb6854b783c60e80311ae3079",
+		1000: "684cf59ba83309552800ef566f2f4d3c1c3887c49360e3875f2eb94d99532c51",
+	}
+	iterations := 1000
+	if testing.Short() {
+		iterations = 1
+	}
+
+	k, u := Basepoint, Basepoint
+	for i := 1; i <= iterations; i++ {
+		out, err := X25519(k, u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, u = out, k
+		if w, ok := want[i]; ok {
+			if got := hex.EncodeToString(k); got != w {
+				t.Errorf("after %d iterations got %s, want %s", i, got, w)
+			}
+		}
+	}
+}
+
+func TestDiffieHellman(t *testing.T) {
+	// RFC 7748, section 6.1.
+	alicePrivate := fromHex("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	alicePublic := fromHex("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	bobPrivate := fromHex("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	bobPublic := fromHex("de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared := fromHex("4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	var dst, scalar [32]byte
+	copy(scalar[:], alicePrivate)
+	ScalarBaseMult(&dst, &scalar)
+	if !bytes.Equal(dst[:], alicePublic) {
+		t.Errorf("Alice's public key: got %x, want %x", dst, alicePublic)
+	}
+	copy(scalar[:], bobPrivate)
+	ScalarBaseMult(&dst, &scalar)
+	if !bytes.Equal(dst[:], bobPublic) {
+		t.Errorf("Bob's public key: got %x, want %x", dst, bobPublic)
+	}
+
+	for _, keys := range [][2][]byte{{alicePrivate, bobPublic}, {bobPrivate, alicePublic}} {
+		out, err := X25519(keys[0], keys[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, shared) {
+			t.Errorf("shared secret: got %x, want %x", out, shared)
+		}
+	}
+}
+
+func TestLowOrderPoint(t *testing.T) {
+	scalar := fromHex(x25519Tests[0].scalar)
+	var zero [32]byte
+	if _, err := X25519(scalar, zero[:]); err == nil {
+		t.Error("X25519 accepted the zero point")
+	}
+	if _, err := X25519(scalar[:31], Basepoint); err == nil {
+		t.Error("X25519 accepted a short scalar")
+	}
+}
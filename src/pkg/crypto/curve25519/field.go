@@ -0,0 +1,127 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve25519
+
+// The field arithmetic below is also found in crypto/ed25519/field.go,
+// which adds the square root helpers needed to decode points. Sharing it
+// would mean exporting it from one of the two packages, and it is not
+// meant to be part of the API of either; fixes to one copy must be made
+// to the other.
+
+// fieldElement represents an element of the field GF(2^255-19). An element
+// t represents the integer t[0] + t[1]*2^16 + t[2]*2^32 + ... + t[15]*2^240.
+// Limbs are kept near 16 bits by feCarry but may be negative or slightly
+// larger between operations.
+type fieldElement [16]int64
+
+// a24 is (486662 + 2) / 4, the constant used by the Montgomery ladder in
+// the form z2 = E * (BB + a24 * E).
+var a24 = fieldElement{0xdb42, 1}
+
+// feCarry propagates the carries between the limbs of h, reducing the
+// overflow from the top limb modulo 2^255-19.
+func feCarry(h *fieldElement) {
+	for i := 0; i < 16; i++ {
+		h[i] += 1 << 16
+		c := h[i] >> 16
+		if i < 15 {
+			h[i+1] += c - 1
+		} else {
+			h[0] += 38 * (c - 1)
+		}
+		h[i] -= c << 16
+	}
+}
+
+// feCSwap swaps f and g if b == 1 and leaves them unchanged if b == 0,
+// without branching on b.
+func feCSwap(f, g *fieldElement, b int64) {
+	mask := -b
+	for i := range f {
+		t := mask & (f[i] ^ g[i])
+		f[i] ^= t
+		g[i] ^= t
+	}
+}
+
+func feAdd(dst, a, b *fieldElement) {
+	for i := range dst {
+		dst[i] = a[i] + b[i]
+	}
+}
+
+func feSub(dst, a, b *fieldElement) {
+	for i := range dst {
+		dst[i] = a[i] - b[i]
+	}
+}
+
+func feMul(dst, a, b *fieldElement) {
+	var t [31]int64
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			t[i+j] += a[i] * b[j]
+		}
+	}
+	// 2^256 = 38 mod 2^255-19.
+	for i := 0; i < 15; i++ {
+		t[i] += 38 * t[i+16]
+	}
+	copy(dst[:], t[:16])
+	feCarry(dst)
+	feCarry(dst)
+}
+
+func feSquare(dst, a *fieldElement) {
+	feMul(dst, a, a)
+}
+
+// feInvert sets dst to the inverse of z, computed as z^(p-2).
+func feInvert(dst, z *fieldElement) {
+	c := *z
+	for i := 253; i >= 0; i-- {
+		feSquare(&c, &c)
+		if i != 2 && i != 4 {
+			feMul(&c, &c, z)
+		}
+	}
+	*dst = c
+}
+
+// feFromBytes sets h to the little-endian value of s, ignoring the top bit
+// as required by RFC 7748.
+func feFromBytes(h *fieldElement, s *[32]byte) {
+	for i := range h {
+		h[i] = int64(s[2*i]) | int64(s[2*i+1])<<8
+	}
+	h[15] &= 0x7fff
+}
+
+// feToBytes sets s to the canonical little-endian encoding of h.
+func feToBytes(s *[32]byte, h *fieldElement) {
+	t := *h
+	feCarry(&t)
+	feCarry(&t)
+	feCarry(&t)
+
+	// Subtract p twice, keeping the result whenever it doesn't borrow.
+	var m fieldElement
+	for j := 0; j < 2; j++ {
+		m[0] = t[0] - 0xffed
+		for i := 1; i < 15; i++ {
+			m[i] = t[i] - 0xffff - ((m[i-1] >> 16) & 1)
+			m[i-1] &= 0xffff
+		}
+		m[15] = t[15] - 0x7fff - ((m[14] >> 16) & 1)
+		b := (m[15] >> 16) & 1
+		m[14] &= 0xffff
+		feCSwap(&t, &m, 1-b)
+	}
+
+	for i := range t {
+		s[2*i] = byte(t[i])
+		s[2*i+1] = byte(t[i] >> 8)
+	}
+}
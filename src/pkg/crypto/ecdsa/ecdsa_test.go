@@ -7,11 +7,13 @@ package ecdsa
 import (
 	"bufio"
 	"compress/bzip2"
+	"crypto"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/asn1"
 	"encoding/hex"
 	"hash"
 	"io"
@@ -80,6 +82,38 @@ func fromHex(s string) *big.Int {
 	return r
 }
 
+func testSigner(t *testing.T, c elliptic.Curve, tag string) {
+	priv, _ := GenerateKey(c, rand.Reader)
+
+	hashed := []byte("testing")
+	var signer crypto.Signer = priv
+	sig, err := signer.Sign(rand.Reader, hashed, crypto.SHA256)
+	if err != nil {
+		t.Errorf("%s: error signing: %s", tag, err)
+		return
+	}
+	var esig ecdsaSignature
+	if rest, err := asn1.Unmarshal(sig, &esig); err != nil || len(rest) != 0 {
+		t.Errorf("%s: malformed signature: %v", tag, err)
+		return
+	}
+
+	pub := signer.Public().(*PublicKey)
+	if !Verify(pub, hashed, esig.R, esig.S) {
+		t.Errorf("%s: Verify failed", tag)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	testSigner(t, elliptic.P224(), "p224")
+	if testing.Short() {
+		return
+	}
+	testSigner(t, elliptic.P256(), "p256")
+	testSigner(t, elliptic.P384(), "p384")
+	testSigner(t, elliptic.P521(), "p521")
+}
+
 func TestVectors(t *testing.T) {
 	// This test runs the full set of NIST test vectors from
 	// http://csrc.nist.gov/groups/STM/cavp/documents/dss/186-3ecdsatestvectors.zip
@@ -0,0 +1,198 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ed25519 implements the Ed25519 signature algorithm, as specified
+// in RFC 8032.
+//
+// Operations that involve the private key take time that is independent
+// of its value.
+package ed25519
+
+import (
+	"bytes"
+	"crypto"
+	cryptorand "crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// PublicKeySize is the size, in bytes, of public keys as used in this package.
+	PublicKeySize = 32
+	// PrivateKeySize is the size, in bytes, of private keys as used in this package.
+	PrivateKeySize = 64
+	// SignatureSize is the size, in bytes, of signatures generated and verified by this package.
+	SignatureSize = 64
+	// SeedSize is the size, in bytes, of private key seeds. These are the
+	// private key representations used by RFC 8032.
+	SeedSize = 32
+)
+
+// PublicKey is the type of Ed25519 public keys.
+type PublicKey []byte
+
+// PrivateKey is the type of Ed25519 private keys. It implements crypto.Signer.
+// It holds the seed followed by the public key.
+type PrivateKey []byte
+
+// Public returns the PublicKey corresponding to priv.
+func (priv PrivateKey) Public() crypto.PublicKey {
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, priv[SeedSize:])
+	return PublicKey(publicKey)
+}
+
+// Seed returns the private key seed corresponding to priv. It is provided
+// for interoperability with RFC 8032, which represents private keys by
+// their seed.
+func (priv PrivateKey) Seed() []byte {
+	seed := make([]byte, SeedSize)
+	copy(seed, priv[:SeedSize])
+	return seed
+}
+
+// Sign signs the given message with priv. Ed25519 performs two passes over
+// the message to be signed, so it cannot be pre-hashed: opts.HashFunc()
+// must return zero to indicate that message is not hashed. rand is ignored.
+func (priv PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	if opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("ed25519: cannot sign hashed message")
+	}
+	return Sign(priv, message), nil
+}
+
+// GenerateKey generates a public/private key pair using entropy from rand.
+// If rand is nil, crypto/rand.Reader will be used.
+func GenerateKey(rand io.Reader) (PublicKey, PrivateKey, error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, nil, err
+	}
+
+	privateKey := NewKeyFromSeed(seed)
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, privateKey[SeedSize:])
+	return publicKey, privateKey, nil
+}
+
+// NewKeyFromSeed calculates a private key from a seed. It will panic if
+// len(seed) is not SeedSize. This function is provided for interoperability
+// with RFC 8032.
+func NewKeyFromSeed(seed []byte) PrivateKey {
+	if l := len(seed); l != SeedSize {
+		panic("ed25519: bad seed length: " + strconv.Itoa(l))
+	}
+
+	var s [32]byte
+	h := sha512.New()
+	h.Write(seed)
+	copy(s[:], h.Sum(nil))
+	clamp(&s)
+
+	var A point
+	var publicKey [32]byte
+	A.scalarBaseMult(&s)
+	A.toBytes(&publicKey)
+
+	privateKey := make([]byte, PrivateKeySize)
+	copy(privateKey, seed)
+	copy(privateKey[SeedSize:], publicKey[:])
+	return privateKey
+}
+
+// clamp prepares the first half of the hash of a seed for use as a secret
+// scalar, as described in RFC 8032, section 5.1.5.
+func clamp(s *[32]byte) {
+	s[0] &= 248
+	s[31] &= 127
+	s[31] |= 64
+}
+
+// Sign signs the message with privateKey and returns a signature. It will
+// panic if len(privateKey) is not PrivateKeySize.
+func Sign(privateKey PrivateKey, message []byte) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed25519: bad private key length: " + strconv.Itoa(l))
+	}
+
+	h := sha512.New()
+	h.Write(privateKey[:SeedSize])
+	digest := h.Sum(nil)
+	var s [32]byte
+	copy(s[:], digest)
+	clamp(&s)
+
+	// The nonce r is derived from the second half of the hash and the
+	// message, so signing is deterministic.
+	h.Reset()
+	h.Write(digest[32:])
+	h.Write(message)
+	var r [32]byte
+	scFromHash(&r, h.Sum(nil))
+
+	var R point
+	var encodedR [32]byte
+	R.scalarBaseMult(&r)
+	R.toBytes(&encodedR)
+
+	h.Reset()
+	h.Write(encodedR[:])
+	h.Write(privateKey[SeedSize:])
+	h.Write(message)
+	var k [32]byte
+	scFromHash(&k, h.Sum(nil))
+
+	var S [32]byte
+	scMulAdd(&S, &k, &s, &r)
+
+	signature := make([]byte, SignatureSize)
+	copy(signature, encodedR[:])
+	copy(signature[32:], S[:])
+	return signature
+}
+
+// Verify reports whether sig is a valid signature of message by publicKey.
+// It will panic if len(publicKey) is not PublicKeySize.
+func Verify(publicKey PublicKey, message, sig []byte) bool {
+	if l := len(publicKey); l != PublicKeySize {
+		panic("ed25519: bad public key length: " + strconv.Itoa(l))
+	}
+
+	if len(sig) != SignatureSize || !scIsCanonical(sig[32:]) {
+		return false
+	}
+
+	var A point
+	var encodedA [32]byte
+	copy(encodedA[:], publicKey)
+	if !A.fromBytes(&encodedA) {
+		return false
+	}
+	A.negate()
+
+	h := sha512.New()
+	h.Write(sig[:32])
+	h.Write(publicKey)
+	h.Write(message)
+	var k [32]byte
+	scFromHash(&k, h.Sum(nil))
+
+	// Check that R = S*B - k*A.
+	var S [32]byte
+	copy(S[:], sig[32:])
+	var kA, SB point
+	kA.scalarMult(&A, &k)
+	SB.scalarBaseMult(&S)
+	SB.add(&kA)
+
+	var R [32]byte
+	SB.toBytes(&R)
+	return bytes.Equal(R[:], sig[:32])
+}
@@ -0,0 +1,131 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+// signTests are the test vectors from RFC 8032, section 7.1.
+var signTests = []struct {
+	seed, publicKey, message, signature string
+}{
+	{
+		"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		"",
+		"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		"72",
+		"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+	{
+		"c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+		"fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+		"af82",
+		"6291d657deec24024827e69c3abe01a30ce548a284743a445e3680d7db5ac3ac18ff9b538d16f290ae67f760984dc6594a7c15e9716ed28dc027beceea1ec40a",
+	},
+}
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestSignVerify(t *testing.T) {
+	for i, test := range signTests {
+		priv := NewKeyFromSeed(fromHex(test.seed))
+		pub := priv.Public().(PublicKey)
+		if got := hex.EncodeToString(pub); got != test.publicKey {
+			t.Errorf("#%d: got public key %s, want %s", i, got, test.publicKey)
+		}
+		if !bytes.Equal(priv.Seed(), fromHex(test.seed)) {
+			t.Errorf("#%d: Seed did not return the seed", i)
+		}
+
+		message := fromHex(test.message)
+		sig := Sign(priv, message)
+		if got := hex.EncodeToString(sig); got != test.signature {
+			t.Errorf("#%d: got signature %s, want %s", i, got, test.signature)
+		}
+		if !Verify(pub, message, sig) {
+			t.Errorf("#%d: valid signature rejected", i)
+		}
+
+		wrongMessage := append(message, 'x')
+		if Verify(pub, wrongMessage, sig) {
+			t.Errorf("#%d: signature of wrong message accepted", i)
+		}
+		sig[0] ^= 1
+		if Verify(pub, message, sig) {
+			t.Errorf("#%d: corrupted signature accepted", i)
+		}
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	pub, priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("test message")
+	sig, err := priv.Sign(nil, message, crypto.Hash(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(pub, message, sig) {
+		t.Error("signature by generated key rejected")
+	}
+
+	if _, err := priv.Sign(nil, message, crypto.SHA256); err == nil {
+		t.Error("Sign accepted a hashed message")
+	}
+}
+
+func TestMalleability(t *testing.T) {
+	// Adding the group order to S gives a signature that satisfies the
+	// verification equation but must be rejected as non-canonical.
+	test := signTests[1]
+	pub := PublicKey(fromHex(test.publicKey))
+	message := fromHex(test.message)
+	sig := fromHex(test.signature)
+
+	carry := 0
+	for i := 0; i < 32; i++ {
+		sum := int(sig[32+i]) + int(order[i]) + carry
+		sig[32+i] = byte(sum)
+		carry = sum >> 8
+	}
+	if Verify(pub, message, sig) {
+		t.Error("non-canonical signature accepted")
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	_, priv, _ := GenerateKey(rand.Reader)
+	message := []byte("Hello, world!")
+	for i := 0; i < b.N; i++ {
+		Sign(priv, message)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	pub, priv, _ := GenerateKey(rand.Reader)
+	message := []byte("Hello, world!")
+	sig := Sign(priv, message)
+	for i := 0; i < b.N; i++ {
+		Verify(pub, message, sig)
+	}
+}
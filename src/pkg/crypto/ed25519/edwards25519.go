@@ -0,0 +1,230 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ed25519
+
+// This file implements the group of points on the twisted Edwards curve
+// -x^2 + y^2 = 1 + d*x^2*y^2 over GF(2^255-19), birationally equivalent to
+// Curve25519, and arithmetic on scalars modulo the order of its base point.
+
+var (
+	// d is -121665/121666.
+	d = fieldElement{0x78a3, 0x1359, 0x4dca, 0x75eb, 0xd8ab, 0x4141, 0x0a4d, 0x0070, 0xe898, 0x7779, 0x4079, 0x8cc7, 0xfe73, 0x2b6f, 0x6cee, 0x5203}
+	// d2 is 2*d.
+	d2 = fieldElement{0xf159, 0x26b2, 0x9b94, 0xebd6, 0xb156, 0x8283, 0x149a, 0x00e0, 0xd130, 0xeef3, 0x80f2, 0x198e, 0xfce7, 0x56df, 0xd9dc, 0x2406}
+	// sqrtM1 is a square root of -1.
+	sqrtM1 = fieldElement{0xa0b0, 0x4a0e, 0x1b27, 0xc4ee, 0xe478, 0xad2f, 0x1806, 0x2f43, 0xd7a7, 0x3dfb, 0x0099, 0x2b4d, 0xdf0b, 0x4fc1, 0x2480, 0x2b83}
+
+	// baseX and baseY are the coordinates of the base point B.
+	baseX = fieldElement{0xd51a, 0x8f25, 0x2d60, 0xc956, 0xa7b2, 0x9525, 0xc760, 0x692c, 0xdc5c, 0xfdd6, 0xe231, 0xc0a4, 0x53fe, 0xcd6e, 0x36d3, 0x2169}
+	baseY = fieldElement{0x6658, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666}
+)
+
+// point is a point on the curve in extended coordinates (X:Y:Z:T), where
+// x = X/Z, y = Y/Z and x*y = T/Z.
+type point struct {
+	X, Y, Z, T fieldElement
+}
+
+func (p *point) setIdentity() {
+	*p = point{}
+	p.Y[0] = 1
+	p.Z[0] = 1
+}
+
+// add sets p to p + q, using the unified addition formula, which is also
+// valid for doubling.
+func (p *point) add(q *point) {
+	var a, b, c, dd, e, f, g, h, t fieldElement
+
+	feSub(&a, &p.Y, &p.X)
+	feSub(&t, &q.Y, &q.X)
+	feMul(&a, &a, &t)
+	feAdd(&b, &p.X, &p.Y)
+	feAdd(&t, &q.X, &q.Y)
+	feMul(&b, &b, &t)
+	feMul(&c, &p.T, &q.T)
+	feMul(&c, &c, &d2)
+	feMul(&dd, &p.Z, &q.Z)
+	feAdd(&dd, &dd, &dd)
+	feSub(&e, &b, &a)
+	feSub(&f, &dd, &c)
+	feAdd(&g, &dd, &c)
+	feAdd(&h, &b, &a)
+
+	feMul(&p.X, &e, &f)
+	feMul(&p.Y, &h, &g)
+	feMul(&p.Z, &g, &f)
+	feMul(&p.T, &e, &h)
+}
+
+// cswap swaps p and q if b == 1 and leaves them unchanged if b == 0,
+// without branching on b.
+func cswap(p, q *point, b int64) {
+	feCSwap(&p.X, &q.X, b)
+	feCSwap(&p.Y, &q.Y, b)
+	feCSwap(&p.Z, &q.Z, b)
+	feCSwap(&p.T, &q.T, b)
+}
+
+// scalarMult sets p to s*q, where s is a little-endian 256-bit scalar. q is
+// overwritten. The sequence of operations does not depend on s.
+func (p *point) scalarMult(q *point, s *[32]byte) {
+	p.setIdentity()
+	for i := 255; i >= 0; i-- {
+		b := int64(s[i/8]>>uint(i&7)) & 1
+		cswap(p, q, b)
+		q.add(p)
+		p.add(p)
+		cswap(p, q, b)
+	}
+}
+
+// scalarBaseMult sets p to s*B.
+func (p *point) scalarBaseMult(s *[32]byte) {
+	var q point
+	q.X = baseX
+	q.Y = baseY
+	q.Z[0] = 1
+	feMul(&q.T, &baseX, &baseY)
+	p.scalarMult(&q, s)
+}
+
+// toBytes sets s to the encoding of p specified in RFC 8032, section 5.1.2.
+func (p *point) toBytes(s *[32]byte) {
+	var zInv, x, y fieldElement
+	feInvert(&zInv, &p.Z)
+	feMul(&x, &p.X, &zInv)
+	feMul(&y, &p.Y, &zInv)
+	feToBytes(s, &y)
+	s[31] ^= feIsNegative(&x) << 7
+}
+
+// fromBytes sets p to the point encoded in s and reports whether s was a
+// valid encoding. It is only used on public values.
+func (p *point) fromBytes(s *[32]byte) bool {
+	var u, v, v3, vxx, zero fieldElement
+
+	// Recover x from y using x^2 = (y^2 - 1) / (d*y^2 + 1), as described
+	// in RFC 8032, section 5.1.3.
+	feFromBytes(&p.Y, s)
+	p.Z = fieldElement{1}
+	feSquare(&u, &p.Y)
+	feMul(&v, &u, &d)
+	feSub(&u, &u, &p.Z)
+	feAdd(&v, &v, &p.Z)
+
+	// x = u * v^3 * (u * v^7)^((p-5)/8)
+	feSquare(&v3, &v)
+	feMul(&v3, &v3, &v)
+	feSquare(&p.X, &v3)
+	feMul(&p.X, &p.X, &v)
+	feMul(&p.X, &p.X, &u)
+	fePow22523(&p.X, &p.X)
+	feMul(&p.X, &p.X, &v3)
+	feMul(&p.X, &p.X, &u)
+
+	feSquare(&vxx, &p.X)
+	feMul(&vxx, &vxx, &v)
+	if feEqual(&vxx, &u) != 1 {
+		feMul(&p.X, &p.X, &sqrtM1)
+		feSquare(&vxx, &p.X)
+		feMul(&vxx, &vxx, &v)
+		if feEqual(&vxx, &u) != 1 {
+			return false
+		}
+	}
+
+	if feEqual(&p.X, &zero) == 1 && s[31]>>7 == 1 {
+		// There is no negative zero.
+		return false
+	}
+	if feIsNegative(&p.X) != s[31]>>7 {
+		feSub(&p.X, &zero, &p.X)
+	}
+	feMul(&p.T, &p.X, &p.Y)
+	return true
+}
+
+// negate sets p to -p.
+func (p *point) negate() {
+	var zero fieldElement
+	feSub(&p.X, &zero, &p.X)
+	feSub(&p.T, &zero, &p.T)
+}
+
+// order is l = 2^252 + 27742317777372353535851937790883648493, the order
+// of the base point, in little-endian order.
+var order = [32]int64{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0x10,
+}
+
+// scReduce sets out to x mod l, where x holds a little-endian number with
+// one (signed) byte per element.
+func scReduce(out *[32]byte, x *[64]int64) {
+	for i := 63; i >= 32; i-- {
+		carry := int64(0)
+		j := i - 32
+		for ; j < i-12; j++ {
+			x[j] += carry - 16*x[i]*order[j-(i-32)]
+			carry = (x[j] + 128) >> 8
+			x[j] -= carry << 8
+		}
+		x[j] += carry
+		x[i] = 0
+	}
+
+	carry := int64(0)
+	for j := 0; j < 32; j++ {
+		x[j] += carry - (x[31]>>4)*order[j]
+		carry = x[j] >> 8
+		x[j] &= 255
+	}
+	for j := 0; j < 32; j++ {
+		x[j] -= carry * order[j]
+	}
+	for i := 0; i < 32; i++ {
+		x[i+1] += x[i] >> 8
+		out[i] = byte(x[i])
+	}
+}
+
+// scFromHash sets out to the 64-byte little-endian number h mod l.
+func scFromHash(out *[32]byte, h []byte) {
+	var x [64]int64
+	for i := range x {
+		x[i] = int64(h[i])
+	}
+	scReduce(out, &x)
+}
+
+// scMulAdd sets out to (a*b + c) mod l.
+func scMulAdd(out, a, b, c *[32]byte) {
+	var x [64]int64
+	for i := 0; i < 32; i++ {
+		x[i] = int64(c[i])
+	}
+	for i := 0; i < 32; i++ {
+		for j := 0; j < 32; j++ {
+			x[i+j] += int64(a[i]) * int64(b[j])
+		}
+	}
+	scReduce(out, &x)
+}
+
+// scIsCanonical reports whether the little-endian scalar s is less than l.
+func scIsCanonical(s []byte) bool {
+	for i := 31; i >= 0; i-- {
+		switch {
+		case int64(s[i]) < order[i]:
+			return true
+		case int64(s[i]) > order[i]:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hkdf
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"testing"
+)
+
+type hkdfTest struct {
+	hash func() hash.Hash
+	ikm  string
+	salt string
+	info string
+	prk  string
+	okm  string
+}
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 5869, appendix A.
+var hkdfTests = []hkdfTest{
+	// Test case 1.
+	{
+		sha256.New,
+		"0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		"000102030405060708090a0b0c",
+		"f0f1f2f3f4f5f6f7f8f9",
+		"077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+		"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865",
+	},
+	// Test case 2, with longer inputs and outputs.
+	{
+		sha256.New,
+		"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b4c4d4e4f",
+		"606162636465666768696a6b6c6d6e6f707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9fa0a1a2a3a4a5a6a7a8a9aaabacadaeaf",
+		"b0b1b2b3b4b5b6b7b8b9babbbcbdbebfc0c1c2c3c4c5c6c7c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedfe0e1e2e3e4e5e6e7e8e9eaebecedeeeff0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		"06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
+		"b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71cc30c58179ec3e87c14c01d5c1f3434f1d87",
+	},
+	// Test case 3, with zero-length salt and info.
+	{
+		sha256.New,
+		"0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b",
+		"",
+		"",
+		"19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+		"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8",
+	},
+	// Test case 4, with SHA-1.
+	{
+		sha1.New,
+		"0b0b0b0b0b0b0b0b0b0b0b",
+		"000102030405060708090a0b0c",
+		"f0f1f2f3f4f5f6f7f8f9",
+		"9b6c18c432a7bf8f0e71c8eb88f4b30baa2ba243",
+		"085a01ea1b10f36933068b56efa5ad81a4f14b822f5b091568a9cdd4f155fda2c22e422478d305f3f896",
+	},
+}
+
+func TestHKDF(t *testing.T) {
+	for i, tt := range hkdfTests {
+		ikm, salt, info := fromHex(tt.ikm), fromHex(tt.salt), fromHex(tt.info)
+		prk, okm := fromHex(tt.prk), fromHex(tt.okm)
+
+		if got := Extract(tt.hash, ikm, salt); !bytes.Equal(got, prk) {
+			t.Errorf("%d: Extract got %x, want %x", i, got, prk)
+		}
+
+		out := make([]byte, len(okm))
+		if _, err := io.ReadFull(New(tt.hash, ikm, salt, info), out); err != nil {
+			t.Errorf("%d: error reading from New: %s", i, err)
+			continue
+		}
+		if !bytes.Equal(out, okm) {
+			t.Errorf("%d: New got %x, want %x", i, out, okm)
+		}
+
+		// Reading in small pieces must give the same result.
+		r := Expand(tt.hash, prk, info)
+		out = out[:0]
+		for len(out) < len(okm) {
+			var piece [7]byte
+			n := len(okm) - len(out)
+			if n > len(piece) {
+				n = len(piece)
+			}
+			if _, err := io.ReadFull(r, piece[:n]); err != nil {
+				t.Fatalf("%d: error reading from Expand: %s", i, err)
+			}
+			out = append(out, piece[:n]...)
+		}
+		if !bytes.Equal(out, okm) {
+			t.Errorf("%d: Expand got %x, want %x", i, out, okm)
+		}
+	}
+}
+
+func TestHKDFNilSalt(t *testing.T) {
+	// A nil salt is equivalent to a string of hash length zeros.
+	ikm := fromHex(hkdfTests[0].ikm)
+	want := Extract(sha256.New, ikm, make([]byte, sha256.Size))
+	if got := Extract(sha256.New, ikm, nil); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestHKDFLimit(t *testing.T) {
+	hash := sha1.New
+	r := New(hash, []byte("secret"), nil, nil)
+
+	// The maximum output is 255 times the hash size.
+	limit := 255 * hash().Size()
+	buf := make([]byte, limit)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("error reading up to the limit: %s", err)
+	}
+	if n, err := r.Read(make([]byte, 1)); err == nil {
+		t.Errorf("read %d bytes past the limit", n)
+	}
+}
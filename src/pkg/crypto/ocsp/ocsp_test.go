@@ -0,0 +1,262 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, pub interface{}, priv interface{}) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+	return cert
+}
+
+type testPKI struct {
+	issuer    *x509.Certificate
+	issuerKey *ecdsa.PrivateKey
+	leaf      *x509.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := rsa.GenerateKey(rand.Reader, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1000, 0)
+	issuerTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:     true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}
+	issuer := createCertificate(t, issuerTemplate, issuerTemplate, &issuerKey.PublicKey, issuerKey)
+
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(0x1234),
+		Subject:      pkix.Name{CommonName: "leaf.example.com"},
+		NotBefore:    now,
+		NotAfter:     now.Add(time.Hour),
+	}
+	leaf := createCertificate(t, leafTemplate, issuer, &leafKey.PublicKey, issuerKey)
+
+	return &testPKI{issuer, issuerKey, leaf}
+}
+
+func TestResponseStatusParse(t *testing.T) {
+	// A tryLater response, which has no responseBytes.
+	der, _ := hex.DecodeString("30030a0103")
+	_, err := ParseResponse(der, nil)
+	respErr, ok := err.(ResponseError)
+	if !ok {
+		t.Fatalf("expected ResponseError, got %#v", err)
+	}
+	if respErr.Status != TryLater {
+		t.Errorf("bad status: got %d, want %d", respErr.Status, TryLater)
+	}
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	pki := newTestPKI(t)
+
+	for _, hash := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512} {
+		der, err := CreateRequest(pki.leaf, pki.issuer, &RequestOptions{Hash: hash})
+		if err != nil {
+			t.Fatalf("%v: CreateRequest: %s", hash, err)
+		}
+
+		req, err := ParseRequest(der)
+		if err != nil {
+			t.Fatalf("%v: ParseRequest: %s", hash, err)
+		}
+
+		if req.HashAlgorithm != hash {
+			t.Errorf("%v: bad hash algorithm: got %v", hash, req.HashAlgorithm)
+		}
+		if req.SerialNumber.Cmp(pki.leaf.SerialNumber) != 0 {
+			t.Errorf("%v: bad serial number: got %v", hash, req.SerialNumber)
+		}
+
+		h := hash.New()
+		h.Write(pki.issuer.RawSubject)
+		if !bytes.Equal(req.IssuerNameHash, h.Sum(nil)) {
+			t.Errorf("%v: bad issuer name hash: %x", hash, req.IssuerNameHash)
+		}
+		if len(req.IssuerKeyHash) != hash.Size() {
+			t.Errorf("%v: bad issuer key hash length: %d", hash, len(req.IssuerKeyHash))
+		}
+
+		marshaled, err := req.Marshal()
+		if err != nil {
+			t.Fatalf("%v: Marshal: %s", hash, err)
+		}
+		if !bytes.Equal(marshaled, der) {
+			t.Errorf("%v: re-marshaled request differs", hash)
+		}
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	pki := newTestPKI(t)
+
+	thisUpdate := time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC)
+	templates := []Response{
+		{
+			Status:       Good,
+			SerialNumber: pki.leaf.SerialNumber,
+			ThisUpdate:   thisUpdate,
+			NextUpdate:   thisUpdate.Add(24 * time.Hour),
+		},
+		{
+			Status:           Revoked,
+			SerialNumber:     pki.leaf.SerialNumber,
+			ThisUpdate:       thisUpdate,
+			RevokedAt:        thisUpdate.Add(-time.Hour),
+			RevocationReason: KeyCompromise,
+		},
+		{
+			Status:       Unknown,
+			SerialNumber: pki.leaf.SerialNumber,
+			ThisUpdate:   thisUpdate,
+		},
+	}
+
+	for i, template := range templates {
+		der, err := CreateResponse(pki.issuer, pki.issuer, template, pki.issuerKey)
+		if err != nil {
+			t.Fatalf("#%d: CreateResponse: %s", i, err)
+		}
+
+		resp, err := ParseResponse(der, pki.issuer)
+		if err != nil {
+			t.Fatalf("#%d: ParseResponse: %s", i, err)
+		}
+
+		if resp.Certificate != nil {
+			t.Errorf("#%d: unexpected embedded certificate", i)
+		}
+		if resp.Status != template.Status {
+			t.Errorf("#%d: bad status: got %d, want %d", i, resp.Status, template.Status)
+		}
+		if resp.SerialNumber.Cmp(template.SerialNumber) != 0 {
+			t.Errorf("#%d: bad serial number: got %v", i, resp.SerialNumber)
+		}
+		if !resp.ThisUpdate.Equal(template.ThisUpdate) {
+			t.Errorf("#%d: bad ThisUpdate: got %v, want %v", i, resp.ThisUpdate, template.ThisUpdate)
+		}
+		if !resp.NextUpdate.Equal(template.NextUpdate) {
+			t.Errorf("#%d: bad NextUpdate: got %v, want %v", i, resp.NextUpdate, template.NextUpdate)
+		}
+		if !resp.RevokedAt.Equal(template.RevokedAt) {
+			t.Errorf("#%d: bad RevokedAt: got %v, want %v", i, resp.RevokedAt, template.RevokedAt)
+		}
+		if resp.RevocationReason != template.RevocationReason {
+			t.Errorf("#%d: bad RevocationReason: got %d, want %d", i, resp.RevocationReason, template.RevocationReason)
+		}
+		if resp.SignatureAlgorithm != x509.ECDSAWithSHA256 {
+			t.Errorf("#%d: bad signature algorithm: got %v", i, resp.SignatureAlgorithm)
+		}
+		if resp.ProducedAt.IsZero() {
+			t.Errorf("#%d: ProducedAt not set", i)
+		}
+	}
+}
+
+func TestResponseBadSignature(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+
+	template := Response{
+		Status:       Good,
+		SerialNumber: pki.leaf.SerialNumber,
+		ThisUpdate:   time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	der, err := CreateResponse(pki.issuer, pki.issuer, template, pki.issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseResponse(der, other.issuer); err == nil {
+		t.Error("response verified against the wrong issuer")
+	} else if _, ok := err.(ParseError); !ok {
+		t.Errorf("expected ParseError, got %#v", err)
+	}
+}
+
+func TestDelegatedResponder(t *testing.T) {
+	pki := newTestPKI(t)
+
+	responderKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1000, 0)
+	for _, authorized := range []bool{true, false} {
+		responderTemplate := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "Test OCSP Responder"},
+			NotBefore:    now,
+			NotAfter:     now.Add(time.Hour),
+		}
+		if authorized {
+			responderTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+		}
+		responder := createCertificate(t, responderTemplate, pki.issuer, &responderKey.PublicKey, pki.issuerKey)
+
+		template := Response{
+			Status:       Good,
+			SerialNumber: pki.leaf.SerialNumber,
+			ThisUpdate:   time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		der, err := CreateResponse(pki.issuer, responder, template, responderKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := ParseResponse(der, pki.issuer)
+		if !authorized {
+			if err == nil {
+				t.Error("response from unauthorized responder was accepted")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseResponse: %s", err)
+		}
+		if resp.Certificate == nil || !reflect.DeepEqual(resp.Certificate.Raw, responder.Raw) {
+			t.Error("embedded responder certificate not returned")
+		}
+		if err := resp.CheckSignatureFrom(pki.issuer); err == nil {
+			t.Error("delegated response unexpectedly verified against the issuer")
+		}
+	}
+}
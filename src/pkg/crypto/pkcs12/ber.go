@@ -0,0 +1,199 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import "errors"
+
+// berToDER converts the BER encoding produced by some PKCS#12 implementations,
+// notably those using indefinite lengths and constructed strings, into a
+// form that encoding/asn1 can parse. Indefinite lengths are replaced with
+// definite ones and constructed strings are flattened into primitive ones;
+// everything else is copied unchanged.
+func berToDER(ber []byte) ([]byte, error) {
+	var out []byte
+	for len(ber) > 0 {
+		der, rest, err := berElementToDER(ber)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, der...)
+		ber = rest
+	}
+	return out, nil
+}
+
+var errBERTruncated = errors.New("BER data truncated")
+
+// berElementToDER converts the first element of ber and returns it along
+// with the remaining input.
+func berElementToDER(ber []byte) (der, rest []byte, err error) {
+	// Identifier octets.
+	offset := 0
+	if offset >= len(ber) {
+		return nil, nil, errBERTruncated
+	}
+	constructed := ber[offset]&0x20 != 0
+	if ber[offset]&0x1f == 0x1f {
+		// High tag number form.
+		offset++
+		for {
+			if offset >= len(ber) {
+				return nil, nil, errBERTruncated
+			}
+			if ber[offset]&0x80 == 0 {
+				break
+			}
+			offset++
+		}
+	}
+	offset++
+	identifier := ber[:offset]
+
+	// Length octets.
+	if offset >= len(ber) {
+		return nil, nil, errBERTruncated
+	}
+	l := ber[offset]
+	offset++
+
+	if l == 0x80 {
+		// Indefinite length: the contents are elements terminated
+		// by an end-of-contents marker.
+		if !constructed {
+			return nil, nil, errors.New("indefinite length used with primitive encoding")
+		}
+		var contents []byte
+		rest = ber[offset:]
+		for {
+			if len(rest) < 2 {
+				return nil, nil, errBERTruncated
+			}
+			if rest[0] == 0 && rest[1] == 0 {
+				rest = rest[2:]
+				break
+			}
+			var child []byte
+			child, rest, err = berElementToDER(rest)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, child...)
+		}
+		der, err = constructedElement(identifier, contents)
+		return der, rest, err
+	}
+
+	length := int(l)
+	if l&0x80 != 0 {
+		numBytes := int(l & 0x7f)
+		if numBytes > 4 {
+			return nil, nil, errors.New("BER length too large")
+		}
+		length = 0
+		for i := 0; i < numBytes; i++ {
+			if offset >= len(ber) {
+				return nil, nil, errBERTruncated
+			}
+			length = length<<8 | int(ber[offset])
+			offset++
+		}
+	}
+	if length < 0 || len(ber)-offset < length {
+		return nil, nil, errBERTruncated
+	}
+	contents := ber[offset : offset+length]
+	rest = ber[offset+length:]
+
+	if constructed {
+		// Children may themselves use indefinite lengths.
+		children, err := berToDER(contents)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err = constructedElement(identifier, children)
+		return der, rest, err
+	}
+	return encodeElement(identifier, contents), rest, nil
+}
+
+// constructedElement returns the encoding of a constructed element whose
+// children have already been converted to DER. A constructed string of one
+// of the universal string types is replaced by a primitive one holding the
+// concatenation of its parts. Context-specific elements are kept: without
+// the schema, an implicitly tagged string can't be told apart from an
+// explicitly tagged one, so they are left to the code parsing them (see
+// encryptedContentInfo.data).
+func constructedElement(identifier, children []byte) ([]byte, error) {
+	if len(identifier) != 1 || identifier[0]&0xc0 != 0 || !isStringTag(identifier[0]&0x1f) {
+		return encodeElement(identifier, children), nil
+	}
+	tag := identifier[0] &^ 0x20
+	if tag == 0x03 {
+		// Each part of a BIT STRING has its own count of unused bits.
+		return nil, errors.New("constructed BIT STRING not supported")
+	}
+	value, err := concatStrings(tag, children)
+	if err != nil {
+		return nil, err
+	}
+	return encodeElement([]byte{tag}, value), nil
+}
+
+// isStringTag reports whether tag is the number of a universal string type.
+func isStringTag(tag byte) bool {
+	switch tag {
+	case 3, 4, 12, 18, 19, 20, 21, 22, 25, 26, 27, 28, 30:
+		return true
+	}
+	return false
+}
+
+// concatStrings returns the concatenated contents of the parts of a
+// constructed string, which are DER encoded primitive elements with the
+// identifier tag.
+func concatStrings(tag byte, parts []byte) ([]byte, error) {
+	var value []byte
+	for rest := parts; len(rest) > 0; {
+		if len(rest) < 2 || rest[0] != tag {
+			return nil, errors.New("invalid part in constructed string")
+		}
+		header, length := 2, int(rest[1])
+		if length&0x80 != 0 {
+			numBytes := length & 0x7f
+			header += numBytes
+			if len(rest) < header {
+				return nil, errBERTruncated
+			}
+			length = 0
+			for _, b := range rest[2:header] {
+				length = length<<8 | int(b)
+			}
+		}
+		if length < 0 || len(rest)-header < length {
+			return nil, errBERTruncated
+		}
+		value = append(value, rest[header:header+length]...)
+		rest = rest[header+length:]
+	}
+	return value, nil
+}
+
+// encodeElement returns the encoding of an element with the given
+// identifier octets and contents, using a definite length.
+func encodeElement(identifier, contents []byte) []byte {
+	out := append([]byte(nil), identifier...)
+	n := len(contents)
+	if n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var lenBytes []byte
+		for ; n > 0; n >>= 8 {
+			lenBytes = append([]byte{byte(n)}, lenBytes...)
+		}
+		out = append(out, 0x80|byte(len(lenBytes)))
+		out = append(out, lenBytes...)
+	}
+	return append(out, contents...)
+}
@@ -0,0 +1,307 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+)
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier([]int{1, 3, 14, 3, 2, 26})
+	oidSHA256 = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 2, 1})
+
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 5})
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 6})
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 3})
+	oidPBEWithSHAAnd2KeyTripleDESCBC = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 4})
+
+	oidPBES2          = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 5, 13})
+	oidPBKDF2         = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 5, 12})
+	oidHMACWithSHA1   = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 2, 7})
+	oidHMACWithSHA256 = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 2, 9})
+	oidDESEDE3CBC     = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 3, 7})
+	oidAES128CBC      = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 2})
+	oidAES192CBC      = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 22})
+	oidAES256CBC      = asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 1, 42})
+)
+
+// maxIterations bounds the iteration counts read from a PFX, so that
+// decoding it can't take an unreasonable time.
+const maxIterations = 1 << 22
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+// pbeParams reflects the parameters of the PKCS#12 password based
+// encryption schemes. See RFC 7292, appendix C.
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// pbes2Params reflects the parameters of PBES2. See RFC 2898, appendix A.4.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params reflects the parameters of PBKDF2. See RFC 2898, appendix A.2.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// bmpString returns s encoded in UCS-2 with a zero terminator, which is how
+// PKCS#12 passwords are converted to bytes. See RFC 7292, appendix B.1.
+func bmpString(s string) ([]byte, error) {
+	ret := make([]byte, 0, 2*len(s)+2)
+
+	for _, r := range s {
+		if t, _ := utf16.EncodeRune(r); t != 0xfffd {
+			// A surrogate pair would be needed, which UCS-2 can't
+			// represent.
+			return nil, errors.New("pkcs12: string contains characters that cannot be encoded in UCS-2")
+		}
+		ret = append(ret, byte(r/256), byte(r%256))
+	}
+
+	return append(ret, 0, 0), nil
+}
+
+// pbkdf is the key derivation function of RFC 7292, appendix B.2. It
+// derives size bytes of material from password and salt for the purpose
+// given by id: 1 for keys, 2 for IVs and 3 for MAC keys.
+func pbkdf(h func() hash.Hash, password, salt []byte, iterations int, id byte, size int) []byte {
+	const v = 64 // The block size of SHA-1 and SHA-256.
+
+	fill := func(in []byte) []byte {
+		if len(in) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(in)+v-1)/v))
+		for i := range out {
+			out[i] = in[i%len(in)]
+		}
+		return out
+	}
+
+	D := make([]byte, v)
+	for i := range D {
+		D[i] = id
+	}
+	I := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < size {
+		hasher := h()
+		hasher.Write(D)
+		hasher.Write(I)
+		A := hasher.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hasher.Reset()
+			hasher.Write(A)
+			A = hasher.Sum(A[:0])
+		}
+		out = append(out, A...)
+
+		if len(out) >= size {
+			break
+		}
+
+		// B is A repeated to v bytes. Each v byte block of I is
+		// replaced by (I_j + B + 1) mod 2^(8v).
+		B := make([]byte, v)
+		for i := range B {
+			B[i] = A[i%len(A)]
+		}
+		for j := 0; j < len(I); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(I[j+k]) + int(B[k]) + carry
+				I[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// verifyMac checks the MAC over the authenticated safe of a PFX.
+func verifyMac(macData *macData, message, password []byte) error {
+	var h func() hash.Hash
+	switch {
+	case macData.Mac.Algorithm.Algorithm.Equal(oidSHA1):
+		h = sha1.New
+	case macData.Mac.Algorithm.Algorithm.Equal(oidSHA256):
+		h = sha256.New
+	default:
+		return NotImplementedError(fmt.Sprintf("unknown digest algorithm: %v", macData.Mac.Algorithm.Algorithm))
+	}
+
+	if macData.Iterations <= 0 || macData.Iterations > maxIterations {
+		return NotImplementedError(fmt.Sprintf("MAC iteration count %d is not supported", macData.Iterations))
+	}
+	key := pbkdf(h, password, macData.MacSalt, macData.Iterations, 3, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(message)
+	if !hmac.Equal(macData.Mac.Digest, mac.Sum(nil)) {
+		return ErrIncorrectPassword
+	}
+	return nil
+}
+
+// pbDecrypt decrypts data that was encrypted with the password based
+// encryption scheme given by algorithm. The PKCS#12 schemes use the UCS-2
+// encoding of the password while PBES2 uses the password as given.
+func pbDecrypt(algorithm pkix.AlgorithmIdentifier, data []byte, password string, encodedPassword []byte) ([]byte, error) {
+	block, iv, err := pbCipher(algorithm, password, encodedPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSize := block.BlockSize()
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errors.New("pkcs12: input is not a multiple of the block size")
+	}
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+
+	// The padding is that of PKCS#5, where each of the n padding bytes
+	// is equal to n. Bad padding most likely means a wrong password.
+	last := int(decrypted[len(decrypted)-1])
+	if last == 0 || last > blockSize {
+		return nil, ErrIncorrectPassword
+	}
+	for _, val := range decrypted[len(decrypted)-last:] {
+		if int(val) != last {
+			return nil, ErrIncorrectPassword
+		}
+	}
+	return decrypted[:len(decrypted)-last], nil
+}
+
+// pbCipher returns the block cipher, keyed from the password, and the IV
+// for the password based encryption scheme given by algorithm.
+func pbCipher(algorithm pkix.AlgorithmIdentifier, password string, encodedPassword []byte) (block cipher.Block, iv []byte, err error) {
+	if algorithm.Algorithm.Equal(oidPBES2) {
+		return pbes2Cipher(algorithm, []byte(password))
+	}
+
+	var params pbeParams
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, err
+	}
+	if params.Iterations <= 0 || params.Iterations > maxIterations {
+		return nil, nil, NotImplementedError(fmt.Sprintf("iteration count %d is not supported", params.Iterations))
+	}
+
+	derive := func(keyLen int) (key, iv []byte) {
+		key = pbkdf(sha1.New, encodedPassword, params.Salt, params.Iterations, 1, keyLen)
+		iv = pbkdf(sha1.New, encodedPassword, params.Salt, params.Iterations, 2, 8)
+		return
+	}
+
+	switch {
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+		key, iv := derive(24)
+		block, err := des.NewTripleDESCipher(key)
+		return block, iv, err
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd2KeyTripleDESCBC):
+		key, iv := derive(16)
+		block, err := des.NewTripleDESCipher(append(key, key[:8]...))
+		return block, iv, err
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+		key, iv := derive(16)
+		block, err := newRC2Cipher(key, 128)
+		return block, iv, err
+	case algorithm.Algorithm.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		key, iv := derive(5)
+		block, err := newRC2Cipher(key, 40)
+		return block, iv, err
+	}
+	return nil, nil, NotImplementedError(fmt.Sprintf("algorithm %v is not supported", algorithm.Algorithm))
+}
+
+// pbes2Cipher returns the block cipher and IV for PBES2 with PBKDF2 and an
+// AES or 3DES cipher in CBC mode. See RFC 2898, section 6.2.
+func pbes2Cipher(algorithm pkix.AlgorithmIdentifier, password []byte) (block cipher.Block, iv []byte, err error) {
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, NotImplementedError(fmt.Sprintf("PBES2 key derivation function %v is not supported", params.KeyDerivationFunc.Algorithm))
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, nil, err
+	}
+	if kdfParams.IterationCount <= 0 || kdfParams.IterationCount > maxIterations {
+		return nil, nil, NotImplementedError(fmt.Sprintf("PBKDF2 iteration count %d is not supported", kdfParams.IterationCount))
+	}
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0 || kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, nil, NotImplementedError(fmt.Sprintf("PBKDF2 pseudorandom function %v is not supported", kdfParams.PRF.Algorithm))
+	}
+
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	case scheme.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case scheme.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case scheme.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	default:
+		return nil, nil, NotImplementedError(fmt.Sprintf("PBES2 encryption scheme %v is not supported", scheme))
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != keyLen {
+		return nil, nil, errors.New("pkcs12: PBKDF2 key length doesn't match cipher")
+	}
+
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, err
+	}
+
+	block, err = newCipher(pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, keyLen, prf))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, errors.New("pkcs12: incorrect IV size")
+	}
+	return block, iv, nil
+}
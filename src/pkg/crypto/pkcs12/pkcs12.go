@@ -0,0 +1,316 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pkcs12 implements some of PKCS#12, as specified in RFC 7292.
+//
+// It is intended for decoding the P12/PFX files used to exchange a private
+// key together with its certificate chain, such as those exported by
+// Windows or OpenSSL. Creating such files is not supported.
+package pkcs12
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+)
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 1})
+	oidEncryptedDataContentType = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 6})
+
+	oidLocalKeyID       = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 21})
+	oidCertTypeX509Cert = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 22, 1})
+
+	oidKeyBag              = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 1})
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 2})
+	oidCertBag             = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 3})
+)
+
+// ErrIncorrectPassword is returned when an incorrect password is detected.
+var ErrIncorrectPassword = errors.New("pkcs12: decryption password incorrect")
+
+// NotImplementedError indicates that the input is not currently supported.
+type NotImplementedError string
+
+func (e NotImplementedError) Error() string {
+	return "pkcs12: " + string(e)
+}
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional"` // [0] IMPLICIT OCTET STRING
+}
+
+// data returns the encrypted content, which BER allows to be split into
+// several OCTET STRINGs.
+func (info *encryptedContentInfo) data() ([]byte, error) {
+	c := info.EncryptedContent
+	if c.FullBytes == nil {
+		return nil, nil
+	}
+	if c.Class != 2 || c.Tag != 0 {
+		return nil, errors.New("pkcs12: invalid encrypted content")
+	}
+	if !c.IsCompound {
+		return c.Bytes, nil
+	}
+	return concatStrings(0x04, c.Bytes)
+}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	Id    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	Id   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// localKeyID returns the value of the localKeyId attribute of bag, if any.
+// It links a private key to its certificate.
+func (bag *safeBag) localKeyID() []byte {
+	for _, attr := range bag.Attributes {
+		if !attr.Id.Equal(oidLocalKeyID) {
+			continue
+		}
+		var id []byte
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &id); err == nil {
+			return id
+		}
+	}
+	return nil
+}
+
+// Decode extracts a private key, the certificate that matches it and any
+// other certificates, such as the rest of its chain, from pfxData, which
+// must be a DER or BER encoded PKCS#12 file protected by password. The
+// private key is returned as an *rsa.PrivateKey or *ecdsa.PrivateKey.
+//
+// The integrity of pfxData is checked using its MAC, if it has one. If the
+// MAC doesn't match or any part fails to decrypt then ErrIncorrectPassword
+// is returned.
+func Decode(pfxData []byte, password string) (privateKey interface{}, certificate *x509.Certificate, caCerts []*x509.Certificate, err error) {
+	encodedPassword, err := bmpString(password)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bags, err := getSafeContents(pfxData, password, encodedPassword)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var keyID []byte
+	var certs []*x509.Certificate
+	var certIDs [][]byte
+	for i := range bags {
+		bag := &bags[i]
+		switch {
+		case bag.Id.Equal(oidCertBag):
+			var cb certBag
+			if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+				return nil, nil, nil, err
+			}
+			if !cb.Id.Equal(oidCertTypeX509Cert) {
+				return nil, nil, nil, NotImplementedError("only X.509 certificates are supported")
+			}
+			cert, err := x509.ParseCertificate(cb.Data)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			certs = append(certs, cert)
+			certIDs = append(certIDs, bag.localKeyID())
+
+		case bag.Id.Equal(oidKeyBag), bag.Id.Equal(oidPKCS8ShroudedKeyBag):
+			if privateKey != nil {
+				return nil, nil, nil, NotImplementedError("expected exactly one private key")
+			}
+			if privateKey, err = decodeKeyBag(bag, password, encodedPassword); err != nil {
+				return nil, nil, nil, err
+			}
+			keyID = bag.localKeyID()
+		}
+	}
+
+	if privateKey == nil {
+		return nil, nil, nil, errors.New("pkcs12: private key missing")
+	}
+
+	match := -1
+	if keyID != nil {
+		for i, id := range certIDs {
+			if bytes.Equal(id, keyID) {
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 {
+		// Fall back to comparing the public keys.
+		for i, cert := range certs {
+			if publicKeyMatches(cert, privateKey) {
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 {
+		return nil, nil, nil, errors.New("pkcs12: certificate missing")
+	}
+
+	certificate = certs[match]
+	for i, cert := range certs {
+		if i != match {
+			caCerts = append(caCerts, cert)
+		}
+	}
+	return privateKey, certificate, caCerts, nil
+}
+
+// publicKeyMatches returns true if the public half of privateKey is the
+// public key of cert.
+func publicKeyMatches(cert *x509.Certificate, privateKey interface{}) bool {
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return false
+	}
+	certKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return false
+	}
+	key, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(certKey, key)
+}
+
+func decodeKeyBag(bag *safeBag, password string, encodedPassword []byte) (interface{}, error) {
+	if bag.Id.Equal(oidKeyBag) {
+		return x509.ParsePKCS8PrivateKey(bag.Value.Bytes)
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(bag.Value.Bytes, &info); err != nil {
+		return nil, err
+	}
+	decrypted, err := pbDecrypt(info.AlgorithmIdentifier, info.EncryptedData, password, encodedPassword)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(decrypted)
+	if err != nil {
+		// The padding check may pass with the wrong password.
+		return nil, ErrIncorrectPassword
+	}
+	return key, nil
+}
+
+// getSafeContents checks the MAC of a PFX and returns the safe bags that it
+// contains, decrypting them where necessary.
+func getSafeContents(pfxData []byte, password string, encodedPassword []byte) (bags []safeBag, err error) {
+	der, err := berToDER(pfxData)
+	if err != nil {
+		return nil, errors.New("pkcs12: error reading P12 data: " + err.Error())
+	}
+
+	var pfx pfxPdu
+	rest, err := asn1.Unmarshal(der, &pfx)
+	if err != nil {
+		return nil, errors.New("pkcs12: error reading P12 data: " + err.Error())
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("pkcs12: trailing data found")
+	}
+
+	if pfx.Version != 3 {
+		return nil, NotImplementedError("can only decode v3 PFX PDU's")
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, NotImplementedError("only password-protected PFX is implemented")
+	}
+
+	var authSafeData []byte
+	if _, err = asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafeData); err != nil {
+		return nil, err
+	}
+
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		if err = verifyMac(&pfx.MacData, authSafeData, encodedPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	var authSafe []contentInfo
+	if _, err = asn1.Unmarshal(authSafeData, &authSafe); err != nil {
+		return nil, err
+	}
+
+	for _, ci := range authSafe {
+		var data []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if _, err = asn1.Unmarshal(ci.Content.Bytes, &data); err != nil {
+				return nil, err
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var encrypted encryptedData
+			if _, err = asn1.Unmarshal(ci.Content.Bytes, &encrypted); err != nil {
+				return nil, err
+			}
+			if encrypted.Version != 0 {
+				return nil, NotImplementedError("only version 0 of EncryptedData is supported")
+			}
+			info := encrypted.EncryptedContentInfo
+			if data, err = info.data(); err != nil {
+				return nil, err
+			}
+			if data, err = pbDecrypt(info.ContentEncryptionAlgorithm, data, password, encodedPassword); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, NotImplementedError("only data and encryptedData content types are supported in authenticated safe")
+		}
+
+		var safeContents []safeBag
+		if _, err = asn1.Unmarshal(data, &safeContents); err != nil {
+			return nil, err
+		}
+		bags = append(bags, safeContents...)
+	}
+
+	return bags, nil
+}
@@ -0,0 +1,296 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeBase64(t *testing.T, s string) []byte {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var decodeTests = []struct {
+	name string
+	pfx  string
+}{
+	// openssl pkcs12 -export -legacy: RC2-40 encrypted certificates, a
+	// 3DES shrouded key and a SHA-1 MAC.
+	{"legacy", legacyPFX},
+	// openssl pkcs12 -export: PBES2 with AES-256 and a SHA-256 MAC.
+	{"modern", modernPFX},
+}
+
+func TestDecode(t *testing.T) {
+	for _, test := range decodeTests {
+		pfx := decodeBase64(t, test.pfx)
+		key, cert, caCerts, err := Decode(pfx, "password")
+		if err != nil {
+			t.Errorf("%s: Decode failed: %s", test.name, err)
+			continue
+		}
+		priv, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			t.Errorf("%s: got key of type %T, want *rsa.PrivateKey", test.name, key)
+			continue
+		}
+		if err := priv.Validate(); err != nil {
+			t.Errorf("%s: private key failed to validate: %s", test.name, err)
+		}
+		if cn := cert.Subject.CommonName; cn != "leaf.example.com" {
+			t.Errorf("%s: got certificate for %q, want leaf.example.com", test.name, cn)
+		}
+		if !publicKeyMatches(cert, priv) {
+			t.Errorf("%s: certificate does not match the private key", test.name)
+		}
+		if len(caCerts) != 1 {
+			t.Errorf("%s: got %d CA certificates, want 1", test.name, len(caCerts))
+		} else if cn := caCerts[0].Subject.CommonName; cn != "Test CA" {
+			t.Errorf("%s: got CA certificate for %q, want Test CA", test.name, cn)
+		}
+
+		if _, _, _, err := Decode(pfx, "wrong"); err != ErrIncorrectPassword {
+			t.Errorf("%s: Decode with the wrong password returned %v, want ErrIncorrectPassword", test.name, err)
+		}
+	}
+}
+
+func TestBMPString(t *testing.T) {
+	got, err := bmpString("Beavis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 'B', 0, 'e', 0, 'a', 0, 'v', 0, 'i', 0, 's', 0, 0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+
+	if _, err := bmpString("\U0001F600"); err == nil {
+		t.Error("expected an error for a character outside the BMP")
+	}
+}
+
+func TestPBKDF(t *testing.T) {
+	password, _ := bmpString("sesame")
+	salt := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	// A key longer than the SHA-1 output, as used by
+	// pbeWithSHAAnd3-KeyTripleDES-CBC, exercises the update of I.
+	key := pbkdf(sha1.New, password, salt, 2048, 1, 24)
+	want, _ := hex.DecodeString("7cd9fd3e2b3be7691a44e3bef0f9ea0fb9b897d4e325d9d1")
+	if !bytes.Equal(key, want) {
+		t.Errorf("got key %x, want %x", key, want)
+	}
+}
+
+func TestIterationLimit(t *testing.T) {
+	params, err := asn1.Marshal(pbeParams{Salt: []byte("salt"), Iterations: maxIterations + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm := pkix.AlgorithmIdentifier{
+		Algorithm:  oidPBEWithSHAAnd3KeyTripleDESCBC,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}
+	if _, _, err := pbCipher(algorithm, "password", []byte("password")); err == nil {
+		t.Error("expected an error for too many iterations")
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{Salt: []byte("salt"), IterationCount: maxIterations + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv, err := asn1.Marshal(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err = asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES128CBC, Parameters: asn1.RawValue{FullBytes: iv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	algorithm = pkix.AlgorithmIdentifier{
+		Algorithm:  oidPBES2,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}
+	if _, _, err := pbCipher(algorithm, "password", nil); err == nil {
+		t.Error("expected an error for too many PBKDF2 iterations")
+	}
+}
+
+func TestBERToDER(t *testing.T) {
+	// SEQUENCE (indefinite) { OCTET STRING (constructed, indefinite)
+	// { "ab", "c" }, INTEGER 1 }
+	ber := []byte{
+		0x30, 0x80,
+		0x24, 0x80, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c', 0x00, 0x00,
+		0x02, 0x01, 0x01,
+		0x00, 0x00,
+	}
+	want := []byte{0x30, 0x08, 0x04, 0x03, 'a', 'b', 'c', 0x02, 0x01, 0x01}
+	got, err := berToDER(ber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+
+	if _, err := berToDER(ber[:len(ber)-2]); err == nil {
+		t.Error("expected an error for truncated input")
+	}
+}
+
+var berStringTests = []struct {
+	ber, der []byte // der is nil if ber must be rejected
+}{
+	// UTF8String (constructed) { "a", "b" }
+	{[]byte{0x2c, 0x06, 0x0c, 0x01, 'a', 0x0c, 0x01, 'b'}, []byte{0x0c, 0x02, 'a', 'b'}},
+	// OCTET STRING (constructed) { OCTET STRING (constructed) { "a" }, "b" }
+	{[]byte{0x24, 0x08, 0x24, 0x03, 0x04, 0x01, 'a', 0x04, 0x01, 'b'}, []byte{0x04, 0x02, 'a', 'b'}},
+	// [0] (constructed) { "a" } may be an explicit tag and is kept.
+	{[]byte{0xa0, 0x80, 0x04, 0x01, 'a', 0x00, 0x00}, []byte{0xa0, 0x03, 0x04, 0x01, 'a'}},
+	// OCTET STRING (constructed) { INTEGER 1 }
+	{[]byte{0x24, 0x03, 0x02, 0x01, 0x01}, nil},
+	// BIT STRING (constructed) { BIT STRING 0x80 }
+	{[]byte{0x23, 0x04, 0x03, 0x02, 0x00, 0x80}, nil},
+}
+
+func TestBERStrings(t *testing.T) {
+	for _, test := range berStringTests {
+		got, err := berToDER(test.ber)
+		switch {
+		case test.der == nil && err == nil:
+			t.Errorf("%x: got %x, want an error", test.ber, got)
+		case test.der != nil && err != nil:
+			t.Errorf("%x: %s", test.ber, err)
+		case !bytes.Equal(got, test.der):
+			t.Errorf("%x: got %x, want %x", test.ber, got, test.der)
+		}
+	}
+}
+
+func TestEncryptedContent(t *testing.T) {
+	prefix := []byte{
+		0x30, 0x80,
+		// data content type
+		0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x07, 0x01,
+		// pbeWithSHAAnd40BitRC2-CBC, without parameters
+		0x30, 0x0c, 0x06, 0x0a, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x01, 0x0c, 0x01, 0x06,
+	}
+	contents := [][]byte{
+		// [0] IMPLICIT OCTET STRING "abc"
+		{0x80, 0x03, 'a', 'b', 'c'},
+		// the same, constructed from "ab" and "c"
+		{0xa0, 0x80, 0x04, 0x02, 'a', 'b', 0x04, 0x01, 'c', 0x00, 0x00},
+	}
+	for _, content := range contents {
+		ber := append(append(append([]byte(nil), prefix...), content...), 0x00, 0x00)
+		der, err := berToDER(ber)
+		if err != nil {
+			t.Errorf("%x: %s", content, err)
+			continue
+		}
+		var info encryptedContentInfo
+		if _, err := asn1.Unmarshal(der, &info); err != nil {
+			t.Errorf("%x: %s", content, err)
+			continue
+		}
+		data, err := info.data()
+		if err != nil || string(data) != "abc" {
+			t.Errorf("%x: got %q, %v, want \"abc\"", content, data, err)
+		}
+	}
+}
+
+const legacyPFX = `MIIH+gIBAzCCB8AGCSqGSIb3DQEHAaCCB7EEggetMIIHqTCCBI8GCSqGSIb3DQEHBqCCBIAwggR8
+AgEAMIIEdQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQYwDgQIXLyHaCSTvjYCAggAgIIESDt6KQcP
+hJ6lqG9nZCLmH9QCG8Hq+oo/lAGkrm53M7SVXfzXk4gfQMPR18SS0sDphu4PGeFgWnpNpUsQa+js
+4aZJoRNfSWyKgOmUlxAD/thkQxv8FBFQRaI2cTo07snmAKN6OyaBIeB3L8+qQAYlnYaVZdHJ1QNE
+G+Eeoou4oFqJBTeBR6hBwilAcxq6obnzjAWj/oKQcfWHbTWfDgcbrDKUmeiXXQQutLXaKUWUermy
+seOKR41Z+/l09nN4CZrUNQn1F7tNSQ35HdT/t7j2F6TGI2qvbNy/Z1K9clJ9YeOJbRUiul/3ugDl
+myi5SEEzo/1QW3cJVTRxTikPiL/FImf5M2jacyFIpJqCenkktRTzUpwoacjrvMOOFYkp7IM5jvb8
+qfGw/t/yltPKZudffA5CCB+Pn+s4GmVItu2I4jYQ5guAPZIP+OL7gCo+Rstsa0XqHI2I0cuXW8pk
+lpDZDdB49T6KCDWQ5oF9KdtYJuGDPAO0N+ZqOrL+JwezjOILX+UEA8avvH1t+AqmjiADHStkvI4G
+pnpeMN/mWO3jPgUaSThRWd3vHYEWlEXyKE1+y367XTTTzuHrKNzaABwIYbAEMDZk0zXoC0OzVsAp
+3JeYZ5KI/a/+j1CshVwDHwX7BdFCiQMqbMBEcjY0fk1mpC+HzZXMn6i6t+pEQ60gLyddPVYkEST8
+445m21Rnh8CaSMP0qmMVRsOzGwBm8Es//pYm9+fefypI7LOULT/6cI2ujCPdS0Pz7ySI/3Lp/SzT
+axqoupMXMn4JkqOOMn/QNvCSMahK+2ZYh4UzYBQMRB7LPFmM9x3Ejl/1c5BL7jbEnPHcgVVStMq/
+v12z4gL++xbXMitKg6zn7EUcQfUnAXVUxUiMT+a+ZswOL9kWCWyunoN5mWojHFxXRmlD5J2xcFS0
+z7hcDdZXieFdMEALf9wEauqSIE5NqNmD+5ZqQ34dRJeiBHiuYeqXVjcGCQ9cm+yY/gk8r3wM473Q
++cePI4aXk9VOcAUQHwYIq+UTYpAWbBTYsHC4y2hVO4M/28jy8tWxE8ZWxg4J5piQ/kKUB4WJhj9D
+vd+uI5jEfZRprjhPqVujKfncCj4l/H9BIZf1p1UKQlLNhgzKiuU1aiFRx3bywHXplukDxr7k1im0
+eQ+QKJ0IQ4lr2EihNO6m2hJO1ulR8PzSziAkEFhE32jiyuW88h7ogjiNCVDMCRCTyEtkh11UFlh6
+y63k6Hg6goH8EyokQ7vtuexRSiHdkeJQqbrZWiTdhrt4oMSqgsGvr89yJpWEQo2gNqF15m4hWYR1
+4nysMBXHU9ncjZaSx8BFE3ee15v4Nh1zy3NSIwR7qql5H1RlpZg0s8SNOtxC96a7aSSh3iQVJhsq
+mYxggPDwdgcfWeWTzsMUIwej0BfJSw4mAx0LcHt+o6Y5IJjgNNxBrSrBiwKcPUXrC6J0Igp7mSjG
+6JsK0iTr1PAwggMSBgkqhkiG9w0BBwGgggMDBIIC/zCCAvswggL3BgsqhkiG9w0BDAoBAqCCAqYw
+ggKiMBwGCiqGSIb3DQEMAQMwDgQIUcXVApaF6+ICAggABIICgNbR2M46ycNWYXqm0j2U4q24gh9J
+BuFhH+0u65a0u+BnWMz9hzP/PkVISmf/9e3T7ecmmXT/0BXxlynmdAgTnSF9icX90AmqcNEM7mxp
+KF3RXShtaLjfOTnzukhZ+8FX+z27n48FyiBMAkEpWRCFaPebka8NZI6VoRtZdUj468HETiElbgtl
+/ffQR3cR6rJLWqo9i+ZrR3LFZPbgSZ0aN6xYVaPD5tb/wM4ebOJ0J7uZYZp8lRhJHQf3SlZ326Us
+OzK/iLP201YW2JJSyL4baLwxg7OOCK16Zc58R1VaxOfRu8wK87+8rJQ9Yk1ZvXcDIPZV8pXtbIQN
+I34E89FwYuNDICa+Dz6tL8CoS2jdsz2Brdp2as5uC5nUXGVrCDFOShPYois31G6WTUyfxAszJ/ed
+xZtQ0MdF/6kYQ336vH6p+usiz8G6wVVv1XwUJf7GT0ZxyPWaHBsXPmCBi9+RjdOKlw4sLhcAj77i
+dltYp/MRhXGp0S//vj/bnwUyrAFEyWHgrKA48Pz4/ndJH4oTEycfGXUS6IHylIwtGn5ZPBkQTjV0
+2ywHg+yWfPxro9+kQ5saP71TkPzCAmq3dYHQkRDwA0cEvJSg9v4sN6kPXWT46qtxn1Vg0+TGnRPe
+2wYuZEmgwiflItFi+0apMyEZqGQ46SmBO12FrJOrO1dYaJJphCnWI2WI4S+ToFmYnQNPeGnTkeba
+t6jE2qj0Z2Bk2zcDOEsGFG9XuDkAPf9a1G0TSTQ6n4Iyu23RKWsuIXAj0BWYqlq66wyO+RX6ygL7
+okhy/fGBC1UCBp1fIxflzSj5/8mV5UxLFJE2z6i75eavmPcQue5q2hE6e5xqPVfwORMxPjAXBgkq
+hkiG9w0BCRQxCh4IAGwAZQBhAGYwIwYJKoZIhvcNAQkVMRYEFI/o1QRUapj+5i/zt2AJSS9b4q5F
+MDEwITAJBgUrDgMCGgUABBT6Zc1LfHn03445odQ9hnYhcVnHhgQI2ZXZQzqam0oCAggA`
+
+const modernPFX = `MIIIiAIBAzCCCD4GCSqGSIb3DQEHAaCCCC8EgggrMIIIJzCCBNIGCSqGSIb3DQEHBqCCBMMwggS/
+AgEAMIIEuAYJKoZIhvcNAQcBMFcGCSqGSIb3DQEFDTBKMCkGCSqGSIb3DQEFDDAcBAhh4cJLbIwk
+UgICCAAwDAYIKoZIhvcNAgkFADAdBglghkgBZQMEASoEEIn9Jvj8pc3S8G4r/tUmDh+AggRQbKam
+i5yLOBR9jYdRAjt+lDuXXRIU+uj8G7piKDZggrQhZki7F9heJzd62hrMeSkKvUCeZQh/Yrxuq4ls
+WQ6X+jbFJWgEQIw6WdaRtnWkWuwMObxDPXhr3+NnG3u9nWqnCZNLHaIDVdGi+fPXf8gcwhRh2DCf
+xPg0cclKgmzOtGVVx0cDspBRMj9RqUTZ5JP499ykJSgcrpQrm1J4G9GmUGQ+k5QEhStfdxiUoTVo
+aZQRz08cEqV1TFfjWgfRLMsC75UEa/wZAnWfwlVX0nI9JIPDo00YiZUR8+DZsxquQgUkTt0N+kNL
+79gwHbLM9sJV5M9rCq+vlV/idK3mGIKDKkcsWlaCfnxXVSMvz2sBhPuT7CyqJXSWHHXz1fDi4lRj
+p9KVlWF56vKiNTdizf9rjZNJhTTRlSA14TvD0FSeEg/afwmlPoNW/ZhRYhtAOqSrwKDGCnl6ANnj
+Rau6YV1BMq+xBNDC6T8pqwytTgT0s3K/w+Tc59JDbSoRWrodE/AGHVRyJK+UDO3UFd+tLdSW7KUh
+TGPRZOlsmCU4zbrONKBYGHRk+IC2y/l5gh9ymJFaQTOkGlw+ZUOS24QvAPMCr6k4AWkkFaUff6Ho
+uYvXpEd/A1Iv7WJMgKTaGPh+5hOy36sGLqxKQB+8O4zuwPJEFj5VCZy5zam0OI42sCZaiSuDa7qw
+OcNZjNqej+FVuJE/uPKNZfRzZ5aRwghfrAFNagglZU5R9Wgn8JrmcQYPvN+YRLbySyEEmVCMIjyP
+uk6wx+EUGaLnWdrMaG6vlADnbz9y0ITQnc3SxYuusf2CTCJHC5QvoGsPQzSr8VbIHBuRnJzxaZtl
+CxTrbfSa+P444iWunvUCIw3o2vQWhG1pZkc72kPaVRO2KD9Mn0fRRFZWI068PoJcXE4t92yDgJQO
+KdU2BMlUOdMiASZEryk448auFG+DH94C909YAVmkJzRQCmEYlySK3ENj4GT2E5FMut0v5bimfGDw
+ZWLdOvGztfSeeNRhTmpvhryFreH8H02S3kFTlxTPHGy/2nYLnF2buJGJBgAxPlHliQ5YXTNJX9sN
+wsBKCHu135pOsYfmxoi/1QGuCDTuzpoJbbsO35frjcatZ08L9S2R1Nv5Xkq6N+l393BmND8T1B0f
+hvLNqyvYN+SKijYyaK1ePulmfzYchnaNJrS3fIm65yi5yr7sqUUneRLr780o8CSq8oUu3Zz1ppTq
+nCtkGaseZO2mEj9fatnNn/bGusOIOAF7m9xt2/QlNPOhG++tEdzbr1iMCKDd66rSldIjIx3XFWJi
+T8FGvIl3WCGIBsCDmJCX69ebUfjDMckVtPl7fE/aLUwHU9mJqKYKI0ZZ0Y4TXBdvV1Rk34YO19F/
+MozKq/n3tAa9We8SvGM3yShCbvgMpR9LdVI2dHQDS5UxTNSaqLUe+upkI7Cp5dDMev5Zuml1HEcI
+hpeiWdxzxY8w6tMS1NXDgHfDMIIDTQYJKoZIhvcNAQcBoIIDPgSCAzowggM2MIIDMgYLKoZIhvcN
+AQwKAQKgggLhMIIC3TBXBgkqhkiG9w0BBQ0wSjApBgkqhkiG9w0BBQwwHAQIjQpCWxmIqzwCAggA
+MAwGCCqGSIb3DQIJBQAwHQYJYIZIAWUDBAEqBBDXormXxeitHHPICmtRmc9zBIICgDlTm0JLvFa3
+6bYxc26hy8Up/CPG5ntMAc3NhiQso1MxrGAYd885M05ZIm9Olp44l3/F9NaCIwEJ7k7y6CsdP6Ol
+dNeWZ9dmTvCb/FWX4L3P428153990I6o3IkfY99hZhn08DfNxPnWiY7/2Du2DZIJfsSSb3xatMNz
+10WqmoYoHwt3Xb13ylN5PJ7pADX55VIMhMi56Sx5Xy/ep/JbCCGqjPAbkYIPsOrTjFqccivgFiIt
+ImUpgKfWVvGPLuuhhiv0f4miflex7NfxJNslu+GBYKc8UF5ACCc4hL5GiGkiJ0QYxiP71kadEKLc
+OdfNa6tGAnecvOgCspm7+MyujX9D9z+wLNwqVE25XwKqonIgYVbJxB/cMdvuEt5DKHCzKO8kJ1lx
+P9aW1vjWgc5KVd/rMQrJ8xJ/FcRqKEcP5cx4S6v8yEC22ykWF9iWVhKuVR6mIJIApOWau2tda07I
++xythAR9HKSzALcEBPWodayiTpF7Ypb8VnJVvc4PUi6/zjXIH3sK6wWljtfrIU+GT9YQy9qNKXrT
+hvkP5+BWv3gP0HrztidEcZDTzbrSGekaWr1E1fbO2zJhdbdUQR3pt4FiCDY3lyXzNt+S7jxeDYOQ
+Zgciv5vpvkvkl9HLtq0JEFsHLQVkEP1kT5n3DAf+uuZygVZ9b2+pYg6AEy2g029f2T5iOO7Be/3p
+tiYY+Q5excVtIspHsV4Yvd4q3CG3aeOxh+fwEVBAzUgv8AxqrWnGID8/xnH+sidqDbHQKGeCdvUh
+6Dp250MkE1JBDPkyUryMRnCiNQ2wwCWG5jPe32TDZ8JX6SriiXJU4I5ppvG5PmWvBOta4OwhK7Sq
+5+cmWLgxPjAXBgkqhkiG9w0BCRQxCh4IAGwAZQBhAGYwIwYJKoZIhvcNAQkVMRYEFI/o1QRUapj+
+5i/zt2AJSS9b4q5FMEEwMTANBglghkgBZQMEAgEFAAQgXpCh3sDKj9blDgkGC/4kzft8UTy5uBT/
+xhu34ByeIZUECP+bonCWfn7vAgIIAA==`
@@ -0,0 +1,50 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pkcs12
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors from RFC 2268, section 5.
+var rc2Tests = []struct {
+	key, plaintext, ciphertext string
+	effectiveBits              int
+}{
+	{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+	{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+	{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+	{"88", "0000000000000000", "61a8a244adacccf0", 64},
+	{"88bca90e90875a", "0000000000000000", "6ccf4308974c267f", 64},
+	{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "1a807d272bbe5db1", 64},
+	{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	{"88bca90e90875a7f0f79c384627bafb216f80a6f85920584c42fceb0be255daf1e", "0000000000000000", "5b78d3a43dfff1f1", 129},
+}
+
+func TestRC2(t *testing.T) {
+	for i, test := range rc2Tests {
+		key, _ := hex.DecodeString(test.key)
+		plaintext, _ := hex.DecodeString(test.plaintext)
+		ciphertext, _ := hex.DecodeString(test.ciphertext)
+
+		c, err := newRC2Cipher(key, test.effectiveBits)
+		if err != nil {
+			t.Fatalf("#%d: %s", i, err)
+		}
+
+		out := make([]byte, rc2BlockSize)
+		c.Encrypt(out, plaintext)
+		if !bytes.Equal(out, ciphertext) {
+			t.Errorf("#%d: Encrypt got %x, want %x", i, out, ciphertext)
+		}
+
+		c.Decrypt(out, ciphertext)
+		if !bytes.Equal(out, plaintext) {
+			t.Errorf("#%d: Decrypt got %x, want %x", i, out, plaintext)
+		}
+	}
+}
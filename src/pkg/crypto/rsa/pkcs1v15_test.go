@@ -51,14 +51,25 @@ var decryptPKCS1v15Tests = []DecryptPKCS1v15Test{
 }
 
 func TestDecryptPKCS1v15(t *testing.T) {
-	for i, test := range decryptPKCS1v15Tests {
-		out, err := DecryptPKCS1v15(nil, rsaPrivateKey, decodeBase64(test.in))
-		if err != nil {
-			t.Errorf("#%d error decrypting", i)
-		}
-		want := []byte(test.out)
-		if !bytes.Equal(out, want) {
-			t.Errorf("#%d got:%#v want:%#v", i, out, want)
+	decryptionFuncs := []func([]byte) ([]byte, error){
+		func(ciphertext []byte) (plaintext []byte, err error) {
+			return DecryptPKCS1v15(nil, rsaPrivateKey, ciphertext)
+		},
+		func(ciphertext []byte) (plaintext []byte, err error) {
+			return rsaPrivateKey.Decrypt(nil, ciphertext, nil)
+		},
+	}
+
+	for _, decryptFunc := range decryptionFuncs {
+		for i, test := range decryptPKCS1v15Tests {
+			out, err := decryptFunc(decodeBase64(test.in))
+			if err != nil {
+				t.Errorf("#%d error decrypting", i)
+			}
+			want := []byte(test.out)
+			if !bytes.Equal(out, want) {
+				t.Errorf("#%d got:%#v want:%#v", i, out, want)
+			}
 		}
 	}
 }
@@ -138,6 +149,22 @@ func TestEncryptPKCS1v15SessionKey(t *testing.T) {
 	}
 }
 
+func TestEncryptPKCS1v15DecrypterWithSessionKey(t *testing.T) {
+	for i, test := range decryptPKCS1v15SessionKeyTests {
+		plaintext, err := rsaPrivateKey.Decrypt(rand.Reader, decodeBase64(test.in), &PKCS1v15DecryptOptions{SessionKeyLen: 4})
+		if err != nil {
+			t.Fatalf("#%d: error decrypting: %s", i, err)
+		}
+		if len(plaintext) != 4 {
+			t.Fatalf("#%d: incorrect length plaintext: got %d, want 4", i, len(plaintext))
+		}
+
+		if test.out != "FAIL" && !bytes.Equal(plaintext, []byte(test.out)) {
+			t.Errorf("#%d: incorrect plaintext: got %x, want %x", i, plaintext, test.out)
+		}
+	}
+}
+
 func TestNonZeroRandomBytes(t *testing.T) {
 	random := rand.Reader
 
@@ -179,6 +206,14 @@ func TestSignPKCS1v15(t *testing.T) {
 		if !bytes.Equal(s, expected) {
 			t.Errorf("#%d got: %x want: %x", i, s, expected)
 		}
+
+		s, err = rsaPrivateKey.Sign(nil, digest, crypto.SHA1)
+		if err != nil {
+			t.Errorf("#%d %s", i, err)
+		}
+		if !bytes.Equal(s, expected) {
+			t.Errorf("#%d Sign got: %x want: %x", i, s, expected)
+		}
 	}
 }
 
@@ -197,6 +232,36 @@ func TestVerifyPKCS1v15(t *testing.T) {
 	}
 }
 
+// These vectors were generated with `openssl dgst -sha3-224 -sign pk` and
+// `openssl dgst -sha3-256 -sign pk`. The digests of "Test.\n" are given
+// directly so that this package does not need to import crypto/sha3.
+var signPKCS1v15SHA3Tests = []struct {
+	hash        crypto.Hash
+	digest, sig string
+}{
+	{crypto.SHA3_224, "eb5dc04bfc2ca7ab066c0af2e742780f3d9359e6e5f2b973c0dd9424", "175d42c068d2dd90e3b0eb234ac1a728b6fbe7cc5b11f29a0dfdb7085605822eeb6af325d60c78a042bb0b5294c6702e41c32ce5310a19780a61160dd6003a77"},
+	{crypto.SHA3_256, "00b9fdfc3a2ebc54780c084d90c2662c14905ae91e1155f9c2097d9f83e80a36", "55e9fba3354dfb51d2c8111794ea552c86afc2cab154652c03324df8c2c51ba72ff7c14de59a6f9ba50d90c13a7537cc3011948369f1f0ec4a49d21eb7e723f9"},
+}
+
+func TestSignPKCS1v15SHA3(t *testing.T) {
+	for i, test := range signPKCS1v15SHA3Tests {
+		digest, _ := hex.DecodeString(test.digest)
+		expected, _ := hex.DecodeString(test.sig)
+
+		s, err := SignPKCS1v15(nil, rsaPrivateKey, test.hash, digest)
+		if err != nil {
+			t.Errorf("#%d %s", i, err)
+			continue
+		}
+		if !bytes.Equal(s, expected) {
+			t.Errorf("#%d got: %x want: %x", i, s, expected)
+		}
+		if err := VerifyPKCS1v15(&rsaPrivateKey.PublicKey, test.hash, digest, expected); err != nil {
+			t.Errorf("#%d %s", i, err)
+		}
+	}
+}
+
 func TestOverlongMessagePKCS1v15(t *testing.T) {
 	ciphertext := decodeBase64("fjOVdirUzFoLlukv80dBllMLjXythIf22feqPrNo0YoIjzyzyoMFiLjAc/Y4krkeZ11XFThIrEvw\nkRiZcCq5ng==")
 	_, err := DecryptPKCS1v15(nil, rsaPrivateKey, ciphertext)
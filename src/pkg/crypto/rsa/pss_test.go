@@ -224,6 +224,22 @@ func TestPSSSigning(t *testing.T) {
 	}
 }
 
+func TestPSSSigner(t *testing.T) {
+	hashed := crypto.SHA1.New()
+	hashed.Write([]byte("testing"))
+	digest := hashed.Sum(nil)
+
+	var signer crypto.Signer = rsaPrivateKey
+	opts := &PSSOptions{SaltLength: PSSSaltLengthEqualsHash, Hash: crypto.SHA1}
+	sig, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPSS(signer.Public().(*PublicKey), crypto.SHA1, digest, sig, opts); err != nil {
+		t.Error(err)
+	}
+}
+
 func bigFromHex(hex string) *big.Int {
 	n, ok := new(big.Int).SetString(hex, 16)
 	if !ok {
@@ -6,6 +6,7 @@ package rsa
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rand"
 	"crypto/sha1"
 	"math/big"
@@ -237,6 +238,14 @@ func TestDecryptOAEP(t *testing.T) {
 			} else if !bytes.Equal(out, message.in) {
 				t.Errorf("#%d,%d (blind) bad result: %#v (want %#v)", i, j, out, message.in)
 			}
+
+			// Decrypt through the crypto.Decrypter interface.
+			out, err = private.Decrypt(random, message.out, &OAEPOptions{Hash: crypto.SHA1})
+			if err != nil {
+				t.Errorf("#%d,%d (Decrypter) error: %s", i, j, err)
+			} else if !bytes.Equal(out, message.in) {
+				t.Errorf("#%d,%d (Decrypter) bad result: %#v (want %#v)", i, j, out, message.in)
+			}
 		}
 		if testing.Short() {
 			break
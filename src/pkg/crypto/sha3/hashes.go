@@ -0,0 +1,63 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sha3 implements the SHA-3 hash functions and the SHAKE
+// extendable-output functions defined in FIPS 202.
+package sha3
+
+import (
+	"crypto"
+	"hash"
+)
+
+func init() {
+	crypto.RegisterHash(crypto.SHA3_224, New224)
+	crypto.RegisterHash(crypto.SHA3_256, New256)
+	crypto.RegisterHash(crypto.SHA3_384, New384)
+	crypto.RegisterHash(crypto.SHA3_512, New512)
+}
+
+// New224 returns a new hash.Hash computing the SHA3-224 checksum.
+func New224() hash.Hash { return &state{rate: 144, outputLen: 28, dsbyte: dsbyteSHA3} }
+
+// New256 returns a new hash.Hash computing the SHA3-256 checksum.
+func New256() hash.Hash { return &state{rate: 136, outputLen: 32, dsbyte: dsbyteSHA3} }
+
+// New384 returns a new hash.Hash computing the SHA3-384 checksum.
+func New384() hash.Hash { return &state{rate: 104, outputLen: 48, dsbyte: dsbyteSHA3} }
+
+// New512 returns a new hash.Hash computing the SHA3-512 checksum.
+func New512() hash.Hash { return &state{rate: 72, outputLen: 64, dsbyte: dsbyteSHA3} }
+
+// Sum224 returns the SHA3-224 checksum of the data.
+func Sum224(data []byte) (digest [28]byte) {
+	h := New224()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
+
+// Sum256 returns the SHA3-256 checksum of the data.
+func Sum256(data []byte) (digest [32]byte) {
+	h := New256()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
+
+// Sum384 returns the SHA3-384 checksum of the data.
+func Sum384(data []byte) (digest [48]byte) {
+	h := New384()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
+
+// Sum512 returns the SHA3-512 checksum of the data.
+func Sum512(data []byte) (digest [64]byte) {
+	h := New512()
+	h.Write(data)
+	h.Sum(digest[:0])
+	return
+}
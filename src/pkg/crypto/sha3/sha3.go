@@ -0,0 +1,136 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+// maxRate is the largest rate, in bytes, of any of the functions in this
+// package; it is the rate of SHAKE128.
+const maxRate = 168
+
+// Domain separation bytes, which also hold the first bit of the pad10*1
+// padding, as specified in FIPS 202, section 6.
+const (
+	dsbyteSHA3  = 0x06
+	dsbyteShake = 0x1f
+)
+
+// state is a Keccak sponge. It absorbs input until the first call to Read
+// (or, on a copy, Sum) and squeezes output thereafter.
+type state struct {
+	a         [25]uint64
+	rate      int  // number of bytes of a absorbed or squeezed per permutation
+	dsbyte    byte // domain separation byte
+	outputLen int  // size, in bytes, of the output of Sum
+
+	buf       [maxRate]byte
+	n         int // bytes of buf filled while absorbing, or consumed while squeezing
+	squeezing bool
+}
+
+// BlockSize returns the rate of the sponge.
+func (d *state) BlockSize() int { return d.rate }
+
+// Size returns the output size of the hash function in bytes.
+func (d *state) Size() int { return d.outputLen }
+
+// Reset clears the internal state.
+func (d *state) Reset() {
+	d.a = [25]uint64{}
+	d.n = 0
+	d.squeezing = false
+}
+
+// Clone returns a copy of the ShakeHash in its current state.
+func (d *state) Clone() ShakeHash {
+	dup := *d
+	return &dup
+}
+
+// xorIn XORs the first rate bytes of buf into the state, in little-endian
+// order.
+func (d *state) xorIn() {
+	for i := 0; i < d.rate/8; i++ {
+		b := d.buf[8*i:]
+		d.a[i] ^= uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+			uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+	}
+}
+
+// copyOut copies the first rate bytes of the state into buf.
+func (d *state) copyOut() {
+	for i := 0; i < d.rate/8; i++ {
+		x := d.a[i]
+		for j := 0; j < 8; j++ {
+			d.buf[8*i+j] = byte(x >> uint(8*j))
+		}
+	}
+}
+
+// Write absorbs more data into the hash's state. It panics if called after
+// output has been read.
+func (d *state) Write(p []byte) (int, error) {
+	if d.squeezing {
+		panic("sha3: Write after Read")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		m := copy(d.buf[d.n:d.rate], p)
+		d.n += m
+		p = p[m:]
+		if d.n == d.rate {
+			d.xorIn()
+			keccakF1600(&d.a)
+			d.n = 0
+		}
+	}
+	return n, nil
+}
+
+// padAndPermute appends the domain separation bits and the padding to the
+// pending input, absorbs it and switches the sponge to squeezing.
+func (d *state) padAndPermute() {
+	d.buf[d.n] = d.dsbyte
+	for i := d.n + 1; i < d.rate; i++ {
+		d.buf[i] = 0
+	}
+	d.buf[d.rate-1] |= 0x80
+	d.xorIn()
+	keccakF1600(&d.a)
+	d.copyOut()
+	d.n = 0
+	d.squeezing = true
+}
+
+// Read squeezes an arbitrary number of bytes from the sponge. After the
+// first call to Read, Write may no longer be called. It never returns an
+// error.
+func (d *state) Read(out []byte) (int, error) {
+	if !d.squeezing {
+		d.padAndPermute()
+	}
+	n := len(out)
+	for len(out) > 0 {
+		if d.n == d.rate {
+			keccakF1600(&d.a)
+			d.copyOut()
+			d.n = 0
+		}
+		m := copy(out, d.buf[d.n:d.rate])
+		d.n += m
+		out = out[m:]
+	}
+	return n, nil
+}
+
+// Sum appends the first Size bytes of output to b. It does not change the
+// underlying hash state, so more data may be written afterwards.
+func (d *state) Sum(b []byte) []byte {
+	if d.squeezing {
+		panic("sha3: Sum after Read")
+	}
+	dup := *d
+	hash := make([]byte, dup.outputLen)
+	dup.Read(hash)
+	return append(b, hash...)
+}
@@ -0,0 +1,209 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"hash"
+	"strings"
+	"testing"
+)
+
+type sha3Test struct {
+	out string
+	in  string
+}
+
+var (
+	long   = "abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq"
+	repeat = strings.Repeat("a", 200)
+)
+
+var golden = map[string]struct {
+	newHash func() hash.Hash
+	tests   []sha3Test
+}{
+	"SHA3-224": {New224, []sha3Test{
+		{"6b4e03423667dbb73b6e15454f0eb1abd4597f9a1b078e3f5b5a6bc7", ""},
+		{"e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf", "abc"},
+		{"8a24108b154ada21c9fd5574494479ba5c7e7ab76ef264ead0fcce33", long},
+		{"455e0ccfc6010738ed93a793dffd79aff36debbd1a7eb6621bd6c722", repeat},
+	}},
+	"SHA3-256": {New256, []sha3Test{
+		{"a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a", ""},
+		{"3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532", "abc"},
+		{"41c0dba2a9d6240849100376a8235e2c82e1b9998a999e21db32dd97496d3376", long},
+		{"cce34485baf2bf2aca99b94833892a4f52896d3d153f7b840cc4f9fe695f1387", repeat},
+	}},
+	"SHA3-384": {New384, []sha3Test{
+		{"0c63a75b845e4f7d01107d852e4c2485c51a50aaaa94fc61995e71bbee983a2ac3713831264adb47fb6bd1e058d5f004", ""},
+		{"ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25", "abc"},
+		{"991c665755eb3a4b6bbdfb75c78a492e8c56a22c5c4d7e429bfdbc32b9d4ad5aa04a1f076e62fea19eef51acd0657c22", long},
+		{"f97756776c1874724c94a8008f7f155553b4bf00fbf8fbeac246624ad59c258a3c0977d9f2543d7cbd75b9ac8fdc0d40", repeat},
+	}},
+	"SHA3-512": {New512, []sha3Test{
+		{"a69f73cca23a9ac5c8b567dc185a756e97c982164fe25859e0d1dcc1475c80a615b2123af1f5f94c11e3e9402c3ac558f500199d95b6d3e301758586281dcd26", ""},
+		{"b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0", "abc"},
+		{"04a371e84ecfb5b8b77cb48610fca8182dd457ce6f326a0fd3d7ec2f1e91636dee691fbe0c985302ba1b0d8dc78c086346b533b49c030d99a27daf1139d6e75e", long},
+		{"eae6c85c6904f11075de9f9d5e1064371d000510fa3d2d79d40cf9be34892fb01859d0a0234e138bcb0ad5c84f6c0dca226a414b0c9a2897cb695f5185fe36ec", repeat},
+	}},
+	"SHAKE128": {func() hash.Hash { return NewShake128() }, []sha3Test{
+		{"7f9c2ba4e88f827d616045507605853ed73b8093f6efbc88eb1a6eacfa66ef26", ""},
+		{"5881092dd818bf5cf8a3ddb793fbcba74097d5c526a6d35f97b83351940f2cc8", "abc"},
+		{"1a96182b50fb8c7e74e0a707788f55e98209b8d91fade8f32f8dd5cff7bf21f5", long},
+		{"70ac9b97e891be583e08929ce4cce50d346b05f9597356d6af94d4643d2af3b6", repeat},
+	}},
+	"SHAKE256": {func() hash.Hash { return NewShake256() }, []sha3Test{
+		{"46b9dd2b0ba88d13233b3feb743eeb243fcd52ea62b81b82b50c27646ed5762fd75dc4ddd8c0f200cb05019d67b592f6fc821c49479ab48640292eacb3b7c4be", ""},
+		{"483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4", "abc"},
+		{"4d8c2dd2435a0128eefbb8c36f6f87133a7911e18d979ee1ae6be5d4fd2e332940d8688a4e6a59aa8060f1f9bc996c05aca3c696a8b66279dc672c740bb224ec", long},
+		{"e49647491c9d12d125a2f75826c96f6307d2fabebcbb9fb1616d76b09499380e8bcf60f72750879140e73fb7453a979b69d25efa8de613462f108ce7f2f1d7c5", repeat},
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for name, g := range golden {
+		for i, test := range g.tests {
+			h := g.newHash()
+			if h.Size() != len(test.out)/2 {
+				t.Errorf("%s: Size() = %d, want %d", name, h.Size(), len(test.out)/2)
+			}
+			// Write the input in pieces of every size, including across
+			// block boundaries, and check that Sum does not disturb the
+			// state.
+			for j := 0; j < 3; j++ {
+				if j < 2 {
+					h.Write([]byte(test.in))
+				} else {
+					for k := 0; k < len(test.in); k++ {
+						h.Write([]byte{test.in[k]})
+					}
+				}
+				if got := hex.EncodeToString(h.Sum(nil)); got != test.out {
+					t.Errorf("%s #%d (pass %d): got %s, want %s", name, i, j, got, test.out)
+				}
+				if got := hex.EncodeToString(h.Sum(nil)); got != test.out {
+					t.Errorf("%s #%d (pass %d): second Sum got %s, want %s", name, i, j, got, test.out)
+				}
+				h.Reset()
+			}
+		}
+	}
+}
+
+func TestSum(t *testing.T) {
+	in := []byte("abc")
+	if got, want := Sum224(in), golden["SHA3-224"].tests[1].out; hex.EncodeToString(got[:]) != want {
+		t.Errorf("Sum224: got %x, want %s", got, want)
+	}
+	if got, want := Sum256(in), golden["SHA3-256"].tests[1].out; hex.EncodeToString(got[:]) != want {
+		t.Errorf("Sum256: got %x, want %s", got, want)
+	}
+	if got, want := Sum384(in), golden["SHA3-384"].tests[1].out; hex.EncodeToString(got[:]) != want {
+		t.Errorf("Sum384: got %x, want %s", got, want)
+	}
+	if got, want := Sum512(in), golden["SHA3-512"].tests[1].out; hex.EncodeToString(got[:]) != want {
+		t.Errorf("Sum512: got %x, want %s", got, want)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	for _, h := range []crypto.Hash{crypto.SHA3_224, crypto.SHA3_256, crypto.SHA3_384, crypto.SHA3_512} {
+		if !h.Available() {
+			t.Errorf("hash %d not available", h)
+			continue
+		}
+		if h.New().Size() != h.Size() {
+			t.Errorf("hash %d: New().Size() = %d, want %d", h, h.New().Size(), h.Size())
+		}
+	}
+}
+
+func TestShakeRead(t *testing.T) {
+	// The last 32 bytes of 512 bytes of SHAKE128 output for the empty
+	// input, and of 300 bytes of SHAKE256 output for "abc".
+	tests := []struct {
+		h      ShakeHash
+		in     string
+		outLen int
+		tail   string
+	}{
+		{NewShake128(), "", 512, "43e41b45a653f2a5c4492c1add544512dda2529833462b71a41a45be97290b6f"},
+		{NewShake256(), "abc", 300, "2ddf384af3334560ea1d363966caa7d8ddcbec7da52b42215c11d5f8ee57f341"},
+	}
+	for i, test := range tests {
+		test.h.Write([]byte(test.in))
+		clone := test.h.Clone()
+
+		// Read the output in uneven pieces.
+		out := make([]byte, test.outLen)
+		for n, step := 0, 1; n < len(out); step += 7 {
+			end := n + step
+			if end > len(out) {
+				end = len(out)
+			}
+			test.h.Read(out[n:end])
+			n = end
+		}
+		if got := hex.EncodeToString(out[len(out)-32:]); got != test.tail {
+			t.Errorf("#%d: got %s, want %s", i, got, test.tail)
+		}
+
+		all := make([]byte, test.outLen)
+		clone.Read(all)
+		if !bytes.Equal(all, out) {
+			t.Errorf("#%d: single Read of clone differs from piecewise Read", i)
+		}
+	}
+
+	out := make([]byte, 64)
+	ShakeSum256(out, []byte("abc"))
+	if got, want := hex.EncodeToString(out), golden["SHAKE256"].tests[1].out; got != want {
+		t.Errorf("ShakeSum256: got %s, want %s", got, want)
+	}
+	out = out[:32]
+	ShakeSum128(out, []byte("abc"))
+	if got, want := hex.EncodeToString(out), golden["SHAKE128"].tests[1].out; got != want {
+		t.Errorf("ShakeSum128: got %s, want %s", got, want)
+	}
+}
+
+func TestWriteAfterRead(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Write after Read did not panic")
+		}
+	}()
+	h := NewShake128()
+	h.Read(make([]byte, 1))
+	h.Write([]byte("x"))
+}
+
+var bench = New256()
+var buf = make([]byte, 8192)
+
+func benchmarkSize(b *testing.B, size int) {
+	b.SetBytes(int64(size))
+	sum := make([]byte, bench.Size())
+	for i := 0; i < b.N; i++ {
+		bench.Reset()
+		bench.Write(buf[:size])
+		bench.Sum(sum[:0])
+	}
+}
+
+func BenchmarkHash8Bytes(b *testing.B) {
+	benchmarkSize(b, 8)
+}
+
+func BenchmarkHash1K(b *testing.B) {
+	benchmarkSize(b, 1024)
+}
+
+func BenchmarkHash8K(b *testing.B) {
+	benchmarkSize(b, 8192)
+}
@@ -0,0 +1,47 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3
+
+import (
+	"hash"
+	"io"
+)
+
+// ShakeHash is the interface implemented by the SHAKE extendable-output
+// functions. In addition to the hash.Hash methods, whose Sum returns Size
+// bytes of output, it can produce output of any length through Read.
+type ShakeHash interface {
+	hash.Hash
+
+	// Read reads more output from the hash. Reading affects the hash's
+	// state, so Read is very different from Sum: after the first call to
+	// Read, Write and Sum must not be called. Read never returns an error.
+	io.Reader
+
+	// Clone returns a copy of the ShakeHash in its current state.
+	Clone() ShakeHash
+}
+
+// NewShake128 returns a new ShakeHash computing SHAKE128. Its Size is 32
+// bytes, which provides the full 128-bit security strength of SHAKE128.
+func NewShake128() ShakeHash { return &state{rate: 168, outputLen: 32, dsbyte: dsbyteShake} }
+
+// NewShake256 returns a new ShakeHash computing SHAKE256. Its Size is 64
+// bytes, which provides the full 256-bit security strength of SHAKE256.
+func NewShake256() ShakeHash { return &state{rate: 136, outputLen: 64, dsbyte: dsbyteShake} }
+
+// ShakeSum128 writes an arbitrary-length digest of data into hash.
+func ShakeSum128(hash, data []byte) {
+	h := NewShake128()
+	h.Write(data)
+	h.Read(hash)
+}
+
+// ShakeSum256 writes an arbitrary-length digest of data into hash.
+func ShakeSum256(hash, data []byte) {
+	h := NewShake256()
+	h.Write(data)
+	h.Read(hash)
+}
@@ -6,6 +6,9 @@ package tls
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"io"
 	"net"
@@ -14,6 +17,12 @@ import (
 )
 
 func testClientScript(t *testing.T, name string, clientScript [][]byte, config *Config) {
+	// The scripts were recorded before X25519 was supported, so the
+	// ClientHello must offer only the NIST curves to match them.
+	if len(config.CurvePreferences) == 0 {
+		config.CurvePreferences = []CurveID{CurveP256, CurveP384, CurveP521}
+	}
+
 	c, s := net.Pipe()
 	cli := Client(c, config)
 	go func() {
@@ -82,6 +91,385 @@ func TestHandshakeClientTLS12(t *testing.T) {
 	testClientScript(t, "TLS12", clientTLS12Script, &config)
 }
 
+// localPipe returns the two ends of a loopback TCP connection. Unlike
+// with net.Pipe, writes are buffered, so a peer aborting the handshake
+// doesn't block on a peer that is still writing.
+func localPipe(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	defer l.Close()
+	c, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	s, err := l.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %s", err)
+	}
+	return c, s
+}
+
+// testClientHandshake runs a handshake between a client and a server and
+// returns the state of both connections and their handshake errors.
+func testClientHandshake(t *testing.T, clientConfig, serverConfig *Config) (cliState, srvState ConnectionState, cliErr, srvErr error) {
+	c, s := localPipe(t)
+	done := make(chan bool)
+	go func() {
+		srv := Server(s, serverConfig)
+		if srvErr = srv.Handshake(); srvErr == nil {
+			srvState = srv.ConnectionState()
+		}
+		s.Close()
+		done <- true
+	}()
+	cli := Client(c, clientConfig)
+	if cliErr = cli.Handshake(); cliErr == nil {
+		cliState = cli.ConnectionState()
+	}
+	c.Close()
+	<-done
+	return
+}
+
+func TestClientResumption(t *testing.T) {
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
+		Certificates: testConfig.Certificates,
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		InsecureSkipVerify: true,
+		ClientSessionCache: NewLRUClientSessionCache(32),
+		ServerName:         "example.golang",
+	}
+
+	testResumeState := func(test string, didResume bool) {
+		state, _, err, _ := testClientHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("%s: handshake failed: %s", test, err)
+		}
+		if state.DidResume != didResume {
+			t.Fatalf("%s resumed: %v, expected: %v", test, state.DidResume, didResume)
+		}
+		if len(state.PeerCertificates) == 0 {
+			t.Fatalf("%s: no peer certificates", test)
+		}
+	}
+
+	testResumeState("Handshake", false)
+	testResumeState("Resume", true)
+
+	clientConfig.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_RC4_128_SHA}
+	testResumeState("DifferentCipherSuite", false)
+	testResumeState("DifferentCipherSuiteRecovers", true)
+
+	// Sessions are cached by server name.
+	clientConfig.ServerName = "other.golang"
+	testResumeState("DifferentServerName", false)
+	testResumeState("DifferentServerNameRecovers", true)
+
+	serverConfig.SessionTicketsDisabled = true
+	testResumeState("TicketsDisabledServer", false)
+	serverConfig.SessionTicketsDisabled = false
+
+	clientConfig.SessionTicketsDisabled = true
+	testResumeState("TicketsDisabledClient", false)
+}
+
+func TestGetClientCertificate(t *testing.T) {
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		Certificates: testConfig.Certificates,
+		ClientAuth:   RequireAnyClientCert,
+	}
+	called := false
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*CertificateRequestInfo) (*Certificate, error) {
+			called = true
+			return &testConfig.Certificates[0], nil
+		},
+	}
+
+	_, state, err, _ := testClientHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if !called {
+		t.Errorf("GetClientCertificate wasn't called")
+	}
+	if len(state.PeerCertificates) != 1 || !bytes.Equal(state.PeerCertificates[0].Raw, testRSACertificate) {
+		t.Errorf("server didn't receive the client certificate")
+	}
+
+	clientConfig.GetClientCertificate = func(*CertificateRequestInfo) (*Certificate, error) {
+		return new(Certificate), nil
+	}
+	if _, _, _, err := testClientHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Errorf("handshake succeeded without a required client certificate")
+	}
+}
+
+func TestVerifyPeerCertificate(t *testing.T) {
+	errVerify := errors.New("certificate rejected")
+	var serverRaw, clientRaw [][]byte
+	var serverErr error
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		Certificates: testConfig.Certificates[:1],
+		ClientAuth:   RequireAnyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			clientRaw = rawCerts
+			return serverErr
+		},
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		Certificates:       testConfig.Certificates[:1],
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if verifiedChains != nil {
+				t.Errorf("got verified chains with InsecureSkipVerify")
+			}
+			serverRaw = rawCerts
+			return nil
+		},
+	}
+
+	if _, _, err, _ := testClientHandshake(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if len(serverRaw) != 1 || !bytes.Equal(serverRaw[0], testRSACertificate) {
+		t.Errorf("client callback got %x, want the server certificate", serverRaw)
+	}
+	if len(clientRaw) != 1 || !bytes.Equal(clientRaw[0], testRSACertificate) {
+		t.Errorf("server callback got %x, want the client certificate", clientRaw)
+	}
+
+	serverErr = errVerify
+	if _, _, _, err := testClientHandshake(t, clientConfig, serverConfig); err != errVerify {
+		t.Errorf("server handshake error = %v, want %v", err, errVerify)
+	}
+	serverErr = nil
+
+	clientConfig.VerifyPeerCertificate = func([][]byte, [][]*x509.Certificate) error {
+		return errVerify
+	}
+	if _, _, err, _ := testClientHandshake(t, clientConfig, serverConfig); err != errVerify {
+		t.Errorf("client handshake error = %v, want %v", err, errVerify)
+	}
+}
+
+// opaqueSigner and opaqueDecrypter hide the concrete type of a private
+// key, like a key kept in a hardware module, and count their uses.
+type opaqueSigner struct {
+	key crypto.Signer
+	n   int
+}
+
+func (s *opaqueSigner) Public() crypto.PublicKey { return s.key.Public() }
+
+func (s *opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.n++
+	return s.key.Sign(rand, digest, opts)
+}
+
+type opaqueDecrypter struct {
+	key crypto.Decrypter
+	n   int
+}
+
+func (d *opaqueDecrypter) Public() crypto.PublicKey { return d.key.Public() }
+
+func (d *opaqueDecrypter) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	d.n++
+	return d.key.Decrypt(rand, msg, opts)
+}
+
+func TestOpaquePrivateKey(t *testing.T) {
+	signer := &opaqueSigner{key: testRSAPrivateKey}
+	decrypter := &opaqueDecrypter{key: testRSAPrivateKey}
+	clientSigner := &opaqueSigner{key: testRSAPrivateKey}
+
+	tests := []struct {
+		suite  uint16
+		server crypto.PrivateKey
+		n      *int
+	}{
+		{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, signer, &signer.n},
+		{TLS_RSA_WITH_RC4_128_SHA, decrypter, &decrypter.n},
+	}
+	for _, test := range tests {
+		serverConfig := &Config{
+			CipherSuites: []uint16{test.suite},
+			Certificates: []Certificate{{
+				Certificate: [][]byte{testRSACertificate},
+				PrivateKey:  test.server,
+			}},
+			ClientAuth: RequireAnyClientCert,
+		}
+		clientConfig := &Config{
+			CipherSuites: []uint16{test.suite},
+			Certificates: []Certificate{{
+				Certificate: [][]byte{testRSACertificate},
+				PrivateKey:  clientSigner,
+			}},
+			InsecureSkipVerify: true,
+		}
+		clientSigner.n = 0
+		_, _, cliErr, srvErr := testClientHandshake(t, clientConfig, serverConfig)
+		if cliErr != nil || srvErr != nil {
+			t.Errorf("%#04x: handshake failed: client %v, server %v", test.suite, cliErr, srvErr)
+			continue
+		}
+		if *test.n != 1 {
+			t.Errorf("%#04x: server key used %d times, want 1", test.suite, *test.n)
+		}
+		if clientSigner.n != 1 {
+			t.Errorf("%#04x: client key used %d times, want 1", test.suite, clientSigner.n)
+		}
+	}
+
+	// A key that can only decrypt can't sign ECDHE parameters.
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		Certificates: []Certificate{{
+			Certificate: [][]byte{testRSACertificate},
+			PrivateKey:  decrypter,
+		}},
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		InsecureSkipVerify: true,
+	}
+	if _, _, _, err := testClientHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Errorf("handshake succeeded with a key that can't sign")
+	}
+}
+
+// renewingServer runs the server side of a handshake on conn, resuming
+// the client's session and issuing a new ticket for it.
+func renewingServer(conn net.Conn, config *Config) error {
+	c := Server(conn, config)
+	config.serverInitOnce.Do(config.serverInit)
+	hs := serverHandshakeState{c: c}
+	isResume, err := hs.readClientHello()
+	if err != nil {
+		return err
+	}
+	if !isResume {
+		return errors.New("client offered no session to resume")
+	}
+	hs.hello.ticketSupported = true
+	steps := []func() error{
+		hs.doResumeHandshake,
+		hs.establishKeys,
+		hs.sendSessionTicket,
+		hs.sendFinished,
+		hs.readFinished,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	c.handshakeComplete = true
+	return nil
+}
+
+func TestClientResumptionNewTicket(t *testing.T) {
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		Certificates: testConfig.Certificates,
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		InsecureSkipVerify: true,
+		ServerName:         "example.golang",
+		ClientSessionCache: NewLRUClientSessionCache(1),
+	}
+	if _, _, err, _ := testClientHandshake(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	old, ok := clientConfig.ClientSessionCache.Get(clientConfig.ServerName)
+	if !ok {
+		t.Fatal("no session cached")
+	}
+
+	c, s := localPipe(t)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- renewingServer(s, serverConfig)
+		s.Close()
+	}()
+	cli := Client(c, clientConfig)
+	if err := cli.Handshake(); err != nil {
+		t.Fatalf("resuming handshake failed: %s", err)
+	}
+	c.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("server: %s", err)
+	}
+	if !cli.ConnectionState().DidResume {
+		t.Fatal("session was not resumed")
+	}
+	session, _ := clientConfig.ClientSessionCache.Get(clientConfig.ServerName)
+	if session == old || bytes.Equal(session.sessionTicket, old.sessionTicket) {
+		t.Fatal("new ticket was not cached")
+	}
+
+	state, _, err, _ := testClientHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake with new ticket failed: %s", err)
+	}
+	if !state.DidResume {
+		t.Fatal("session was not resumed with the new ticket")
+	}
+}
+
+func TestLRUClientSessionCache(t *testing.T) {
+	// Initialize cache of capacity 4.
+	cache := NewLRUClientSessionCache(4)
+	cs := make([]ClientSessionState, 6)
+	keys := []string{"0", "1", "2", "3", "4", "5", "6"}
+
+	// Add 4 entries to the cache and look them up.
+	for i := 0; i < 4; i++ {
+		cache.Put(keys[i], &cs[i])
+	}
+	for i := 0; i < 4; i++ {
+		if s, ok := cache.Get(keys[i]); !ok || s != &cs[i] {
+			t.Fatalf("session cache failed lookup for added key: %s", keys[i])
+		}
+	}
+
+	// Add 2 more entries to the cache. First 2 should be evicted.
+	for i := 4; i < 6; i++ {
+		cache.Put(keys[i], &cs[i])
+	}
+	for i := 0; i < 2; i++ {
+		if s, ok := cache.Get(keys[i]); ok || s != nil {
+			t.Fatalf("session cache should have evicted key: %s", keys[i])
+		}
+	}
+
+	// Touch entry 2. LRU should evict 3 next.
+	cache.Get(keys[2])
+	cache.Put(keys[0], &cs[0])
+	if s, ok := cache.Get(keys[3]); ok || s != nil {
+		t.Fatalf("session cache should have evicted key 3")
+	}
+
+	// Update entry 0 in place.
+	cache.Put(keys[0], &cs[3])
+	if s, ok := cache.Get(keys[0]); !ok || s != &cs[3] {
+		t.Fatalf("session cache failed update for key 0")
+	}
+}
+
 var connect = flag.Bool("connect", false, "connect to a TLS server on :10443")
 
 func TestRunClient(t *testing.T) {
@@ -117,6 +505,7 @@ func TestEmptyRecords(t *testing.T) {
 	// Conn.Read.
 	config := *testConfig
 	config.CipherSuites = []uint16{TLS_RSA_WITH_AES_256_CBC_SHA}
+	config.CurvePreferences = []CurveID{CurveP256, CurveP384, CurveP521}
 
 	c, s := net.Pipe()
 	cli := Client(c, &config)
@@ -2484,3 +2873,61 @@ var ecdheECDSAAESClientScript = [][]byte{
 		0xb4, 0xaa, 0x1c, 0x79, 0xda, 0x79, 0x27,
 	},
 }
+
+func TestOCSPStapling(t *testing.T) {
+	staple := []byte("fake OCSP response")
+	cert := testConfig.Certificates[0]
+	cert.OCSPStaple = staple
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		Certificates: []Certificate{cert},
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		InsecureSkipVerify: true,
+	}
+
+	state, srvState, err, _ := testClientHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if !bytes.Equal(state.OCSPResponse, staple) {
+		t.Errorf("got stapled response %q, want %q", state.OCSPResponse, staple)
+	}
+	if srvState.OCSPResponse != nil {
+		t.Errorf("server reported a stapled response: %q", srvState.OCSPResponse)
+	}
+}
+
+func TestCurvePreferences(t *testing.T) {
+	tests := []struct {
+		client, server []CurveID
+		ok             bool
+	}{
+		{nil, nil, true},
+		{[]CurveID{X25519}, nil, true},
+		{[]CurveID{X25519, CurveP256}, []CurveID{CurveP256}, true},
+		{[]CurveID{CurveP384}, []CurveID{X25519, CurveP384}, true},
+		{[]CurveID{X25519}, []CurveID{CurveP256}, false},
+	}
+
+	for i, test := range tests {
+		serverConfig := &Config{
+			CipherSuites:     []uint16{TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+			Certificates:     testConfig.Certificates,
+			CurvePreferences: test.server,
+		}
+		clientConfig := &Config{
+			CipherSuites:       []uint16{TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA},
+			InsecureSkipVerify: true,
+			CurvePreferences:   test.client,
+		}
+		_, _, cliErr, srvErr := testClientHandshake(t, clientConfig, serverConfig)
+		if test.ok && (cliErr != nil || srvErr != nil) {
+			t.Errorf("#%d: handshake failed: client: %v, server: %v", i, cliErr, srvErr)
+		}
+		if !test.ok && cliErr == nil && srvErr == nil {
+			t.Errorf("#%d: handshake succeeded without a common curve", i)
+		}
+	}
+}
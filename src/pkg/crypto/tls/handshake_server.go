@@ -111,15 +111,7 @@ func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
 
 	hs.hello = new(serverHelloMsg)
 
-	supportedCurve := false
-Curves:
-	for _, curve := range hs.clientHello.supportedCurves {
-		switch curve {
-		case curveP256, curveP384, curveP521:
-			supportedCurve = true
-			break Curves
-		}
-	}
+	_, supportedCurve := config.selectCurve(hs.clientHello.supportedCurves)
 
 	supportedPointFormat := false
 	for _, pointFormat := range hs.clientHello.supportedPoints {
@@ -197,6 +189,10 @@ Curves:
 func (hs *serverHandshakeState) checkForResumption() bool {
 	c := hs.c
 
+	if c.config.SessionTicketsDisabled {
+		return false
+	}
+
 	var ok bool
 	if hs.sessionState, ok = c.decryptTicket(hs.clientHello.sessionTicket); !ok {
 		return false
@@ -264,12 +260,15 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 	config := hs.c.config
 	c := hs.c
 
-	if len(config.Certificates) == 0 {
-		return c.sendAlert(alertInternalError)
-	}
-	cert := &config.Certificates[0]
-	if len(hs.clientHello.serverName) > 0 {
-		cert = config.getCertificateForName(hs.clientHello.serverName)
+	cert, err := config.getCertificate(&ClientHelloInfo{
+		CipherSuites:    hs.clientHello.cipherSuites,
+		ServerName:      hs.clientHello.serverName,
+		SupportedCurves: hs.clientHello.supportedCurves,
+		SupportedPoints: hs.clientHello.supportedPoints,
+	})
+	if err != nil {
+		c.sendAlert(alertInternalError)
+		return err
 	}
 
 	if hs.clientHello.ocspStapling && len(cert.OCSPStaple) > 0 {
@@ -363,6 +362,13 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			return err
 		}
 
+		if config.VerifyPeerCertificate != nil {
+			if err := config.VerifyPeerCertificate(certMsg.certificates, c.verifiedChains); err != nil {
+				c.sendAlert(alertBadCertificate)
+				return err
+			}
+		}
+
 		msg, err = c.readHandshake()
 		if err != nil {
 			return err
@@ -12,6 +12,7 @@ import (
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -261,6 +262,40 @@ func TestResumption(t *testing.T) {
 	testServerScript(t, "Resume", serverResumeTest, testConfig, nil)
 }
 
+func TestGetCertificate(t *testing.T) {
+	var serverName string
+	serverConfig := &Config{
+		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		GetCertificate: func(clientHello *ClientHelloInfo) (*Certificate, error) {
+			serverName = clientHello.ServerName
+			return &testConfig.Certificates[1], nil
+		},
+	}
+	clientConfig := &Config{
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+		InsecureSkipVerify: true,
+		ServerName:         "test.golang",
+	}
+
+	state, _, err, _ := testClientHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if serverName != "test.golang" {
+		t.Errorf("GetCertificate got server name %q, want %q", serverName, "test.golang")
+	}
+	if !bytes.Equal(state.PeerCertificates[0].Raw, testSNICertificate) {
+		t.Errorf("server didn't send the certificate from GetCertificate")
+	}
+
+	serverConfig.GetCertificate = func(*ClientHelloInfo) (*Certificate, error) {
+		return nil, errors.New("no certificate")
+	}
+	if _, _, _, err := testClientHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Errorf("handshake succeeded although GetCertificate failed")
+	}
+}
+
 func TestTLS12ClientCertServer(t *testing.T) {
 	config := *testConfig
 	config.MaxVersion = VersionTLS12
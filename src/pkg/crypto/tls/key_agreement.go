@@ -6,6 +6,7 @@ package tls
 
 import (
 	"crypto"
+	"crypto/curve25519"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/md5"
@@ -28,12 +29,6 @@ func (ka rsaKeyAgreement) generateServerKeyExchange(config *Config, cert *Certif
 }
 
 func (ka rsaKeyAgreement) processClientKeyExchange(config *Config, cert *Certificate, ckx *clientKeyExchangeMsg, version uint16) ([]byte, error) {
-	preMasterSecret := make([]byte, 48)
-	_, err := io.ReadFull(config.rand(), preMasterSecret[2:])
-	if err != nil {
-		return nil, err
-	}
-
 	if len(ckx.ciphertext) < 2 {
 		return nil, errors.New("bad ClientKeyExchange")
 	}
@@ -47,7 +42,13 @@ func (ka rsaKeyAgreement) processClientKeyExchange(config *Config, cert *Certifi
 		ciphertext = ckx.ciphertext[2:]
 	}
 
-	err = rsa.DecryptPKCS1v15SessionKey(config.rand(), cert.PrivateKey.(*rsa.PrivateKey), ciphertext, preMasterSecret)
+	priv, ok := cert.PrivateKey.(crypto.Decrypter)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Decrypter")
+	}
+	// Perform constant time RSA PKCS#1 v1.5 decryption. A padding error
+	// yields a random pre-master secret rather than an error.
+	preMasterSecret, err := priv.Decrypt(config.rand(), ciphertext, &rsa.PKCS1v15DecryptOptions{SessionKeyLen: 48})
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +129,36 @@ func hashForServerKeyExchange(sigType uint8, version uint16, slices ...[]byte) (
 	return md5SHA1Hash(slices), crypto.MD5SHA1
 }
 
+// curveForCurveID returns the elliptic.Curve identified by id, which must
+// be one of the NIST curves. X25519 is handled separately.
+func curveForCurveID(id CurveID) (elliptic.Curve, bool) {
+	switch id {
+	case CurveP256:
+		return elliptic.P256(), true
+	case CurveP384:
+		return elliptic.P384(), true
+	case CurveP521:
+		return elliptic.P521(), true
+	}
+	return nil, false
+}
+
+// selectCurve returns the first of the curves offered by a client that is
+// also in the configured preferences and is implemented by this package.
+func (c *Config) selectCurve(clientCurves []CurveID) (CurveID, bool) {
+	for _, curve := range clientCurves {
+		for _, preferred := range c.curvePreferences() {
+			if curve != preferred {
+				continue
+			}
+			if _, ok := curveForCurveID(curve); ok || curve == X25519 {
+				return curve, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // ecdheRSAKeyAgreement implements a TLS key agreement where the server
 // generates a ephemeral EC public/private key pair and signs it. The
 // pre-master secret is then calculated using ECDH. The signature may
@@ -135,43 +166,40 @@ func hashForServerKeyExchange(sigType uint8, version uint16, slices ...[]byte) (
 type ecdheKeyAgreement struct {
 	version    uint16
 	sigType    uint8
+	curveid    CurveID
 	privateKey []byte
 	curve      elliptic.Curve
 	x, y       *big.Int
+	// publicKey is the peer's X25519 public key, if X25519 is in use.
+	publicKey []byte
 }
 
 func (ka *ecdheKeyAgreement) generateServerKeyExchange(config *Config, cert *Certificate, clientHello *clientHelloMsg, hello *serverHelloMsg) (*serverKeyExchangeMsg, error) {
-	var curveid uint16
-
-Curve:
-	for _, c := range clientHello.supportedCurves {
-		switch c {
-		case curveP256:
-			ka.curve = elliptic.P256()
-			curveid = c
-			break Curve
-		case curveP384:
-			ka.curve = elliptic.P384()
-			curveid = c
-			break Curve
-		case curveP521:
-			ka.curve = elliptic.P521()
-			curveid = c
-			break Curve
-		}
-	}
-
-	if curveid == 0 {
+	curveid, ok := config.selectCurve(clientHello.supportedCurves)
+	if !ok {
 		return nil, errors.New("tls: no supported elliptic curves offered")
 	}
+	ka.curveid = curveid
 
-	var x, y *big.Int
-	var err error
-	ka.privateKey, x, y, err = elliptic.GenerateKey(ka.curve, config.rand())
-	if err != nil {
-		return nil, err
+	var ecdhePublic []byte
+	if curveid == X25519 {
+		var scalar, public [32]byte
+		if _, err := io.ReadFull(config.rand(), scalar[:]); err != nil {
+			return nil, err
+		}
+		curve25519.ScalarBaseMult(&public, &scalar)
+		ka.privateKey = scalar[:]
+		ecdhePublic = public[:]
+	} else {
+		ka.curve, _ = curveForCurveID(curveid)
+		var x, y *big.Int
+		var err error
+		ka.privateKey, x, y, err = elliptic.GenerateKey(ka.curve, config.rand())
+		if err != nil {
+			return nil, err
+		}
+		ecdhePublic = elliptic.Marshal(ka.curve, x, y)
 	}
-	ecdhePublic := elliptic.Marshal(ka.curve, x, y)
 
 	// http://tools.ietf.org/html/rfc4492#section-5.4
 	serverECDHParams := make([]byte, 1+2+1+len(ecdhePublic))
@@ -182,30 +210,26 @@ Curve:
 	copy(serverECDHParams[4:], ecdhePublic)
 
 	digest, hashFunc := hashForServerKeyExchange(ka.sigType, ka.version, clientHello.random, hello.random, serverECDHParams)
-	var sig []byte
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
 	switch ka.sigType {
 	case signatureECDSA:
-		privKey, ok := cert.PrivateKey.(*ecdsa.PrivateKey)
-		if !ok {
-			return nil, errors.New("ECDHE ECDSA requires an ECDSA server private key")
-		}
-		r, s, err := ecdsa.Sign(config.rand(), privKey, digest)
-		if err != nil {
-			return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
+		if _, ok := priv.Public().(*ecdsa.PublicKey); !ok {
+			return nil, errors.New("ECDHE ECDSA requires an ECDSA server key")
 		}
-		sig, err = asn1.Marshal(ecdsaSignature{r, s})
 	case signatureRSA:
-		privKey, ok := cert.PrivateKey.(*rsa.PrivateKey)
-		if !ok {
-			return nil, errors.New("ECDHE RSA requires a RSA server private key")
-		}
-		sig, err = rsa.SignPKCS1v15(config.rand(), privKey, hashFunc, digest)
-		if err != nil {
-			return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
+		if _, ok := priv.Public().(*rsa.PublicKey); !ok {
+			return nil, errors.New("ECDHE RSA requires a RSA server key")
 		}
 	default:
 		return nil, errors.New("unknown ECDHE signature algorithm")
 	}
+	sig, err := priv.Sign(config.rand(), digest, hashFunc)
+	if err != nil {
+		return nil, errors.New("failed to sign ECDHE parameters: " + err.Error())
+	}
 
 	skx := new(serverKeyExchangeMsg)
 	sigAndHashLen := 0
@@ -231,6 +255,14 @@ func (ka *ecdheKeyAgreement) processClientKeyExchange(config *Config, cert *Cert
 	if len(ckx.ciphertext) == 0 || int(ckx.ciphertext[0]) != len(ckx.ciphertext)-1 {
 		return nil, errors.New("bad ClientKeyExchange")
 	}
+	if ka.curveid == X25519 {
+		preMasterSecret, err := curve25519.X25519(ka.privateKey, ckx.ciphertext[1:])
+		if err != nil {
+			return nil, errors.New("bad ClientKeyExchange")
+		}
+		return preMasterSecret, nil
+	}
+
 	x, y := elliptic.Unmarshal(ka.curve, ckx.ciphertext[1:])
 	if x == nil {
 		return nil, errors.New("bad ClientKeyExchange")
@@ -252,16 +284,16 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	if skx.key[0] != 3 { // named curve
 		return errors.New("server selected unsupported curve")
 	}
-	curveid := uint16(skx.key[1])<<8 | uint16(skx.key[2])
+	ka.curveid = CurveID(skx.key[1])<<8 | CurveID(skx.key[2])
 
-	switch curveid {
-	case curveP256:
-		ka.curve = elliptic.P256()
-	case curveP384:
-		ka.curve = elliptic.P384()
-	case curveP521:
-		ka.curve = elliptic.P521()
-	default:
+	offered := false
+	for _, c := range clientHello.supportedCurves {
+		if c == ka.curveid {
+			offered = true
+			break
+		}
+	}
+	if !offered {
 		return errors.New("server selected unsupported curve")
 	}
 
@@ -269,9 +301,22 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	if publicLen+4 > len(skx.key) {
 		return errServerKeyExchange
 	}
-	ka.x, ka.y = elliptic.Unmarshal(ka.curve, skx.key[4:4+publicLen])
-	if ka.x == nil {
-		return errServerKeyExchange
+	public := skx.key[4 : 4+publicLen]
+
+	if ka.curveid == X25519 {
+		if len(public) != curve25519.PointSize {
+			return errServerKeyExchange
+		}
+		ka.publicKey = public
+	} else {
+		var ok bool
+		if ka.curve, ok = curveForCurveID(ka.curveid); !ok {
+			return errors.New("server selected unsupported curve")
+		}
+		ka.x, ka.y = elliptic.Unmarshal(ka.curve, public)
+		if ka.x == nil {
+			return errServerKeyExchange
+		}
 	}
 	serverECDHParams := skx.key[:4+publicLen]
 
@@ -325,19 +370,34 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 }
 
 func (ka *ecdheKeyAgreement) generateClientKeyExchange(config *Config, clientHello *clientHelloMsg, cert *x509.Certificate) ([]byte, *clientKeyExchangeMsg, error) {
-	if ka.curve == nil {
+	if ka.curveid == 0 {
 		return nil, nil, errors.New("missing ServerKeyExchange message")
 	}
-	priv, mx, my, err := elliptic.GenerateKey(ka.curve, config.rand())
-	if err != nil {
-		return nil, nil, err
-	}
-	x, _ := ka.curve.ScalarMult(ka.x, ka.y, priv)
-	preMasterSecret := make([]byte, (ka.curve.Params().BitSize+7)>>3)
-	xBytes := x.Bytes()
-	copy(preMasterSecret[len(preMasterSecret)-len(xBytes):], xBytes)
 
-	serialized := elliptic.Marshal(ka.curve, mx, my)
+	var preMasterSecret, serialized []byte
+	if ka.curveid == X25519 {
+		var scalar, public [32]byte
+		if _, err := io.ReadFull(config.rand(), scalar[:]); err != nil {
+			return nil, nil, err
+		}
+		var err error
+		if preMasterSecret, err = curve25519.X25519(scalar[:], ka.publicKey); err != nil {
+			return nil, nil, errServerKeyExchange
+		}
+		curve25519.ScalarBaseMult(&public, &scalar)
+		serialized = public[:]
+	} else {
+		priv, mx, my, err := elliptic.GenerateKey(ka.curve, config.rand())
+		if err != nil {
+			return nil, nil, err
+		}
+		x, _ := ka.curve.ScalarMult(ka.x, ka.y, priv)
+		preMasterSecret = make([]byte, (ka.curve.Params().BitSize+7)>>3)
+		xBytes := x.Bytes()
+		copy(preMasterSecret[len(preMasterSecret)-len(xBytes):], xBytes)
+
+		serialized = elliptic.Marshal(ka.curve, mx, my)
+	}
 
 	ckx := new(clientKeyExchangeMsg)
 	ckx.ciphertext = make([]byte, 1+len(serialized))
@@ -0,0 +1,76 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"time"
+)
+
+// CRLPool is a set of certificate revocation lists. It implements
+// RevocationChecker so that it can be used in VerifyOptions.
+type CRLPool struct {
+	byIssuer map[string][]*pkix.CertificateList
+}
+
+// NewCRLPool returns a new, empty CRLPool.
+func NewCRLPool() *CRLPool {
+	return &CRLPool{
+		make(map[string][]*pkix.CertificateList),
+	}
+}
+
+// tbsCertListIssuer reflects the leading fields of a TBSCertList, in order
+// to recover the DER encoding of the issuer's name.
+type tbsCertListIssuer struct {
+	Version   int `asn1:"optional,default:2"`
+	Signature pkix.AlgorithmIdentifier
+	Issuer    asn1.RawValue
+}
+
+// rawIssuer returns the DER encoded issuer of crl.
+func rawIssuer(crl *pkix.CertificateList) ([]byte, error) {
+	if len(crl.TBSCertList.Raw) == 0 {
+		return asn1.Marshal(crl.TBSCertList.Issuer)
+	}
+	var tbs tbsCertListIssuer
+	if _, err := asn1.Unmarshal(crl.TBSCertList.Raw, &tbs); err != nil {
+		return nil, err
+	}
+	return tbs.Issuer.FullBytes, nil
+}
+
+// AddCRL adds a CRL, such as one returned by ParseCRL, to the pool. The
+// signature on the CRL is checked against the issuer when it is used.
+func (p *CRLPool) AddCRL(crl *pkix.CertificateList) {
+	if crl == nil {
+		panic("adding nil CertificateList to CRLPool")
+	}
+
+	issuer, err := rawIssuer(crl)
+	if err != nil {
+		return
+	}
+	p.byIssuer[string(issuer)] = append(p.byIssuer[string(issuer)], crl)
+}
+
+// CheckRevocation returns a CertificateInvalidError with reason Revoked if
+// any unexpired CRL in p that was signed by issuer lists cert as having been
+// revoked by the time now. CRLs with invalid signatures are ignored, as is
+// the absence of any CRL for issuer.
+func (p *CRLPool) CheckRevocation(cert, issuer *Certificate, now time.Time) error {
+	for _, crl := range p.byIssuer[string(issuer.RawSubject)] {
+		if crl.HasExpired(now) || issuer.CheckCRLSignature(crl) != nil {
+			continue
+		}
+		for _, revoked := range crl.TBSCertList.RevokedCertificates {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 && !revoked.RevocationTime.After(now) {
+				return CertificateInvalidError{cert, Revoked}
+			}
+		}
+	}
+	return nil
+}
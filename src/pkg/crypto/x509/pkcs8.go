@@ -5,10 +5,19 @@
 package x509
 
 import (
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/pbkdf2"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 )
 
 // pkcs8 reflects an ASN.1, PKCS#8 PrivateKey. See
@@ -48,7 +57,299 @@ func ParsePKCS8PrivateKey(der []byte) (key interface{}, err error) {
 		}
 		return key, nil
 
+	case privKey.Algo.Algorithm.Equal(oidPublicKeyEd25519):
+		// RFC 8410, Section 7: the private key is the seed, wrapped in
+		// an OCTET STRING, and the parameters must be absent.
+		if len(privKey.Algo.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: invalid Ed25519 private key parameters")
+		}
+		var seed []byte
+		if _, err := asn1.Unmarshal(privKey.PrivateKey, &seed); err != nil {
+			return nil, errors.New("x509: failed to parse Ed25519 private key embedded in PKCS#8: " + err.Error())
+		}
+		if l := len(seed); l != ed25519.SeedSize {
+			return nil, fmt.Errorf("x509: invalid Ed25519 private key length: %d", l)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+
 	default:
 		return nil, fmt.Errorf("x509: PKCS#8 wrapping contained private key with unknown algorithm: %v", privKey.Algo.Algorithm)
 	}
 }
+
+// MarshalPKCS8PrivateKey converts a private key to PKCS#8 encoded form. The
+// following key types are supported: *rsa.PrivateKey, *ecdsa.PrivateKey and
+// ed25519.PrivateKey.
+func MarshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
+	var privKey pkcs8
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		privKey.Algo = pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyRSA,
+			Parameters: asn1.RawValue{Tag: 5},
+		}
+		privKey.PrivateKey = MarshalPKCS1PrivateKey(k)
+
+	case *ecdsa.PrivateKey:
+		oid, ok := oidFromNamedCurve(k.Curve)
+		if !ok {
+			return nil, errors.New("x509: unknown curve while marshalling to PKCS#8")
+		}
+		oidBytes, err := asn1.Marshal(oid)
+		if err != nil {
+			return nil, errors.New("x509: failed to marshal curve OID: " + err.Error())
+		}
+		privKey.Algo = pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyECDSA,
+			Parameters: asn1.RawValue{FullBytes: oidBytes},
+		}
+		if privKey.PrivateKey, err = MarshalECPrivateKey(k); err != nil {
+			return nil, errors.New("x509: failed to marshal EC private key while building PKCS#8: " + err.Error())
+		}
+
+	case ed25519.PrivateKey:
+		privKey.Algo = pkix.AlgorithmIdentifier{
+			Algorithm: oidPublicKeyEd25519,
+		}
+		seed, err := asn1.Marshal(k.Seed())
+		if err != nil {
+			return nil, errors.New("x509: failed to marshal Ed25519 private key: " + err.Error())
+		}
+		privKey.PrivateKey = seed
+
+	default:
+		return nil, fmt.Errorf("x509: unknown key type while marshalling PKCS#8: %T", key)
+	}
+
+	return asn1.Marshal(privKey)
+}
+
+// encryptedPrivateKeyInfo reflects an ASN.1, PKCS#8 EncryptedPrivateKeyInfo.
+// See RFC5208, section 6.
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params reflects the parameters of the PBES2 encryption scheme. See
+// RFC2898, appendix A.4.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params reflects the parameters of the PBKDF2 key derivation
+// function. See RFC2898, appendix A.2.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+)
+
+// pbes2Ciphers maps the encryption schemes that may be used with PBES2 to
+// the block ciphers used for PEM encryption.
+var pbes2Ciphers = []struct {
+	cipher PEMCipher
+	oid    asn1.ObjectIdentifier
+}{
+	{PEMCipherDES, asn1.ObjectIdentifier{1, 3, 14, 3, 2, 7}},
+	{PEMCipher3DES, asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}},
+	{PEMCipherAES128, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}},
+	{PEMCipherAES192, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}},
+	{PEMCipherAES256, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}},
+}
+
+// pbkdf2Iterations is the iteration count used when encrypting keys.
+const pbkdf2Iterations = 2048
+
+// maxPBKDF2Iterations bounds the iteration count read from encrypted
+// data, so that decrypting it can't take an unreasonable time.
+const maxPBKDF2Iterations = 1 << 22
+
+// ParseEncryptedPKCS8PrivateKey decrypts and parses a PKCS#8
+// EncryptedPrivateKeyInfo, as written by MarshalEncryptedPKCS8PrivateKey or
+// "openssl pkcs8 -topk8 -v2 aes256". Only the PBES2 scheme with PBKDF2 and a
+// DES, 3DES or AES cipher in CBC mode is supported. If an incorrect password
+// is detected an IncorrectPasswordError is returned.
+func ParseEncryptedPKCS8PrivateKey(der, password []byte) (key interface{}, err error) {
+	var encrypted encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &encrypted); err != nil {
+		return nil, err
+	}
+	if !encrypted.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("x509: unsupported PKCS#8 encryption algorithm: %v", encrypted.Algo.Algorithm)
+	}
+	decrypted, err := decryptPBES2(encrypted.Algo, encrypted.EncryptedData, password)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePKCS8PrivateKey(decrypted)
+}
+
+// decryptPBES2 decrypts data that was encrypted with password using the
+// PBES2 scheme of RFC 2898, whose parameters are given by algo. If an
+// incorrect password is detected an IncorrectPasswordError is returned.
+func decryptPBES2(algo pkix.AlgorithmIdentifier, data, password []byte) ([]byte, error) {
+	if !algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("x509: %v is not the PBES2 encryption scheme", algo.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("x509: failed to parse PBES2 parameters: " + err.Error())
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("x509: unsupported PBES2 key derivation function: %v", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, errors.New("x509: failed to parse PBKDF2 parameters: " + err.Error())
+	}
+	if kdfParams.IterationCount <= 0 || kdfParams.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("x509: unsupported PBKDF2 iteration count: %d", kdfParams.IterationCount)
+	}
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0 || kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("x509: unsupported PBKDF2 pseudorandom function: %v", kdfParams.PRF.Algorithm)
+	}
+
+	var ciph *rfc1423Algo
+	for _, c := range pbes2Ciphers {
+		if params.EncryptionScheme.Algorithm.Equal(c.oid) {
+			ciph = cipherByKey(c.cipher)
+			break
+		}
+	}
+	if ciph == nil {
+		return nil, fmt.Errorf("x509: unsupported PBES2 encryption scheme: %v", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, errors.New("x509: failed to parse PBES2 IV: " + err.Error())
+	}
+	if len(iv) != ciph.blockSize {
+		return nil, errors.New("x509: incorrect IV size")
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != ciph.keySize {
+		return nil, errors.New("x509: PBKDF2 key length doesn't match cipher")
+	}
+
+	if len(data) == 0 || len(data)%ciph.blockSize != 0 {
+		return nil, errors.New("x509: invalid padding")
+	}
+	block, err := ciph.cipherFunc(pbkdf2.Key(password, kdfParams.Salt, kdfParams.IterationCount, ciph.keySize, prf))
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
+
+	// The padding is the same as that used for PEM encryption. See RFC
+	// 2898, section 6.1.1.
+	last := int(decrypted[len(decrypted)-1])
+	if last == 0 || last > ciph.blockSize {
+		return nil, IncorrectPasswordError
+	}
+	for _, val := range decrypted[len(decrypted)-last:] {
+		if int(val) != last {
+			return nil, IncorrectPasswordError
+		}
+	}
+	return decrypted[:len(decrypted)-last], nil
+}
+
+// MarshalEncryptedPKCS8PrivateKey converts a private key to an encrypted
+// PKCS#8 EncryptedPrivateKeyInfo using PBES2 with PBKDF2 and HMAC-SHA256 to
+// derive a key from password for the given cipher. The key types supported
+// are those of MarshalPKCS8PrivateKey.
+func MarshalEncryptedPKCS8PrivateKey(rand io.Reader, key interface{}, password []byte, alg PEMCipher) ([]byte, error) {
+	var ciph *rfc1423Algo
+	var cipherOID asn1.ObjectIdentifier
+	for _, c := range pbes2Ciphers {
+		if c.cipher == alg {
+			ciph = cipherByKey(c.cipher)
+			cipherOID = c.oid
+			break
+		}
+	}
+	if ciph == nil {
+		return nil, errors.New("x509: unknown encryption mode")
+	}
+
+	plaintext, err := MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, errors.New("x509: cannot generate salt: " + err.Error())
+	}
+	iv := make([]byte, ciph.blockSize)
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, errors.New("x509: cannot generate IV: " + err.Error())
+	}
+
+	block, err := ciph.cipherFunc(pbkdf2.Key(password, salt, pbkdf2Iterations, ciph.keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	pad := ciph.blockSize - len(plaintext)%ciph.blockSize
+	encrypted := make([]byte, len(plaintext), len(plaintext)+pad)
+	copy(encrypted, plaintext)
+	for i := 0; i < pad; i++ {
+		encrypted = append(encrypted, byte(pad))
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF: pkix.AlgorithmIdentifier{
+			Algorithm:  oidHMACWithSHA256,
+			Parameters: asn1.RawValue{Tag: 5},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivBytes, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBKDF2,
+			Parameters: asn1.RawValue{FullBytes: kdfParams},
+		},
+		EncryptionScheme: pkix.AlgorithmIdentifier{
+			Algorithm:  cipherOID,
+			Parameters: asn1.RawValue{FullBytes: ivBytes},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPBES2,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: encrypted,
+	})
+}
@@ -5,7 +5,12 @@
 package x509
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/hex"
+	"reflect"
 	"testing"
 )
 
@@ -26,3 +31,107 @@ func TestPKCS8(t *testing.T) {
 		t.Errorf("failed to decode PKCS8 with EC private key: %s", err)
 	}
 }
+
+func TestPKCS8Marshal(t *testing.T) {
+	for _, keyHex := range []string{pkcs8RSAPrivateKeyHex, pkcs8ECPrivateKeyHex} {
+		derBytes, _ := hex.DecodeString(keyHex)
+		key, err := ParsePKCS8PrivateKey(derBytes)
+		if err != nil {
+			t.Fatalf("failed to decode PKCS8 key: %s", err)
+		}
+
+		reserialised, err := MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Errorf("failed to marshal %T: %s", key, err)
+			continue
+		}
+
+		key2, err := ParsePKCS8PrivateKey(reserialised)
+		if err != nil {
+			t.Errorf("failed to decode marshaled %T: %s", key, err)
+			continue
+		}
+		if !reflect.DeepEqual(key, key2) {
+			t.Errorf("%T did not round-trip through PKCS8", key)
+		}
+	}
+
+	if _, err := MarshalPKCS8PrivateKey(struct{}{}); err == nil {
+		t.Error("MarshalPKCS8PrivateKey accepted an unknown key type")
+	}
+}
+
+// Generated using:
+//   openssl ecparam -genkey -name prime256v1 -noout | openssl pkcs8 -topk8 -nocrypt -outform DER
+var pkcs8P256PrivateKeyHex = `308187020100301306072a8648ce3d020106082a8648ce3d030107046d306b0201010420ecf836551b4b187965eaa35da20c78c577e58852f6583ba43ff0ced0771a0feba144034200046cf7fa7c4eb77d34e826bd05a8903f49346a924d54a5c56322211536480b722042e4c7e53fa0bdd11b3f37deac3bd3840901183961ff3bd380ca1a890a3e099a`
+
+// The key above, encrypted using the password "password" with:
+//   openssl pkcs8 -topk8 -v2 aes256 -v2prf hmacWithSHA1 -iter 1000 -outform DER
+//   openssl pkcs8 -topk8 -v2 des3 -iter 1000 -outform DER
+var encryptedPKCS8Tests = []struct {
+	name string
+	der  string
+}{
+	{"AES-256, HMAC-SHA1", `3081de304906092a864886f70d01050d303c301b06092a864886f70d01050c300e0408bdaa1d3ac977134a020203e8301d060960864801650304012a0410a9df1faef1ab211fb9c33b930605a6c3048190506c46c30143703a69d9f61cc6c50431567583de57777f32c1593c6848120e4dd84047c4f6d7f9b07ce8eae46568df2ea1b9dc301d81600a5114598371ca190735bf829b599b43d9cc1aeb392fc28152a6a8b33395c4a81eb925cec402774ae09a63453a3437922fa9fbc35c2aa204af7c06050943d338c998e5ec9eba5783292c554ac7fe180eb7633c2bd6da696ecc`},
+	{"3DES, HMAC-SHA256", `3081e3304e06092a864886f70d01050d3041302906092a864886f70d01050c301c0408379c3d8f1e52d6b4020203e8300c06082a864886f70d02090500301406082a864886f70d0307040850d429a042f955280481902a955316e05ab82d87582c8bfd49d45863ad141cb0ccd861773fe5ca26d9de4a93a0c6fefeb52d853193a0211373a3c47e0f07c8c4ca3fedd37f7f6bbc765d3be876cdb895ffaa623dc63582d39de740ad8ea689b519e9558c40720870ce0bdcb0a79e598f21419b84267778db7641f722823bba9e9b11f4826b4f2804ce6b60a1f13c547d58bc58cdaf4212a9eac135`},
+}
+
+func TestParseEncryptedPKCS8(t *testing.T) {
+	derBytes, _ := hex.DecodeString(pkcs8P256PrivateKeyHex)
+	want, err := ParsePKCS8PrivateKey(derBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range encryptedPKCS8Tests {
+		derBytes, _ := hex.DecodeString(test.der)
+		key, err := ParseEncryptedPKCS8PrivateKey(derBytes, []byte("password"))
+		if err != nil {
+			t.Errorf("%s: failed to decrypt: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(key, want) {
+			t.Errorf("%s: decrypted key doesn't match", test.name)
+		}
+	}
+}
+
+func TestEncryptedPKCS8RoundTrip(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := []byte("kremvax1")
+
+	for _, alg := range []PEMCipher{PEMCipherDES, PEMCipher3DES, PEMCipherAES128, PEMCipherAES192, PEMCipherAES256} {
+		for _, key := range []interface{}{rsaPrivateKey, ecKey} {
+			der, err := MarshalEncryptedPKCS8PrivateKey(rand.Reader, key, password, alg)
+			if err != nil {
+				t.Errorf("cipher %d, %T: failed to encrypt: %s", alg, key, err)
+				continue
+			}
+
+			decrypted, err := ParseEncryptedPKCS8PrivateKey(der, password)
+			if err != nil {
+				t.Errorf("cipher %d, %T: failed to decrypt: %s", alg, key, err)
+				continue
+			}
+			switch k := key.(type) {
+			case *rsa.PrivateKey:
+				d, ok := decrypted.(*rsa.PrivateKey)
+				if !ok || k.D.Cmp(d.D) != 0 {
+					t.Errorf("cipher %d: RSA key did not round-trip", alg)
+				}
+			case *ecdsa.PrivateKey:
+				d, ok := decrypted.(*ecdsa.PrivateKey)
+				if !ok || k.D.Cmp(d.D) != 0 {
+					t.Errorf("cipher %d: EC key did not round-trip", alg)
+				}
+			}
+
+			if _, err := ParseEncryptedPKCS8PrivateKey(der, []byte("wrong")); err == nil {
+				t.Errorf("cipher %d, %T: decrypted with the wrong password", alg, key)
+			}
+		}
+	}
+}
@@ -32,6 +32,9 @@ const (
 	// IncompatibleUsage results when the certificate's key usage indicates
 	// that it may only be used for a different purpose.
 	IncompatibleUsage
+	// Revoked results when the RevocationChecker given in the
+	// VerifyOptions reports that the certificate has been revoked.
+	Revoked
 )
 
 // CertificateInvalidError results when an odd error occurs. Users of this
@@ -53,6 +56,8 @@ func (e CertificateInvalidError) Error() string {
 		return "x509: too many intermediates for path length constraint"
 	case IncompatibleUsage:
 		return "x509: certificate specifies an incompatible key usage"
+	case Revoked:
+		return "x509: certificate has been revoked"
 	}
 	return "x509: unknown error"
 }
@@ -135,6 +140,18 @@ type VerifyOptions struct {
 	// constraint down the chain which mirrors Windows CryptoAPI behaviour,
 	// but not the spec. To accept any key usage, include ExtKeyUsageAny.
 	KeyUsages []ExtKeyUsage
+	// RevocationChecker, if not nil, is consulted for every certificate in
+	// a chain except the root. Chains containing a certificate that it
+	// reports as revoked are discarded.
+	RevocationChecker RevocationChecker
+}
+
+// A RevocationChecker reports whether certificates have been revoked. A
+// *CRLPool is a RevocationChecker.
+type RevocationChecker interface {
+	// CheckRevocation returns a non-nil error if cert, which was issued by
+	// issuer, had been revoked at the time now.
+	CheckRevocation(cert, issuer *Certificate, now time.Time) error
 }
 
 const (
@@ -153,17 +170,8 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 		return CertificateInvalidError{c, Expired}
 	}
 
-	if len(c.PermittedDNSDomains) > 0 {
-		for _, domain := range c.PermittedDNSDomains {
-			if opts.DNSName == domain ||
-				(strings.HasSuffix(opts.DNSName, domain) &&
-					len(opts.DNSName) >= 1+len(domain) &&
-					opts.DNSName[len(opts.DNSName)-len(domain)-1] == '.') {
-				continue
-			}
-
-			return CertificateInvalidError{c, CANotAuthorizedForThisName}
-		}
+	if err := c.checkNameConstraints(currentChain, opts); err != nil {
+		return err
 	}
 
 	// KeyUsage status flags are ignored. From Engineering Security, Peter
@@ -197,12 +205,134 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	return nil
 }
 
+// checkNameConstraints checks the names in the certificates that c issued,
+// directly or through intermediates, and the name being verified, against
+// the name constraints in c.
+func (c *Certificate) checkNameConstraints(issuedChain []*Certificate, opts *VerifyOptions) error {
+	if len(c.PermittedDNSDomains) == 0 && len(c.ExcludedDNSDomains) == 0 &&
+		len(c.PermittedIPRanges) == 0 && len(c.ExcludedIPRanges) == 0 &&
+		len(c.PermittedEmailAddresses) == 0 && len(c.ExcludedEmailAddresses) == 0 {
+		return nil
+	}
+
+	var dnsNames, emailAddresses []string
+	var ipAddresses []net.IP
+	if len(opts.DNSName) > 0 {
+		if ip := net.ParseIP(opts.DNSName); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, opts.DNSName)
+		}
+	}
+	for _, cert := range issuedChain {
+		dnsNames = append(dnsNames, cert.DNSNames...)
+		emailAddresses = append(emailAddresses, cert.EmailAddresses...)
+		ipAddresses = append(ipAddresses, cert.IPAddresses...)
+	}
+
+	for _, name := range dnsNames {
+		if !nameAllowed(name, c.PermittedDNSDomains, c.ExcludedDNSDomains, matchDomainConstraint) {
+			return CertificateInvalidError{c, CANotAuthorizedForThisName}
+		}
+	}
+	for _, email := range emailAddresses {
+		if !nameAllowed(email, c.PermittedEmailAddresses, c.ExcludedEmailAddresses, matchEmailConstraint) {
+			return CertificateInvalidError{c, CANotAuthorizedForThisName}
+		}
+	}
+	for _, ip := range ipAddresses {
+		if !ipAllowed(ip, c.PermittedIPRanges, c.ExcludedIPRanges) {
+			return CertificateInvalidError{c, CANotAuthorizedForThisName}
+		}
+	}
+
+	return nil
+}
+
+// nameAllowed returns true if name matches none of the excluded constraints
+// and, if there are any permitted constraints, matches one of them.
+func nameAllowed(name string, permitted, excluded []string, match func(name, constraint string) bool) bool {
+	for _, constraint := range excluded {
+		if match(name, constraint) {
+			return false
+		}
+	}
+
+	if len(permitted) == 0 {
+		return true
+	}
+	for _, constraint := range permitted {
+		if match(name, constraint) {
+			return true
+		}
+	}
+	return false
+}
+
+func ipAllowed(ip net.IP, permitted, excluded []*net.IPNet) bool {
+	for _, ipNet := range excluded {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(permitted) == 0 {
+		return true
+	}
+	for _, ipNet := range permitted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomainConstraint returns true if domain is within the subtree given
+// by constraint. See RFC 5280, section 4.2.1.10: a constraint of
+// "example.com" matches example.com and all of its subdomains while
+// ".example.com" only matches the subdomains.
+func matchDomainConstraint(domain, constraint string) bool {
+	if len(constraint) == 0 {
+		return true
+	}
+
+	domain = toLowerCaseASCII(domain)
+	constraint = toLowerCaseASCII(constraint)
+
+	if constraint[0] == '.' {
+		return strings.HasSuffix(domain, constraint)
+	}
+	return domain == constraint || strings.HasSuffix(domain, "."+constraint)
+}
+
+// matchEmailConstraint returns true if email is within the subtree given by
+// constraint, which is either a particular mailbox, all the mailboxes on a
+// host, or all the mailboxes in a domain when it begins with a period.
+func matchEmailConstraint(email, constraint string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	local, host := email[:at], toLowerCaseASCII(email[at+1:])
+
+	if at := strings.LastIndex(constraint, "@"); at >= 0 {
+		// The local part of a mailbox is case-sensitive.
+		return local == constraint[:at] && host == toLowerCaseASCII(constraint[at+1:])
+	}
+
+	constraint = toLowerCaseASCII(constraint)
+	if len(constraint) > 0 && constraint[0] == '.' {
+		return strings.HasSuffix(host, constraint)
+	}
+	return host == constraint
+}
+
 // Verify attempts to verify c by building one or more chains from c to a
 // certificate in opts.Roots, using certificates in opts.Intermediates if
 // needed. If successful, it returns one or more chains where the first
 // element of the chain is c and the last element is from opts.Roots.
 //
-// WARNING: this doesn't do any revocation checking.
+// Revocation is only checked if opts.RevocationChecker is set.
 func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err error) {
 	// Use Windows's own verification and chain building.
 	if opts.Roots == nil && runtime.GOOS == "windows" {
@@ -238,24 +368,60 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		keyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
 	}
 
-	// If any key usage is acceptable then we're done.
+	// If any key usage is acceptable then every candidate will do.
+	anyKeyUsage := false
 	for _, usage := range keyUsages {
 		if usage == ExtKeyUsageAny {
-			chains = candidateChains
-			return
+			anyKeyUsage = true
+			break
 		}
 	}
 
-	for _, candidate := range candidateChains {
-		if checkChainForKeyUsage(candidate, keyUsages) {
-			chains = append(chains, candidate)
+	if anyKeyUsage {
+		chains = candidateChains
+	} else {
+		for _, candidate := range candidateChains {
+			if checkChainForKeyUsage(candidate, keyUsages) {
+				chains = append(chains, candidate)
+			}
 		}
 	}
 
 	if len(chains) == 0 {
 		err = CertificateInvalidError{c, IncompatibleUsage}
+		return
+	}
+
+	if opts.RevocationChecker != nil {
+		chains, err = checkChainsForRevocation(chains, &opts)
+	}
+
+	return
+}
+
+// checkChainsForRevocation returns the chains in which no certificate has
+// been revoked according to opts.RevocationChecker. If there are none, the
+// error from the RevocationChecker is returned.
+func checkChainsForRevocation(chains [][]*Certificate, opts *VerifyOptions) (good [][]*Certificate, err error) {
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+nextChain:
+	for _, chain := range chains {
+		for i := 0; i < len(chain)-1; i++ {
+			if e := opts.RevocationChecker.CheckRevocation(chain[i], chain[i+1], now); e != nil {
+				err = e
+				continue nextChain
+			}
+		}
+		good = append(good, chain)
 	}
 
+	if len(good) > 0 {
+		err = nil
+	}
 	return
 }
 
@@ -5,9 +5,14 @@
 package x509
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
+	"math/big"
+	"net"
 	"runtime"
 	"strings"
 	"testing"
@@ -753,3 +758,162 @@ zJVSk/BwJVmcIGfE7vmLV2H0knZ9P4SNVbfo5azV8fUZVqZa+5Acr5Pr5RzUZ5dd
 BA6+C4OmF4O5MBKgxTMVBbkN+8cFduPYSo38NBejxiEovjBFMR7HeL5YYTisO+IB
 ZQ==
 -----END CERTIFICATE-----`
+
+// generateCert creates a certificate from template, signed by parent, or
+// self-signed if parent is nil, and returns it along with its private key.
+func generateCert(t *testing.T, template, parent *Certificate, parentKey *ecdsa.PrivateKey) (*Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	template.NotBefore = time.Unix(1000, 0)
+	template.NotAfter = time.Unix(100000, 0)
+	derBytes, err := CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(derBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+var nameConstraintsTests = []struct {
+	dnsNames       []string
+	emailAddresses []string
+	ipAddresses    []net.IP
+	ok             bool
+}{
+	{dnsNames: []string{"example.com"}, ok: true},
+	{dnsNames: []string{"www.example.com", "EXAMPLE.COM"}, ok: true},
+	{dnsNames: []string{"www.example.org"}, ok: false},
+	{dnsNames: []string{"badexample.com"}, ok: false},
+	{dnsNames: []string{"secret.example.com"}, ok: false},
+	{dnsNames: []string{"a.secret.example.com"}, ok: false},
+	{emailAddresses: []string{"gopher@example.com"}, ok: true},
+	{emailAddresses: []string{"gopher@mail.example.com"}, ok: false},
+	{emailAddresses: []string{"root@example.com"}, ok: false},
+	{emailAddresses: []string{"Root@example.com"}, ok: true},
+	{ipAddresses: []net.IP{net.IPv4(10, 1, 2, 3)}, ok: true},
+	{ipAddresses: []net.IP{net.IPv4(10, 9, 2, 3)}, ok: false},
+	{ipAddresses: []net.IP{net.IPv4(192, 168, 0, 1)}, ok: false},
+	{ipAddresses: []net.IP{net.ParseIP("2001:db8::1")}, ok: false},
+}
+
+func TestNameConstraints(t *testing.T) {
+	root, rootKey := generateCert(t, &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Root"},
+		BasicConstraintsValid: true,
+		IsCA: true,
+	}, nil, nil)
+
+	intermediate, intermediateKey := generateCert(t, &Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "Intermediate"},
+		BasicConstraintsValid: true,
+		IsCA: true,
+
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         []string{"example.com"},
+		ExcludedDNSDomains:          []string{"secret.example.com"},
+		PermittedIPRanges:           []*net.IPNet{{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)}},
+		ExcludedIPRanges:            []*net.IPNet{{IP: net.IPv4(10, 9, 0, 0), Mask: net.CIDRMask(16, 32)}},
+		PermittedEmailAddresses:     []string{"example.com"},
+		ExcludedEmailAddresses:      []string{"root@example.com"},
+	}, root, rootKey)
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	intermediates := NewCertPool()
+	intermediates.AddCert(intermediate)
+
+	for i, test := range nameConstraintsTests {
+		leaf, _ := generateCert(t, &Certificate{
+			SerialNumber:   big.NewInt(3),
+			Subject:        pkix.Name{CommonName: "Leaf"},
+			DNSNames:       test.dnsNames,
+			EmailAddresses: test.emailAddresses,
+			IPAddresses:    test.ipAddresses,
+		}, intermediate, intermediateKey)
+
+		_, err := leaf.Verify(VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   time.Unix(2000, 0),
+		})
+		if test.ok && err != nil {
+			t.Errorf("#%d: unexpected error: %s", i, err)
+		}
+		if !test.ok {
+			if err == nil {
+				t.Errorf("#%d: verification succeeded despite name constraints", i)
+			} else if e, ok := err.(CertificateInvalidError); !ok || e.Reason != CANotAuthorizedForThisName || e.Cert != intermediate {
+				t.Errorf("#%d: unexpected error: %s", i, err)
+			}
+		}
+	}
+}
+
+func TestRevocationChecker(t *testing.T) {
+	root, rootKey := generateCert(t, &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Root"},
+		BasicConstraintsValid: true,
+		IsCA: true,
+	}, nil, nil)
+
+	leaf, _ := generateCert(t, &Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Leaf"},
+		DNSNames:     []string{"leaf.example.com"},
+	}, root, rootKey)
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	opts := VerifyOptions{
+		DNSName:           "leaf.example.com",
+		Roots:             roots,
+		CurrentTime:       time.Unix(5000, 0),
+		RevocationChecker: NewCRLPool(),
+	}
+
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Fatalf("unexpected error with no CRLs: %s", err)
+	}
+
+	crlBytes, err := root.CreateCRL(rand.Reader, rootKey, []pkix.RevokedCertificate{
+		{SerialNumber: big.NewInt(42), RevocationTime: time.Unix(4000, 0).UTC()},
+	}, time.Unix(3000, 0), time.Unix(10000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseCRL(crlBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewCRLPool()
+	pool.AddCRL(crl)
+	opts.RevocationChecker = pool
+
+	_, err = leaf.Verify(opts)
+	if e, ok := err.(CertificateInvalidError); !ok || e.Reason != Revoked || e.Cert != leaf {
+		t.Errorf("expected revocation error, got %v", err)
+	}
+
+	// Before the revocation time the certificate is still good.
+	opts.CurrentTime = time.Unix(3500, 0)
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Errorf("unexpected error before revocation: %s", err)
+	}
+
+	// An expired CRL is ignored.
+	opts.CurrentTime = time.Unix(20000, 0)
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Errorf("unexpected error with expired CRL: %s", err)
+	}
+}
@@ -10,9 +10,10 @@ import (
 	"crypto"
 	"crypto/dsa"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
-	"crypto/sha1"
+	_ "crypto/sha1"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/pem"
@@ -46,37 +47,23 @@ func ParsePKIXPublicKey(derBytes []byte) (pub interface{}, err error) {
 }
 
 // MarshalPKIXPublicKey serialises a public key to DER-encoded PKIX format.
+// The following key types are supported: *rsa.PublicKey, *ecdsa.PublicKey
+// and ed25519.PublicKey.
 func MarshalPKIXPublicKey(pub interface{}) ([]byte, error) {
-	var pubBytes []byte
-
-	switch pub := pub.(type) {
-	case *rsa.PublicKey:
-		pubBytes, _ = asn1.Marshal(rsaPublicKey{
-			N: pub.N,
-			E: pub.E,
-		})
-	default:
-		return nil, errors.New("x509: unknown public key type")
+	pubBytes, publicKeyAlgorithm, err := marshalPublicKey(pub)
+	if err != nil {
+		return nil, err
 	}
 
 	pkix := pkixPublicKey{
-		Algo: pkix.AlgorithmIdentifier{
-			Algorithm: []int{1, 2, 840, 113549, 1, 1, 1},
-			// This is a NULL parameters value which is technically
-			// superfluous, but most other code includes it and, by
-			// doing this, we match their public key hashes.
-			Parameters: asn1.RawValue{
-				Tag: 5,
-			},
-		},
+		Algo: publicKeyAlgorithm,
 		BitString: asn1.BitString{
 			Bytes:     pubBytes,
 			BitLength: 8 * len(pubBytes),
 		},
 	}
 
-	ret, _ := asn1.Marshal(pkix)
-	return ret, nil
+	return asn1.Marshal(pkix)
 }
 
 // These structures reflect the ASN.1 structure of X.509 certificates.:
@@ -143,6 +130,16 @@ const (
 	ECDSAWithSHA256
 	ECDSAWithSHA384
 	ECDSAWithSHA512
+	PureEd25519
+
+	// The SHA-3 based algorithms are only available if crypto/sha3 is
+	// linked into the binary.
+	SHA3_256WithRSA
+	SHA3_384WithRSA
+	SHA3_512WithRSA
+	ECDSAWithSHA3_256
+	ECDSAWithSHA3_384
+	ECDSAWithSHA3_512
 )
 
 type PublicKeyAlgorithm int
@@ -152,6 +149,7 @@ const (
 	RSA
 	DSA
 	ECDSA
+	Ed25519
 )
 
 // OIDs for signature algorithms
@@ -203,6 +201,23 @@ const (
 //
 // ecdsa-with-SHA512 OBJECT IDENTIFIER ::= { iso(1) member-body(2)
 //    us(840) ansi-X9-62(10045) signatures(4) ecdsa-with-SHA2(3) 4 }
+//
+// NIST Computer Security Objects Register, signature algorithms using SHA-3
+//
+// sigAlgs OBJECT IDENTIFIER ::= { joint-iso-itu-t(2) country(16) us(840)
+//    organization(1) gov(101) csor(3) nistAlgorithm(4) 3 }
+//
+// id-rsassa-pkcs1-v1_5-with-sha3-256 OBJECT IDENTIFIER ::= { sigAlgs 14 }
+//
+// id-rsassa-pkcs1-v1_5-with-sha3-384 OBJECT IDENTIFIER ::= { sigAlgs 15 }
+//
+// id-rsassa-pkcs1-v1_5-with-sha3-512 OBJECT IDENTIFIER ::= { sigAlgs 16 }
+//
+// id-ecdsa-with-sha3-256 OBJECT IDENTIFIER ::= { sigAlgs 10 }
+//
+// id-ecdsa-with-sha3-384 OBJECT IDENTIFIER ::= { sigAlgs 11 }
+//
+// id-ecdsa-with-sha3-512 OBJECT IDENTIFIER ::= { sigAlgs 12 }
 
 var (
 	oidSignatureMD2WithRSA      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 2}
@@ -217,6 +232,14 @@ var (
 	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
 	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
 	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSignatureEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+	oidSignatureSHA3_256WithRSA   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 14}
+	oidSignatureSHA3_384WithRSA   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 15}
+	oidSignatureSHA3_512WithRSA   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 16}
+	oidSignatureECDSAWithSHA3_256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 10}
+	oidSignatureECDSAWithSHA3_384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 11}
+	oidSignatureECDSAWithSHA3_512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 12}
 )
 
 func getSignatureAlgorithmFromOID(oid asn1.ObjectIdentifier) SignatureAlgorithm {
@@ -245,6 +268,20 @@ func getSignatureAlgorithmFromOID(oid asn1.ObjectIdentifier) SignatureAlgorithm
 		return ECDSAWithSHA384
 	case oid.Equal(oidSignatureECDSAWithSHA512):
 		return ECDSAWithSHA512
+	case oid.Equal(oidSignatureEd25519):
+		return PureEd25519
+	case oid.Equal(oidSignatureSHA3_256WithRSA):
+		return SHA3_256WithRSA
+	case oid.Equal(oidSignatureSHA3_384WithRSA):
+		return SHA3_384WithRSA
+	case oid.Equal(oidSignatureSHA3_512WithRSA):
+		return SHA3_512WithRSA
+	case oid.Equal(oidSignatureECDSAWithSHA3_256):
+		return ECDSAWithSHA3_256
+	case oid.Equal(oidSignatureECDSAWithSHA3_384):
+		return ECDSAWithSHA3_384
+	case oid.Equal(oidSignatureECDSAWithSHA3_512):
+		return ECDSAWithSHA3_512
 	}
 	return UnknownSignatureAlgorithm
 }
@@ -263,10 +300,15 @@ func getSignatureAlgorithmFromOID(oid asn1.ObjectIdentifier) SignatureAlgorithm
 //
 // id-ecPublicKey OBJECT IDENTIFIER ::= {
 //       iso(1) member-body(2) us(840) ansi-X9-62(10045) keyType(2) 1 }
+//
+// RFC 8410, 3 Curve25519 and Curve448 Algorithm Identifiers
+//
+// id-Ed25519 OBJECT IDENTIFIER ::= { 1 3 101 112 }
 var (
-	oidPublicKeyRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
-	oidPublicKeyDSA   = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
-	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyDSA     = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
+	oidPublicKeyECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyEd25519 = oidSignatureEd25519
 )
 
 func getPublicKeyAlgorithmFromOID(oid asn1.ObjectIdentifier) PublicKeyAlgorithm {
@@ -277,6 +319,8 @@ func getPublicKeyAlgorithmFromOID(oid asn1.ObjectIdentifier) PublicKeyAlgorithm
 		return DSA
 	case oid.Equal(oidPublicKeyECDSA):
 		return ECDSA
+	case oid.Equal(oidPublicKeyEd25519):
+		return Ed25519
 	}
 	return UnknownPublicKeyAlgorithm
 }
@@ -487,6 +531,11 @@ type Certificate struct {
 	// Name constraints
 	PermittedDNSDomainsCritical bool // if true then the name constraints are marked critical.
 	PermittedDNSDomains         []string
+	ExcludedDNSDomains          []string
+	PermittedIPRanges           []*net.IPNet
+	ExcludedIPRanges            []*net.IPNet
+	PermittedEmailAddresses     []string
+	ExcludedEmailAddresses      []string
 
 	// CRL Distribution Points
 	CRLDistributionPoints []string
@@ -591,6 +640,12 @@ func (c *Certificate) CheckSignatureFrom(parent *Certificate) (err error) {
 // CheckSignature verifies that signature is a valid signature over signed from
 // c's public key.
 func (c *Certificate) CheckSignature(algo SignatureAlgorithm, signed, signature []byte) (err error) {
+	return checkSignature(algo, signed, signature, c.PublicKey)
+}
+
+// checkSignature verifies that signature is a valid signature over signed from
+// a crypto.PublicKey.
+func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey interface{}) (err error) {
 	var hashType crypto.Hash
 
 	switch algo {
@@ -602,6 +657,22 @@ func (c *Certificate) CheckSignature(algo SignatureAlgorithm, signed, signature
 		hashType = crypto.SHA384
 	case SHA512WithRSA, ECDSAWithSHA512:
 		hashType = crypto.SHA512
+	case SHA3_256WithRSA, ECDSAWithSHA3_256:
+		hashType = crypto.SHA3_256
+	case SHA3_384WithRSA, ECDSAWithSHA3_384:
+		hashType = crypto.SHA3_384
+	case SHA3_512WithRSA, ECDSAWithSHA3_512:
+		hashType = crypto.SHA3_512
+	case PureEd25519:
+		// Ed25519 signs the message itself rather than a digest.
+		pub, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return errors.New("x509: Ed25519 verification failure")
+		}
+		return
 	default:
 		return ErrUnsupportedAlgorithm
 	}
@@ -614,7 +685,7 @@ func (c *Certificate) CheckSignature(algo SignatureAlgorithm, signed, signature
 	h.Write(signed)
 	digest := h.Sum(nil)
 
-	switch pub := c.PublicKey.(type) {
+	switch pub := publicKey.(type) {
 	case *rsa.PublicKey:
 		return rsa.VerifyPKCS1v15(pub, hashType, digest, signature)
 	case *dsa.PublicKey:
@@ -675,7 +746,80 @@ type nameConstraints struct {
 }
 
 type generalSubtree struct {
-	Name string `asn1:"tag:2,optional,ia5"`
+	Name asn1.RawValue
+}
+
+// parseNameConstraintsExtension parses the value of a name constraints
+// extension into out. It reports whether any of the constraints were of a
+// type that isn't supported, in which case they have been ignored.
+func parseNameConstraintsExtension(out *Certificate, value []byte) (unhandled bool, err error) {
+	var constraints nameConstraints
+	if _, err = asn1.Unmarshal(value, &constraints); err != nil {
+		return false, err
+	}
+
+	parse := func(subtrees []generalSubtree) (dnsNames []string, ipRanges []*net.IPNet, emails []string, err error) {
+		for _, subtree := range subtrees {
+			name := subtree.Name
+			if name.Class != 2 {
+				unhandled = true
+				continue
+			}
+			switch name.Tag {
+			case 1:
+				emails = append(emails, string(name.Bytes))
+			case 2:
+				dnsNames = append(dnsNames, string(name.Bytes))
+			case 7:
+				// An iPAddress constraint is the address followed by
+				// the mask, each of four or sixteen bytes.
+				l := len(name.Bytes)
+				if l != 2*net.IPv4len && l != 2*net.IPv6len {
+					return nil, nil, nil, errors.New("x509: invalid IP address in name constraints")
+				}
+				ip := make(net.IP, l/2)
+				copy(ip, name.Bytes)
+				mask := make(net.IPMask, l/2)
+				copy(mask, name.Bytes[l/2:])
+				ipRanges = append(ipRanges, &net.IPNet{IP: ip, Mask: mask})
+			default:
+				unhandled = true
+			}
+		}
+		return
+	}
+
+	if out.PermittedDNSDomains, out.PermittedIPRanges, out.PermittedEmailAddresses, err = parse(constraints.Permitted); err != nil {
+		return false, err
+	}
+	if out.ExcludedDNSDomains, out.ExcludedIPRanges, out.ExcludedEmailAddresses, err = parse(constraints.Excluded); err != nil {
+		return false, err
+	}
+	return unhandled, nil
+}
+
+// marshalSubtrees returns the GeneralSubtrees for the given names.
+func marshalSubtrees(dnsNames []string, ipRanges []*net.IPNet, emails []string) (subtrees []generalSubtree) {
+	for _, name := range dnsNames {
+		subtrees = append(subtrees, generalSubtree{Name: asn1.RawValue{Tag: 2, Class: 2, Bytes: []byte(name)}})
+	}
+	for _, ipNet := range ipRanges {
+		ip, mask := ipNet.IP, ipNet.Mask
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			if len(mask) == net.IPv6len {
+				mask = mask[12:]
+			}
+		}
+		b := make([]byte, 0, len(ip)+len(mask))
+		b = append(b, ip...)
+		b = append(b, mask...)
+		subtrees = append(subtrees, generalSubtree{Name: asn1.RawValue{Tag: 7, Class: 2, Bytes: b}})
+	}
+	for _, email := range emails {
+		subtrees = append(subtrees, generalSubtree{Name: asn1.RawValue{Tag: 1, Class: 2, Bytes: []byte(email)}})
+	}
+	return
 }
 
 // RFC 5280, 4.2.2.1
@@ -763,11 +907,74 @@ func parsePublicKey(algo PublicKeyAlgorithm, keyData *publicKeyInfo) (interface{
 			Y:     y,
 		}
 		return pub, nil
+	case Ed25519:
+		// RFC 8410, Section 3: the parameters must be absent.
+		if len(keyData.Algorithm.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: Ed25519 key encoded with illegal parameters")
+		}
+		if len(asn1Data) != ed25519.PublicKeySize {
+			return nil, errors.New("x509: wrong Ed25519 public key size")
+		}
+		pub := make([]byte, ed25519.PublicKeySize)
+		copy(pub, asn1Data)
+		return ed25519.PublicKey(pub), nil
 	default:
 		return nil, nil
 	}
 }
 
+func parseSANExtension(value []byte) (dnsNames, emailAddresses []string, ipAddresses []net.IP, err error) {
+	// RFC 5280, 4.2.1.6
+
+	// SubjectAltName ::= GeneralNames
+	//
+	// GeneralNames ::= SEQUENCE SIZE (1..MAX) OF GeneralName
+	//
+	// GeneralName ::= CHOICE {
+	//      otherName                       [0]     OtherName,
+	//      rfc822Name                      [1]     IA5String,
+	//      dNSName                         [2]     IA5String,
+	//      x400Address                     [3]     ORAddress,
+	//      directoryName                   [4]     Name,
+	//      ediPartyName                    [5]     EDIPartyName,
+	//      uniformResourceIdentifier       [6]     IA5String,
+	//      iPAddress                       [7]     OCTET STRING,
+	//      registeredID                    [8]     OBJECT IDENTIFIER }
+	var seq asn1.RawValue
+	if _, err = asn1.Unmarshal(value, &seq); err != nil {
+		return
+	}
+	if !seq.IsCompound || seq.Tag != 16 || seq.Class != 0 {
+		err = asn1.StructuralError{Msg: "bad SAN sequence"}
+		return
+	}
+
+	rest := seq.Bytes
+	for len(rest) > 0 {
+		var v asn1.RawValue
+		rest, err = asn1.Unmarshal(rest, &v)
+		if err != nil {
+			return
+		}
+		switch v.Tag {
+		case 1:
+			emailAddresses = append(emailAddresses, string(v.Bytes))
+		case 2:
+			dnsNames = append(dnsNames, string(v.Bytes))
+		case 7:
+			switch len(v.Bytes) {
+			case net.IPv4len, net.IPv6len:
+				ipAddresses = append(ipAddresses, v.Bytes)
+			default:
+				err = errors.New("x509: certificate contained IP address of length " + strconv.Itoa(len(v.Bytes)))
+				return
+			}
+		}
+	}
+
+	return
+}
+
 func parseCertificate(in *certificate) (*Certificate, error) {
 	out := new(Certificate)
 	out.Raw = in.Raw
@@ -841,58 +1048,12 @@ func parseCertificate(in *certificate) (*Certificate, error) {
 					continue
 				}
 			case 17:
-				// RFC 5280, 4.2.1.6
-
-				// SubjectAltName ::= GeneralNames
-				//
-				// GeneralNames ::= SEQUENCE SIZE (1..MAX) OF GeneralName
-				//
-				// GeneralName ::= CHOICE {
-				//      otherName                       [0]     OtherName,
-				//      rfc822Name                      [1]     IA5String,
-				//      dNSName                         [2]     IA5String,
-				//      x400Address                     [3]     ORAddress,
-				//      directoryName                   [4]     Name,
-				//      ediPartyName                    [5]     EDIPartyName,
-				//      uniformResourceIdentifier       [6]     IA5String,
-				//      iPAddress                       [7]     OCTET STRING,
-				//      registeredID                    [8]     OBJECT IDENTIFIER }
-				var seq asn1.RawValue
-				_, err := asn1.Unmarshal(e.Value, &seq)
+				out.DNSNames, out.EmailAddresses, out.IPAddresses, err = parseSANExtension(e.Value)
 				if err != nil {
 					return nil, err
 				}
-				if !seq.IsCompound || seq.Tag != 16 || seq.Class != 0 {
-					return nil, asn1.StructuralError{Msg: "bad SAN sequence"}
-				}
-
-				parsedName := false
 
-				rest := seq.Bytes
-				for len(rest) > 0 {
-					var v asn1.RawValue
-					rest, err = asn1.Unmarshal(rest, &v)
-					if err != nil {
-						return nil, err
-					}
-					switch v.Tag {
-					case 1:
-						out.EmailAddresses = append(out.EmailAddresses, string(v.Bytes))
-						parsedName = true
-					case 2:
-						out.DNSNames = append(out.DNSNames, string(v.Bytes))
-						parsedName = true
-					case 7:
-						switch len(v.Bytes) {
-						case net.IPv4len, net.IPv6len:
-							out.IPAddresses = append(out.IPAddresses, v.Bytes)
-						default:
-							return nil, errors.New("x509: certificate contained IP address of length " + strconv.Itoa(len(v.Bytes)))
-						}
-					}
-				}
-
-				if parsedName {
+				if len(out.DNSNames) > 0 || len(out.EmailAddresses) > 0 {
 					continue
 				}
 				// If we didn't parse any of the names then we
@@ -914,25 +1075,14 @@ func parseCertificate(in *certificate) (*Certificate, error) {
 				//
 				// BaseDistance ::= INTEGER (0..MAX)
 
-				var constraints nameConstraints
-				_, err := asn1.Unmarshal(e.Value, &constraints)
+				unhandled, err := parseNameConstraintsExtension(out, e.Value)
 				if err != nil {
 					return nil, err
 				}
 
-				if len(constraints.Excluded) > 0 && e.Critical {
+				if unhandled && e.Critical {
 					return out, UnhandledCriticalExtension{}
 				}
-
-				for _, subtree := range constraints.Permitted {
-					if len(subtree.Name) == 0 {
-						if e.Critical {
-							return out, UnhandledCriticalExtension{}
-						}
-						continue
-					}
-					out.PermittedDNSDomains = append(out.PermittedDNSDomains, subtree.Name)
-				}
 				continue
 
 			case 31:
@@ -1129,6 +1279,27 @@ func oidInExtensions(oid asn1.ObjectIdentifier, extensions []pkix.Extension) boo
 	return false
 }
 
+// marshalSANs marshals a list of addresses into the contents of an X.509
+// SubjectAlternativeName extension.
+func marshalSANs(dnsNames, emailAddresses []string, ipAddresses []net.IP) (derBytes []byte, err error) {
+	var rawValues []asn1.RawValue
+	for _, name := range dnsNames {
+		rawValues = append(rawValues, asn1.RawValue{Tag: 2, Class: 2, Bytes: []byte(name)})
+	}
+	for _, email := range emailAddresses {
+		rawValues = append(rawValues, asn1.RawValue{Tag: 1, Class: 2, Bytes: []byte(email)})
+	}
+	for _, rawIP := range ipAddresses {
+		// If possible, we always want to encode IPv4 addresses in 4 bytes.
+		ip := rawIP.To4()
+		if ip == nil {
+			ip = rawIP
+		}
+		rawValues = append(rawValues, asn1.RawValue{Tag: 7, Class: 2, Bytes: ip})
+	}
+	return asn1.Marshal(rawValues)
+}
+
 func buildExtensions(template *Certificate) (ret []pkix.Extension, err error) {
 	ret = make([]pkix.Extension, 10 /* maximum number of elements. */)
 	n := 0
@@ -1230,22 +1401,7 @@ func buildExtensions(template *Certificate) (ret []pkix.Extension, err error) {
 	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0) &&
 		!oidInExtensions(oidExtensionSubjectAltName, template.ExtraExtensions) {
 		ret[n].Id = oidExtensionSubjectAltName
-		var rawValues []asn1.RawValue
-		for _, name := range template.DNSNames {
-			rawValues = append(rawValues, asn1.RawValue{Tag: 2, Class: 2, Bytes: []byte(name)})
-		}
-		for _, email := range template.EmailAddresses {
-			rawValues = append(rawValues, asn1.RawValue{Tag: 1, Class: 2, Bytes: []byte(email)})
-		}
-		for _, rawIP := range template.IPAddresses {
-			// If possible, we always want to encode IPv4 addresses in 4 bytes.
-			ip := rawIP.To4()
-			if ip == nil {
-				ip = rawIP
-			}
-			rawValues = append(rawValues, asn1.RawValue{Tag: 7, Class: 2, Bytes: ip})
-		}
-		ret[n].Value, err = asn1.Marshal(rawValues)
+		ret[n].Value, err = marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses)
 		if err != nil {
 			return
 		}
@@ -1266,16 +1422,16 @@ func buildExtensions(template *Certificate) (ret []pkix.Extension, err error) {
 		n++
 	}
 
-	if len(template.PermittedDNSDomains) > 0 &&
+	if (len(template.PermittedDNSDomains) > 0 || len(template.ExcludedDNSDomains) > 0 ||
+		len(template.PermittedIPRanges) > 0 || len(template.ExcludedIPRanges) > 0 ||
+		len(template.PermittedEmailAddresses) > 0 || len(template.ExcludedEmailAddresses) > 0) &&
 		!oidInExtensions(oidExtensionNameConstraints, template.ExtraExtensions) {
 		ret[n].Id = oidExtensionNameConstraints
 		ret[n].Critical = template.PermittedDNSDomainsCritical
 
 		var out nameConstraints
-		out.Permitted = make([]generalSubtree, len(template.PermittedDNSDomains))
-		for i, permitted := range template.PermittedDNSDomains {
-			out.Permitted[i] = generalSubtree{Name: permitted}
-		}
+		out.Permitted = marshalSubtrees(template.PermittedDNSDomains, template.PermittedIPRanges, template.PermittedEmailAddresses)
+		out.Excluded = marshalSubtrees(template.ExcludedDNSDomains, template.ExcludedIPRanges, template.ExcludedEmailAddresses)
 		ret[n].Value, err = asn1.Marshal(out)
 		if err != nil {
 			return
@@ -1320,24 +1476,9 @@ func subjectBytes(cert *Certificate) ([]byte, error) {
 	return asn1.Marshal(cert.Subject.ToRDNSequence())
 }
 
-// CreateCertificate creates a new certificate based on a template. The
-// following members of template are used: SerialNumber, Subject, NotBefore,
-// NotAfter, KeyUsage, ExtKeyUsage, UnknownExtKeyUsage, BasicConstraintsValid,
-// IsCA, MaxPathLen, SubjectKeyId, DNSNames, PermittedDNSDomainsCritical,
-// PermittedDNSDomains.
-//
-// The certificate is signed by parent. If parent is equal to template then the
-// certificate is self-signed. The parameter pub is the public key of the
-// signee and priv is the private key of the signer.
-//
-// The returned slice is the certificate in DER encoding.
-//
-// The only supported key types are RSA and ECDSA (*rsa.PublicKey or
-// *ecdsa.PublicKey for pub, *rsa.PrivateKey or *ecdsa.PublicKey for priv).
-func CreateCertificate(rand io.Reader, template, parent *Certificate, pub interface{}, priv interface{}) (cert []byte, err error) {
-	var publicKeyBytes []byte
-	var publicKeyAlgorithm pkix.AlgorithmIdentifier
-
+// marshalPublicKey returns the encoded public key and the algorithm
+// identifier to use in a SubjectPublicKeyInfo for pub.
+func marshalPublicKey(pub interface{}) (publicKeyBytes []byte, publicKeyAlgorithm pkix.AlgorithmIdentifier, err error) {
 	switch pub := pub.(type) {
 	case *rsa.PublicKey:
 		publicKeyBytes, err = asn1.Marshal(rsaPublicKey{
@@ -1345,10 +1486,16 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub interf
 			E: pub.E,
 		})
 		publicKeyAlgorithm.Algorithm = oidPublicKeyRSA
+		// This is a NULL parameters value which is technically
+		// superfluous, but most other code includes it and, by
+		// doing this, we match their public key hashes.
+		publicKeyAlgorithm.Parameters = asn1.RawValue{
+			Tag: 5,
+		}
 	case *ecdsa.PublicKey:
 		oid, ok := oidFromNamedCurve(pub.Curve)
 		if !ok {
-			return nil, errors.New("x509: unknown elliptic curve")
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: unknown elliptic curve")
 		}
 		publicKeyAlgorithm.Algorithm = oidPublicKeyECDSA
 		var paramBytes []byte
@@ -1358,37 +1505,78 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub interf
 		}
 		publicKeyAlgorithm.Parameters.FullBytes = paramBytes
 		publicKeyBytes = elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	case ed25519.PublicKey:
+		publicKeyBytes = pub
+		publicKeyAlgorithm.Algorithm = oidPublicKeyEd25519
 	default:
-		return nil, errors.New("x509: only RSA and ECDSA public keys supported")
+		return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: only RSA, ECDSA and Ed25519 public keys supported")
 	}
 
-	var signatureAlgorithm pkix.AlgorithmIdentifier
-	var hashFunc crypto.Hash
+	return publicKeyBytes, publicKeyAlgorithm, nil
+}
 
-	switch priv := priv.(type) {
-	case *rsa.PrivateKey:
-		signatureAlgorithm.Algorithm = oidSignatureSHA1WithRSA
+// signingParamsForPublicKey returns the hash function and signature
+// algorithm to use when signing with the private key matching pub.
+func signingParamsForPublicKey(pub interface{}) (hashFunc crypto.Hash, sigAlgo pkix.AlgorithmIdentifier, err error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
 		hashFunc = crypto.SHA1
-	case *ecdsa.PrivateKey:
-		switch priv.Curve {
+		sigAlgo.Algorithm = oidSignatureSHA1WithRSA
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
 		case elliptic.P224(), elliptic.P256():
 			hashFunc = crypto.SHA256
-			signatureAlgorithm.Algorithm = oidSignatureECDSAWithSHA256
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA256
 		case elliptic.P384():
 			hashFunc = crypto.SHA384
-			signatureAlgorithm.Algorithm = oidSignatureECDSAWithSHA384
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA384
 		case elliptic.P521():
 			hashFunc = crypto.SHA512
-			signatureAlgorithm.Algorithm = oidSignatureECDSAWithSHA512
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA512
 		default:
-			return nil, errors.New("x509: unknown elliptic curve")
+			err = errors.New("x509: unknown elliptic curve")
 		}
+	case ed25519.PublicKey:
+		// Ed25519 signs the message itself, so hashFunc is left as
+		// zero.
+		sigAlgo.Algorithm = oidSignatureEd25519
 	default:
-		return nil, errors.New("x509: only RSA and ECDSA private keys supported")
+		err = errors.New("x509: only RSA, ECDSA and Ed25519 keys supported")
 	}
+	return
+}
 
+// CreateCertificate creates a new certificate based on a template. The
+// following members of template are used: SerialNumber, Subject, NotBefore,
+// NotAfter, KeyUsage, ExtKeyUsage, UnknownExtKeyUsage, BasicConstraintsValid,
+// IsCA, MaxPathLen, SubjectKeyId, DNSNames, PermittedDNSDomainsCritical,
+// PermittedDNSDomains, ExcludedDNSDomains, PermittedIPRanges,
+// ExcludedIPRanges, PermittedEmailAddresses, ExcludedEmailAddresses.
+//
+// The certificate is signed by parent. If parent is equal to template then the
+// certificate is self-signed. The parameter pub is the public key of the
+// signee and priv is the private key of the signer.
+//
+// The returned slice is the certificate in DER encoding.
+//
+// The only supported key types are RSA, ECDSA and Ed25519 (*rsa.PublicKey,
+// *ecdsa.PublicKey or ed25519.PublicKey for pub). priv must implement
+// crypto.Signer with a public key of one of those types, such as
+// *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey or a key kept in a
+// hardware module.
+func CreateCertificate(rand io.Reader, template, parent *Certificate, pub interface{}, priv interface{}) (cert []byte, err error) {
+	publicKeyBytes, publicKeyAlgorithm, err := marshalPublicKey(pub)
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	key, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
+	}
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(key.Public())
+	if err != nil {
+		return nil, err
 	}
 
 	if len(parent.SubjectKeyId) > 0 {
@@ -1429,24 +1617,14 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub interf
 
 	c.Raw = tbsCertContents
 
-	h := hashFunc.New()
-	h.Write(tbsCertContents)
-	digest := h.Sum(nil)
-
-	var signature []byte
-
-	switch priv := priv.(type) {
-	case *rsa.PrivateKey:
-		signature, err = rsa.SignPKCS1v15(rand, priv, hashFunc, digest)
-	case *ecdsa.PrivateKey:
-		var r, s *big.Int
-		if r, s, err = ecdsa.Sign(rand, priv, digest); err == nil {
-			signature, err = asn1.Marshal(ecdsaSignature{r, s})
-		}
-	default:
-		panic("internal error")
+	signed := tbsCertContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
 	}
 
+	signature, err := key.Sign(rand, signed, hashFunc)
 	if err != nil {
 		return
 	}
@@ -1494,17 +1672,20 @@ func ParseDERCRL(derBytes []byte) (certList *pkix.CertificateList, err error) {
 // CreateCRL returns a DER encoded CRL, signed by this Certificate, that
 // contains the given list of revoked certificates.
 //
-// The only supported key type is RSA (*rsa.PrivateKey for priv).
+// priv must implement crypto.Signer with an RSA, ECDSA or Ed25519 public key.
 func (c *Certificate) CreateCRL(rand io.Reader, priv interface{}, revokedCerts []pkix.RevokedCertificate, now, expiry time.Time) (crlBytes []byte, err error) {
-	rsaPriv, ok := priv.(*rsa.PrivateKey)
+	key, ok := priv.(crypto.Signer)
 	if !ok {
-		return nil, errors.New("x509: non-RSA private keys not supported")
+		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
+	}
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(key.Public())
+	if err != nil {
+		return nil, err
 	}
+
 	tbsCertList := pkix.TBSCertificateList{
-		Version: 2,
-		Signature: pkix.AlgorithmIdentifier{
-			Algorithm: oidSignatureSHA1WithRSA,
-		},
+		Version:             2,
+		Signature:           signatureAlgorithm,
 		Issuer:              c.Subject.ToRDNSequence(),
 		ThisUpdate:          now.UTC(),
 		NextUpdate:          expiry.UTC(),
@@ -1516,20 +1697,262 @@ func (c *Certificate) CreateCRL(rand io.Reader, priv interface{}, revokedCerts [
 		return
 	}
 
-	h := sha1.New()
-	h.Write(tbsCertListContents)
-	digest := h.Sum(nil)
+	signed := tbsCertListContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
 
-	signature, err := rsa.SignPKCS1v15(rand, rsaPriv, crypto.SHA1, digest)
+	signature, err := key.Sign(rand, signed, hashFunc)
 	if err != nil {
 		return
 	}
 
 	return asn1.Marshal(pkix.CertificateList{
-		TBSCertList: tbsCertList,
-		SignatureAlgorithm: pkix.AlgorithmIdentifier{
-			Algorithm: oidSignatureSHA1WithRSA,
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: signatureAlgorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// CertificateRequest represents a PKCS #10, certificate signature request.
+type CertificateRequest struct {
+	Raw                      []byte // Complete ASN.1 DER content (CSR, signature algorithm and signature).
+	RawTBSCertificateRequest []byte // Certificate request info part of raw ASN.1 DER content.
+	RawSubjectPublicKeyInfo  []byte // DER encoded SubjectPublicKeyInfo.
+	RawSubject               []byte // DER encoded Subject.
+
+	Version            int
+	Signature          []byte
+	SignatureAlgorithm SignatureAlgorithm
+
+	PublicKeyAlgorithm PublicKeyAlgorithm
+	PublicKey          interface{}
+
+	Subject pkix.Name
+
+	// Extensions contains the raw extensions requested in the CSR. When
+	// parsing CSRs, this can be used to extract extensions that are not
+	// parsed by this package. When marshaling CSRs, the Extensions field
+	// is ignored, see ExtraExtensions.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// marshaled CSR. Values override any extensions that would otherwise
+	// be produced based on the other fields. The ExtraExtensions field is
+	// not populated when parsing CSRs, see Extensions.
+	ExtraExtensions []pkix.Extension
+
+	// Subject Alternate Name values.
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+}
+
+// These structures reflect the ASN.1 structure of X.509 certificate
+// signature requests (see RFC 2986):
+
+type tbsCertificateRequest struct {
+	Raw        asn1.RawContent
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  publicKeyInfo
+	Attributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+type certificateRequest struct {
+	Raw                asn1.RawContent
+	TBSCSR             tbsCertificateRequest
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// pkcs10Attribute reflects the Attribute structure of RFC 2986, 4.1.
+type pkcs10Attribute struct {
+	Id     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// oidExtensionRequest is a PKCS#9 OBJECT IDENTIFIER that indicates requested
+// extensions in a CSR.
+var oidExtensionRequest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+
+// CreateCertificateRequest creates a new certificate request based on a
+// template. The following members of template are used: Subject, DNSNames,
+// EmailAddresses, IPAddresses and ExtraExtensions. The requested extensions
+// are carried in a PKCS #9 extensionRequest attribute.
+//
+// priv is the private key to sign the CSR with, and the corresponding public
+// key will be included in the CSR. It must implement crypto.Signer with an
+// RSA, ECDSA or Ed25519 public key, such as *rsa.PrivateKey,
+// *ecdsa.PrivateKey or ed25519.PrivateKey.
+//
+// The returned slice is the certificate request in DER encoding.
+func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv interface{}) (csr []byte, err error) {
+	key, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("x509: certificate private key does not implement crypto.Signer")
+	}
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyBytes, publicKeyAlgorithm, err := marshalPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	var extensions []pkix.Extension
+	if (len(template.DNSNames) > 0 || len(template.EmailAddresses) > 0 || len(template.IPAddresses) > 0) &&
+		!oidInExtensions(oidExtensionSubjectAltName, template.ExtraExtensions) {
+		sanBytes, err := marshalSANs(template.DNSNames, template.EmailAddresses, template.IPAddresses)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{
+			Id:    oidExtensionSubjectAltName,
+			Value: sanBytes,
+		})
+	}
+	extensions = append(extensions, template.ExtraExtensions...)
+
+	var attributes []asn1.RawValue
+	if len(extensions) > 0 {
+		extensionsBytes, err := asn1.Marshal(extensions)
+		if err != nil {
+			return nil, err
+		}
+		attributeBytes, err := asn1.Marshal(pkcs10Attribute{
+			Id:     oidExtensionRequest,
+			Values: []asn1.RawValue{{FullBytes: extensionsBytes}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, asn1.RawValue{FullBytes: attributeBytes})
+	}
+
+	asn1Subject := template.RawSubject
+	if len(asn1Subject) == 0 {
+		asn1Subject, err = asn1.Marshal(template.Subject.ToRDNSequence())
+		if err != nil {
+			return
+		}
+	}
+
+	tbsCSR := tbsCertificateRequest{
+		Version: 0, // PKCS #10, RFC 2986
+		Subject: asn1.RawValue{FullBytes: asn1Subject},
+		PublicKey: publicKeyInfo{
+			Algorithm: publicKeyAlgorithm,
+			PublicKey: asn1.BitString{
+				Bytes:     publicKeyBytes,
+				BitLength: len(publicKeyBytes) * 8,
+			},
+		},
+		Attributes: attributes,
+	}
+
+	tbsCSRContents, err := asn1.Marshal(tbsCSR)
+	if err != nil {
+		return
+	}
+	tbsCSR.Raw = tbsCSRContents
+
+	signed := tbsCSRContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
+
+	signature, err := key.Sign(rand, signed, hashFunc)
+	if err != nil {
+		return
+	}
+
+	return asn1.Marshal(certificateRequest{
+		TBSCSR:             tbsCSR,
+		SignatureAlgorithm: signatureAlgorithm,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: len(signature) * 8,
 		},
-		SignatureValue: asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
 	})
 }
+
+// ParseCertificateRequest parses a single certificate request from the
+// given ASN.1 DER data.
+func ParseCertificateRequest(asn1Data []byte) (*CertificateRequest, error) {
+	var csr certificateRequest
+
+	rest, err := asn1.Unmarshal(asn1Data, &csr)
+	if err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, asn1.SyntaxError{Msg: "trailing data"}
+	}
+
+	return parseCertificateRequest(&csr)
+}
+
+func parseCertificateRequest(in *certificateRequest) (*CertificateRequest, error) {
+	out := &CertificateRequest{
+		Raw: in.Raw,
+		RawTBSCertificateRequest: in.TBSCSR.Raw,
+		RawSubjectPublicKeyInfo:  in.TBSCSR.PublicKey.Raw,
+		RawSubject:               in.TBSCSR.Subject.FullBytes,
+
+		Signature:          in.SignatureValue.RightAlign(),
+		SignatureAlgorithm: getSignatureAlgorithmFromOID(in.SignatureAlgorithm.Algorithm),
+
+		PublicKeyAlgorithm: getPublicKeyAlgorithmFromOID(in.TBSCSR.PublicKey.Algorithm.Algorithm),
+
+		Version: in.TBSCSR.Version,
+	}
+
+	var err error
+	out.PublicKey, err = parsePublicKey(out.PublicKeyAlgorithm, &in.TBSCSR.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject pkix.RDNSequence
+	if _, err := asn1.Unmarshal(in.TBSCSR.Subject.FullBytes, &subject); err != nil {
+		return nil, err
+	}
+	out.Subject.FillFromRDNSequence(&subject)
+
+	for _, rawAttr := range in.TBSCSR.Attributes {
+		var attr pkcs10Attribute
+		if _, err := asn1.Unmarshal(rawAttr.FullBytes, &attr); err != nil {
+			return nil, err
+		}
+		if !attr.Id.Equal(oidExtensionRequest) || len(attr.Values) == 0 {
+			continue
+		}
+		var extensions []pkix.Extension
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &extensions); err != nil {
+			return nil, err
+		}
+		out.Extensions = append(out.Extensions, extensions...)
+	}
+
+	for _, e := range out.Extensions {
+		if e.Id.Equal(oidExtensionSubjectAltName) {
+			out.DNSNames, out.EmailAddresses, out.IPAddresses, err = parseSANExtension(e.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// CheckSignature verifies that the signature on c is a valid signature.
+func (c *CertificateRequest) CheckSignature() error {
+	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificateRequest, c.Signature, c.PublicKey)
+}
@@ -6,18 +6,22 @@ package x509
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/dsa"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	_ "crypto/sha256"
+	_ "crypto/sha3"
 	_ "crypto/sha512"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
+	"io"
 	"math/big"
 	"net"
 	"reflect"
@@ -64,6 +68,26 @@ func TestParsePKIXPublicKey(t *testing.T) {
 	}
 }
 
+func TestMarshalPKIXECDSAPublicKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubBytes, err := MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal ECDSA public key: %s", err)
+	}
+	pub, err := ParsePKIXPublicKey(pubBytes)
+	if err != nil {
+		t.Fatalf("failed to parse marshaled ECDSA public key: %s", err)
+	}
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok || ecdsaPub.Curve != elliptic.P384() || ecdsaPub.X.Cmp(priv.X) != 0 || ecdsaPub.Y.Cmp(priv.Y) != 0 {
+		t.Errorf("ECDSA public key did not round-trip: %#v", pub)
+	}
+}
+
 var pemPublicKey = `-----BEGIN PUBLIC KEY-----
 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA3VoPN9PKUjKFLMwOge6+
 wnDi8sbETGIx2FKXGgqtAKpzmem53kRGEQg8WeqRmp12wgp74TGpkEXsGae7RS1k
@@ -287,6 +311,18 @@ var certBytes = "308203223082028ba00302010202106edf0d9499fd4533dd1297fc42a93be13
 	"9048084225c53e8acb7feb6f04d16dc574a2f7a27c7b603c77cd0ece48027f012fb69b37e02a2a" +
 	"36dcd585d6ace53f546f961e05af"
 
+// opaqueSigner hides the concrete type of a private key, like a key kept
+// in a hardware module.
+type opaqueSigner struct {
+	key crypto.Signer
+}
+
+func (s opaqueSigner) Public() crypto.PublicKey { return s.key.Public() }
+
+func (s opaqueSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
 func TestCreateSelfSignedCertificate(t *testing.T) {
 	random := rand.Reader
 
@@ -310,6 +346,8 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		{"RSA/ECDSA", &rsaPriv.PublicKey, ecdsaPriv, false},
 		{"ECDSA/RSA", &ecdsaPriv.PublicKey, rsaPriv, false},
 		{"ECDSA/ECDSA", &ecdsaPriv.PublicKey, ecdsaPriv, true},
+		{"RSA/opaque RSA", &rsaPriv.PublicKey, opaqueSigner{rsaPriv}, true},
+		{"ECDSA/opaque ECDSA", &ecdsaPriv.PublicKey, opaqueSigner{ecdsaPriv}, true},
 	}
 
 	testExtKeyUsage := []ExtKeyUsage{ExtKeyUsageClientAuth, ExtKeyUsageServerAuth}
@@ -345,6 +383,12 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 
 			PolicyIdentifiers:   []asn1.ObjectIdentifier{[]int{1, 2, 3}},
 			PermittedDNSDomains: []string{".example.com", "example.com"},
+			ExcludedDNSDomains:  []string{"bad.example.com"},
+			PermittedIPRanges:   []*net.IPNet{{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}},
+			ExcludedIPRanges:    []*net.IPNet{{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)}},
+
+			PermittedEmailAddresses: []string{"example.com"},
+			ExcludedEmailAddresses:  []string{"root@example.com"},
 
 			CRLDistributionPoints: []string{"http://crl1.example.com/ca1.crl", "http://crl2.example.com/ca1.crl"},
 
@@ -382,6 +426,26 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 			t.Errorf("%s: failed to parse name constraints: %#v", test.name, cert.PermittedDNSDomains)
 		}
 
+		if !reflect.DeepEqual(cert.ExcludedDNSDomains, template.ExcludedDNSDomains) {
+			t.Errorf("%s: excluded DNS domains differ from template. Got %v, want %v", test.name, cert.ExcludedDNSDomains, template.ExcludedDNSDomains)
+		}
+
+		if !reflect.DeepEqual(cert.PermittedIPRanges, template.PermittedIPRanges) {
+			t.Errorf("%s: permitted IP ranges differ from template. Got %v, want %v", test.name, cert.PermittedIPRanges, template.PermittedIPRanges)
+		}
+
+		if !reflect.DeepEqual(cert.ExcludedIPRanges, template.ExcludedIPRanges) {
+			t.Errorf("%s: excluded IP ranges differ from template. Got %v, want %v", test.name, cert.ExcludedIPRanges, template.ExcludedIPRanges)
+		}
+
+		if !reflect.DeepEqual(cert.PermittedEmailAddresses, template.PermittedEmailAddresses) {
+			t.Errorf("%s: permitted email addresses differ from template. Got %v, want %v", test.name, cert.PermittedEmailAddresses, template.PermittedEmailAddresses)
+		}
+
+		if !reflect.DeepEqual(cert.ExcludedEmailAddresses, template.ExcludedEmailAddresses) {
+			t.Errorf("%s: excluded email addresses differ from template. Got %v, want %v", test.name, cert.ExcludedEmailAddresses, template.ExcludedEmailAddresses)
+		}
+
 		if cert.Subject.CommonName != commonName {
 			t.Errorf("%s: subject wasn't correctly copied from the template. Got %s, want %s", test.name, cert.Subject.CommonName, commonName)
 		}
@@ -514,6 +578,65 @@ kBg71w/iEcSY3wUKgHGcJJrObZw7wys91I5kENljqw/Samdr3ka+jBJa
 -----END CERTIFICATE-----
 `
 
+// Self-signed certificate using ECDSA with SHA3-256 & P-256
+var ecdsaSHA3_256CertPem = `
+-----BEGIN CERTIFICATE-----
+MIIBXzCCAQSgAwIBAgIBATALBglghkgBZQMEAwowHjEcMBoGA1UEAwwTRUNEU0Eg
+U0hBMy0yNTYgdGVzdDAeFw0xMzA2MDEwMDAwMDBaFw0yMzA2MDEwMDAwMDBaMB4x
+HDAaBgNVBAMME0VDRFNBIFNIQTMtMjU2IHRlc3QwWTATBgcqhkjOPQIBBggqhkjO
+PQMBBwNCAAR7q3PxzFj0vdbYG5SV8f3CKqjIDV/pacdv/BN/xtwQ+SRbxqoLDuf8
+ffbQx04egPeLSifkjC18ntMpLxVx3J1TozIwMDAPBgNVHRMBAf8EBTADAQH/MB0G
+A1UdDgQWBBQoC0Sc/XsrsyNyamdKEWvVY7lKgjALBglghkgBZQMEAwoDSAAwRQIg
+cp6sJ9Z/8FZxnhKZnMPJ7Pfx+uBNk56rDiu1zULPLlsCIQC6besxgmacUvn0Qjhh
+oMd8gkKhB0nIzQvNoGS0Po/UFg==
+-----END CERTIFICATE-----
+`
+
+// Self-signed certificate using RSA with SHA3-384
+var rsaSHA3_384CertPem = `
+-----BEGIN CERTIFICATE-----
+MIIB4DCCAUmgAwIBAgIBATANBglghkgBZQMEAw8FADAcMRowGAYDVQQDDBFSU0Eg
+U0hBMy0zODQgdGVzdDAeFw0xMzA2MDEwMDAwMDBaFw0yMzA2MDEwMDAwMDBaMBwx
+GjAYBgNVBAMMEVJTQSBTSEEzLTM4NCB0ZXN0MIGfMA0GCSqGSIb3DQEBAQUAA4GN
+ADCBiQKBgQDJhB7mZy/nwoZWRDIkS9MobUvKu05Lib53zj7yVz/6Kjbj/8eZVL0Z
+5yqX5K21J76wMUCa5Are6/owwSmBvOj1A43BjvNsr3YRLfNHHQptkgm62p41F7Kb
+YOgsUSZKcLUfM5Y44Y0KRmIFoYuNowUBzHKHjB04ErLP2Dx+5/mTOwIDAQABozIw
+MDAPBgNVHRMBAf8EBTADAQH/MB0GA1UdDgQWBBRZh44644M77w+sTuwx8uM6OKAs
+eTANBglghkgBZQMEAw8FAAOBgQB46O1giLtTnzVlPaP2HxvDWAE+D7R1hhfPxZdd
+eN5kVsGDldPOiwmLICXvIhoaezGva8MRjgIWttmgCLswq3UiW7XpISAfTkKAsWNe
+DHB9u0f9gpVB5OpqbRicIJV6Lzm6qeZsNTYgo8qqQHhVVXIQu0Sh/KQB7QbMNwpY
+B4bPew==
+-----END CERTIFICATE-----
+`
+
+func TestSHA3(t *testing.T) {
+	tests := []struct {
+		sigAlgo SignatureAlgorithm
+		pemCert string
+	}{
+		{ECDSAWithSHA3_256, ecdsaSHA3_256CertPem},
+		{SHA3_384WithRSA, rsaSHA3_384CertPem},
+	}
+	for i, test := range tests {
+		pemBlock, _ := pem.Decode([]byte(test.pemCert))
+		cert, err := ParseCertificate(pemBlock.Bytes)
+		if err != nil {
+			t.Errorf("%d: failed to parse certificate: %s", i, err)
+			continue
+		}
+		if sa := cert.SignatureAlgorithm; sa != test.sigAlgo {
+			t.Errorf("%d: signature algorithm is %v, want %v", i, sa, test.sigAlgo)
+		}
+		if err = cert.CheckSignatureFrom(cert); err != nil {
+			t.Errorf("%d: certificate verification failed: %s", i, err)
+		}
+		cert.Signature[len(cert.Signature)-1] ^= 1
+		if err = cert.CheckSignatureFrom(cert); err == nil {
+			t.Errorf("%d: corrupted signature accepted", i)
+		}
+	}
+}
+
 var ecdsaTests = []struct {
 	sigAlgo SignatureAlgorithm
 	pemCert string
@@ -638,6 +761,99 @@ YW1wbGUuY29tMAsGCSqGSIb3DQEBBQNBAHKZKoS1wEQOGhgklx4+/yFYQlnqwKXvar/ZecQvJwui
 0seMQnwBhwdBkHfVIU2Fu5VUMRyxlf0ZNaDXcpU581k=
 -----END CERTIFICATE-----`
 
+// Self-signed Ed25519 certificate and its key, generated using:
+//   openssl genpkey -algorithm ed25519 -out ed.key
+//   openssl req -new -x509 -key ed.key -subj /CN=ed25519.example.com
+var ed25519CertPem = `-----BEGIN CERTIFICATE-----
+MIIBUzCCAQWgAwIBAgIUZOY7QW/us3KCd4InvsuWa5nmkR8wBQYDK2VwMB4xHDAa
+BgNVBAMME2VkMjU1MTkuZXhhbXBsZS5jb20wIBcNMjYxMDE4MTQyNTQ2WhgPMjEy
+NjA5MjQxNDI1NDZaMB4xHDAaBgNVBAMME2VkMjU1MTkuZXhhbXBsZS5jb20wKjAF
+BgMrZXADIQDv0EuXc3uAZdHNx7GWXKdpzxXg5WG86Utom5TBT7+QB6NTMFEwHQYD
+VR0OBBYEFPgs8NLuMvuPOmkNIYFVEsw5s2kWMB8GA1UdIwQYMBaAFPgs8NLuMvuP
+OmkNIYFVEsw5s2kWMA8GA1UdEwEB/wQFMAMBAf8wBQYDK2VwA0EApKFWYr1vnT9Z
+J1yvWUd+uHKvo2bXONR+9GlLqcxTNONKaEX6WROY1VvE3krdGwUweEeEBsPAhHvZ
+Atm5fcpgDQ==
+-----END CERTIFICATE-----
+`
+
+var ed25519PKCS8Hex = "302e020100300506032b6570042204203bfdd51068f39718729c3be4cf1e9ddefa8ea7a65a49f50b0cbe6e80d25457ee"
+
+var ed25519PKIXHex = "302a300506032b6570032100efd04b97737b8065d1cdc7b1965ca769cf15e0e561bce94b689b94c14fbf9007"
+
+func TestEd25519(t *testing.T) {
+	pemBlock, _ := pem.Decode([]byte(ed25519CertPem))
+	cert, err := ParseCertificate(pemBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+	if sa := cert.SignatureAlgorithm; sa != PureEd25519 {
+		t.Errorf("signature algorithm is %v, want PureEd25519", sa)
+	}
+	if pka := cert.PublicKeyAlgorithm; pka != Ed25519 {
+		t.Errorf("public key algorithm is %v, want Ed25519", pka)
+	}
+	if err = cert.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("certificate verification failed: %s", err)
+	}
+
+	pkixDER, _ := hex.DecodeString(ed25519PKIXHex)
+	pub, err := ParsePKIXPublicKey(pkixDER)
+	if err != nil {
+		t.Fatalf("failed to parse public key: %s", err)
+	}
+	if !reflect.DeepEqual(pub, cert.PublicKey) {
+		t.Errorf("public key doesn't match the certificate")
+	}
+	if der, err := MarshalPKIXPublicKey(pub); err != nil || !bytes.Equal(der, pkixDER) {
+		t.Errorf("MarshalPKIXPublicKey: got %x, %v, want %x", der, err, pkixDER)
+	}
+
+	pkcs8, _ := hex.DecodeString(ed25519PKCS8Hex)
+	key, err := ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %s", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		t.Fatalf("got key of type %T, want ed25519.PrivateKey", key)
+	}
+	if !reflect.DeepEqual(priv.Public(), cert.PublicKey) {
+		t.Errorf("private key doesn't match the certificate")
+	}
+	if der, err := MarshalPKCS8PrivateKey(priv); err != nil || !bytes.Equal(der, pkcs8) {
+		t.Errorf("MarshalPKCS8PrivateKey: got %x, %v, want %x", der, err, pkcs8)
+	}
+
+	// Issue a certificate with the key and check that it verifies.
+	leafPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Unix(1000, 0),
+		NotAfter:     time.Unix(100000, 0),
+	}
+	der, err := CreateCertificate(rand.Reader, &template, cert, leafPub, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	leaf, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse created certificate: %s", err)
+	}
+	if sa := leaf.SignatureAlgorithm; sa != PureEd25519 {
+		t.Errorf("created certificate has signature algorithm %v, want PureEd25519", sa)
+	}
+	if !reflect.DeepEqual(leaf.PublicKey, leafPub) {
+		t.Errorf("created certificate has the wrong public key")
+	}
+	if err := leaf.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("created certificate failed to verify: %s", err)
+	}
+}
+
 func TestCRLCreation(t *testing.T) {
 	block, _ := pem.Decode([]byte(pemPrivateKey))
 	priv, _ := ParsePKCS1PrivateKey(block.Bytes)
@@ -658,14 +874,25 @@ func TestCRLCreation(t *testing.T) {
 		},
 	}
 
-	crlBytes, err := cert.CreateCRL(rand.Reader, priv, revokedCerts, now, expiry)
-	if err != nil {
-		t.Errorf("error creating CRL: %s", err)
+	for _, key := range []interface{}{priv, opaqueSigner{priv}} {
+		crlBytes, err := cert.CreateCRL(rand.Reader, key, revokedCerts, now, expiry)
+		if err != nil {
+			t.Errorf("%T: error creating CRL: %s", key, err)
+			continue
+		}
+
+		crl, err := ParseDERCRL(crlBytes)
+		if err != nil {
+			t.Errorf("%T: error reparsing CRL: %s", key, err)
+			continue
+		}
+		if err := cert.CheckCRLSignature(crl); err != nil {
+			t.Errorf("%T: bad CRL signature: %s", key, err)
+		}
 	}
 
-	_, err = ParseDERCRL(crlBytes)
-	if err != nil {
-		t.Errorf("error reparsing CRL: %s", err)
+	if _, err := cert.CreateCRL(rand.Reader, priv.PublicKey, revokedCerts, now, expiry); err == nil {
+		t.Errorf("created a CRL with a key that can't sign")
 	}
 }
 
@@ -718,6 +945,141 @@ func TestParsePEMCRL(t *testing.T) {
 	// Can't check the signature here without a package cycle.
 }
 
+func TestCreateCertificateRequest(t *testing.T) {
+	random := rand.Reader
+
+	block, _ := pem.Decode([]byte(pemPrivateKey))
+	rsaPriv, err := ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse private key: %s", err)
+	}
+
+	ecdsa256Priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %s", err)
+	}
+
+	ecdsa384Priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		priv    interface{}
+		sigAlgo SignatureAlgorithm
+	}{
+		{"RSA", rsaPriv, SHA1WithRSA},
+		{"ECDSA-256", ecdsa256Priv, ECDSAWithSHA256},
+		{"ECDSA-384", ecdsa384Priv, ECDSAWithSHA384},
+		{"opaque ECDSA-256", opaqueSigner{ecdsa256Priv}, ECDSAWithSHA256},
+	}
+
+	extraExtensionData := []byte("extra extension")
+
+	for _, test := range tests {
+		template := CertificateRequest{
+			Subject: pkix.Name{
+				CommonName:   "test.example.com",
+				Organization: []string{"Σ Acme Co"},
+			},
+			DNSNames:       []string{"test.example.com"},
+			EmailAddresses: []string{"gopher@golang.org"},
+			IPAddresses:    []net.IP{net.IPv4(127, 0, 0, 1).To4(), net.ParseIP("2001:4860:0:2001::68")},
+			ExtraExtensions: []pkix.Extension{
+				{
+					Id:    []int{1, 2, 3, 4},
+					Value: extraExtensionData,
+				},
+			},
+		}
+		derBytes, err := CreateCertificateRequest(random, &template, test.priv)
+		if err != nil {
+			t.Errorf("%s: failed to create certificate request: %s", test.name, err)
+			continue
+		}
+
+		out, err := ParseCertificateRequest(derBytes)
+		if err != nil {
+			t.Errorf("%s: failed to parse certificate request: %s", test.name, err)
+			continue
+		}
+
+		if out.SignatureAlgorithm != test.sigAlgo {
+			t.Errorf("%s: signature algorithm = %v, want %v", test.name, out.SignatureAlgorithm, test.sigAlgo)
+		}
+		if err := out.CheckSignature(); err != nil {
+			t.Errorf("%s: failed to check certificate request signature: %s", test.name, err)
+		}
+
+		if out.Subject.CommonName != template.Subject.CommonName {
+			t.Errorf("%s: output subject common name and template subject common name don't match", test.name)
+		} else if len(out.Subject.Organization) != len(template.Subject.Organization) {
+			t.Errorf("%s: output subject organisation and template subject organisation don't match", test.name)
+		}
+		if !reflect.DeepEqual(out.DNSNames, template.DNSNames) {
+			t.Errorf("%s: DNS names differ from template. Got %v, want %v", test.name, out.DNSNames, template.DNSNames)
+		}
+		if !reflect.DeepEqual(out.EmailAddresses, template.EmailAddresses) {
+			t.Errorf("%s: emails differ from template. Got %v, want %v", test.name, out.EmailAddresses, template.EmailAddresses)
+		}
+		if !reflect.DeepEqual(out.IPAddresses, template.IPAddresses) {
+			t.Errorf("%s: IPs differ from template. Got %v, want %v", test.name, out.IPAddresses, template.IPAddresses)
+		}
+
+		found := false
+		for _, ext := range out.Extensions {
+			if ext.Id.Equal(template.ExtraExtensions[0].Id) {
+				found = bytes.Equal(ext.Value, extraExtensionData)
+			}
+		}
+		if !found {
+			t.Errorf("%s: extra extension not present in certificate request", test.name)
+		}
+
+		out.RawTBSCertificateRequest[len(out.RawTBSCertificateRequest)-1] ^= 1
+		if err := out.CheckSignature(); err == nil {
+			t.Errorf("%s: tampered certificate request passed signature check", test.name)
+		}
+	}
+}
+
+func TestParseCertificateRequest(t *testing.T) {
+	derBytes, _ := base64.StdEncoding.DecodeString(csrBase64)
+	csr, err := ParseCertificateRequest(derBytes)
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		t.Errorf("CSR signature check failed: %s", err)
+	}
+	if csr.SignatureAlgorithm != SHA256WithRSA {
+		t.Errorf("signature algorithm = %v, want %v", csr.SignatureAlgorithm, SHA256WithRSA)
+	}
+	if _, ok := csr.PublicKey.(*rsa.PublicKey); !ok {
+		t.Errorf("public key is %T, want *rsa.PublicKey", csr.PublicKey)
+	}
+	if csr.Subject.CommonName != "test.example.com" || len(csr.Subject.Locality) != 1 || csr.Subject.Locality[0] != "Mountain View" {
+		t.Errorf("unexpected subject: %+v", csr.Subject)
+	}
+	if want := []string{"test.example.com", "www.example.com"}; !reflect.DeepEqual(csr.DNSNames, want) {
+		t.Errorf("DNS names = %v, want %v", csr.DNSNames, want)
+	}
+	if want := []string{"gopher@golang.org"}; !reflect.DeepEqual(csr.EmailAddresses, want) {
+		t.Errorf("email addresses = %v, want %v", csr.EmailAddresses, want)
+	}
+	if len(csr.IPAddresses) != 1 || !csr.IPAddresses[0].Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("IP addresses = %v, want [127.0.0.1]", csr.IPAddresses)
+	}
+}
+
+// csrBase64 is a CSR with a subjectAltName extension request, generated by
+//   openssl req -new -newkey rsa:1024 -nodes -sha256 \
+//     -subj "/C=US/ST=California/L=Mountain View/O=Acme Co/CN=test.example.com" \
+//     -addext "subjectAltName=DNS:test.example.com,DNS:www.example.com,email:gopher@golang.org,IP:127.0.0.1" \
+//     -outform DER
+const csrBase64 = "MIIB/zCCAWgCAQAwZzELMAkGA1UEBhMCVVMxEzARBgNVBAgMCkNhbGlmb3JuaWExFjAUBgNVBAcMDU1vdW50YWluIFZpZXcxEDAOBgNVBAoMB0FjbWUgQ28xGTAXBgNVBAMMEHRlc3QuZXhhbXBsZS5jb20wgZ8wDQYJKoZIhvcNAQEBBQADgY0AMIGJAoGBAL+CAZNLNKGlMqughgSsTYLCM1fID1NqgCFUOIf0v2a+Lakaj7rC8IDpby3SARwsiJNP+YR22SjWd6NV8eE+MBLYNj8oOYUn8iZwhaRhV//4ChcsVR77ojnW129Ng3kOYvQmpEZOC9oUyGv+z5s+1QqcOCCu0m50qteDZkLGxDEFAgMBAAGgWDBWBgkqhkiG9w0BCQ4xSTBHMEUGA1UdEQQ+MDyCEHRlc3QuZXhhbXBsZS5jb22CD3d3dy5leGFtcGxlLmNvbYERZ29waGVyQGdvbGFuZy5vcmeHBH8AAAEwDQYJKoZIhvcNAQELBQADgYEAqwcj14FO66KgqE1JW32Jb+WctL+U6xEOKnpaWd02Hq/u4VU3SbFe2C0jzyoN7auRyeRGN0XZ64GW89GoOdJhXOT0wEVWjBP10HlZGhmU9Q2EOdU3m1d58KBNUAcdWw/6N0srQDxCvRQAm4jvWeryeyNUVFXv5EJgFvWTCn/s07g="
+
 const derCRLBase64 = "MIINqzCCDJMCAQEwDQYJKoZIhvcNAQEFBQAwVjEZMBcGA1UEAxMQUEtJIEZJTk1FQ0NBTklDQTEVMBMGA1UEChMMRklOTUVDQ0FOSUNBMRUwEwYDVQQLEwxGSU5NRUNDQU5JQ0ExCzAJBgNVBAYTAklUFw0xMTA1MDQxNjU3NDJaFw0xMTA1MDQyMDU3NDJaMIIMBzAhAg4Ze1od49Lt1qIXBydAzhcNMDkwNzE2MDg0MzIyWjAAMCECDl0HSL9bcZ1Ci/UHJ0DPFw0wOTA3MTYwODQzMTNaMAAwIQIOESB9tVAmX3cY7QcnQNAXDTA5MDcxNjA4NDUyMlowADAhAg4S1tGAQ3mHt8uVBydA1RcNMDkwODA0MTUyNTIyWjAAMCECDlQ249Y7vtC25ScHJ0DWFw0wOTA4MDQxNTI1MzdaMAAwIQIOISMop3NkA4PfYwcnQNkXDTA5MDgwNDExMDAzNFowADAhAg56/BMoS29KEShTBydA2hcNMDkwODA0MTEwMTAzWjAAMCECDnBp/22HPH5CSWoHJ0DbFw0wOTA4MDQxMDU0NDlaMAAwIQIOV9IP+8CD8bK+XAcnQNwXDTA5MDgwNDEwNTcxN1owADAhAg4v5aRz0IxWqYiXBydA3RcNMDkwODA0MTA1NzQ1WjAAMCECDlOU34VzvZAybQwHJ0DeFw0wOTA4MDQxMDU4MjFaMAAwIAINO4CD9lluIxcwBydBAxcNMDkwNzIyMTUzMTU5WjAAMCECDgOllfO8Y1QA7/wHJ0ExFw0wOTA3MjQxMTQxNDNaMAAwIQIOJBX7jbiCdRdyjgcnQUQXDTA5MDkxNjA5MzAwOFowADAhAg5iYSAgmDrlH/RZBydBRRcNMDkwOTE2MDkzMDE3WjAAMCECDmu6k6srP3jcMaQHJ0FRFw0wOTA4MDQxMDU2NDBaMAAwIQIOX8aHlO0V+WVH4QcnQVMXDTA5MDgwNDEwNTcyOVowADAhAg5flK2rg3NnsRgDBydBzhcNMTEwMjAxMTUzMzQ2WjAAMCECDg35yJDL1jOPTgoHJ0HPFw0xMTAyMDExNTM0MjZaMAAwIQIOMyFJ6+e9iiGVBQcnQdAXDTA5MDkxODEzMjAwNVowADAhAg5Emb/Oykucmn8fBydB1xcNMDkwOTIxMTAxMDQ3WjAAMCECDjQKCncV+MnUavMHJ0HaFw0wOTA5MjIwODE1MjZaMAAwIQIOaxiFUt3dpd+tPwcnQfQXDTEwMDYxODA4NDI1MVowADAhAg5G7P8nO0tkrMt7BydB9RcNMTAwNjE4MDg0MjMwWjAAMCECDmTCC3SXhmDRst4HJ0H2Fw0wOTA5MjgxMjA3MjBaMAAwIQIOHoGhUr/pRwzTKgcnQfcXDTA5MDkyODEyMDcyNFowADAhAg50wrcrCiw8mQmPBydCBBcNMTAwMjE2MTMwMTA2WjAAMCECDifWmkvwyhEqwEcHJ0IFFw0xMDAyMTYxMzAxMjBaMAAwIQIOfgPmlW9fg+osNgcnQhwXDTEwMDQxMzA5NTIwMFowADAhAg4YHAGuA6LgCk7tBydCHRcNMTAwNDEzMDk1MTM4WjAAMCECDi1zH1bxkNJhokAHJ0IsFw0xMDA0MTMwOTU5MzBaMAAwIQIOMipNccsb/wo2fwcnQi0XDTEwMDQxMzA5NTkwMFowADAhAg46lCmvPl4GpP6ABydCShcNMTAwMTE5MDk1MjE3WjAAMCECDjaTcaj+wBpcGAsHJ0JLFw0xMDAxMTkwOTUyMzRaMAAwIQIOOMC13EOrBuxIOQcnQloXDTEwMDIwMTA5NDcwNVowADAhAg5KmZl+krz4RsmrBydCWxcNMTAwMjAxMDk0NjQwWjAAMCECDmLG3zQJ/fzdSsUHJ0JiFw0xMDAzMDEwOTUxNDBaMAAwIQIOP39ksgHdojf4owcnQmMXDTEwMDMwMTA5NTExN1owADAhAg4LDQzvWNRlD6v9BydCZBcNMTAwMzAxMDk0NjIyWjAAMCECDkmNfeclaFhIaaUHJ0JlFw0xMDAzMDEwOTQ2MDVaMAAwIQIOT/qWWfpH/m8NTwcnQpQXDTEwMDUxMTA5MTgyMVowADAhAg5m/ksYxvCEgJSvBydClRcNMTAwNTExMDkxODAxWjAAMCECDgvf3Ohq6JOPU9AHJ0KWFw0xMDA1MTEwOTIxMjNaMAAwIQIOKSPas10z4jNVIQcnQpcXDTEwMDUxMTA5MjEwMlowADAhAg4mCWmhoZ3lyKCDBydCohcNMTEwNDI4MTEwMjI1WjAAMCECDkeiyRsBMK0Gvr4HJ0KjFw0xMTA0MjgxMTAyMDdaMAAwIQIOa09b/nH2+55SSwcnQq4XDTExMDQwMTA4Mjk0NlowADAhAg5O7M7iq7gGplr1BydCrxcNMTEwNDAxMDgzMDE3WjAAMCECDjlT6mJxUjTvyogHJ0K1Fw0xMTAxMjcxNTQ4NTJaMAAwIQIODS/l4UUFLe21NAcnQrYXDTExMDEyNzE1NDgyOFowADAhAg5lPRA0XdOUF6lSBydDHhcNMTEwMTI4MTQzNTA1WjAAMCECDixKX4fFGGpENwgHJ0MfFw0xMTAxMjgxNDM1MzBaMAAwIQIORNBkqsPnpKTtbAcnQ08XDTEwMDkwOTA4NDg0MlowADAhAg5QL+EMM3lohedEBydDUBcNMTAwOTA5MDg0ODE5WjAAMCECDlhDnHK+HiTRAXcHJ0NUFw0xMDEwMTkxNjIxNDBaMAAwIQIOdBFqAzq/INz53gcnQ1UXDTEwMTAxOTE2MjA0NFowADAhAg4OjR7s8MgKles1BydDWhcNMTEwMTI3MTY1MzM2WjAAMCECDmfR/elHee+d0SoHJ0NbFw0xMTAxMjcxNjUzNTZaMAAwIQIOBTKv2ui+KFMI+wcnQ5YXDTEwMDkxNTEwMjE1N1owADAhAg49F3c/GSah+oRUBydDmxcNMTEwMTI3MTczMjMzWjAAMCECDggv4I61WwpKFMMHJ0OcFw0xMTAxMjcxNzMyNTVaMAAwIQIOXx/Y8sEvwS10LAcnQ6UXDTExMDEyODExMjkzN1owADAhAg5LSLbnVrSKaw/9BydDphcNMTEwMTI4MTEyOTIwWjAAMCECDmFFoCuhKUeACQQHJ0PfFw0xMTAxMTExMDE3MzdaMAAwIQIOQTDdFh2fSPF6AAcnQ+AXDTExMDExMTEwMTcxMFowADAhAg5B8AOXX61FpvbbBydD5RcNMTAxMDA2MTAxNDM2WjAAMCECDh41P2Gmi7PkwI4HJ0PmFw0xMDEwMDYxMDE2MjVaMAAwIQIOWUHGLQCd+Ale9gcnQ/0XDTExMDUwMjA3NTYxMFowADAhAg5Z2c9AYkikmgWOBydD/hcNMTEwNTAyMDc1NjM0WjAAMCECDmf/UD+/h8nf+74HJ0QVFw0xMTA0MTUwNzI4MzNaMAAwIQIOICvj4epy3MrqfwcnRBYXDTExMDQxNTA3Mjg1NlowADAhAg4bouRMfOYqgv4xBydEHxcNMTEwMzA4MTYyNDI1WjAAMCECDhebWHGoKiTp7pEHJ0QgFw0xMTAzMDgxNjI0NDhaMAAwIQIOX+qnxxAqJ8LtawcnRDcXDTExMDEzMTE1MTIyOFowADAhAg4j0fICqZ+wkOdqBydEOBcNMTEwMTMxMTUxMTQxWjAAMCECDhmXjsV4SUpWtAMHJ0RLFw0xMTAxMjgxMTI0MTJaMAAwIQIODno/w+zG43kkTwcnREwXDTExMDEyODExMjM1MlowADAhAg4b1gc88767Fr+LBydETxcNMTEwMTI4MTEwMjA4WjAAMCECDn+M3Pa1w2nyFeUHJ0RQFw0xMTAxMjgxMDU4NDVaMAAwIQIOaduoyIH61tqybAcnRJUXDTEwMTIxNTA5NDMyMlowADAhAg4nLqQPkyi3ESAKBydElhcNMTAxMjE1MDk0MzM2WjAAMCECDi504NIMH8578gQHJ0SbFw0xMTAyMTQxNDA1NDFaMAAwIQIOGuaM8PDaC5u1egcnRJwXDTExMDIxNDE0MDYwNFowADAhAg4ehYq/BXGnB5PWBydEnxcNMTEwMjA0MDgwOTUxWjAAMCECDkSD4eS4FxW5H20HJ0SgFw0xMTAyMDQwODA5MjVaMAAwIQIOOCcb6ilYObt1egcnRKEXDTExMDEyNjEwNDEyOVowADAhAg58tISWCCwFnKGnBydEohcNMTEwMjA0MDgxMzQyWjAAMCECDn5rjtabY/L/WL0HJ0TJFw0xMTAyMDQxMTAzNDFaMAAwDQYJKoZIhvcNAQEFBQADggEBAGnF2Gs0+LNiYCW1Ipm83OXQYP/bd5tFFRzyz3iepFqNfYs4D68/QihjFoRHQoXEB0OEe1tvaVnnPGnEOpi6krwekquMxo4H88B5SlyiFIqemCOIss0SxlCFs69LmfRYvPPvPEhoXtQ3ZThe0UvKG83GOklhvGl6OaiRf4Mt+m8zOT4Wox/j6aOBK6cw6qKCdmD+Yj1rrNqFGg1CnSWMoD6S6mwNgkzwdBUJZ22BwrzAAo4RHa2Uy3ef1FjwD0XtU5N3uDSxGGBEDvOe5z82rps3E22FpAA8eYl8kaXtmWqyvYU0epp4brGuTxCuBMCAsxt/OjIjeNNQbBGkwxgfYA0="
 
 const pemCRLBase64 = "LS0tLS1CRUdJTiBYNTA5IENSTC0tLS0tDQpNSUlCOWpDQ0FWOENBUUV3RFFZSktvWklodmNOQVFFRkJRQXdiREVhTUJnR0ExVUVDaE1SVWxOQklGTmxZM1Z5DQphWFI1SUVsdVl5NHhIakFjQmdOVkJBTVRGVkpUUVNCUWRXSnNhV01nVW05dmRDQkRRU0IyTVRFdU1Dd0dDU3FHDQpTSWIzRFFFSkFSWWZjbk5oYTJWdmJuSnZiM1J6YVdkdVFISnpZWE5sWTNWeWFYUjVMbU52YlJjTk1URXdNakl6DQpNVGt5T0RNd1doY05NVEV3T0RJeU1Ua3lPRE13V2pDQmpEQktBaEVBckRxb2g5RkhKSFhUN09QZ3V1bjQrQmNODQpNRGt4TVRBeU1UUXlOekE1V2pBbU1Bb0dBMVVkRlFRRENnRUpNQmdHQTFVZEdBUVJHQTh5TURBNU1URXdNakUwDQpNalExTlZvd1BnSVJBTEd6blowOTVQQjVhQU9MUGc1N2ZNTVhEVEF5TVRBeU16RTBOVEF4TkZvd0dqQVlCZ05WDQpIUmdFRVJnUE1qQXdNakV3TWpNeE5EVXdNVFJhb0RBd0xqQWZCZ05WSFNNRUdEQVdnQlQxVERGNlVRTS9MTmVMDQpsNWx2cUhHUXEzZzltekFMQmdOVkhSUUVCQUlDQUlRd0RRWUpLb1pJaHZjTkFRRUZCUUFEZ1lFQUZVNUFzNk16DQpxNVBSc2lmYW9iUVBHaDFhSkx5QytNczVBZ2MwYld5QTNHQWR4dXI1U3BQWmVSV0NCamlQL01FSEJXSkNsQkhQDQpHUmNxNXlJZDNFakRrYUV5eFJhK2k2N0x6dmhJNmMyOUVlNks5cFNZd2ppLzdSVWhtbW5Qclh0VHhsTDBsckxyDQptUVFKNnhoRFJhNUczUUE0Q21VZHNITnZicnpnbUNZcHZWRT0NCi0tLS0tRU5EIFg1MDkgQ1JMLS0tLS0NCg0K"
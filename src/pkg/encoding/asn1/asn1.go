@@ -632,6 +632,13 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 		universalTag = tagGeneralizedTime
 	}
 
+	// A field tagged "set" is a SET or SET OF, which Marshal also
+	// writes with the SET tag, rather than the SEQUENCE tag of the
+	// struct or slice holding it.
+	if params.set {
+		universalTag = tagSet
+	}
+
 	expectedClass := classUniversal
 	expectedTag := universalTag
 
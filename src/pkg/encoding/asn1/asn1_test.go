@@ -353,8 +353,9 @@ var parseFieldParametersTestData []parseFieldParametersTest = []parseFieldParame
 	{"optional,explicit", fieldParameters{optional: true, explicit: true, tag: new(int)}},
 	{"default:42", fieldParameters{defaultValue: newInt64(42)}},
 	{"tag:17", fieldParameters{tag: newInt(17)}},
+	{"generalized", fieldParameters{generalized: true}},
 	{"optional,explicit,default:42,tag:17", fieldParameters{optional: true, explicit: true, defaultValue: newInt64(42), tag: newInt(17)}},
-	{"optional,explicit,default:42,tag:17,rubbish1", fieldParameters{true, true, false, newInt64(42), newInt(17), 0, false, false}},
+	{"optional,explicit,default:42,tag:17,rubbish1", fieldParameters{true, true, false, newInt64(42), newInt(17), 0, false, false, false}},
 	{"set", fieldParameters{set: true}},
 }
 
@@ -389,6 +390,10 @@ type TestBigInt struct {
 	X *big.Int
 }
 
+type TestSet struct {
+	Ints []int `asn1:"set"`
+}
+
 var unmarshalTestData = []struct {
 	in  []byte
 	out interface{}
@@ -737,3 +742,28 @@ var derEncodedPaypalNULCertBytes = []byte{
 	0xc8, 0x64, 0x8c, 0xb5, 0x50, 0x23, 0x82, 0x6f, 0xdb, 0xb8, 0x22, 0x1c, 0x43,
 	0x96, 0x07, 0xa8, 0xbb,
 }
+
+func TestUnmarshalSet(t *testing.T) {
+	in := TestSet{Ints: []int{1, 2, 3}}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x30, 0x0b, 0x31, 0x09, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x03}
+	if !bytes.Equal(data, want) {
+		t.Errorf("Marshal = %x, want %x", data, want)
+	}
+	var out TestSet
+	if _, err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal = %+v, want %+v", out, in)
+	}
+
+	// A SEQUENCE OF is not a SET OF.
+	data[2] = 0x30
+	if _, err := Unmarshal(data, &out); err == nil {
+		t.Error("Unmarshal accepted a SEQUENCE for a set field")
+	}
+}
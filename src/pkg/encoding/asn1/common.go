@@ -76,6 +76,7 @@ type fieldParameters struct {
 	stringType   int    // the string tag to use when marshaling.
 	set          bool   // true iff this should be encoded as a SET
 	omitEmpty    bool   // true iff this should be omitted if empty when marshaling.
+	generalized  bool   // true iff a time should be marshaled as GeneralizedTime.
 
 	// Invariants:
 	//   if explicit is set, tag is non-nil.
@@ -121,6 +122,8 @@ func parseFieldParameters(str string) (ret fieldParameters) {
 			}
 		case part == "omitempty":
 			ret.omitEmpty = true
+		case part == "generalized":
+			ret.generalized = true
 		}
 	}
 	return
@@ -310,6 +310,31 @@ func marshalUTCTime(out *forkableWriter, t time.Time) (err error) {
 		return
 	}
 
+	return marshalTimeCommon(out, t, month, day)
+}
+
+func marshalGeneralizedTime(out *forkableWriter, t time.Time) (err error) {
+	year, month, day := t.Date()
+	if year < 0 || year > 9999 {
+		return StructuralError{"cannot represent time as GeneralizedTime"}
+	}
+
+	err = marshalTwoDigits(out, year/100)
+	if err != nil {
+		return
+	}
+
+	err = marshalTwoDigits(out, year%100)
+	if err != nil {
+		return
+	}
+
+	return marshalTimeCommon(out, t, month, day)
+}
+
+// marshalTimeCommon writes the month, day, time of day and zone, which are
+// shared by the UTCTime and GeneralizedTime encodings.
+func marshalTimeCommon(out *forkableWriter, t time.Time, month time.Month, day int) (err error) {
 	err = marshalTwoDigits(out, int(month))
 	if err != nil {
 		return
@@ -378,9 +403,15 @@ func stripTagAndLength(in []byte) []byte {
 func marshalBody(out *forkableWriter, value reflect.Value, params fieldParameters) (err error) {
 	switch value.Type() {
 	case timeType:
+		if params.generalized {
+			return marshalGeneralizedTime(out, value.Interface().(time.Time))
+		}
 		return marshalUTCTime(out, value.Interface().(time.Time))
 	case bitStringType:
 		return marshalBitString(out, value.Interface().(BitString))
+	case flagType:
+		// A Flag is signalled by the presence of the element alone.
+		return nil
 	case objectIdentifierType:
 		return marshalObjectIdentifier(out, value.Interface().(ObjectIdentifier))
 	case bigIntType:
@@ -523,6 +554,13 @@ func marshalField(out *forkableWriter, v reflect.Value, params fieldParameters)
 		}
 	}
 
+	if params.generalized {
+		if tag != tagUTCTime {
+			return StructuralError{"generalized given to non-time member"}
+		}
+		tag = tagGeneralizedTime
+	}
+
 	if params.set {
 		if tag != tagSequence {
 			return StructuralError{"non sequence tagged as set"}
@@ -568,6 +606,14 @@ func marshalField(out *forkableWriter, v reflect.Value, params fieldParameters)
 }
 
 // Marshal returns the ASN.1 encoding of val.
+//
+// In addition to the struct tags recognised by Unmarshal, the following
+// can be used:
+//
+//	generalized	causes time.Time values to be marshaled as GeneralizedTime instead of UTCTime
+//
+// A Flag is marshaled as an element with no contents. A Flag that may be
+// false should be marked optional, so that it is omitted when false.
 func Marshal(val interface{}) ([]byte, error) {
 	var out bytes.Buffer
 	v := reflect.ValueOf(val)
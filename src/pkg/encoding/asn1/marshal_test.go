@@ -58,6 +58,15 @@ type omitEmptyTest struct {
 	A []string `asn1:"omitempty"`
 }
 
+type generalizedTimeTest struct {
+	A time.Time `asn1:"generalized"`
+}
+
+type implicitFlagTest struct {
+	A Flag `asn1:"tag:0,optional"`
+	B Flag `asn1:"tag:1,optional"`
+}
+
 type testSET []int
 
 var PST = time.FixedZone("PST", -8*60*60)
@@ -83,6 +92,9 @@ var marshalTests = []marshalTest{
 	{time.Unix(0, 0).UTC(), "170d3730303130313030303030305a"},
 	{time.Unix(1258325776, 0).UTC(), "170d3039313131353232353631365a"},
 	{time.Unix(1258325776, 0).In(PST), "17113039313131353134353631362d30383030"},
+	{implicitFlagTest{true, false}, "30028000"},
+	{generalizedTimeTest{time.Unix(1258325776, 0).UTC()}, "3011180f32303039313131353232353631365a"},
+	{generalizedTimeTest{time.Date(2051, 1, 2, 3, 4, 5, 0, time.UTC)}, "3011180f32303531303130323033303430355a"},
 	{BitString{[]byte{0x80}, 1}, "03020780"},
 	{BitString{[]byte{0x81, 0xf0}, 12}, "03030481f0"},
 	{ObjectIdentifier([]int{1, 2, 3, 4}), "06032a0304"},
@@ -146,3 +158,44 @@ func TestInvalidUTF8(t *testing.T) {
 		t.Errorf("invalid UTF8 string was accepted")
 	}
 }
+
+func TestGeneralizedNonTime(t *testing.T) {
+	type test struct {
+		A int `asn1:"generalized"`
+	}
+	if _, err := Marshal(test{1}); err == nil {
+		t.Errorf("generalized integer was accepted")
+	}
+}
+
+func TestGeneralizedTimeRoundTrip(t *testing.T) {
+	in := generalizedTimeTest{time.Date(2051, 1, 2, 3, 4, 5, 0, time.UTC)}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out generalizedTimeTest
+	if _, err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.A.Equal(in.A) {
+		t.Errorf("got %v, want %v", out.A, in.A)
+	}
+}
+
+func TestFlagRoundTrip(t *testing.T) {
+	for _, in := range []implicitFlagTest{{true, false}, {false, true}, {false, false}} {
+		data, err := Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out implicitFlagTest
+		if _, err := Unmarshal(data, &out); err != nil {
+			t.Errorf("%v: %v", in, err)
+			continue
+		}
+		if out != in {
+			t.Errorf("got %v, want %v", out, in)
+		}
+	}
+}
@@ -181,7 +181,7 @@ var pkgDeps = map[string][]string{
 
 	// One of a kind.
 	"archive/tar":         {"L4", "OS", "syscall"},
-	"archive/zip":         {"L4", "OS", "compress/flate"},
+	"archive/zip":         {"L4", "OS", "CRYPTO", "compress/flate", "crypto/rand"},
 	"compress/bzip2":      {"L4"},
 	"compress/flate":      {"L4"},
 	"compress/gzip":       {"L4", "compress/flate"},
@@ -239,7 +239,9 @@ var pkgDeps = map[string][]string{
 	// Basic networking.
 	// Because net must be used by any package that wants to
 	// do networking portably, it must have a small dependency set: just L1+basic os.
-	"net": {"L1", "CGO", "os", "syscall", "time"},
+	"net":            {"L1", "CGO", "net/dnsmessage", "net/netip", "os", "syscall", "time"},
+	"net/dnsmessage": {"L1"},
+	"net/netip":      {"L1"},
 
 	// NET enables use of basic network-related packages.
 	"NET": {
@@ -251,27 +253,37 @@ var pkgDeps = map[string][]string{
 
 	// Uses of networking.
 	"log/syslog":    {"L4", "OS", "net"},
-	"net/mail":      {"L4", "NET", "OS"},
+	"net/mail":      {"L4", "NET", "OS", "mime/multipart", "mime/quotedprintable"},
 	"net/textproto": {"L4", "OS", "net"},
 
 	// Core crypto.
-	"crypto/aes":    {"L3"},
-	"crypto/des":    {"L3"},
-	"crypto/hmac":   {"L3"},
-	"crypto/md5":    {"L3"},
-	"crypto/rc4":    {"L3"},
-	"crypto/sha1":   {"L3"},
-	"crypto/sha256": {"L3"},
-	"crypto/sha512": {"L3"},
+	"crypto/aes":        {"L3"},
+	"crypto/curve25519": {"L3"},
+	"crypto/des":        {"L3"},
+	"crypto/hkdf":       {"L3", "crypto/hmac"},
+	"crypto/hmac":       {"L3"},
+	"crypto/md5":        {"L3"},
+	"crypto/pbkdf2":     {"L3", "crypto/hmac"},
+	"crypto/rc4":        {"L3"},
+	"crypto/scrypt":     {"L3", "crypto/pbkdf2", "crypto/sha256"},
+	"crypto/sha1":       {"L3"},
+	"crypto/sha256":     {"L3"},
+	"crypto/sha3":       {"L3"},
+	"crypto/sha512":     {"L3"},
 
 	"CRYPTO": {
 		"crypto/aes",
+		"crypto/curve25519",
 		"crypto/des",
+		"crypto/hkdf",
 		"crypto/hmac",
 		"crypto/md5",
+		"crypto/pbkdf2",
 		"crypto/rc4",
+		"crypto/scrypt",
 		"crypto/sha1",
 		"crypto/sha256",
+		"crypto/sha3",
 		"crypto/sha512",
 	},
 
@@ -283,7 +295,8 @@ var pkgDeps = map[string][]string{
 	// Mathematical crypto: dependencies on fmt (L4) and math/big.
 	// We could avoid some of the fmt, but math/big imports fmt anyway.
 	"crypto/dsa":      {"L4", "CRYPTO", "math/big"},
-	"crypto/ecdsa":    {"L4", "CRYPTO", "crypto/elliptic", "math/big"},
+	"crypto/ecdsa":    {"L4", "CRYPTO", "crypto/elliptic", "encoding/asn1", "math/big"},
+	"crypto/ed25519":  {"L3", "CRYPTO", "crypto/rand"},
 	"crypto/elliptic": {"L4", "CRYPTO", "math/big"},
 	"crypto/rsa":      {"L4", "CRYPTO", "crypto/rand", "math/big"},
 
@@ -291,6 +304,7 @@ var pkgDeps = map[string][]string{
 		"CRYPTO",
 		"crypto/dsa",
 		"crypto/ecdsa",
+		"crypto/ed25519",
 		"crypto/elliptic",
 		"crypto/rand",
 		"crypto/rsa",
@@ -301,17 +315,20 @@ var pkgDeps = map[string][]string{
 	// SSL/TLS.
 	"crypto/tls": {
 		"L4", "CRYPTO-MATH", "CGO", "OS",
-		"crypto/x509", "encoding/pem", "net", "syscall",
+		"container/list", "crypto/x509", "encoding/pem", "net", "syscall",
 	},
 	"crypto/x509": {
 		"L4", "CRYPTO-MATH", "OS", "CGO",
 		"crypto/x509/pkix", "encoding/pem", "encoding/hex", "net", "syscall",
 	},
 	"crypto/x509/pkix": {"L4", "CRYPTO-MATH"},
+	"crypto/ocsp":      {"L4", "CRYPTO-MATH", "crypto/x509", "crypto/x509/pkix"},
+	"crypto/pkcs12":    {"L4", "CRYPTO-MATH", "crypto/x509", "crypto/x509/pkix"},
 
 	// Simple net+crypto-aware packages.
-	"mime/multipart": {"L4", "OS", "mime", "crypto/rand", "net/textproto"},
-	"net/smtp":       {"L4", "CRYPTO", "NET", "crypto/tls"},
+	"mime/multipart":       {"L4", "OS", "mime", "crypto/rand", "mime/quotedprintable", "net/textproto"},
+	"mime/quotedprintable": {"L4"},
+	"net/smtp":             {"L4", "CRYPTO", "NET", "crypto/rand", "crypto/tls", "io/ioutil"},
 
 	// HTTP, kingpin of dependencies.
 	"net/http": {
@@ -327,7 +344,7 @@ var pkgDeps = map[string][]string{
 	"net/http/httputil": {"L4", "NET", "OS", "net/http"},
 	"net/http/pprof":    {"L4", "OS", "html/template", "net/http", "runtime/pprof"},
 	"net/rpc":           {"L4", "NET", "encoding/gob", "net/http", "text/template"},
-	"net/rpc/jsonrpc":   {"L4", "NET", "encoding/json", "net/rpc"},
+	"net/rpc/jsonrpc":   {"L4", "NET", "encoding/json", "net/http", "net/rpc"},
 }
 
 // isMacro reports whether p is a package dependency macro
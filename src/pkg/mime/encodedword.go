@@ -0,0 +1,323 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// A WordEncoder is an RFC 2047 encoded-word encoder.
+type WordEncoder byte
+
+const (
+	// BEncoding represents Base64 encoding scheme as defined by RFC 2045.
+	BEncoding = WordEncoder('b')
+	// QEncoding represents the Q-encoding scheme as defined by RFC 2047.
+	QEncoding = WordEncoder('q')
+)
+
+// maxEncodedWordLen is the maximum length of an encoded-word, including
+// its delimiters, as set by RFC 2047 section 2.
+const maxEncodedWordLen = 75
+
+// Encode returns the encoded-word form of s. If s is ASCII without
+// special characters, it is returned unchanged. The provided charset is
+// the IANA charset name of s. It is case insensitive.
+//
+// Long input is split into several encoded-words separated by spaces,
+// each no longer than 75 characters. A UTF-8 character is never split
+// between two encoded-words.
+func (e WordEncoder) Encode(charset, s string) string {
+	if !needsEncoding(s) {
+		return s
+	}
+	return e.encodeWord(charset, s)
+}
+
+func needsEncoding(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; (b < ' ' || b > '~') && b != '\t' {
+			return true
+		}
+	}
+	// Text that looks like an encoded-word must be encoded itself to
+	// survive decoding unchanged.
+	return strings.Contains(s, "=?")
+}
+
+// encodeWord encodes s into one or more encoded-words.
+func (e WordEncoder) encodeWord(charset, s string) string {
+	var buf bytes.Buffer
+	prefix := "=?" + charset + "?" + string(e) + "?"
+	// room is the length of encoded text that fits in each word.
+	room := maxEncodedWordLen - len(prefix) - len("?=")
+	isUTF8 := strings.EqualFold(charset, "utf-8")
+
+	start := 0 // start of the text of the current word
+	n := 0     // encoded length of the current word
+	for i := 0; i < len(s); {
+		// Take a whole character if the charset is UTF-8, else a byte.
+		size := 1
+		if isUTF8 {
+			_, size = utf8.DecodeRuneInString(s[i:])
+		}
+		var next int
+		if e == BEncoding {
+			next = base64.StdEncoding.EncodedLen(i + size - start)
+		} else {
+			next = n + qEncodedLen(s[i:i+size])
+		}
+		if next > room && i > start {
+			e.writeWord(&buf, prefix, s[start:i])
+			buf.WriteByte(' ')
+			start, n = i, 0
+			continue
+		}
+		n = next
+		i += size
+	}
+	e.writeWord(&buf, prefix, s[start:])
+	return buf.String()
+}
+
+// writeWord writes s to buf as a single encoded-word.
+func (e WordEncoder) writeWord(buf *bytes.Buffer, prefix, s string) {
+	buf.WriteString(prefix)
+	if e == BEncoding {
+		w := base64.NewEncoder(base64.StdEncoding, buf)
+		io.WriteString(w, s)
+		w.Close()
+	} else {
+		writeQString(buf, s)
+	}
+	buf.WriteString("?=")
+}
+
+// qEncodedLen returns the length of the Q-encoding of s.
+func qEncodedLen(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if isQLiteral(s[i]) || s[i] == ' ' {
+			n++
+		} else {
+			n += 3
+		}
+	}
+	return n
+}
+
+// isQLiteral reports whether b may appear unencoded in Q-encoded text.
+func isQLiteral(b byte) bool {
+	return b > ' ' && b <= '~' && b != '=' && b != '?' && b != '_'
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// writeQString writes the Q-encoding of s to buf.
+func writeQString(buf *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == ' ':
+			buf.WriteByte('_')
+		case isQLiteral(b):
+			buf.WriteByte(b)
+		default:
+			buf.WriteByte('=')
+			buf.WriteByte(upperhex[b>>4])
+			buf.WriteByte(upperhex[b&0x0f])
+		}
+	}
+}
+
+// A WordDecoder decodes MIME headers containing RFC 2047 encoded-words.
+type WordDecoder struct {
+	// CharsetReader, if non-nil, defines a function to generate
+	// charset-conversion readers, converting from the provided
+	// charset into UTF-8.
+	// Charsets are always lower-case. utf-8, iso-8859-1 and us-ascii
+	// charsets are handled by default.
+	// One of the CharsetReader's result values must be non-nil.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+}
+
+var errInvalidWord = errors.New("mime: invalid RFC 2047 encoded-word")
+
+// Decode decodes an RFC 2047 encoded-word.
+func (d *WordDecoder) Decode(word string) (string, error) {
+	charset, content, err := parseWord(word)
+	if err != nil {
+		return "", err
+	}
+	return d.convert(charset, content)
+}
+
+// parseWord splits an encoded-word into its charset, in lower case,
+// and its decoded content.
+func parseWord(word string) (charset string, content []byte, err error) {
+	fields := strings.Split(word, "?")
+	if len(fields) != 5 || fields[0] != "=" || fields[4] != "=" || len(fields[2]) != 1 {
+		return "", nil, errInvalidWord
+	}
+	// An RFC 2231 language suffix, as in "utf-8*en", is ignored.
+	charset = strings.ToLower(fields[1])
+	if i := strings.Index(charset, "*"); i >= 0 {
+		charset = charset[:i]
+	}
+	if charset == "" {
+		return "", nil, errInvalidWord
+	}
+
+	switch fields[2] {
+	case "b", "B":
+		content, err = base64.StdEncoding.DecodeString(fields[3])
+	case "q", "Q":
+		content, err = decodeQString(fields[3])
+	default:
+		return "", nil, errInvalidWord
+	}
+	if err != nil {
+		return "", nil, errInvalidWord
+	}
+	return charset, content, nil
+}
+
+// convert converts content from charset to UTF-8.
+func (d *WordDecoder) convert(charset string, content []byte) (string, error) {
+	switch charset {
+	case "utf-8", "us-ascii":
+		return string(content), nil
+	case "iso-8859-1":
+		b := make([]rune, len(content))
+		for i, c := range content {
+			b[i] = rune(c)
+		}
+		return string(b), nil
+	}
+	if d.CharsetReader == nil {
+		return "", fmt.Errorf("mime: unhandled charset %q", charset)
+	}
+	r, err := d.CharsetReader(charset, bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeQString decodes the Q-encoded text s.
+func decodeQString(s string) ([]byte, error) {
+	dec := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '_':
+			dec = append(dec, ' ')
+		case c == '=':
+			if i+2 >= len(s) {
+				return nil, errInvalidWord
+			}
+			b, err := readHexByte(s[i+1], s[i+2])
+			if err != nil {
+				return nil, err
+			}
+			dec = append(dec, b)
+			i += 2
+		case (c <= '~' && c >= ' ') || c == '\n' || c == '\r' || c == '\t':
+			dec = append(dec, c)
+		default:
+			return nil, errInvalidWord
+		}
+	}
+	return dec, nil
+}
+
+// readHexByte returns the byte from its quoted-printable representation.
+func readHexByte(a, b byte) (byte, error) {
+	hb, err := fromHex(a)
+	if err != nil {
+		return 0, err
+	}
+	lb, err := fromHex(b)
+	if err != nil {
+		return 0, err
+	}
+	return hb<<4 | lb, nil
+}
+
+func fromHex(b byte) (byte, error) {
+	switch {
+	case b >= '0' && b <= '9':
+		return b - '0', nil
+	case b >= 'A' && b <= 'F':
+		return b - 'A' + 10, nil
+	// Accept badly encoded bytes.
+	case b >= 'a' && b <= 'f':
+		return b - 'a' + 10, nil
+	}
+	return 0, fmt.Errorf("mime: invalid hex byte %#02x", b)
+}
+
+// DecodeHeader decodes all the encoded-words of the given string.
+// Malformed encoded-words are left unchanged. DecodeHeader returns an
+// error only if the charset of a well-formed encoded-word cannot be
+// converted to UTF-8.
+func (d *WordDecoder) DecodeHeader(header string) (string, error) {
+	var buf bytes.Buffer
+	betweenWords := false // whether the last text written was a decoded word
+	for {
+		start := strings.Index(header, "=?")
+		if start < 0 {
+			break
+		}
+		// The word ends at the first "?=" after its charset, encoding
+		// and the '?' that starts its encoded text.
+		end := -1
+		if i := strings.Index(header[start+2:], "?"); i >= 0 {
+			cur := start + 2 + i + 1
+			if j := strings.Index(header[cur:], "?"); j >= 0 {
+				cur += j + 1
+				if k := strings.Index(header[cur:], "?="); k >= 0 {
+					end = cur + k + len("?=")
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+
+		charset, text, err := parseWord(header[start:end])
+		if err != nil {
+			// Keep the malformed word as it is and carry on after it.
+			buf.WriteString(header[:start+2])
+			header = header[start+2:]
+			betweenWords = false
+			continue
+		}
+		content, err := d.convert(charset, text)
+		if err != nil {
+			return "", err
+		}
+
+		// Whitespace between two adjacent encoded-words is dropped,
+		// as required by RFC 2047 section 6.2.
+		if !betweenWords || strings.TrimLeft(header[:start], " \t\r\n") != "" {
+			buf.WriteString(header[:start])
+		}
+		buf.WriteString(content)
+		header = header[end:]
+		betweenWords = true
+	}
+	buf.WriteString(header)
+	return buf.String(), nil
+}
@@ -2,13 +2,9 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// The file define a quoted-printable decoder, as specified in RFC 2045.
-// Deviations:
-// 1. in addition to "=\r\n", "=\n" is also treated as soft line break.
-// 2. it will pass through a '\r' or '\n' not preceded by '=', consistent
-//    with other broken QP encoders & decoders.
-
-package multipart
+// Package quotedprintable implements quoted-printable encoding as
+// specified by RFC 2045.
+package quotedprintable
 
 import (
 	"bufio"
@@ -17,14 +13,16 @@ import (
 	"io"
 )
 
-type qpReader struct {
+// Reader is a quoted-printable decoder.
+type Reader struct {
 	br   *bufio.Reader
 	rerr error  // last read error
 	line []byte // to be consumed before more of br
 }
 
-func newQuotedPrintableReader(r io.Reader) io.Reader {
-	return &qpReader{
+// NewReader returns a quoted-printable reader, decoding from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
 		br: bufio.NewReader(r),
 	}
 }
@@ -36,10 +34,10 @@ func fromHex(b byte) (byte, error) {
 	case b >= 'A' && b <= 'F':
 		return b - 'A' + 10, nil
 	}
-	return 0, fmt.Errorf("multipart: invalid quoted-printable hex byte 0x%02x", b)
+	return 0, fmt.Errorf("quotedprintable: invalid quoted-printable hex byte 0x%02x", b)
 }
 
-func (q *qpReader) readHexByte(v []byte) (b byte, err error) {
+func (q *Reader) readHexByte(v []byte) (b byte, err error) {
 	if len(v) < 2 {
 		return 0, io.ErrUnexpectedEOF
 	}
@@ -67,7 +65,11 @@ var (
 	softSuffix = []byte("=")
 )
 
-func (q *qpReader) Read(p []byte) (n int, err error) {
+// Read reads and decodes quoted-printable data from the underlying reader.
+// In addition to "=\r\n", "=\n" is also treated as a soft line break,
+// and a '\r' or '\n' not preceded by '=' is passed through, consistent
+// with other broken quoted-printable encoders and decoders.
+func (q *Reader) Read(p []byte) (n int, err error) {
 	for len(p) > 0 {
 		if len(q.line) == 0 {
 			if q.rerr != nil {
@@ -84,7 +86,7 @@ func (q *qpReader) Read(p []byte) (n int, err error) {
 				rightStripped := wholeLine[len(q.line):]
 				q.line = q.line[:len(q.line)-1]
 				if !bytes.HasPrefix(rightStripped, lf) && !bytes.HasPrefix(rightStripped, crlf) {
-					q.rerr = fmt.Errorf("multipart: invalid bytes after =: %q", rightStripped)
+					q.rerr = fmt.Errorf("quotedprintable: invalid bytes after =: %q", rightStripped)
 				}
 			} else if hasLF {
 				if hasCR {
@@ -107,7 +109,7 @@ func (q *qpReader) Read(p []byte) (n int, err error) {
 		case b == '\t' || b == '\r' || b == '\n':
 			break
 		case b < ' ' || b > '~':
-			return n, fmt.Errorf("multipart: invalid unescaped byte 0x%02x in quoted-printable body", b)
+			return n, fmt.Errorf("quotedprintable: invalid unescaped byte 0x%02x in quoted-printable body", b)
 		}
 		p[0] = b
 		p = p[1:]
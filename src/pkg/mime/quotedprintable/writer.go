@@ -0,0 +1,123 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quotedprintable
+
+import "io"
+
+const lineMaxLen = 76
+
+// A Writer is a quoted-printable writer that implements io.WriteCloser.
+type Writer struct {
+	// Binary mode treats the writer's input as pure binary and processes
+	// end of line bytes as binary data. Otherwise "\r\n", "\r" and "\n"
+	// each end a line and are written as "\r\n".
+	Binary bool
+
+	w    io.Writer
+	i    int
+	line [lineMaxLen + 2]byte // room for a soft line break
+	cr   bool
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write encodes p using quoted-printable encoding and writes it to the
+// underlying io.Writer. It limits line length to 76 characters. The
+// encoded bytes are not necessarily flushed until the Writer is closed.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	for i, b := range p {
+		if err := w.writeByte(b); err != nil {
+			return i, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the Writer, flushing any unwritten data to the
+// underlying io.Writer, but does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if err := w.checkLastByte(); err != nil {
+		return err
+	}
+	return w.flush("")
+}
+
+func (w *Writer) writeByte(b byte) error {
+	if !w.Binary && (b == '\n' || b == '\r') {
+		// A "\n" right after a "\r" completes the same line break.
+		if w.cr && b == '\n' {
+			w.cr = false
+			return nil
+		}
+		w.cr = b == '\r'
+		if err := w.checkLastByte(); err != nil {
+			return err
+		}
+		return w.flush("\r\n")
+	}
+	w.cr = false
+	if b >= '!' && b <= '~' && b != '=' || b == ' ' || b == '\t' {
+		return w.write(b)
+	}
+	return w.encode(b)
+}
+
+// write appends the literal byte b to the current line.
+func (w *Writer) write(b byte) error {
+	if err := w.makeRoom(1); err != nil {
+		return err
+	}
+	w.line[w.i] = b
+	w.i++
+	return nil
+}
+
+const upperhex = "0123456789ABCDEF"
+
+// encode appends the encoded form of b to the current line.
+func (w *Writer) encode(b byte) error {
+	if err := w.makeRoom(3); err != nil {
+		return err
+	}
+	w.line[w.i] = '='
+	w.line[w.i+1] = upperhex[b>>4]
+	w.line[w.i+2] = upperhex[b&0x0f]
+	w.i += 3
+	return nil
+}
+
+// makeRoom inserts a soft line break if n more bytes and a trailing '='
+// do not fit on the current line.
+func (w *Writer) makeRoom(n int) error {
+	if w.i+n < lineMaxLen {
+		return nil
+	}
+	return w.flush("=\r\n")
+}
+
+// checkLastByte encodes the last byte of the current line if it is
+// whitespace, which would otherwise be lost before a line break.
+func (w *Writer) checkLastByte() error {
+	if w.i == 0 {
+		return nil
+	}
+	b := w.line[w.i-1]
+	if b != ' ' && b != '\t' {
+		return nil
+	}
+	w.i--
+	return w.encode(b)
+}
+
+// flush writes the current line followed by suffix.
+func (w *Writer) flush(suffix string) error {
+	n := copy(w.line[w.i:], suffix)
+	_, err := w.w.Write(w.line[:w.i+n])
+	w.i = 0
+	return err
+}
@@ -37,6 +37,39 @@ type Dialer struct {
 	// network being dialed.
 	// If nil, a local address is automatically chosen.
 	LocalAddr Addr
+
+	// DualStack enables RFC 6555-compliant "Happy Eyeballs"
+	// dialing when the network is "tcp" and the destination is a
+	// host name with both IPv4 and IPv6 addresses. It allows a
+	// client to tolerate networks where one address family is
+	// silently broken: the IPv6 addresses are tried first and,
+	// if no connection is made within FallbackDelay, the IPv4
+	// addresses are raced against them.
+	DualStack bool
+
+	// FallbackDelay specifies the length of time to wait before
+	// spawning a fallback connection, when DualStack is enabled.
+	// If zero, a default delay of 300ms is used.
+	FallbackDelay time.Duration
+
+	// KeepAlive specifies the keep-alive period for an active
+	// network connection.
+	// If zero, keep-alives are not enabled. Network protocols
+	// that do not support keep-alives ignore this field.
+	KeepAlive time.Duration
+
+	// Control, if not nil, is called after creating the network
+	// connection but before actually dialing, with the raw
+	// file descriptor of the socket. It can be used to set socket
+	// options that the package does not expose.
+	//
+	// Network and address parameters passed to Control are not
+	// necessarily the ones passed to Dial. For example, dialing
+	// "tcp" will cause Control to be called with "tcp4" or "tcp6",
+	// and the address is the one being connected to, with the
+	// host name resolved. If Control returns an error, the dial
+	// fails with that error. Control is ignored on Plan 9.
+	Control func(network, address string, fd uintptr) error
 }
 
 // Return either now+Timeout or Deadline, whichever comes first.
@@ -53,6 +86,37 @@ func (d *Dialer) deadline() time.Time {
 	}
 }
 
+func (d *Dialer) fallbackDelay() time.Duration {
+	if d.FallbackDelay > 0 {
+		return d.FallbackDelay
+	}
+	return 300 * time.Millisecond
+}
+
+// partialDeadline returns the deadline to use for a single address,
+// when multiple addresses are pending.
+func partialDeadline(now, deadline time.Time, addrsRemaining int) (time.Time, error) {
+	if deadline.IsZero() {
+		return deadline, nil
+	}
+	timeRemaining := deadline.Sub(now)
+	if timeRemaining <= 0 {
+		return time.Time{}, errTimeout
+	}
+	// Tentatively allocate equal time to each remaining address.
+	timeout := timeRemaining / time.Duration(addrsRemaining)
+	// If the time per address is too short, steal from the end of the list.
+	const saneMinimum = 2 * time.Second
+	if timeout < saneMinimum {
+		if timeRemaining < saneMinimum {
+			timeout = timeRemaining
+		} else {
+			timeout = saneMinimum
+		}
+	}
+	return now.Add(timeout), nil
+}
+
 func parseNetwork(net string) (afnet string, proto int, err error) {
 	i := last(net, ':')
 	if i < 0 { // no colon
@@ -94,7 +158,7 @@ func resolveAddr(op, net, addr string, deadline time.Time) (netaddr, error) {
 	case "unix", "unixgram", "unixpacket":
 		return ResolveUnixAddr(afnet, addr)
 	}
-	return resolveInternetAddr(afnet, addr, deadline)
+	return resolveInternetAddr(op, afnet, addr, deadline)
 }
 
 // Dial connects to the address on the named network.
@@ -142,74 +206,245 @@ func DialTimeout(network, address string, timeout time.Duration) (Conn, error) {
 //
 // See func Dial for a description of the network and address
 // parameters.
+//
+// When the address is a host name with several IP addresses, Dial
+// tries them in turn until one succeeds, sharing the remaining time
+// before the deadline among them.
 func (d *Dialer) Dial(network, address string) (Conn, error) {
-	return resolveAndDial(network, address, d.LocalAddr, d.deadline())
+	deadline := d.deadline()
+	ra, err := resolveAddr("dial", network, address, deadline)
+	if err != nil {
+		return nil, &OpError{Op: "dial", Net: network, Addr: nil, Err: err}
+	}
+	dialer := func(deadline time.Time) (Conn, error) {
+		return dialSingle(network, address, d.LocalAddr, ra.toAddr(), deadline, d.Control)
+	}
+	if ras, ok := ra.(addrList); ok {
+		dialer = func(deadline time.Time) (Conn, error) {
+			return dialSerial(network, address, d.LocalAddr, ras, deadline, nil, d.Control)
+		}
+		if d.DualStack && network == "tcp" {
+			if ipv4, ipv6 := ras.partition(); len(ipv4) > 0 && len(ipv6) > 0 {
+				dialer = func(deadline time.Time) (Conn, error) {
+					return dialParallel(network, address, d.LocalAddr, ipv6, ipv4, deadline, d.fallbackDelay(), d.Control)
+				}
+			}
+		}
+	}
+	c, err := dial(network, ra.toAddr(), dialer, deadline)
+	if d.KeepAlive > 0 && err == nil {
+		if tc, ok := c.(*TCPConn); ok {
+			tc.SetKeepAlive(true)
+			tc.SetKeepAlivePeriod(d.KeepAlive)
+			testHookSetKeepAlive()
+		}
+	}
+	return c, err
+}
+
+var (
+	testHookDialTCP      = dialTCP
+	testHookSetKeepAlive = func() {}
+) // changed by dial_test.go
+
+// dialParallel races two copies of dialSerial, giving the first a
+// head start of fallbackDelay. It returns the first established
+// connection and closes the others. Otherwise it returns an error
+// from the first primary address.
+func dialParallel(net, addr string, la Addr, primaries, fallbacks addrList, deadline time.Time, fallbackDelay time.Duration, ctrlFn func(string, string, uintptr) error) (Conn, error) {
+	type dialResult struct {
+		Conn
+		error
+		primary bool
+		done    bool
+	}
+	results := make(chan dialResult) // unbuffered
+	returned := make(chan struct{})
+	defer close(returned)
+
+	startRacer := func(primary bool) {
+		ras := primaries
+		if !primary {
+			ras = fallbacks
+		}
+		c, err := dialSerial(net, addr, la, ras, deadline, returned, ctrlFn)
+		select {
+		case results <- dialResult{Conn: c, error: err, primary: primary, done: true}:
+		case <-returned:
+			if c != nil {
+				c.Close()
+			}
+		}
+	}
+
+	var primary, fallback dialResult
+
+	// Start the main racer.
+	go startRacer(true)
+
+	// Start the timer for the fallback racer.
+	fallbackTimer := time.NewTimer(fallbackDelay)
+	defer fallbackTimer.Stop()
+
+	for {
+		select {
+		case <-fallbackTimer.C:
+			go startRacer(false)
+
+		case res := <-results:
+			if res.error == nil {
+				return res.Conn, nil
+			}
+			if res.primary {
+				primary = res
+			} else {
+				fallback = res
+			}
+			if primary.done && fallback.done {
+				return nil, primary.error
+			}
+			if res.primary && fallbackTimer.Stop() {
+				// If we were able to stop the timer, that means it
+				// was running (hadn't yet started the fallback), but
+				// we just got an error on the primary path, so start
+				// the fallback immediately (in 0 nanoseconds).
+				fallbackTimer.Reset(0)
+			}
+		}
+	}
+}
+
+// dialSerial connects to a list of addresses in sequence, returning
+// either the first successful connection, or the first error. The
+// time left before deadline is spread across the addresses still to
+// be tried. Dialing stops early once returned is closed.
+func dialSerial(net, addr string, la Addr, ras addrList, deadline time.Time, returned <-chan struct{}, ctrlFn func(string, string, uintptr) error) (Conn, error) {
+	var firstErr error // The error from the first address is most relevant.
+
+	for i, ra := range ras {
+		select {
+		case <-returned:
+			return nil, &OpError{Op: "dial", Net: net, Addr: ra.toAddr(), Err: errCanceled}
+		default:
+		}
+
+		partial, err := partialDeadline(time.Now(), deadline, len(ras)-i)
+		if err != nil {
+			// Ran out of time.
+			if firstErr == nil {
+				firstErr = &OpError{Op: "dial", Net: net, Addr: ra.toAddr(), Err: err}
+			}
+			break
+		}
+
+		c, err := dialSingle(net, addr, la, ra.toAddr(), partial, ctrlFn)
+		if err == nil {
+			return c, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = &OpError{Op: "dial", Net: net, Addr: nil, Err: errNoSuitableAddress}
+	}
+	return nil, firstErr
 }
 
-func dial(net, addr string, la, ra Addr, deadline time.Time) (Conn, error) {
+// dialSingle attempts to establish and returns a single connection to
+// the destination address. If ctrlFn is not nil, it is called with
+// the socket before connecting.
+func dialSingle(net, addr string, la, ra Addr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (Conn, error) {
 	if la != nil && la.Network() != ra.Network() {
 		return nil, &OpError{Op: "dial", Net: net, Addr: ra, Err: errors.New("mismatched local address type " + la.Network())}
 	}
 	switch ra := ra.(type) {
 	case *TCPAddr:
 		la, _ := la.(*TCPAddr)
-		return dialTCP(net, la, ra, deadline)
+		return testHookDialTCP(net, la, ra, deadline, ctrlFn)
 	case *UDPAddr:
 		la, _ := la.(*UDPAddr)
-		return dialUDP(net, la, ra, deadline)
+		return dialUDP(net, la, ra, deadline, ctrlFn)
 	case *IPAddr:
 		la, _ := la.(*IPAddr)
-		return dialIP(net, la, ra, deadline)
+		return dialIP(net, la, ra, deadline, ctrlFn)
 	case *UnixAddr:
 		la, _ := la.(*UnixAddr)
-		return dialUnix(net, la, ra, deadline)
+		return dialUnix(net, la, ra, deadline, ctrlFn)
 	default:
 		return nil, &OpError{Op: "dial", Net: net, Addr: ra, Err: &AddrError{Err: "unexpected address type", Addr: addr}}
 	}
 }
 
-type stringAddr struct {
-	net, addr string
+// ListenConfig contains options for listening to an address.
+type ListenConfig struct {
+	// Control, if not nil, is called after creating the network
+	// connection but before binding it to the operating system,
+	// with the raw file descriptor of the socket. It can be used
+	// to set socket options such as SO_REUSEPORT that must be
+	// applied before the socket is bound.
+	//
+	// As with Dialer.Control, the network passed to Control is
+	// qualified with the address family of the socket, as in
+	// "tcp4" or "tcp6". If Control returns an error, the listen
+	// fails with that error. Control is ignored on Plan 9.
+	Control func(network, address string, fd uintptr) error
 }
 
-func (a stringAddr) Network() string { return a.net }
-func (a stringAddr) String() string  { return a.addr }
-
 // Listen announces on the local network address laddr.
 // The network net must be a stream-oriented network: "tcp", "tcp4",
 // "tcp6", "unix" or "unixpacket".
 // See Dial for the syntax of laddr.
 func Listen(net, laddr string) (Listener, error) {
+	var lc ListenConfig
+	return lc.Listen(net, laddr)
+}
+
+// ListenPacket announces on the local network address laddr.
+// The network net must be a packet-oriented network: "udp", "udp4",
+// "udp6", "ip", "ip4", "ip6" or "unixgram".
+// See Dial for the syntax of laddr.
+func ListenPacket(net, laddr string) (PacketConn, error) {
+	var lc ListenConfig
+	return lc.ListenPacket(net, laddr)
+}
+
+// Listen announces on the local network address laddr.
+//
+// See func Listen for a description of the network and address
+// parameters.
+func (lc *ListenConfig) Listen(net, laddr string) (Listener, error) {
 	la, err := resolveAddr("listen", net, laddr, noDeadline)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Addr: nil, Err: err}
 	}
 	switch la := la.toAddr().(type) {
 	case *TCPAddr:
-		return ListenTCP(net, la)
+		return listenTCP(net, la, lc.Control)
 	case *UnixAddr:
-		return ListenUnix(net, la)
+		return listenUnix(net, la, lc.Control)
 	default:
 		return nil, &OpError{Op: "listen", Net: net, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: laddr}}
 	}
 }
 
 // ListenPacket announces on the local network address laddr.
-// The network net must be a packet-oriented network: "udp", "udp4",
-// "udp6", "ip", "ip4", "ip6" or "unixgram".
-// See Dial for the syntax of laddr.
-func ListenPacket(net, laddr string) (PacketConn, error) {
+//
+// See func ListenPacket for a description of the network and address
+// parameters.
+func (lc *ListenConfig) ListenPacket(net, laddr string) (PacketConn, error) {
 	la, err := resolveAddr("listen", net, laddr, noDeadline)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Addr: nil, Err: err}
 	}
 	switch la := la.toAddr().(type) {
 	case *UDPAddr:
-		return ListenUDP(net, la)
+		return listenUDP(net, la, lc.Control)
 	case *IPAddr:
-		return ListenIP(net, la)
+		return listenIP(net, la, lc.Control)
 	case *UnixAddr:
-		return ListenUnixgram(net, la)
+		return listenUnixgram(net, la, lc.Control)
 	default:
 		return nil, &OpError{Op: "listen", Net: net, Addr: la, Err: &AddrError{Err: "unexpected address type", Addr: laddr}}
 	}
@@ -12,21 +12,16 @@ import (
 
 var testingIssue5349 bool // used during tests
 
-// resolveAndDialChannel is the simple pure-Go implementation of
-// resolveAndDial, still used on operating systems where the deadline
-// hasn't been pushed down into the pollserver. (Plan 9 and some old
-// versions of Windows)
-func resolveAndDialChannel(net, addr string, localAddr Addr, deadline time.Time) (Conn, error) {
+// dialChannel is the simple pure-Go implementation of dial, still
+// used on operating systems where the deadline hasn't been pushed
+// down into the pollserver. (Plan 9 and some old versions of Windows)
+func dialChannel(net string, ra Addr, dialer func(time.Time) (Conn, error), deadline time.Time) (Conn, error) {
 	var timeout time.Duration
 	if !deadline.IsZero() {
 		timeout = deadline.Sub(time.Now())
 	}
 	if timeout <= 0 {
-		ra, err := resolveAddr("dial", net, addr, noDeadline)
-		if err != nil {
-			return nil, &OpError{Op: "dial", Net: net, Addr: nil, Err: err}
-		}
-		return dial(net, addr, localAddr, ra.toAddr(), noDeadline)
+		return dialer(noDeadline)
 	}
 	t := time.NewTimer(timeout)
 	defer t.Stop()
@@ -35,31 +30,15 @@ func resolveAndDialChannel(net, addr string, localAddr Addr, deadline time.Time)
 		error
 	}
 	ch := make(chan pair, 1)
-	resolvedAddr := make(chan Addr, 1)
 	go func() {
 		if testingIssue5349 {
 			time.Sleep(time.Millisecond)
 		}
-		ra, err := resolveAddr("dial", net, addr, noDeadline)
-		if err != nil {
-			ch <- pair{nil, &OpError{Op: "dial", Net: net, Addr: nil, Err: err}}
-			return
-		}
-		resolvedAddr <- ra.toAddr() // in case we need it for OpError
-		c, err := dial(net, addr, localAddr, ra.toAddr(), noDeadline)
+		c, err := dialer(noDeadline)
 		ch <- pair{c, err}
 	}()
 	select {
 	case <-t.C:
-		// Try to use the real Addr in our OpError, if we resolved it
-		// before the timeout. Otherwise we just use stringAddr.
-		var ra Addr
-		select {
-		case a := <-resolvedAddr:
-			ra = a
-		default:
-			ra = &stringAddr{net, addr}
-		}
 		err := &OpError{
 			Op:   "dial",
 			Net:  net,
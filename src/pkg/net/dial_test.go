@@ -5,6 +5,7 @@
 package net
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -404,3 +405,274 @@ func TestDialer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPartialDeadline(t *testing.T) {
+	now := time.Date(2013, time.June, 1, 0, 0, 0, 0, time.UTC)
+	var testCases = []struct {
+		now            time.Time
+		deadline       time.Time
+		addrs          int
+		expectDeadline time.Time
+		expectErr      error
+	}{
+		// Regular division.
+		{now, now.Add(12 * time.Second), 1, now.Add(12 * time.Second), nil},
+		{now, now.Add(12 * time.Second), 2, now.Add(6 * time.Second), nil},
+		{now, now.Add(12 * time.Second), 3, now.Add(4 * time.Second), nil},
+		// Bump against the 2-second sane minimum.
+		{now, now.Add(12 * time.Second), 999, now.Add(2 * time.Second), nil},
+		// Total available is now below the sane minimum.
+		{now, now.Add(1900 * time.Millisecond), 999, now.Add(1900 * time.Millisecond), nil},
+		// Null deadline.
+		{now, noDeadline, 1, noDeadline, nil},
+		// Step the clock forward and cross the deadline.
+		{now.Add(-1 * time.Millisecond), now, 1, now, nil},
+		{now.Add(0 * time.Millisecond), now, 1, noDeadline, errTimeout},
+		{now.Add(1 * time.Millisecond), now, 1, noDeadline, errTimeout},
+	}
+	for i, tt := range testCases {
+		deadline, err := partialDeadline(tt.now, tt.deadline, tt.addrs)
+		if err != tt.expectErr {
+			t.Errorf("#%d: got %v; want %v", i, err, tt.expectErr)
+		}
+		if !deadline.Equal(tt.expectDeadline) {
+			t.Errorf("#%d: got %v; want %v", i, deadline, tt.expectDeadline)
+		}
+	}
+}
+
+// slowDialTCP is a testHookDialTCP that takes at least slowDialDelay
+// to fail when dialing an IPv6 address, as if its route were a black
+// hole.
+func slowDialTCP(net string, laddr, raddr *TCPAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*TCPConn, error) {
+	if raddr.IP.To4() != nil {
+		return dialTCP(net, laddr, raddr, deadline, ctrlFn)
+	}
+	time.Sleep(slowDialDelay)
+	return nil, &OpError{Op: "dial", Net: net, Addr: raddr, Err: errTimeout}
+}
+
+const slowDialDelay = time.Second
+
+func acceptAll(ln Listener) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		c.Close()
+	}
+}
+
+func TestDialParallel(t *testing.T) {
+	ln, err := Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go acceptAll(ln)
+	port := ln.Addr().(*TCPAddr).Port
+
+	testHookDialTCP = slowDialTCP
+	defer func() { testHookDialTCP = dialTCP }()
+
+	ipv6 := addrList{&TCPAddr{IP: ParseIP("2001:db8::1"), Port: port}}
+	ipv4 := addrList{&TCPAddr{IP: IPv4(127, 0, 0, 1).To4(), Port: port}}
+	const fallbackDelay = 100 * time.Millisecond
+
+	// The slow IPv6 primary loses to the IPv4 fallback, which
+	// starts once fallbackDelay has passed.
+	start := time.Now()
+	c, err := dialParallel("tcp", "", nil, ipv6, ipv4, noDeadline, fallbackDelay, nil)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("dialParallel failed: %v", err)
+	}
+	if ra := c.RemoteAddr().(*TCPAddr); !ra.IP.Equal(IPv4(127, 0, 0, 1)) {
+		t.Errorf("connected to %v; want the IPv4 fallback", ra)
+	}
+	c.Close()
+	if elapsed < fallbackDelay || elapsed >= slowDialDelay {
+		t.Errorf("dialParallel took %v; want between %v and %v", elapsed, fallbackDelay, slowDialDelay)
+	}
+
+	// A fast IPv4 primary wins before the fallback starts.
+	start = time.Now()
+	c, err = dialParallel("tcp", "", nil, ipv4, ipv6, noDeadline, slowDialDelay, nil)
+	elapsed = time.Since(start)
+	if err != nil {
+		t.Fatalf("dialParallel failed: %v", err)
+	}
+	c.Close()
+	if elapsed >= slowDialDelay {
+		t.Errorf("dialParallel took %v; want less than %v", elapsed, slowDialDelay)
+	}
+}
+
+func TestDialParallelPrimaryFailure(t *testing.T) {
+	ln, err := Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go acceptAll(ln)
+
+	// A closed port refuses the primary right away, so the
+	// fallback does not wait for its delay.
+	closed := newLocalListener(t)
+	refused := addrList{closed.Addr().(*TCPAddr)}
+	closed.Close()
+	ipv4 := addrList{ln.Addr().(*TCPAddr)}
+
+	start := time.Now()
+	c, err := dialParallel("tcp", "", nil, refused, ipv4, noDeadline, slowDialDelay, nil)
+	if err != nil {
+		t.Fatalf("dialParallel failed: %v", err)
+	}
+	c.Close()
+	if elapsed := time.Since(start); elapsed >= slowDialDelay {
+		t.Errorf("dialParallel took %v; want less than %v", elapsed, slowDialDelay)
+	}
+
+	// With every address failing, the primary's error is returned.
+	_, err = dialParallel("tcp", "", nil, refused, refused, noDeadline, 10*time.Millisecond, nil)
+	if err == nil {
+		t.Fatal("dialParallel to closed ports succeeded")
+	}
+	if oe, ok := err.(*OpError); !ok || oe.Addr.String() != refused[0].toAddr().String() {
+		t.Errorf("dialParallel error = %v; want one for %v", err, refused[0].toAddr())
+	}
+}
+
+func TestDialSerial(t *testing.T) {
+	ln, err := Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go acceptAll(ln)
+
+	closed := newLocalListener(t)
+	refused := closed.Addr().(*TCPAddr)
+	closed.Close()
+
+	ras := addrList{refused, ln.Addr().(*TCPAddr)}
+	c, err := dialSerial("tcp", "", nil, ras, time.Now().Add(5*time.Second), nil, nil)
+	if err != nil {
+		t.Fatalf("dialSerial failed: %v", err)
+	}
+	if ra := c.RemoteAddr().String(); ra != ln.Addr().String() {
+		t.Errorf("connected to %v; want %v", ra, ln.Addr())
+	}
+	c.Close()
+
+	// The first error is the one reported.
+	_, err = dialSerial("tcp", "", nil, addrList{refused, refused}, noDeadline, nil, nil)
+	if oe, ok := err.(*OpError); !ok || oe.Addr.String() != refused.String() {
+		t.Errorf("dialSerial error = %v; want one for %v", err, refused)
+	}
+
+	// A closed channel stops dialing.
+	returned := make(chan struct{})
+	close(returned)
+	_, err = dialSerial("tcp", "", nil, ras, noDeadline, returned, nil)
+	if oe, ok := err.(*OpError); !ok || oe.Err != errCanceled {
+		t.Errorf("dialSerial error = %v; want %v", err, errCanceled)
+	}
+}
+
+func TestDialerKeepAlive(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+	go acceptAll(ln)
+
+	called := false
+	testHookSetKeepAlive = func() { called = true }
+	defer func() { testHookSetKeepAlive = func() {} }()
+
+	for _, keepAlive := range []time.Duration{0, 30 * time.Second} {
+		called = false
+		d := &Dialer{KeepAlive: keepAlive}
+		c, err := d.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("Dial failed: %v", err)
+		}
+		c.Close()
+		if got, want := called, keepAlive != 0; got != want {
+			t.Errorf("Dialer.KeepAlive=%v: SetKeepAlive called %v; want %v", keepAlive, got, want)
+		}
+	}
+}
+
+func TestDialerControl(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("skipping test on %q", runtime.GOOS)
+	}
+
+	ln, err := Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	go acceptAll(ln)
+
+	var network, address string
+	d := &Dialer{Control: func(n, a string, fd uintptr) error {
+		network, address = n, a
+		return nil
+	}}
+	c, err := d.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	c.Close()
+	if network != "tcp4" || address != ln.Addr().String() {
+		t.Errorf("Control called with %q, %q; want %q, %q", network, address, "tcp4", ln.Addr())
+	}
+
+	errControl := errors.New("control failed")
+	d = &Dialer{Control: func(string, string, uintptr) error { return errControl }}
+	if _, err := d.Dial("tcp", ln.Addr().String()); err == nil {
+		t.Fatal("Dial succeeded; want error from Control")
+	} else if oe, ok := err.(*OpError); !ok || oe.Err != errControl {
+		t.Errorf("Dial error = %v; want %v", err, errControl)
+	}
+}
+
+func TestListenConfigControl(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("skipping test on %q", runtime.GOOS)
+	}
+
+	var networks []string
+	lc := &ListenConfig{Control: func(n, a string, fd uintptr) error {
+		networks = append(networks, n)
+		return nil
+	}}
+	ln, err := lc.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ln.Close()
+	c, err := lc.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	c.Close()
+	if want := []string{"tcp4", "udp4"}; !reflect.DeepEqual(networks, want) {
+		t.Errorf("Control called with networks %q; want %q", networks, want)
+	}
+
+	errControl := errors.New("control failed")
+	lc = &ListenConfig{Control: func(string, string, uintptr) error { return errControl }}
+	if ln, err := lc.Listen("tcp", "127.0.0.1:0"); err == nil {
+		ln.Close()
+		t.Error("Listen succeeded; want error from Control")
+	}
+	if c, err := lc.ListenPacket("udp", "127.0.0.1:0"); err == nil {
+		c.Close()
+		t.Error("ListenPacket succeeded; want error from Control")
+	}
+}
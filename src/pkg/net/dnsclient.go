@@ -6,6 +6,7 @@ package net
 
 import (
 	"math/rand"
+	"net/dnsmessage"
 	"sort"
 )
 
@@ -61,15 +62,79 @@ func reverseaddr(addr string) (arpa string, err error) {
 	return string(buf), nil
 }
 
-// Find answer for name in dns message.
+// newRequest returns a recursive query for name, which must be rooted.
+// For TCP, the query is prefixed with its two-byte length.
+func newRequest(id uint16, name string, qtype dnsmessage.Type, useTCP bool) ([]byte, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	if useTCP {
+		buf = make([]byte, 2, 514)
+	}
+	b := dnsmessage.NewBuilder(buf, dnsmessage.Header{ID: id, RecursionDesired: true})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		return nil, err
+	}
+	if useTCP {
+		mlen := uint16(len(msg) - 2)
+		msg[0], msg[1] = byte(mlen>>8), byte(mlen)
+	}
+	return msg, nil
+}
+
+// parseAnswers parses the DNS response msg, returning its header and
+// those records of its answer section that are of type qtype or CNAME.
+// Parsing stops at the first malformed record, which might be truncated
+// or malicious; the records before it are still returned.
+func parseAnswers(msg []byte, qtype dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		return h, nil, err
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return h, nil, err
+	}
+	var rrs []dnsmessage.Resource
+	for {
+		rh, err := p.AnswerHeader()
+		if err != nil {
+			break
+		}
+		if rh.Type != qtype && rh.Type != dnsmessage.TypeCNAME {
+			if err := p.SkipAnswer(); err != nil {
+				break
+			}
+			continue
+		}
+		rr, err := p.Answer()
+		if err != nil {
+			break
+		}
+		rrs = append(rrs, rr)
+	}
+	return h, rrs, nil
+}
+
+// Find answer for name in the answer section rrs of a dns message
+// with header h.
 // On return, if err == nil, addrs != nil.
-func answer(name, server string, dns *dnsMsg, qtype uint16) (cname string, addrs []dnsRR, err error) {
-	addrs = make([]dnsRR, 0, len(dns.answer))
+func answer(name, server string, h dnsmessage.Header, rrs []dnsmessage.Resource, qtype dnsmessage.Type) (cname string, addrs []dnsmessage.Resource, err error) {
+	addrs = make([]dnsmessage.Resource, 0, len(rrs))
 
-	if dns.rcode == dnsRcodeNameError && dns.recursion_available {
+	if h.RCode == dnsmessage.RCodeNameError && h.RecursionAvailable {
 		return "", nil, &DNSError{Err: noSuchHost, Name: name}
 	}
-	if dns.rcode != dnsRcodeSuccess {
+	if h.RCode != dnsmessage.RCodeSuccess {
 		// None of the error codes make sense
 		// for the query we sent.  If we didn't get
 		// a name error and we didn't get success,
@@ -85,22 +150,15 @@ func answer(name, server string, dns *dnsMsg, qtype uint16) (cname string, addrs
 Cname:
 	for cnameloop := 0; cnameloop < 10; cnameloop++ {
 		addrs = addrs[0:0]
-		for _, rr := range dns.answer {
-			if _, justHeader := rr.(*dnsRR_Header); justHeader {
-				// Corrupt record: we only have a
-				// header. That header might say it's
-				// of type qtype, but we don't
-				// actually have it. Skip.
-				continue
-			}
-			h := rr.Header()
-			if h.Class == dnsClassINET && h.Name == name {
-				switch h.Rrtype {
+		for _, rr := range rrs {
+			rh := &rr.Header
+			if rh.Class == dnsmessage.ClassINET && string(rh.Name.Data[:rh.Name.Length]) == name {
+				switch rh.Type {
 				case qtype:
 					addrs = append(addrs, rr)
-				case dnsTypeCNAME:
+				case dnsmessage.TypeCNAME:
 					// redirect to cname
-					name = rr.(*dnsRR_CNAME).Cname
+					name = rr.Body.(*dnsmessage.CNAMEResource).CNAME.String()
 					continue Cname
 				}
 			}
@@ -6,7 +6,7 @@ package net
 
 import (
 	"encoding/hex"
-	"reflect"
+	"net/dnsmessage"
 	"testing"
 )
 
@@ -15,24 +15,22 @@ func TestDNSParseSRVReply(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	msg := new(dnsMsg)
-	ok := msg.Unpack(data)
-	if !ok {
-		t.Fatalf("unpacking packet failed")
+	h, rrs, err := parseAnswers(data, dnsmessage.TypeSRV)
+	if err != nil {
+		t.Fatalf("parseAnswers failed: %v", err)
 	}
-	msg.String() // exercise this code path
-	if g, e := len(msg.answer), 5; g != e {
-		t.Errorf("len(msg.answer) = %d; want %d", g, e)
+	if g, e := len(rrs), 5; g != e {
+		t.Errorf("len(rrs) = %d; want %d", g, e)
 	}
-	for idx, rr := range msg.answer {
-		if g, e := rr.Header().Rrtype, uint16(dnsTypeSRV); g != e {
-			t.Errorf("rr[%d].Header().Rrtype = %d; want %d", idx, g, e)
+	for idx, rr := range rrs {
+		if g, e := rr.Header.Type, dnsmessage.TypeSRV; g != e {
+			t.Errorf("rrs[%d].Header.Type = %v; want %v", idx, g, e)
 		}
-		if _, ok := rr.(*dnsRR_SRV); !ok {
-			t.Errorf("answer[%d] = %T; want *dnsRR_SRV", idx, rr)
+		if _, ok := rr.Body.(*dnsmessage.SRVResource); !ok {
+			t.Errorf("rrs[%d].Body = %T; want *dnsmessage.SRVResource", idx, rr.Body)
 		}
 	}
-	_, addrs, err := answer("_xmpp-server._tcp.google.com.", "foo:53", msg, uint16(dnsTypeSRV))
+	_, addrs, err := answer("_xmpp-server._tcp.google.com.", "foo:53", h, rrs, dnsmessage.TypeSRV)
 	if err != nil {
 		t.Fatalf("answer: %v", err)
 	}
@@ -40,15 +38,9 @@ func TestDNSParseSRVReply(t *testing.T) {
 		t.Errorf("len(addrs) = %d; want %d", g, e)
 		t.Logf("addrs = %#v", addrs)
 	}
-	// repack and unpack.
-	data2, ok := msg.Pack()
-	msg2 := new(dnsMsg)
-	msg2.Unpack(data2)
-	switch {
-	case !ok:
-		t.Errorf("failed to repack message")
-	case !reflect.DeepEqual(msg, msg2):
-		t.Errorf("repacked message differs from original")
+	// Records of other types are skipped.
+	if _, rrs, err = parseAnswers(data, dnsmessage.TypeA); err != nil || len(rrs) != 0 {
+		t.Errorf("parseAnswers(TypeA) = %d records, %v; want 0, nil", len(rrs), err)
 	}
 }
 
@@ -57,30 +49,15 @@ func TestDNSParseCorruptSRVReply(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	msg := new(dnsMsg)
-	ok := msg.Unpack(data)
-	if !ok {
-		t.Fatalf("unpacking packet failed")
+	h, rrs, err := parseAnswers(data, dnsmessage.TypeSRV)
+	if err != nil {
+		t.Fatalf("parseAnswers failed: %v", err)
 	}
-	msg.String() // exercise this code path
-	if g, e := len(msg.answer), 5; g != e {
-		t.Errorf("len(msg.answer) = %d; want %d", g, e)
+	// The corrupt final record is dropped.
+	if g, e := len(rrs), 4; g != e {
+		t.Errorf("len(rrs) = %d; want %d", g, e)
 	}
-	for idx, rr := range msg.answer {
-		if g, e := rr.Header().Rrtype, uint16(dnsTypeSRV); g != e {
-			t.Errorf("rr[%d].Header().Rrtype = %d; want %d", idx, g, e)
-		}
-		if idx == 4 {
-			if _, ok := rr.(*dnsRR_Header); !ok {
-				t.Errorf("answer[%d] = %T; want *dnsRR_Header", idx, rr)
-			}
-		} else {
-			if _, ok := rr.(*dnsRR_SRV); !ok {
-				t.Errorf("answer[%d] = %T; want *dnsRR_SRV", idx, rr)
-			}
-		}
-	}
-	_, addrs, err := answer("_xmpp-server._tcp.google.com.", "foo:53", msg, uint16(dnsTypeSRV))
+	_, addrs, err := answer("_xmpp-server._tcp.google.com.", "foo:53", h, rrs, dnsmessage.TypeSRV)
 	if err != nil {
 		t.Fatalf("answer: %v", err)
 	}
@@ -90,6 +67,35 @@ func TestDNSParseCorruptSRVReply(t *testing.T) {
 	}
 }
 
+func TestNewRequest(t *testing.T) {
+	for _, useTCP := range []bool{false, true} {
+		msg, err := newRequest(0x1234, "www.example.com.", dnsmessage.TypeAAAA, useTCP)
+		if err != nil {
+			t.Fatalf("newRequest(useTCP=%v): %v", useTCP, err)
+		}
+		if useTCP {
+			if n := int(msg[0])<<8 | int(msg[1]); n != len(msg)-2 {
+				t.Errorf("length prefix = %d; want %d", n, len(msg)-2)
+			}
+			msg = msg[2:]
+		}
+		var m dnsmessage.Message
+		if err := m.Unpack(msg); err != nil {
+			t.Fatalf("Unpack(useTCP=%v): %v", useTCP, err)
+		}
+		if m.ID != 0x1234 || m.Response || !m.RecursionDesired {
+			t.Errorf("header = %+v", m.Header)
+		}
+		if len(m.Questions) != 1 {
+			t.Fatalf("got %d questions; want 1", len(m.Questions))
+		}
+		q := m.Questions[0]
+		if q.Name.String() != "www.example.com." || q.Type != dnsmessage.TypeAAAA || q.Class != dnsmessage.ClassINET {
+			t.Errorf("question = %v %v %v", q.Name, q.Type, q.Class)
+		}
+	}
+}
+
 // Valid DNS SRV reply
 const dnsSRVReply = "0901818000010005000000000c5f786d70702d736572766572045f74637006676f6f67" +
 	"6c6503636f6d0000210001c00c002100010000012c00210014000014950c786d70702d" +
@@ -10,7 +10,6 @@
 // TODO(rsc):
 //	Check periodically whether /etc/resolv.conf has changed.
 //	Could potentially handle many outstanding lookups faster.
-//	Could have a small cache.
 //	Random UDP source port (net.Dial should do that for us).
 //	Random request IDs.
 
@@ -19,41 +18,33 @@ package net
 import (
 	"io"
 	"math/rand"
+	"net/dnsmessage"
 	"sync"
 	"time"
 )
 
 // Send a request on the connection and hope for a reply.
 // Up to cfg.attempts attempts.
-func exchange(cfg *dnsConfig, c Conn, name string, qtype uint16) (*dnsMsg, error) {
+func exchange(cfg *dnsConfig, c Conn, name string, qtype dnsmessage.Type) (dnsmessage.Header, []dnsmessage.Resource, error) {
 	_, useTCP := c.(*TCPConn)
 	if len(name) >= 256 {
-		return nil, &DNSError{Err: "name too long", Name: name}
+		return dnsmessage.Header{}, nil, &DNSError{Err: "name too long", Name: name}
 	}
-	out := new(dnsMsg)
-	out.id = uint16(rand.Int()) ^ uint16(time.Now().UnixNano())
-	out.question = []dnsQuestion{
-		{name, qtype, dnsClassINET},
-	}
-	out.recursion_desired = true
-	msg, ok := out.Pack()
-	if !ok {
-		return nil, &DNSError{Err: "internal error - cannot pack message", Name: name}
-	}
-	if useTCP {
-		mlen := uint16(len(msg))
-		msg = append([]byte{byte(mlen >> 8), byte(mlen)}, msg...)
+	id := uint16(rand.Int()) ^ uint16(time.Now().UnixNano())
+	msg, err := newRequest(id, name, qtype, useTCP)
+	if err != nil {
+		return dnsmessage.Header{}, nil, &DNSError{Err: "internal error - cannot pack message", Name: name}
 	}
 	for attempt := 0; attempt < cfg.attempts; attempt++ {
 		n, err := c.Write(msg)
 		if err != nil {
-			return nil, err
+			return dnsmessage.Header{}, nil, err
 		}
 
 		if cfg.timeout == 0 {
 			c.SetReadDeadline(noDeadline)
 		} else {
-			c.SetReadDeadline(time.Now().Add(time.Duration(cfg.timeout) * time.Second))
+			c.SetReadDeadline(time.Now().Add(cfg.timeout))
 		}
 		buf := make([]byte, 2000)
 		if useTCP {
@@ -75,61 +66,69 @@ func exchange(cfg *dnsConfig, c Conn, name string, qtype uint16) (*dnsMsg, error
 			if e, ok := err.(Error); ok && e.Timeout() {
 				continue
 			}
-			return nil, err
+			return dnsmessage.Header{}, nil, err
 		}
-		buf = buf[:n]
-		in := new(dnsMsg)
-		if !in.Unpack(buf) || in.id != out.id {
+		h, rrs, err := parseAnswers(buf[:n], qtype)
+		if err != nil || h.ID != id {
 			continue
 		}
-		return in, nil
+		return h, rrs, nil
 	}
 	var server string
 	if a := c.RemoteAddr(); a != nil {
 		server = a.String()
 	}
-	return nil, &DNSError{Err: "no answer from server", Name: name, Server: server, IsTimeout: true}
+	return dnsmessage.Header{}, nil, &DNSError{Err: "no answer from server", Name: name, Server: server, IsTimeout: true}
 }
 
 // Do a lookup for a single name, which must be rooted
 // (otherwise answer will not find the answers).
-func tryOneName(cfg *dnsConfig, name string, qtype uint16) (cname string, addrs []dnsRR, err error) {
+func (r *Resolver) tryOneName(cfg *dnsConfig, name string, qtype dnsmessage.Type) (cname string, addrs []dnsmessage.Resource, err error) {
 	if len(cfg.servers) == 0 {
 		return "", nil, &DNSError{Err: "no DNS servers", Name: name}
 	}
+	if r.Cache {
+		if cname, addrs, ok := r.cache.get(name, qtype); ok {
+			return cname, addrs, nil
+		}
+	}
 	for i := 0; i < len(cfg.servers); i++ {
 		// Calling Dial here is scary -- we have to be sure
 		// not to dial a name that will require a DNS lookup,
 		// or Dial will call back here to translate it.
 		// The DNS config parser has already checked that
 		// all the cfg.servers[i] are IP addresses, which
-		// Dial will use without a DNS lookup.
-		server := cfg.servers[i] + ":53"
-		c, cerr := Dial("udp", server)
+		// Dial will use without a DNS lookup. A Resolver's
+		// Servers are required to be IP addresses too.
+		server := cfg.servers[i]
+		c, cerr := r.dial("udp", server)
 		if cerr != nil {
 			err = cerr
 			continue
 		}
-		msg, merr := exchange(cfg, c, name, qtype)
+		h, rrs, merr := exchange(cfg, c, name, qtype)
 		c.Close()
 		if merr != nil {
 			err = merr
 			continue
 		}
-		if msg.truncated { // see RFC 5966
-			c, cerr = Dial("tcp", server)
+		if h.Truncated { // see RFC 5966
+			c, cerr = r.dial("tcp", server)
 			if cerr != nil {
 				err = cerr
 				continue
 			}
-			msg, merr = exchange(cfg, c, name, qtype)
+			h, rrs, merr = exchange(cfg, c, name, qtype)
 			c.Close()
 			if merr != nil {
 				err = merr
 				continue
 			}
 		}
-		cname, addrs, err = answer(name, server, msg, qtype)
+		cname, addrs, err = answer(name, server, h, rrs, qtype)
+		if err == nil && r.Cache {
+			r.cache.put(name, qtype, cname, addrs, minTTL(rrs))
+		}
 		if err == nil || err.(*DNSError).Err == noSuchHost {
 			break
 		}
@@ -137,20 +136,38 @@ func tryOneName(cfg *dnsConfig, name string, qtype uint16) (cname string, addrs
 	return
 }
 
-func convertRR_A(records []dnsRR) []IP {
+// minTTL returns the smallest TTL of the records in rrs.
+func minTTL(rrs []dnsmessage.Resource) uint32 {
+	var ttl uint32
+	for i, rr := range rrs {
+		if i == 0 || rr.Header.TTL < ttl {
+			ttl = rr.Header.TTL
+		}
+	}
+	return ttl
+}
+
+func (r *Resolver) dial(network, server string) (Conn, error) {
+	if r.Dial != nil {
+		return r.Dial(network, server)
+	}
+	return Dial(network, server)
+}
+
+func convertRR_A(records []dnsmessage.Resource) []IP {
 	addrs := make([]IP, len(records))
 	for i, rr := range records {
-		a := rr.(*dnsRR_A).A
-		addrs[i] = IPv4(byte(a>>24), byte(a>>16), byte(a>>8), byte(a))
+		a := rr.Body.(*dnsmessage.AResource).A
+		addrs[i] = IPv4(a[0], a[1], a[2], a[3])
 	}
 	return addrs
 }
 
-func convertRR_AAAA(records []dnsRR) []IP {
+func convertRR_AAAA(records []dnsmessage.Resource) []IP {
 	addrs := make([]IP, len(records))
 	for i, rr := range records {
 		a := make(IP, IPv6len)
-		copy(a, rr.(*dnsRR_AAAA).AAAA[:])
+		copy(a, rr.Body.(*dnsmessage.AAAAResource).AAAA[:])
 		addrs[i] = a
 	}
 	return addrs
@@ -163,13 +180,48 @@ func loadConfig() { cfg, dnserr = dnsReadConfig() }
 
 var onceLoadConfig sync.Once
 
-func lookup(name string, qtype uint16) (cname string, addrs []dnsRR, err error) {
+// config returns the system DNS configuration with r's settings
+// applied. If r lists its own servers, a missing /etc/resolv.conf is
+// not an error.
+func (r *Resolver) config() (*dnsConfig, error) {
+	onceLoadConfig.Do(loadConfig)
+	if dnserr != nil || cfg == nil {
+		if len(r.Servers) == 0 {
+			return nil, dnserr
+		}
+	}
+	if len(r.Servers) == 0 && r.Timeout == 0 && r.Attempts == 0 {
+		return cfg, nil
+	}
+
+	conf := &dnsConfig{ndots: 1, timeout: 5 * time.Second, attempts: 2}
+	if dnserr == nil && cfg != nil {
+		*conf = *cfg
+	}
+	if len(r.Servers) > 0 {
+		conf.servers = make([]string, len(r.Servers))
+		for i, s := range r.Servers {
+			if _, _, err := SplitHostPort(s); err != nil {
+				s = JoinHostPort(s, "53")
+			}
+			conf.servers[i] = s
+		}
+	}
+	if r.Timeout > 0 {
+		conf.timeout = r.Timeout
+	}
+	if r.Attempts > 0 {
+		conf.attempts = r.Attempts
+	}
+	return conf, nil
+}
+
+func (r *Resolver) lookup(name string, qtype dnsmessage.Type) (cname string, addrs []dnsmessage.Resource, err error) {
 	if !isDomainName(name) {
 		return name, nil, &DNSError{Err: "invalid domain name", Name: name}
 	}
-	onceLoadConfig.Do(loadConfig)
-	if dnserr != nil || cfg == nil {
-		err = dnserr
+	cfg, err := r.config()
+	if err != nil {
 		return
 	}
 	// If name is rooted (trailing dot) or has enough dots,
@@ -181,7 +233,7 @@ func lookup(name string, qtype uint16) (cname string, addrs []dnsRR, err error)
 			rname += "."
 		}
 		// Can try as ordinary name.
-		cname, addrs, err = tryOneName(cfg, rname, qtype)
+		cname, addrs, err = r.tryOneName(cfg, rname, qtype)
 		if err == nil {
 			return
 		}
@@ -196,7 +248,7 @@ func lookup(name string, qtype uint16) (cname string, addrs []dnsRR, err error)
 		if rname[len(rname)-1] != '.' {
 			rname += "."
 		}
-		cname, addrs, err = tryOneName(cfg, rname, qtype)
+		cname, addrs, err = r.tryOneName(cfg, rname, qtype)
 		if err == nil {
 			return
 		}
@@ -207,7 +259,7 @@ func lookup(name string, qtype uint16) (cname string, addrs []dnsRR, err error)
 	if !rooted {
 		rname += "."
 	}
-	cname, addrs, err = tryOneName(cfg, rname, qtype)
+	cname, addrs, err = r.tryOneName(cfg, rname, qtype)
 	if err == nil {
 		return
 	}
@@ -216,22 +268,21 @@ func lookup(name string, qtype uint16) (cname string, addrs []dnsRR, err error)
 
 // goLookupHost is the native Go implementation of LookupHost.
 // Used only if cgoLookupHost refuses to handle the request
-// (that is, only if cgoLookupHost is the stub in cgo_stub.go).
+// (that is, only if cgoLookupHost is the stub in cgo_stub.go)
+// or if the Resolver prefers it.
 // Normally we let cgo use the C library resolver instead of
 // depending on our lookup code, so that Go and C get the same
 // answers.
-func goLookupHost(name string) (addrs []string, err error) {
+func (r *Resolver) goLookupHost(name string) (addrs []string, err error) {
 	// Use entries from /etc/hosts if they match.
 	addrs = lookupStaticHost(name)
 	if len(addrs) > 0 {
 		return
 	}
-	onceLoadConfig.Do(loadConfig)
-	if dnserr != nil || cfg == nil {
-		err = dnserr
+	if _, err = r.config(); err != nil {
 		return
 	}
-	ips, err := goLookupIP(name)
+	ips, err := r.goLookupIP(name)
 	if err != nil {
 		return
 	}
@@ -244,11 +295,12 @@ func goLookupHost(name string) (addrs []string, err error) {
 
 // goLookupIP is the native Go implementation of LookupIP.
 // Used only if cgoLookupIP refuses to handle the request
-// (that is, only if cgoLookupIP is the stub in cgo_stub.go).
+// (that is, only if cgoLookupIP is the stub in cgo_stub.go)
+// or if the Resolver prefers it.
 // Normally we let cgo use the C library resolver instead of
 // depending on our lookup code, so that Go and C get the same
 // answers.
-func goLookupIP(name string) (addrs []IP, err error) {
+func (r *Resolver) goLookupIP(name string) (addrs []IP, err error) {
 	// Use entries from /etc/hosts if possible.
 	haddrs := lookupStaticHost(name)
 	if len(haddrs) > 0 {
@@ -261,20 +313,18 @@ func goLookupIP(name string) (addrs []IP, err error) {
 			return
 		}
 	}
-	onceLoadConfig.Do(loadConfig)
-	if dnserr != nil || cfg == nil {
-		err = dnserr
+	if _, err = r.config(); err != nil {
 		return
 	}
-	var records []dnsRR
+	var records []dnsmessage.Resource
 	var cname string
 	var err4, err6 error
-	cname, records, err4 = lookup(name, dnsTypeA)
+	cname, records, err4 = r.lookup(name, dnsmessage.TypeA)
 	addrs = convertRR_A(records)
 	if cname != "" {
 		name = cname
 	}
-	_, records, err6 = lookup(name, dnsTypeAAAA)
+	_, records, err6 = r.lookup(name, dnsmessage.TypeAAAA)
 	if err4 != nil && err6 == nil {
 		// Ignore A error because AAAA lookup succeeded.
 		err4 = nil
@@ -296,20 +346,19 @@ func goLookupIP(name string) (addrs []IP, err error) {
 
 // goLookupCNAME is the native Go implementation of LookupCNAME.
 // Used only if cgoLookupCNAME refuses to handle the request
-// (that is, only if cgoLookupCNAME is the stub in cgo_stub.go).
+// (that is, only if cgoLookupCNAME is the stub in cgo_stub.go)
+// or if the Resolver prefers it.
 // Normally we let cgo use the C library resolver instead of
 // depending on our lookup code, so that Go and C get the same
 // answers.
-func goLookupCNAME(name string) (cname string, err error) {
-	onceLoadConfig.Do(loadConfig)
-	if dnserr != nil || cfg == nil {
-		err = dnserr
+func (r *Resolver) goLookupCNAME(name string) (cname string, err error) {
+	if _, err = r.config(); err != nil {
 		return
 	}
-	_, rr, err := lookup(name, dnsTypeCNAME)
+	_, rr, err := r.lookup(name, dnsmessage.TypeCNAME)
 	if err != nil {
 		return
 	}
-	cname = rr[0].(*dnsRR_CNAME).Cname
+	cname = rr[0].Body.(*dnsmessage.CNAMEResource).CNAME.String()
 	return
 }
@@ -7,7 +7,12 @@
 package net
 
 import (
+	"errors"
+	"net/dnsmessage"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestTCPLookup(t *testing.T) {
@@ -19,9 +24,211 @@ func TestTCPLookup(t *testing.T) {
 		t.Fatalf("Dial failed: %v", err)
 	}
 	defer c.Close()
-	cfg := &dnsConfig{timeout: 10, attempts: 3}
-	_, err = exchange(cfg, c, "com.", dnsTypeALL)
+	cfg := &dnsConfig{timeout: 10 * time.Second, attempts: 3}
+	_, _, err = exchange(cfg, c, "com.", dnsmessage.TypeALL)
 	if err != nil {
 		t.Fatalf("exchange failed: %v", err)
 	}
 }
+
+// A fakeDNSServer answers DNS queries on a local UDP socket and counts
+// the queries it receives.
+type fakeDNSServer struct {
+	PacketConn
+
+	// answer returns the records to send for q. If drop is true,
+	// the query is not answered.
+	answer func(q dnsmessage.Question) (rrs []dnsmessage.Resource, drop bool)
+
+	mu      sync.Mutex
+	queries int
+}
+
+func newFakeDNSServer(t *testing.T, answer func(q dnsmessage.Question) ([]dnsmessage.Resource, bool)) *fakeDNSServer {
+	c, err := ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	s := &fakeDNSServer{PacketConn: c, answer: answer}
+	go s.serve()
+	return s
+}
+
+func (s *fakeDNSServer) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var req dnsmessage.Message
+		if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) != 1 {
+			continue
+		}
+		s.mu.Lock()
+		s.queries++
+		s.mu.Unlock()
+
+		rrs, drop := s.answer(req.Questions[0])
+		if drop {
+			continue
+		}
+		resp := dnsmessage.Message{
+			Header: dnsmessage.Header{
+				ID:                 req.ID,
+				Response:           true,
+				RecursionDesired:   req.RecursionDesired,
+				RecursionAvailable: true,
+			},
+			Questions: req.Questions,
+			Answers:   rrs,
+		}
+		if len(rrs) == 0 {
+			resp.RCode = dnsmessage.RCodeNameError
+		}
+		if msg, err := resp.Pack(); err == nil {
+			s.WriteTo(msg, addr)
+		}
+	}
+}
+
+func (s *fakeDNSServer) numQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// exampleZone answers for a few names under example.com. The records of
+// nocache.example.com have a zero TTL.
+func exampleZone(q dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+	hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+	name := q.Name.String()
+	switch {
+	case name == "www.example.com." && q.Type == dnsmessage.TypeA:
+		return []dnsmessage.Resource{
+			{Header: hdr, Body: &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}},
+		}, false
+	case name == "www.example.com." && q.Type == dnsmessage.TypeAAAA:
+		rr := &dnsmessage.AAAAResource{}
+		copy(rr.AAAA[:], ParseIP("2001:db8::1"))
+		return []dnsmessage.Resource{{Header: hdr, Body: rr}}, false
+	case name == "example.com." && q.Type == dnsmessage.TypeMX:
+		return []dnsmessage.Resource{
+			{Header: hdr, Body: &dnsmessage.MXResource{Pref: 20, MX: dnsmessage.MustNewName("mx2.example.com.")}},
+			{Header: hdr, Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mx1.example.com.")}},
+		}, false
+	case name == "example.com." && q.Type == dnsmessage.TypeTXT:
+		return []dnsmessage.Resource{
+			{Header: hdr, Body: &dnsmessage.TXTResource{TXT: []string{"hel", "lo"}}},
+		}, false
+	case name == "nocache.example.com." && q.Type == dnsmessage.TypeTXT:
+		hdr.TTL = 0
+		return []dnsmessage.Resource{
+			{Header: hdr, Body: &dnsmessage.TXTResource{TXT: []string{"fresh"}}},
+		}, false
+	}
+	return nil, false
+}
+
+func TestResolverServers(t *testing.T) {
+	s := newFakeDNSServer(t, exampleZone)
+	defer s.Close()
+	r := &Resolver{Servers: []string{s.LocalAddr().String()}}
+
+	ips, err := r.LookupIP("www.example.com.")
+	if err != nil {
+		t.Fatalf("LookupIP failed: %v", err)
+	}
+	if len(ips) != 2 || !ips[0].Equal(IPv4(192, 0, 2, 1)) || !ips[1].Equal(ParseIP("2001:db8::1")) {
+		t.Errorf("LookupIP = %v, want [192.0.2.1 2001:db8::1]", ips)
+	}
+
+	mx, err := r.LookupMX("example.com.")
+	if err != nil {
+		t.Fatalf("LookupMX failed: %v", err)
+	}
+	if len(mx) != 2 || mx[0].Host != "mx1.example.com." || mx[1].Host != "mx2.example.com." {
+		t.Errorf("LookupMX = %v, want mx1 and mx2 in order of preference", mx)
+	}
+
+	if _, err := r.LookupTXT("missing.example.com."); err == nil {
+		t.Error("LookupTXT of a missing name succeeded")
+	} else if e, ok := err.(*DNSError); !ok || e.Err != noSuchHost {
+		t.Errorf("LookupTXT of a missing name: got %v, want %q error", err, noSuchHost)
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	tests := []struct {
+		cache       bool
+		name        string
+		wantQueries int
+	}{
+		{false, "example.com.", 3},
+		{true, "example.com.", 1},
+		{true, "nocache.example.com.", 3},
+	}
+	for _, tt := range tests {
+		s := newFakeDNSServer(t, exampleZone)
+		r := &Resolver{Servers: []string{s.LocalAddr().String()}, Cache: tt.cache}
+		for i := 0; i < 3; i++ {
+			if _, err := r.LookupTXT(tt.name); err != nil {
+				t.Fatalf("LookupTXT(%q) failed: %v", tt.name, err)
+			}
+		}
+		if n := s.numQueries(); n != tt.wantQueries {
+			t.Errorf("Cache=%v, LookupTXT(%q) three times: server saw %d queries, want %d", tt.cache, tt.name, n, tt.wantQueries)
+		}
+		s.Close()
+	}
+}
+
+func TestResolverTimeout(t *testing.T) {
+	s := newFakeDNSServer(t, func(dnsmessage.Question) ([]dnsmessage.Resource, bool) { return nil, true })
+	defer s.Close()
+	r := &Resolver{
+		Servers:  []string{s.LocalAddr().String()},
+		Timeout:  50 * time.Millisecond,
+		Attempts: 2,
+	}
+
+	start := time.Now()
+	_, err := r.LookupTXT("example.com.")
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("lookup took %v, want about 100ms", d)
+	}
+	if e, ok := err.(*DNSError); !ok || !e.Timeout() {
+		t.Errorf("got %v, want a timeout error", err)
+	}
+	if n := s.numQueries(); n != 2 {
+		t.Errorf("server saw %d queries, want 2", n)
+	}
+}
+
+func TestResolverDial(t *testing.T) {
+	s := newFakeDNSServer(t, exampleZone)
+	defer s.Close()
+
+	var dialed []string
+	r := &Resolver{
+		Servers: []string{"192.0.2.53", "[2001:db8::53]:5353"},
+		Dial: func(network, address string) (Conn, error) {
+			dialed = append(dialed, network+" "+address)
+			if len(dialed) == 1 {
+				return nil, errors.New("unreachable")
+			}
+			return Dial(network, s.LocalAddr().String())
+		},
+	}
+	txt, err := r.LookupTXT("example.com.")
+	if err != nil {
+		t.Fatalf("LookupTXT failed: %v", err)
+	}
+	if len(txt) != 1 || txt[0] != "hello" {
+		t.Errorf("LookupTXT = %q, want [hello]", txt)
+	}
+	want := []string{"udp 192.0.2.53:53", "udp [2001:db8::53]:5353"}
+	if !reflect.DeepEqual(dialed, want) {
+		t.Errorf("dialed %q, want %q", dialed, want)
+	}
+}
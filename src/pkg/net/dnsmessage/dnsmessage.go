@@ -0,0 +1,331 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dnsmessage provides a mostly RFC 1035 compliant implementation of
+// DNS message packing and unpacking.
+//
+// The Parser reads a message one section and one record at a time, so a
+// caller can skip what it does not need without allocating. The Builder
+// writes a message in the same order into a caller-supplied buffer. Message
+// packs and unpacks a whole message at once and is the most convenient
+// when performance does not matter.
+//
+// This package does not depend on package net, which uses it to implement
+// its DNS client.
+package dnsmessage
+
+import (
+	"errors"
+	"strconv"
+)
+
+// A Type is a type of DNS request and response.
+type Type uint16
+
+const (
+	// ResourceHeader.Type and Question.Type
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeSOA   Type = 6
+	TypePTR   Type = 12
+	TypeMX    Type = 15
+	TypeTXT   Type = 16
+	TypeAAAA  Type = 28
+	TypeSRV   Type = 33
+	TypeOPT   Type = 41
+
+	// Question.Type
+	TypeWKS   Type = 11
+	TypeHINFO Type = 13
+	TypeMINFO Type = 14
+	TypeAXFR  Type = 252
+	TypeALL   Type = 255
+)
+
+var typeNames = map[Type]string{
+	TypeA:     "TypeA",
+	TypeNS:    "TypeNS",
+	TypeCNAME: "TypeCNAME",
+	TypeSOA:   "TypeSOA",
+	TypePTR:   "TypePTR",
+	TypeMX:    "TypeMX",
+	TypeTXT:   "TypeTXT",
+	TypeAAAA:  "TypeAAAA",
+	TypeSRV:   "TypeSRV",
+	TypeOPT:   "TypeOPT",
+	TypeWKS:   "TypeWKS",
+	TypeHINFO: "TypeHINFO",
+	TypeMINFO: "TypeMINFO",
+	TypeAXFR:  "TypeAXFR",
+	TypeALL:   "TypeALL",
+}
+
+func (t Type) String() string {
+	if n, ok := typeNames[t]; ok {
+		return n
+	}
+	return strconv.Itoa(int(t))
+}
+
+// A Class is a type of network.
+type Class uint16
+
+const (
+	// ResourceHeader.Class and Question.Class
+	ClassINET   Class = 1
+	ClassCSNET  Class = 2
+	ClassCHAOS  Class = 3
+	ClassHESIOD Class = 4
+
+	// Question.Class
+	ClassANY Class = 255
+)
+
+var classNames = map[Class]string{
+	ClassINET:   "ClassINET",
+	ClassCSNET:  "ClassCSNET",
+	ClassCHAOS:  "ClassCHAOS",
+	ClassHESIOD: "ClassHESIOD",
+	ClassANY:    "ClassANY",
+}
+
+func (c Class) String() string {
+	if n, ok := classNames[c]; ok {
+		return n
+	}
+	return strconv.Itoa(int(c))
+}
+
+// An OpCode is a DNS operation code.
+type OpCode uint16
+
+// An RCode is a DNS response status code.
+type RCode uint16
+
+const (
+	// Header.RCode
+	RCodeSuccess        RCode = 0
+	RCodeFormatError    RCode = 1
+	RCodeServerFailure  RCode = 2
+	RCodeNameError      RCode = 3
+	RCodeNotImplemented RCode = 4
+	RCodeRefused        RCode = 5
+)
+
+var rCodeNames = map[RCode]string{
+	RCodeSuccess:        "RCodeSuccess",
+	RCodeFormatError:    "RCodeFormatError",
+	RCodeServerFailure:  "RCodeServerFailure",
+	RCodeNameError:      "RCodeNameError",
+	RCodeNotImplemented: "RCodeNotImplemented",
+	RCodeRefused:        "RCodeRefused",
+}
+
+func (r RCode) String() string {
+	if n, ok := rCodeNames[r]; ok {
+		return n
+	}
+	return strconv.Itoa(int(r))
+}
+
+var (
+	// ErrNotStarted indicates that the prerequisite information isn't
+	// available yet because the previous records haven't been
+	// appropriately parsed, skipped or finished.
+	ErrNotStarted = errors.New("dnsmessage: parsing/packing of this type isn't available yet")
+
+	// ErrSectionDone indicates that all records in the section have
+	// been parsed or finished.
+	ErrSectionDone = errors.New("dnsmessage: parsing/packing of this section has completed")
+
+	errBaseLen            = errors.New("dnsmessage: insufficient data for base length type")
+	errCalcLen            = errors.New("dnsmessage: insufficient data for calculated length type")
+	errReserved           = errors.New("dnsmessage: segment prefix is reserved")
+	errTooManyPtr         = errors.New("dnsmessage: too many pointers (>10)")
+	errInvalidPtr         = errors.New("dnsmessage: invalid pointer")
+	errNilResourceBody    = errors.New("dnsmessage: nil resource body")
+	errResourceLen        = errors.New("dnsmessage: insufficient data for resource body length")
+	errSegTooLong         = errors.New("dnsmessage: segment length too long")
+	errZeroSegLen         = errors.New("dnsmessage: zero length segment")
+	errResTooLong         = errors.New("dnsmessage: resource length too long")
+	errTooManyQuestions   = errors.New("dnsmessage: too many Questions to pack (>65535)")
+	errTooManyAnswers     = errors.New("dnsmessage: too many Answers to pack (>65535)")
+	errTooManyAuthorities = errors.New("dnsmessage: too many Authorities to pack (>65535)")
+	errTooManyAdditionals = errors.New("dnsmessage: too many Additionals to pack (>65535)")
+	errNonCanonicalName   = errors.New("dnsmessage: name is not in canonical format (it must end with a .)")
+	errTooLongName        = errors.New("dnsmessage: name too long (>255 bytes)")
+	errStringTooLong      = errors.New("dnsmessage: character string exceeds maximum length (255)")
+)
+
+// Internal constants.
+const (
+	// packStartingCap is the default initial buffer size allocated during
+	// packing.
+	//
+	// The starting capacity doesn't matter too much, but most DNS responses
+	// will be <= 512 bytes as it is the limit for DNS over UDP without
+	// EDNS(0).
+	packStartingCap = 512
+
+	// uint16Len is the length (in bytes) of a uint16.
+	uint16Len = 2
+
+	// uint32Len is the length (in bytes) of a uint32.
+	uint32Len = 4
+
+	// headerLen is the length (in bytes) of a DNS header.
+	//
+	// A header is comprised of 6 uint16s and no padding.
+	headerLen = 6 * uint16Len
+)
+
+// Header bits.
+const (
+	headerBitQR = 1 << 15 // query/response (response=1)
+	headerBitAA = 1 << 10 // authoritative
+	headerBitTC = 1 << 9  // truncated
+	headerBitRD = 1 << 8  // recursion desired
+	headerBitRA = 1 << 7  // recursion available
+)
+
+// Header is a representation of a DNS message header.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	OpCode             OpCode
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	RCode              RCode
+}
+
+func (m *Header) pack() (id uint16, bits uint16) {
+	id = m.ID
+	bits = uint16(m.OpCode)<<11 | uint16(m.RCode)&0xF
+	if m.RecursionAvailable {
+		bits |= headerBitRA
+	}
+	if m.RecursionDesired {
+		bits |= headerBitRD
+	}
+	if m.Truncated {
+		bits |= headerBitTC
+	}
+	if m.Authoritative {
+		bits |= headerBitAA
+	}
+	if m.Response {
+		bits |= headerBitQR
+	}
+	return
+}
+
+// A section is one of the parts of a message, in the order they appear.
+type section uint8
+
+const (
+	sectionNotStarted section = iota
+	sectionHeader
+	sectionQuestions
+	sectionAnswers
+	sectionAuthorities
+	sectionAdditionals
+	sectionDone
+)
+
+// header is the wire format for a DNS message header.
+type header struct {
+	id          uint16
+	bits        uint16
+	questions   uint16
+	answers     uint16
+	authorities uint16
+	additionals uint16
+}
+
+func (h *header) count(sec section) uint16 {
+	switch sec {
+	case sectionQuestions:
+		return h.questions
+	case sectionAnswers:
+		return h.answers
+	case sectionAuthorities:
+		return h.authorities
+	case sectionAdditionals:
+		return h.additionals
+	}
+	return 0
+}
+
+// pack writes the header into msg, which must be at least headerLen bytes
+// long.
+func (h *header) pack(msg []byte) {
+	putUint16(msg[0:], h.id)
+	putUint16(msg[2:], h.bits)
+	putUint16(msg[4:], h.questions)
+	putUint16(msg[6:], h.answers)
+	putUint16(msg[8:], h.authorities)
+	putUint16(msg[10:], h.additionals)
+}
+
+func (h *header) unpack(msg []byte, off int) (int, error) {
+	if off+headerLen > len(msg) {
+		return off, errBaseLen
+	}
+	h.id = getUint16(msg[off:])
+	h.bits = getUint16(msg[off+2:])
+	h.questions = getUint16(msg[off+4:])
+	h.answers = getUint16(msg[off+6:])
+	h.authorities = getUint16(msg[off+8:])
+	h.additionals = getUint16(msg[off+10:])
+	return off + headerLen, nil
+}
+
+func (h *header) header() Header {
+	return Header{
+		ID:                 h.id,
+		Response:           (h.bits & headerBitQR) != 0,
+		OpCode:             OpCode(h.bits>>11) & 0xF,
+		Authoritative:      (h.bits & headerBitAA) != 0,
+		Truncated:          (h.bits & headerBitTC) != 0,
+		RecursionDesired:   (h.bits & headerBitRD) != 0,
+		RecursionAvailable: (h.bits & headerBitRA) != 0,
+		RCode:              RCode(h.bits & 0xF),
+	}
+}
+
+func getUint16(b []byte) uint16 { return uint16(b[0])<<8 | uint16(b[1]) }
+
+func getUint32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func putUint16(b []byte, v uint16) {
+	b[0] = byte(v >> 8)
+	b[1] = byte(v)
+}
+
+func appendUint16(msg []byte, v uint16) []byte {
+	return append(msg, byte(v>>8), byte(v))
+}
+
+func appendUint32(msg []byte, v uint32) []byte {
+	return append(msg, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func unpackUint16(msg []byte, off int) (uint16, int, error) {
+	if off+uint16Len > len(msg) {
+		return 0, off, errBaseLen
+	}
+	return getUint16(msg[off:]), off + uint16Len, nil
+}
+
+func unpackUint32(msg []byte, off int) (uint32, int, error) {
+	if off+uint32Len > len(msg) {
+		return 0, off, errBaseLen
+	}
+	return getUint32(msg[off:]), off + uint32Len, nil
+}
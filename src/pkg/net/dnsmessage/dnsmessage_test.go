@@ -0,0 +1,611 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnsmessage
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustNewName(name string) Name {
+	n, err := NewName(name)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func largeTestMsg() Message {
+	name := mustNewName("foo.bar.example.com.")
+	return Message{
+		Header: Header{Response: true, Authoritative: true, RecursionDesired: true},
+		Questions: []Question{
+			{
+				Name:  name,
+				Type:  TypeA,
+				Class: ClassINET,
+			},
+		},
+		Answers: []Resource{
+			{
+				ResourceHeader{Name: name, Type: TypeA, Class: ClassINET, TTL: 300},
+				&AResource{[4]byte{127, 0, 0, 1}},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeA, Class: ClassINET, TTL: 300},
+				&AResource{[4]byte{127, 0, 0, 2}},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeAAAA, Class: ClassINET},
+				&AAAAResource{[16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeCNAME, Class: ClassINET},
+				&CNAMEResource{mustNewName("alias.example.com.")},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeSOA, Class: ClassINET},
+				&SOAResource{
+					NS:      mustNewName("ns1.example.com."),
+					MBox:    mustNewName("mb.example.com."),
+					Serial:  1,
+					Refresh: 2,
+					Retry:   3,
+					Expire:  4,
+					MinTTL:  5,
+				},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypePTR, Class: ClassINET},
+				&PTRResource{mustNewName("ptr.example.com.")},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeMX, Class: ClassINET},
+				&MXResource{7, mustNewName("mx.example.com.")},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeSRV, Class: ClassINET},
+				&SRVResource{8, 9, 11, mustNewName("srv.example.com.")},
+			},
+			{
+				ResourceHeader{Name: name, Type: Type(99), Class: ClassINET},
+				&UnknownResource{Type(99), []byte{1, 2, 3}},
+			},
+		},
+		Authorities: []Resource{
+			{
+				ResourceHeader{Name: name, Type: TypeNS, Class: ClassINET},
+				&NSResource{mustNewName("ns1.example.com.")},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeNS, Class: ClassINET},
+				&NSResource{mustNewName("ns2.example.com.")},
+			},
+		},
+		Additionals: []Resource{
+			{
+				ResourceHeader{Name: name, Type: TypeTXT, Class: ClassINET},
+				&TXTResource{[]string{"So Long, and Thanks for All the Fish"}},
+			},
+			{
+				ResourceHeader{Name: name, Type: TypeTXT, Class: ClassINET},
+				&TXTResource{[]string{"Hamster Huey", "and the Gooey Kablooie", ""}},
+			},
+			{
+				mustEDNS0ResourceHeader(4096, 0xfe0|RCodeSuccess, false),
+				&OPTResource{
+					Options: []Option{
+						{
+							Code: 10, // see RFC 7873
+							Data: []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func mustEDNS0ResourceHeader(l int, extrc RCode, do bool) ResourceHeader {
+	var h ResourceHeader
+	h.SetEDNS0(l, extrc, do)
+	return h
+}
+
+// clearLengths zeroes the Length of each resource header in m, which
+// depends on how names were compressed.
+func clearLengths(m *Message) {
+	for _, rs := range [][]Resource{m.Answers, m.Authorities, m.Additionals} {
+		for i := range rs {
+			rs[i].Header.Length = 0
+		}
+	}
+}
+
+func TestMessagePackUnpack(t *testing.T) {
+	want := largeTestMsg()
+	buf, err := want.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	var got Message
+	if err := got.Unpack(buf); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	clearLengths(&got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Packing again gives the same bytes.
+	buf2, err := got.Pack()
+	if err != nil {
+		t.Fatalf("second Pack: %v", err)
+	}
+	if !bytes.Equal(buf, buf2) {
+		t.Errorf("repacked message differs:\n%x\n%x", buf, buf2)
+	}
+}
+
+func TestAppendPack(t *testing.T) {
+	m := largeTestMsg()
+	want, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	// Compression pointers are relative to the start of the message,
+	// not of the buffer.
+	prefix := []byte{0xde, 0xad}
+	got, err := m.AppendPack(append([]byte(nil), prefix...))
+	if err != nil {
+		t.Fatalf("AppendPack: %v", err)
+	}
+	if !bytes.Equal(got[:2], prefix) || !bytes.Equal(got[2:], want) {
+		t.Errorf("AppendPack = %x; want %x%x", got, prefix, want)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	m := largeTestMsg()
+	compressed, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	b := NewBuilder(nil, m.Header)
+	b.StartQuestions()
+	for _, q := range m.Questions {
+		if err := b.Question(q); err != nil {
+			t.Fatalf("Question: %v", err)
+		}
+	}
+	b.StartAnswers()
+	for _, r := range m.Answers {
+		if err := b.Resource(r); err != nil {
+			t.Fatalf("Resource: %v", err)
+		}
+	}
+	uncompressed, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if len(compressed) >= len(uncompressed) {
+		t.Errorf("compressed message is %d bytes; uncompressed answers alone are %d", len(compressed), len(uncompressed))
+	}
+	var got Message
+	if err := got.Unpack(uncompressed); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	if len(got.Answers) != len(m.Answers) {
+		t.Errorf("got %d answers; want %d", len(got.Answers), len(m.Answers))
+	}
+}
+
+func TestSRVTargetNotCompressed(t *testing.T) {
+	name := mustNewName("srv.example.com.")
+	b := NewBuilder(nil, Header{})
+	b.EnableCompression()
+	b.StartQuestions()
+	b.Question(Question{Name: name, Type: TypeSRV, Class: ClassINET})
+	b.StartAnswers()
+	if err := b.SRVResource(ResourceHeader{Name: name, Class: ClassINET}, SRVResource{Target: name}); err != nil {
+		t.Fatalf("SRVResource: %v", err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	// The target is spelled out in full at the end of the message.
+	want := []byte("\x03srv\x07example\x03com\x00")
+	if !bytes.HasSuffix(msg, want) {
+		t.Errorf("message %x does not end with uncompressed target %x", msg, want)
+	}
+}
+
+func TestParser(t *testing.T) {
+	m := largeTestMsg()
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	var p Parser
+	h, err := p.Start(buf)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if h != m.Header {
+		t.Errorf("Start = %+v; want %+v", h, m.Header)
+	}
+	if _, err := p.AnswerHeader(); err != ErrNotStarted {
+		t.Errorf("AnswerHeader before questions = %v; want %v", err, ErrNotStarted)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatalf("SkipAllQuestions: %v", err)
+	}
+	if _, err := p.Question(); err != ErrSectionDone {
+		t.Errorf("Question after SkipAllQuestions = %v; want %v", err, ErrSectionDone)
+	}
+
+	// Typed access to the first two answers.
+	for i := 0; i < 2; i++ {
+		rh, err := p.AnswerHeader()
+		if err != nil {
+			t.Fatalf("AnswerHeader: %v", err)
+		}
+		if rh.Type != TypeA {
+			t.Fatalf("answer %d type = %v; want %v", i, rh.Type, TypeA)
+		}
+		if _, err := p.AAAAResource(); err != ErrNotStarted {
+			t.Errorf("AAAAResource on an A record = %v; want %v", err, ErrNotStarted)
+		}
+		a, err := p.AResource()
+		if err != nil {
+			t.Fatalf("AResource: %v", err)
+		}
+		if want := m.Answers[i].Body.(*AResource); a != *want {
+			t.Errorf("answer %d = %v; want %v", i, a, *want)
+		}
+	}
+	if _, err := p.AResource(); err != ErrNotStarted {
+		t.Errorf("AResource without a header = %v; want %v", err, ErrNotStarted)
+	}
+
+	// Skip one after parsing its header, one without.
+	if _, err := p.AnswerHeader(); err != nil {
+		t.Fatalf("AnswerHeader: %v", err)
+	}
+	if err := p.SkipAnswer(); err != nil {
+		t.Fatalf("SkipAnswer: %v", err)
+	}
+	if err := p.SkipAnswer(); err != nil {
+		t.Fatalf("SkipAnswer: %v", err)
+	}
+	rest, err := p.AllAnswers()
+	if err != nil {
+		t.Fatalf("AllAnswers: %v", err)
+	}
+	if len(rest) != len(m.Answers)-4 {
+		t.Errorf("AllAnswers returned %d records; want %d", len(rest), len(m.Answers)-4)
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		t.Fatalf("SkipAllAuthorities: %v", err)
+	}
+
+	var opt ResourceHeader
+	for {
+		rh, err := p.AdditionalHeader()
+		if err == ErrSectionDone {
+			break
+		}
+		if err != nil {
+			t.Fatalf("AdditionalHeader: %v", err)
+		}
+		if rh.Type != TypeOPT {
+			if err := p.SkipAdditional(); err != nil {
+				t.Fatalf("SkipAdditional: %v", err)
+			}
+			continue
+		}
+		opt = rh
+		r, err := p.OPTResource()
+		if err != nil {
+			t.Fatalf("OPTResource: %v", err)
+		}
+		if want := m.Additionals[2].Body.(*OPTResource); !reflect.DeepEqual(r, *want) {
+			t.Errorf("OPTResource = %+v; want %+v", r, *want)
+		}
+	}
+	if opt.Class != 4096 || opt.DNSSECAllowed() || opt.ExtendedRCode(RCodeSuccess) != 0xfe0 {
+		t.Errorf("OPT header = %+v", opt)
+	}
+}
+
+func TestEDNS0(t *testing.T) {
+	for _, tt := range []struct {
+		extRCode RCode
+		do       bool
+	}{
+		{RCodeSuccess, false},
+		{RCodeSuccess, true},
+		{0x10 | RCodeServerFailure, false},
+		{0xff0, true},
+	} {
+		h := mustEDNS0ResourceHeader(1232, tt.extRCode, tt.do)
+		if h.Name.String() != "." || h.Type != TypeOPT || h.Class != 1232 {
+			t.Errorf("SetEDNS0(1232, %#x, %v) = %+v", tt.extRCode, tt.do, h)
+		}
+		if got := h.DNSSECAllowed(); got != tt.do {
+			t.Errorf("DNSSECAllowed = %v; want %v", got, tt.do)
+		}
+		if got := h.ExtendedRCode(tt.extRCode & 0xF); got != tt.extRCode {
+			t.Errorf("ExtendedRCode = %#x; want %#x", got, tt.extRCode)
+		}
+	}
+}
+
+func TestBuilderSections(t *testing.T) {
+	var b Builder
+	if err := b.StartQuestions(); err != ErrNotStarted {
+		t.Errorf("StartQuestions on zero Builder = %v; want %v", err, ErrNotStarted)
+	}
+	if _, err := b.Finish(); err != ErrNotStarted {
+		t.Errorf("Finish on zero Builder = %v; want %v", err, ErrNotStarted)
+	}
+
+	b = NewBuilder(nil, Header{})
+	q := Question{Name: mustNewName("example.com."), Type: TypeA, Class: ClassINET}
+	if err := b.Question(q); err != ErrNotStarted {
+		t.Errorf("Question before StartQuestions = %v; want %v", err, ErrNotStarted)
+	}
+	rh := ResourceHeader{Name: q.Name, Class: ClassINET}
+	if err := b.AResource(rh, AResource{}); err != ErrNotStarted {
+		t.Errorf("AResource before StartAnswers = %v; want %v", err, ErrNotStarted)
+	}
+	if err := b.StartAdditionals(); err != nil {
+		t.Fatalf("StartAdditionals: %v", err)
+	}
+	if err := b.StartAnswers(); err != ErrSectionDone {
+		t.Errorf("StartAnswers after StartAdditionals = %v; want %v", err, ErrSectionDone)
+	}
+	if err := b.Question(q); err != ErrSectionDone {
+		t.Errorf("Question after StartAdditionals = %v; want %v", err, ErrSectionDone)
+	}
+	if err := b.AResource(rh, AResource{}); err != nil {
+		t.Fatalf("AResource: %v", err)
+	}
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	var m Message
+	if err := m.Unpack(msg); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	if len(m.Questions) != 0 || len(m.Answers) != 0 || len(m.Additionals) != 1 {
+		t.Errorf("got %d questions, %d answers, %d additionals; want 0, 0, 1", len(m.Questions), len(m.Answers), len(m.Additionals))
+	}
+	if err := b.AResource(rh, AResource{}); err != ErrSectionDone {
+		t.Errorf("AResource after Finish = %v; want %v", err, ErrSectionDone)
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body ResourceBody
+		err  error
+	}{
+		{"example.com", &AResource{}, errNonCanonicalName},
+		{"", &AResource{}, errNonCanonicalName},
+		{"a..com.", &AResource{}, errZeroSegLen},
+		{strings.Repeat("a", 64) + ".com.", &AResource{}, errSegTooLong},
+		{"example.com.", nil, errNilResourceBody},
+		{"example.com.", &TXTResource{[]string{strings.Repeat("a", 256)}}, errStringTooLong},
+	}
+	for _, tt := range tests {
+		m := Message{Answers: []Resource{{ResourceHeader{Name: mustNewName(tt.name)}, tt.body}}}
+		if _, err := m.Pack(); err != tt.err {
+			t.Errorf("Pack with name %q, body %T: got %v; want %v", tt.name, tt.body, err, tt.err)
+		}
+	}
+	if _, err := NewName(strings.Repeat("a", 256)); err != errTooLongName {
+		t.Errorf("NewName with 256 bytes: got %v; want %v", err, errTooLongName)
+	}
+}
+
+func TestRootName(t *testing.T) {
+	m := Message{Questions: []Question{{Name: mustNewName("."), Type: TypeNS, Class: ClassINET}}}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if want := []byte{0, 0, 2, 0, 1}; !bytes.Equal(buf[headerLen:], want) {
+		t.Errorf("packed root question = %x; want %x", buf[headerLen:], want)
+	}
+	var got Message
+	if err := got.Unpack(buf); err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	if n := got.Questions[0].Name.String(); n != "." {
+		t.Errorf("unpacked name = %q; want %q", n, ".")
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	// A header announcing a single question.
+	const hdr = "0000 0000 0001 0000 0000 0000 "
+	tests := []struct {
+		desc string
+		hex  string
+		err  error
+	}{
+		{"short header", "0000 0000", errBaseLen},
+		{"pointer loop", hdr + "c00c 0001 0001", errTooManyPtr},
+		{"reserved label", hdr + "4000 0001 0001", errReserved},
+		{"truncated label", hdr + "0561 62", errCalcLen},
+		{"truncated pointer", hdr + "c0", errInvalidPtr},
+		{"truncated question", hdr + "00 0001", errBaseLen},
+	}
+	for _, tt := range tests {
+		b, err := hex.DecodeString(strings.Replace(tt.hex, " ", "", -1))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		var m Message
+		if err := m.Unpack(b); err != tt.err {
+			t.Errorf("%s: Unpack = %v; want %v", tt.desc, err, tt.err)
+		}
+	}
+}
+
+func TestLongNameUnpack(t *testing.T) {
+	// Four 63-byte labels make a 256-byte name once the dots are added.
+	label := "3f" + strings.Repeat("61", 63)
+	b, err := hex.DecodeString("000000000001000000000000" + strings.Repeat(label, 4) + "00" + "00010001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := m.Unpack(b); err != errTooLongName {
+		t.Errorf("Unpack = %v; want %v", err, errTooLongName)
+	}
+}
+
+func TestCorruptSRVReply(t *testing.T) {
+	// The last record's length is 0xff instead of 0x21; it runs past
+	// the end of the message.
+	b, err := hex.DecodeString(dnsSRVCorruptReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := m.Unpack(b); err != errResourceLen {
+		t.Errorf("Unpack = %v; want %v", err, errResourceLen)
+	}
+
+	// A Parser can still read the records before it.
+	var p Parser
+	if _, err := p.Start(b); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatalf("SkipAllQuestions: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := p.AnswerHeader(); err != nil {
+			t.Fatalf("AnswerHeader %d: %v", i, err)
+		}
+		srv, err := p.SRVResource()
+		if err != nil {
+			t.Fatalf("SRVResource %d: %v", i, err)
+		}
+		if srv.Port != 5269 {
+			t.Errorf("SRVResource %d port = %d; want 5269", i, srv.Port)
+		}
+	}
+	if _, err := p.AnswerHeader(); err != nil {
+		t.Fatalf("AnswerHeader 4: %v", err)
+	}
+	if _, err := p.SRVResource(); err != errResourceLen {
+		t.Errorf("SRVResource 4 = %v; want %v", err, errResourceLen)
+	}
+}
+
+func TestTypeString(t *testing.T) {
+	for _, tt := range []struct {
+		s    fmt.Stringer
+		want string
+	}{
+		{TypeAAAA, "TypeAAAA"},
+		{Type(1234), "1234"},
+		{ClassINET, "ClassINET"},
+		{RCodeNameError, "RCodeNameError"},
+	} {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("String() = %q; want %q", got, tt.want)
+		}
+	}
+}
+
+// Corrupt DNS SRV reply, with its final RR having a bogus length.
+const dnsSRVCorruptReply = "0901818000010005000000000c5f786d70702d736572766572045f74637006676f6f67" +
+	"6c6503636f6d0000210001c00c002100010000012c00210014000014950c786d70702d" +
+	"73657276657234016c06676f6f676c6503636f6d00c00c002100010000012c00210014" +
+	"000014950c786d70702d73657276657232016c06676f6f676c6503636f6d00c00c0021" +
+	"00010000012c00210014000014950c786d70702d73657276657233016c06676f6f676c" +
+	"6503636f6d00c00c002100010000012c00200005000014950b786d70702d7365727665" +
+	"72016c06676f6f676c6503636f6d00c00c002100010000012c00FF0014000014950c78" +
+	"6d70702d73657276657231016c06676f6f676c6503636f6d00"
+
+func BenchmarkBuildQuery(b *testing.B) {
+	buf := make([]byte, 0, packStartingCap)
+	q := Question{Name: mustNewName("foo.bar.example.com."), Type: TypeA, Class: ClassINET}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		bld := NewBuilder(buf[:0], Header{ID: 1, RecursionDesired: true})
+		bld.StartQuestions()
+		bld.Question(q)
+		if _, err := bld.Finish(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseA(b *testing.B) {
+	m := largeTestMsg()
+	buf, err := m.Pack()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var p Parser
+		if _, err := p.Start(buf); err != nil {
+			b.Fatal(err)
+		}
+		if err := p.SkipAllQuestions(); err != nil {
+			b.Fatal(err)
+		}
+		for {
+			h, err := p.AnswerHeader()
+			if err == ErrSectionDone {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			if h.Type != TypeA {
+				if err := p.SkipAnswer(); err != nil {
+					b.Fatal(err)
+				}
+				continue
+			}
+			if _, err := p.AResource(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkPackUnpack(b *testing.B) {
+	m := largeTestMsg()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		msg, err := m.AppendPack(buf[:0])
+		if err != nil {
+			b.Fatal(err)
+		}
+		var m2 Message
+		if err := m2.Unpack(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnsmessage
+
+// A Parser allows incrementally parsing a DNS message.
+//
+// When parsing is started, the Header is parsed. Next, each Question can be
+// either parsed or skipped. Alternatively, all Questions can be skipped at
+// once. When all Questions have been parsed, attempting to parse Questions
+// will return ErrSectionDone. The same is true for the Answers, Authorities
+// and Additionals sections, which must be handled in that order.
+//
+// A resource's header is parsed before its body. After a header has been
+// parsed, the body can be read with Answer, Authority or Additional, with
+// the typed method such as AResource, or skipped. Parsing a header twice
+// returns the same header without advancing.
+//
+// A Parser does not copy the message it is given; the Names and Resources
+// it returns do not refer to it.
+type Parser struct {
+	msg    []byte
+	header header
+
+	section        section
+	off            int
+	index          int
+	resHeaderValid bool
+	resHeader      ResourceHeader
+}
+
+// Start parses the header and enables the parsing of Questions.
+func (p *Parser) Start(msg []byte) (Header, error) {
+	*p = Parser{msg: msg}
+	var err error
+	if p.off, err = p.header.unpack(msg, 0); err != nil {
+		return Header{}, err
+	}
+	p.section = sectionQuestions
+	return p.header.header(), nil
+}
+
+func (p *Parser) checkAdvance(sec section) error {
+	if p.section < sec {
+		return ErrNotStarted
+	}
+	if p.section > sec {
+		return ErrSectionDone
+	}
+	p.resHeaderValid = false
+	if p.index == int(p.header.count(sec)) {
+		p.index = 0
+		p.section++
+		return ErrSectionDone
+	}
+	return nil
+}
+
+func (p *Parser) resource(sec section) (Resource, error) {
+	var r Resource
+	var err error
+	r.Header, err = p.resourceHeader(sec)
+	if err != nil {
+		return r, err
+	}
+	p.resHeaderValid = false
+	r.Body, err = unpackResourceBody(p.msg, p.off, r.Header)
+	if err != nil {
+		return Resource{}, err
+	}
+	p.off += int(r.Header.Length)
+	p.index++
+	return r, nil
+}
+
+func (p *Parser) resourceHeader(sec section) (ResourceHeader, error) {
+	if p.resHeaderValid {
+		return p.resHeader, nil
+	}
+	if err := p.checkAdvance(sec); err != nil {
+		return ResourceHeader{}, err
+	}
+	var hdr ResourceHeader
+	off, err := hdr.unpack(p.msg, p.off)
+	if err != nil {
+		return ResourceHeader{}, err
+	}
+	p.resHeaderValid = true
+	p.resHeader = hdr
+	p.off = off
+	return hdr, nil
+}
+
+func (p *Parser) skipResource(sec section) error {
+	if p.resHeaderValid {
+		newOff := p.off + int(p.resHeader.Length)
+		if newOff > len(p.msg) {
+			return errResourceLen
+		}
+		p.off = newOff
+		p.resHeaderValid = false
+		p.index++
+		return nil
+	}
+	if err := p.checkAdvance(sec); err != nil {
+		return err
+	}
+	var err error
+	p.off, err = skipResource(p.msg, p.off)
+	if err != nil {
+		return err
+	}
+	p.index++
+	return nil
+}
+
+// skipResource returns the offset of the first byte after the resource
+// record starting at off.
+func skipResource(msg []byte, off int) (int, error) {
+	newOff, err := skipName(msg, off)
+	if err != nil {
+		return off, err
+	}
+	// Skip type, class and TTL.
+	newOff += uint16Len + uint16Len + uint32Len
+	length, newOff, err := unpackUint16(msg, newOff)
+	if err != nil {
+		return off, err
+	}
+	if newOff += int(length); newOff > len(msg) {
+		return off, errResourceLen
+	}
+	return newOff, nil
+}
+
+// Question parses a single Question.
+func (p *Parser) Question() (Question, error) {
+	if err := p.checkAdvance(sectionQuestions); err != nil {
+		return Question{}, err
+	}
+	var q Question
+	off, err := q.unpack(p.msg, p.off)
+	if err != nil {
+		return Question{}, err
+	}
+	p.off = off
+	p.index++
+	return q, nil
+}
+
+// AllQuestions parses all Questions.
+func (p *Parser) AllQuestions() ([]Question, error) {
+	// Multiple questions are valid according to the spec,
+	// but servers don't actually support them. There will
+	// be at most one question here.
+	//
+	// Do not pre-allocate based on info in p.header, since
+	// the data is untrusted.
+	qs := []Question{}
+	for {
+		q, err := p.Question()
+		if err == ErrSectionDone {
+			return qs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+}
+
+// SkipQuestion skips a single Question.
+func (p *Parser) SkipQuestion() error {
+	if err := p.checkAdvance(sectionQuestions); err != nil {
+		return err
+	}
+	off, err := skipName(p.msg, p.off)
+	if err != nil {
+		return err
+	}
+	// Skip type and class.
+	if off += 2 * uint16Len; off > len(p.msg) {
+		return errBaseLen
+	}
+	p.off = off
+	p.index++
+	return nil
+}
+
+// SkipAllQuestions skips all Questions.
+func (p *Parser) SkipAllQuestions() error {
+	for {
+		if err := p.SkipQuestion(); err == ErrSectionDone {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// AnswerHeader parses a single Answer ResourceHeader.
+func (p *Parser) AnswerHeader() (ResourceHeader, error) {
+	return p.resourceHeader(sectionAnswers)
+}
+
+// Answer parses a single Answer Resource.
+func (p *Parser) Answer() (Resource, error) {
+	return p.resource(sectionAnswers)
+}
+
+// AllAnswers parses all Answer Resources.
+func (p *Parser) AllAnswers() ([]Resource, error) {
+	return p.allResources(sectionAnswers)
+}
+
+// SkipAnswer skips a single Answer Resource.
+func (p *Parser) SkipAnswer() error {
+	return p.skipResource(sectionAnswers)
+}
+
+// SkipAllAnswers skips all Answer Resources.
+func (p *Parser) SkipAllAnswers() error {
+	return p.skipAllResources(sectionAnswers)
+}
+
+// AuthorityHeader parses a single Authority ResourceHeader.
+func (p *Parser) AuthorityHeader() (ResourceHeader, error) {
+	return p.resourceHeader(sectionAuthorities)
+}
+
+// Authority parses a single Authority Resource.
+func (p *Parser) Authority() (Resource, error) {
+	return p.resource(sectionAuthorities)
+}
+
+// AllAuthorities parses all Authority Resources.
+func (p *Parser) AllAuthorities() ([]Resource, error) {
+	return p.allResources(sectionAuthorities)
+}
+
+// SkipAuthority skips a single Authority Resource.
+func (p *Parser) SkipAuthority() error {
+	return p.skipResource(sectionAuthorities)
+}
+
+// SkipAllAuthorities skips all Authority Resources.
+func (p *Parser) SkipAllAuthorities() error {
+	return p.skipAllResources(sectionAuthorities)
+}
+
+// AdditionalHeader parses a single Additional ResourceHeader.
+func (p *Parser) AdditionalHeader() (ResourceHeader, error) {
+	return p.resourceHeader(sectionAdditionals)
+}
+
+// Additional parses a single Additional Resource.
+func (p *Parser) Additional() (Resource, error) {
+	return p.resource(sectionAdditionals)
+}
+
+// AllAdditionals parses all Additional Resources.
+func (p *Parser) AllAdditionals() ([]Resource, error) {
+	return p.allResources(sectionAdditionals)
+}
+
+// SkipAdditional skips a single Additional Resource.
+func (p *Parser) SkipAdditional() error {
+	return p.skipResource(sectionAdditionals)
+}
+
+// SkipAllAdditionals skips all Additional Resources.
+func (p *Parser) SkipAllAdditionals() error {
+	return p.skipAllResources(sectionAdditionals)
+}
+
+func (p *Parser) allResources(sec section) ([]Resource, error) {
+	// Do not pre-allocate based on info in p.header, since
+	// the data is untrusted.
+	rs := []Resource{}
+	for {
+		r, err := p.resource(sec)
+		if err == ErrSectionDone {
+			return rs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+}
+
+func (p *Parser) skipAllResources(sec section) error {
+	for {
+		if err := p.skipResource(sec); err == ErrSectionDone {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// body checks that a ResourceHeader of type t has just been parsed and
+// returns the offset of its body.
+func (p *Parser) body(t Type) (int, error) {
+	if !p.resHeaderValid || p.resHeader.Type != t {
+		return 0, ErrNotStarted
+	}
+	return p.off, nil
+}
+
+// finishBody advances past the body of the current resource.
+func (p *Parser) finishBody() {
+	p.off += int(p.resHeader.Length)
+	p.resHeaderValid = false
+	p.index++
+}
+
+// AResource parses a single AResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) AResource() (AResource, error) {
+	off, err := p.body(TypeA)
+	if err != nil {
+		return AResource{}, err
+	}
+	r, err := unpackAResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return AResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// NSResource parses a single NSResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) NSResource() (NSResource, error) {
+	off, err := p.body(TypeNS)
+	if err != nil {
+		return NSResource{}, err
+	}
+	r, err := unpackNSResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return NSResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// CNAMEResource parses a single CNAMEResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) CNAMEResource() (CNAMEResource, error) {
+	off, err := p.body(TypeCNAME)
+	if err != nil {
+		return CNAMEResource{}, err
+	}
+	r, err := unpackCNAMEResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return CNAMEResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// SOAResource parses a single SOAResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) SOAResource() (SOAResource, error) {
+	off, err := p.body(TypeSOA)
+	if err != nil {
+		return SOAResource{}, err
+	}
+	r, err := unpackSOAResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return SOAResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// PTRResource parses a single PTRResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) PTRResource() (PTRResource, error) {
+	off, err := p.body(TypePTR)
+	if err != nil {
+		return PTRResource{}, err
+	}
+	r, err := unpackPTRResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return PTRResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// MXResource parses a single MXResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) MXResource() (MXResource, error) {
+	off, err := p.body(TypeMX)
+	if err != nil {
+		return MXResource{}, err
+	}
+	r, err := unpackMXResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return MXResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// TXTResource parses a single TXTResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) TXTResource() (TXTResource, error) {
+	off, err := p.body(TypeTXT)
+	if err != nil {
+		return TXTResource{}, err
+	}
+	r, err := unpackTXTResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return TXTResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// AAAAResource parses a single AAAAResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) AAAAResource() (AAAAResource, error) {
+	off, err := p.body(TypeAAAA)
+	if err != nil {
+		return AAAAResource{}, err
+	}
+	r, err := unpackAAAAResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return AAAAResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// SRVResource parses a single SRVResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) SRVResource() (SRVResource, error) {
+	off, err := p.body(TypeSRV)
+	if err != nil {
+		return SRVResource{}, err
+	}
+	r, err := unpackSRVResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return SRVResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// OPTResource parses a single OPTResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) OPTResource() (OPTResource, error) {
+	off, err := p.body(TypeOPT)
+	if err != nil {
+		return OPTResource{}, err
+	}
+	r, err := unpackOPTResource(p.msg, off, p.resHeader.Length)
+	if err != nil {
+		return OPTResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
+
+// UnknownResource parses a single UnknownResource.
+//
+// One of the XXXHeader methods must have been called before calling this
+// method.
+func (p *Parser) UnknownResource() (UnknownResource, error) {
+	if !p.resHeaderValid {
+		return UnknownResource{}, ErrNotStarted
+	}
+	r, err := unpackUnknownResource(p.resHeader.Type, p.msg, p.off, p.resHeader.Length)
+	if err != nil {
+		return UnknownResource{}, err
+	}
+	p.finishBody()
+	return r, nil
+}
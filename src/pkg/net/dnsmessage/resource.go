@@ -0,0 +1,662 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dnsmessage
+
+// A Question is a DNS query.
+type Question struct {
+	Name  Name
+	Type  Type
+	Class Class
+}
+
+// pack appends the wire format of the Question to msg.
+func (q *Question) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	msg, err := q.Name.pack(msg, compression, compressionOff)
+	if err != nil {
+		return msg, err
+	}
+	msg = appendUint16(msg, uint16(q.Type))
+	return appendUint16(msg, uint16(q.Class)), nil
+}
+
+func (q *Question) unpack(msg []byte, off int) (int, error) {
+	newOff, err := q.Name.unpack(msg, off)
+	if err != nil {
+		return off, err
+	}
+	typ, newOff, err := unpackUint16(msg, newOff)
+	if err != nil {
+		return off, err
+	}
+	class, newOff, err := unpackUint16(msg, newOff)
+	if err != nil {
+		return off, err
+	}
+	q.Type, q.Class = Type(typ), Class(class)
+	return newOff, nil
+}
+
+// A ResourceHeader is the header of a DNS resource record. There are
+// many types of DNS resource records, but they all share the same header.
+type ResourceHeader struct {
+	// Name is the domain name for which this resource record pertains.
+	Name Name
+
+	// Type is the type of DNS resource record.
+	//
+	// This field will be set automatically during packing.
+	Type Type
+
+	// Class is the class of network to which this DNS resource record
+	// pertains.
+	Class Class
+
+	// TTL is the length of time (measured in seconds) which this resource
+	// record is valid for (time to live). All Resources in a set should
+	// have the same TTL (RFC 2181 Section 5.2).
+	TTL uint32
+
+	// Length is the length of data in the resource record after the
+	// header.
+	//
+	// This field will be set automatically during packing.
+	Length uint16
+}
+
+// pack appends the wire format of the ResourceHeader to msg.
+//
+// lenOff is the offset in msg where the Length field was packed.
+func (h *ResourceHeader) pack(msg []byte, compression map[string]int, compressionOff int) (newMsg []byte, lenOff int, err error) {
+	oldMsg := msg
+	if msg, err = h.Name.pack(msg, compression, compressionOff); err != nil {
+		return oldMsg, 0, err
+	}
+	msg = appendUint16(msg, uint16(h.Type))
+	msg = appendUint16(msg, uint16(h.Class))
+	msg = appendUint32(msg, h.TTL)
+	lenOff = len(msg)
+	msg = appendUint16(msg, h.Length)
+	return msg, lenOff, nil
+}
+
+func (h *ResourceHeader) unpack(msg []byte, off int) (int, error) {
+	newOff, err := h.Name.unpack(msg, off)
+	if err != nil {
+		return off, err
+	}
+	if newOff+3*uint16Len+uint32Len > len(msg) {
+		return off, errBaseLen
+	}
+	h.Type = Type(getUint16(msg[newOff:]))
+	h.Class = Class(getUint16(msg[newOff+2:]))
+	h.TTL = getUint32(msg[newOff+4:])
+	h.Length = getUint16(msg[newOff+8:])
+	return newOff + 3*uint16Len + uint32Len, nil
+}
+
+// fixLen updates a packed ResourceHeader to include the length of the
+// ResourceBody.
+//
+// lenOff is the offset of the ResourceHeader.Length field in msg.
+//
+// preLen is the length that msg was before the ResourceBody was packed.
+func (h *ResourceHeader) fixLen(msg []byte, lenOff int, preLen int) error {
+	conLen := len(msg) - preLen
+	if conLen > int(^uint16(0)) {
+		return errResTooLong
+	}
+
+	// Fill in the length now that we know how long the content is.
+	putUint16(msg[lenOff:], uint16(conLen))
+	h.Length = uint16(conLen)
+
+	return nil
+}
+
+// EDNS(0) wire constants.
+const (
+	edns0Version = 0
+
+	edns0DNSSECOK     = 0x00008000
+	ednsVersionMask   = 0x00ff0000
+	edns0DNSSECOKMask = 0x00ff8000
+)
+
+// SetEDNS0 configures h for EDNS(0).
+//
+// The provided extRCode must be an extended RCode.
+func (h *ResourceHeader) SetEDNS0(udpPayloadLen int, extRCode RCode, dnssecOK bool) {
+	h.Name = Name{Data: [255]byte{'.'}, Length: 1} // RFC 6891 section 6.1.2
+	h.Type = TypeOPT
+	h.Class = Class(udpPayloadLen)
+	h.TTL = uint32(extRCode) >> 4 << 24
+	if dnssecOK {
+		h.TTL |= edns0DNSSECOK
+	}
+}
+
+// DNSSECAllowed reports whether the DNSSEC OK bit is set.
+func (h *ResourceHeader) DNSSECAllowed() bool {
+	return h.TTL&edns0DNSSECOKMask == edns0DNSSECOK // RFC 6891 section 6.1.3
+}
+
+// ExtendedRCode returns an extended RCode.
+//
+// The provided rcode must be the RCode in DNS message header.
+func (h *ResourceHeader) ExtendedRCode(rcode RCode) RCode {
+	if h.TTL&ednsVersionMask == edns0Version { // RFC 6891 section 6.1.3
+		return RCode(h.TTL>>24<<4) | rcode
+	}
+	return rcode
+}
+
+// A Resource is a DNS resource record.
+type Resource struct {
+	Header ResourceHeader
+	Body   ResourceBody
+}
+
+// A ResourceBody is a DNS resource record minus the header.
+type ResourceBody interface {
+	// pack appends the wire format of the ResourceBody to msg.
+	pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error)
+
+	// realType returns the actual type of the Resource. This is used to
+	// fill in the header Type field.
+	realType() Type
+}
+
+// pack appends the wire format of the Resource to msg.
+func (r *Resource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	if r.Body == nil {
+		return msg, errNilResourceBody
+	}
+	oldMsg := msg
+	r.Header.Type = r.Body.realType()
+	msg, lenOff, err := r.Header.pack(msg, compression, compressionOff)
+	if err != nil {
+		return msg, err
+	}
+	preLen := len(msg)
+	msg, err = r.Body.pack(msg, compression, compressionOff)
+	if err != nil {
+		return oldMsg, err
+	}
+	if err := r.Header.fixLen(msg, lenOff, preLen); err != nil {
+		return oldMsg, err
+	}
+	return msg, nil
+}
+
+// unpackResourceBody unpacks the body of the record described by hdr,
+// which starts at off.
+func unpackResourceBody(msg []byte, off int, hdr ResourceHeader) (ResourceBody, error) {
+	var (
+		r   ResourceBody
+		err error
+	)
+	switch hdr.Type {
+	case TypeA:
+		var rb AResource
+		rb, err = unpackAResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeNS:
+		var rb NSResource
+		rb, err = unpackNSResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeCNAME:
+		var rb CNAMEResource
+		rb, err = unpackCNAMEResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeSOA:
+		var rb SOAResource
+		rb, err = unpackSOAResource(msg, off, hdr.Length)
+		r = &rb
+	case TypePTR:
+		var rb PTRResource
+		rb, err = unpackPTRResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeMX:
+		var rb MXResource
+		rb, err = unpackMXResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeTXT:
+		var rb TXTResource
+		rb, err = unpackTXTResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeAAAA:
+		var rb AAAAResource
+		rb, err = unpackAAAAResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeSRV:
+		var rb SRVResource
+		rb, err = unpackSRVResource(msg, off, hdr.Length)
+		r = &rb
+	case TypeOPT:
+		var rb OPTResource
+		rb, err = unpackOPTResource(msg, off, hdr.Length)
+		r = &rb
+	default:
+		var rb UnknownResource
+		rb, err = unpackUnknownResource(hdr.Type, msg, off, hdr.Length)
+		r = &rb
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// checkResourceLen reports whether a body of length bytes starting at off
+// fits in msg.
+func checkResourceLen(msg []byte, off int, length uint16) error {
+	if off+int(length) > len(msg) {
+		return errResourceLen
+	}
+	return nil
+}
+
+// checkResourceEnd verifies that unpacking a body stopped exactly at the
+// end declared by its header.
+func checkResourceEnd(off, newOff int, length uint16) error {
+	if newOff != off+int(length) {
+		return errResourceLen
+	}
+	return nil
+}
+
+// An AResource is an A Resource record.
+type AResource struct {
+	A [4]byte
+}
+
+func (r *AResource) realType() Type {
+	return TypeA
+}
+
+func (r *AResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return append(msg, r.A[:]...), nil
+}
+
+func unpackAResource(msg []byte, off int, length uint16) (AResource, error) {
+	var r AResource
+	if length != uint16(len(r.A)) {
+		return AResource{}, errResourceLen
+	}
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return AResource{}, err
+	}
+	copy(r.A[:], msg[off:])
+	return r, nil
+}
+
+// An NSResource is an NS Resource record.
+type NSResource struct {
+	NS Name
+}
+
+func (r *NSResource) realType() Type {
+	return TypeNS
+}
+
+func (r *NSResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return r.NS.pack(msg, compression, compressionOff)
+}
+
+func unpackNSResource(msg []byte, off int, length uint16) (NSResource, error) {
+	var r NSResource
+	if err := unpackNameResource(&r.NS, msg, off, length); err != nil {
+		return NSResource{}, err
+	}
+	return r, nil
+}
+
+// A CNAMEResource is a CNAME Resource record.
+type CNAMEResource struct {
+	CNAME Name
+}
+
+func (r *CNAMEResource) realType() Type {
+	return TypeCNAME
+}
+
+func (r *CNAMEResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return r.CNAME.pack(msg, compression, compressionOff)
+}
+
+func unpackCNAMEResource(msg []byte, off int, length uint16) (CNAMEResource, error) {
+	var r CNAMEResource
+	if err := unpackNameResource(&r.CNAME, msg, off, length); err != nil {
+		return CNAMEResource{}, err
+	}
+	return r, nil
+}
+
+// unpackNameResource unpacks a body consisting of a single domain name.
+func unpackNameResource(n *Name, msg []byte, off int, length uint16) error {
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return err
+	}
+	newOff, err := n.unpack(msg, off)
+	if err != nil {
+		return err
+	}
+	return checkResourceEnd(off, newOff, length)
+}
+
+// An SOAResource is an SOA Resource record.
+type SOAResource struct {
+	NS      Name
+	MBox    Name
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+
+	// MinTTL the is the default TTL of Resources records which did not
+	// contain a TTL value and the TTL of negative responses. (RFC 2308
+	// Section 4)
+	MinTTL uint32
+}
+
+func (r *SOAResource) realType() Type {
+	return TypeSOA
+}
+
+func (r *SOAResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg, err := r.NS.pack(msg, compression, compressionOff)
+	if err != nil {
+		return oldMsg, err
+	}
+	msg, err = r.MBox.pack(msg, compression, compressionOff)
+	if err != nil {
+		return oldMsg, err
+	}
+	msg = appendUint32(msg, r.Serial)
+	msg = appendUint32(msg, r.Refresh)
+	msg = appendUint32(msg, r.Retry)
+	msg = appendUint32(msg, r.Expire)
+	return appendUint32(msg, r.MinTTL), nil
+}
+
+func unpackSOAResource(msg []byte, off int, length uint16) (SOAResource, error) {
+	var r SOAResource
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return SOAResource{}, err
+	}
+	newOff, err := r.NS.unpack(msg, off)
+	if err != nil {
+		return SOAResource{}, err
+	}
+	if newOff, err = r.MBox.unpack(msg, newOff); err != nil {
+		return SOAResource{}, err
+	}
+	for _, v := range []*uint32{&r.Serial, &r.Refresh, &r.Retry, &r.Expire, &r.MinTTL} {
+		if *v, newOff, err = unpackUint32(msg, newOff); err != nil {
+			return SOAResource{}, err
+		}
+	}
+	if err := checkResourceEnd(off, newOff, length); err != nil {
+		return SOAResource{}, err
+	}
+	return r, nil
+}
+
+// A PTRResource is a PTR Resource record.
+type PTRResource struct {
+	PTR Name
+}
+
+func (r *PTRResource) realType() Type {
+	return TypePTR
+}
+
+func (r *PTRResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return r.PTR.pack(msg, compression, compressionOff)
+}
+
+func unpackPTRResource(msg []byte, off int, length uint16) (PTRResource, error) {
+	var r PTRResource
+	if err := unpackNameResource(&r.PTR, msg, off, length); err != nil {
+		return PTRResource{}, err
+	}
+	return r, nil
+}
+
+// An MXResource is an MX Resource record.
+type MXResource struct {
+	Pref uint16
+	MX   Name
+}
+
+func (r *MXResource) realType() Type {
+	return TypeMX
+}
+
+func (r *MXResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg = appendUint16(msg, r.Pref)
+	msg, err := r.MX.pack(msg, compression, compressionOff)
+	if err != nil {
+		return oldMsg, err
+	}
+	return msg, nil
+}
+
+func unpackMXResource(msg []byte, off int, length uint16) (MXResource, error) {
+	var r MXResource
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return MXResource{}, err
+	}
+	pref, newOff, err := unpackUint16(msg, off)
+	if err != nil {
+		return MXResource{}, err
+	}
+	r.Pref = pref
+	if newOff, err = r.MX.unpack(msg, newOff); err != nil {
+		return MXResource{}, err
+	}
+	if err := checkResourceEnd(off, newOff, length); err != nil {
+		return MXResource{}, err
+	}
+	return r, nil
+}
+
+// A TXTResource is a TXT Resource record.
+type TXTResource struct {
+	// TXT holds the record's character strings, each at most 255
+	// bytes long. How they combine is up to the application; RFC 7208
+	// for example joins them without separators.
+	TXT []string
+}
+
+func (r *TXTResource) realType() Type {
+	return TypeTXT
+}
+
+func (r *TXTResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	for _, s := range r.TXT {
+		if len(s) > 255 {
+			return oldMsg, errStringTooLong
+		}
+		msg = append(msg, byte(len(s)))
+		msg = append(msg, s...)
+	}
+	return msg, nil
+}
+
+func unpackTXTResource(msg []byte, off int, length uint16) (TXTResource, error) {
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return TXTResource{}, err
+	}
+	var txts []string
+	for n, end := off, off+int(length); n < end; {
+		l := int(msg[n])
+		n++
+		if n+l > end {
+			return TXTResource{}, errCalcLen
+		}
+		txts = append(txts, string(msg[n:n+l]))
+		n += l
+	}
+	return TXTResource{txts}, nil
+}
+
+// An AAAAResource is an AAAA Resource record.
+type AAAAResource struct {
+	AAAA [16]byte
+}
+
+func (r *AAAAResource) realType() Type {
+	return TypeAAAA
+}
+
+func (r *AAAAResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return append(msg, r.AAAA[:]...), nil
+}
+
+func unpackAAAAResource(msg []byte, off int, length uint16) (AAAAResource, error) {
+	var r AAAAResource
+	if length != uint16(len(r.AAAA)) {
+		return AAAAResource{}, errResourceLen
+	}
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return AAAAResource{}, err
+	}
+	copy(r.AAAA[:], msg[off:])
+	return r, nil
+}
+
+// An SRVResource is an SRV Resource record.
+type SRVResource struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   Name // Not compressed as per RFC 2782.
+}
+
+func (r *SRVResource) realType() Type {
+	return TypeSRV
+}
+
+func (r *SRVResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	oldMsg := msg
+	msg = appendUint16(msg, r.Priority)
+	msg = appendUint16(msg, r.Weight)
+	msg = appendUint16(msg, r.Port)
+	msg, err := r.Target.pack(msg, nil, compressionOff)
+	if err != nil {
+		return oldMsg, err
+	}
+	return msg, nil
+}
+
+func unpackSRVResource(msg []byte, off int, length uint16) (SRVResource, error) {
+	var r SRVResource
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return SRVResource{}, err
+	}
+	newOff := off
+	var err error
+	for _, v := range []*uint16{&r.Priority, &r.Weight, &r.Port} {
+		if *v, newOff, err = unpackUint16(msg, newOff); err != nil {
+			return SRVResource{}, err
+		}
+	}
+	// Some servers compress the target anyway, so accept pointers.
+	if newOff, err = r.Target.unpack(msg, newOff); err != nil {
+		return SRVResource{}, err
+	}
+	if err := checkResourceEnd(off, newOff, length); err != nil {
+		return SRVResource{}, err
+	}
+	return r, nil
+}
+
+// An Option represents a DNS message option within OPTResource.
+//
+// The message option is part of the extension mechanisms for DNS as
+// defined in RFC 6891.
+type Option struct {
+	Code uint16 // option code
+	Data []byte
+}
+
+// An OPTResource is an OPT pseudo Resource record.
+//
+// The pseudo resource record is part of the extension mechanisms for DNS
+// as defined in RFC 6891. Use ResourceHeader.SetEDNS0 to fill in the
+// header fields that it repurposes.
+type OPTResource struct {
+	Options []Option
+}
+
+func (r *OPTResource) realType() Type {
+	return TypeOPT
+}
+
+func (r *OPTResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	for _, opt := range r.Options {
+		if len(opt.Data) > int(^uint16(0)) {
+			return msg, errResTooLong
+		}
+		msg = appendUint16(msg, opt.Code)
+		msg = appendUint16(msg, uint16(len(opt.Data)))
+		msg = append(msg, opt.Data...)
+	}
+	return msg, nil
+}
+
+func unpackOPTResource(msg []byte, off int, length uint16) (OPTResource, error) {
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return OPTResource{}, err
+	}
+	var opts []Option
+	for n, end := off, off+int(length); n < end; {
+		if n+2*uint16Len > end {
+			return OPTResource{}, errBaseLen
+		}
+		var o Option
+		o.Code = getUint16(msg[n:])
+		l := int(getUint16(msg[n+2:]))
+		n += 2 * uint16Len
+		if n+l > end {
+			return OPTResource{}, errCalcLen
+		}
+		o.Data = make([]byte, l)
+		copy(o.Data, msg[n:])
+		n += l
+		opts = append(opts, o)
+	}
+	return OPTResource{opts}, nil
+}
+
+// An UnknownResource is a catch-all container for unknown record types.
+type UnknownResource struct {
+	Type Type
+	Data []byte
+}
+
+func (r *UnknownResource) realType() Type {
+	return r.Type
+}
+
+func (r *UnknownResource) pack(msg []byte, compression map[string]int, compressionOff int) ([]byte, error) {
+	return append(msg, r.Data...), nil
+}
+
+func unpackUnknownResource(recordType Type, msg []byte, off int, length uint16) (UnknownResource, error) {
+	if err := checkResourceLen(msg, off, length); err != nil {
+		return UnknownResource{}, err
+	}
+	parsed := UnknownResource{
+		Type: recordType,
+		Data: make([]byte, length),
+	}
+	copy(parsed.Data, msg[off:])
+	return parsed, nil
+}
@@ -12,7 +12,10 @@
 
 package net
 
-import "errors"
+import (
+	"errors"
+	"net/netip"
+)
 
 // IP address lengths (bytes).
 const (
@@ -482,6 +485,36 @@ func (n *IPNet) String() string {
 	return nn.String() + "/" + itod(uint(l))
 }
 
+// Prefix returns n as a netip.Prefix. It reports false if n is not a
+// valid IPv4 or IPv6 network or its mask is not in the canonical
+// form.
+func (n *IPNet) Prefix() (p netip.Prefix, ok bool) {
+	nn, m := networkNumberAndMask(n)
+	if nn == nil || m == nil {
+		return netip.Prefix{}, false
+	}
+	ones, bits := m.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	ip, _ := netip.AddrFromSlice(nn)
+	return netip.PrefixFrom(ip, ones), true
+}
+
+// IPNetFromPrefix returns the network p as an IPNet, or nil if p is
+// not valid. An IPv4 prefix gives 4-byte IP and Mask fields, as
+// ParseCIDR does.
+func IPNetFromPrefix(p netip.Prefix) *IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	ip := p.Addr()
+	return &IPNet{
+		IP:   IP(ip.AsSlice()),
+		Mask: CIDRMask(p.Bits(), ip.BitLen()),
+	}
+}
+
 // Parse IPv4 address (d.d.d.d).
 func parseIPv4(s string) IP {
 	var p [IPv4len]byte
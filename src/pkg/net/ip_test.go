@@ -5,6 +5,7 @@
 package net
 
 import (
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -212,6 +213,41 @@ func TestIPNetString(t *testing.T) {
 	}
 }
 
+var ipNetPrefixTests = []struct {
+	in  *IPNet
+	out string // "" if there is no Prefix
+}{
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 255, 255, 0)}, "192.168.1.0/24"},
+	{&IPNet{IP: IP{192, 168, 1, 0}, Mask: CIDRMask(24, 32)}, "192.168.1.0/24"},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: CIDRMask(120, 128)}, "192.168.1.0/24"},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(32, 128)}, "2001:db8::/32"},
+	{&IPNet{IP: IPv6zero, Mask: CIDRMask(0, 128)}, "::/0"},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 0, 255, 0)}, ""},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: IPv4Mask(255, 255, 255, 0)}, ""},
+	{&IPNet{}, ""},
+}
+
+func TestIPNetPrefix(t *testing.T) {
+	for _, tt := range ipNetPrefixTests {
+		p, ok := tt.in.Prefix()
+		if ok != (tt.out != "") || ok && p.String() != tt.out {
+			t.Errorf("IPNet.Prefix(%v) = %v, %v; want %q", tt.in, p, ok, tt.out)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		n := IPNetFromPrefix(p)
+		_, want, _ := ParseCIDR(tt.out)
+		if !reflect.DeepEqual(n, want) {
+			t.Errorf("IPNetFromPrefix(%v) = %#v; want %#v", p, n, want)
+		}
+	}
+	if n := IPNetFromPrefix(netip.Prefix{}); n != nil {
+		t.Errorf("IPNetFromPrefix of the zero Prefix = %v; want nil", n)
+	}
+}
+
 var cidrMaskTests = []struct {
 	ones int
 	bits int
@@ -46,7 +46,7 @@ func ResolveIPAddr(net, addr string) (*IPAddr, error) {
 	default:
 		return nil, UnknownNetworkError(net)
 	}
-	a, err := resolveInternetAddr(afnet, addr, noDeadline)
+	a, err := resolveInternetAddr("resolve", afnet, addr, noDeadline)
 	if err != nil {
 		return nil, err
 	}
@@ -66,10 +66,10 @@ func (c *IPConn) WriteMsgIP(b, oob []byte, addr *IPAddr) (n, oobn int, err error
 // netProto, which must be "ip", "ip4", or "ip6" followed by a colon
 // and a protocol number or name.
 func DialIP(netProto string, laddr, raddr *IPAddr) (*IPConn, error) {
-	return dialIP(netProto, laddr, raddr, noDeadline)
+	return dialIP(netProto, laddr, raddr, noDeadline, nil)
 }
 
-func dialIP(netProto string, laddr, raddr *IPAddr, deadline time.Time) (*IPConn, error) {
+func dialIP(netProto string, laddr, raddr *IPAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*IPConn, error) {
 	return nil, syscall.EPLAN9
 }
 
@@ -78,5 +78,9 @@ func dialIP(netProto string, laddr, raddr *IPAddr, deadline time.Time) (*IPConn,
 // methods can be used to receive and send IP packets with per-packet
 // addressing.
 func ListenIP(netProto string, laddr *IPAddr) (*IPConn, error) {
+	return listenIP(netProto, laddr, nil)
+}
+
+func listenIP(netProto string, laddr *IPAddr, ctrlFn func(string, string, uintptr) error) (*IPConn, error) {
 	return nil, syscall.EPLAN9
 }
@@ -58,6 +58,28 @@ func (al addrList) toAddr() Addr {
 	}
 }
 
+// partition divides the list into its IPv4 and IPv6 addresses,
+// keeping their relative order.
+func (al addrList) partition() (ipv4, ipv6 addrList) {
+	for _, a := range al {
+		var ip IP
+		switch a := a.(type) {
+		case *TCPAddr:
+			ip = a.IP
+		case *UDPAddr:
+			ip = a.IP
+		case *IPAddr:
+			ip = a.IP
+		}
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, a)
+		} else {
+			ipv6 = append(ipv6, a)
+		}
+	}
+	return
+}
+
 var errNoSuitableAddress = errors.New("no suitable address found")
 
 // firstFavoriteAddr returns an address or a list of addresses that
@@ -106,6 +128,34 @@ func firstFavoriteAddr(filter func(IP) IP, ips []IP, inetaddr func(IP) netaddr)
 	}
 }
 
+// allFavoriteAddrs is like firstFavoriteAddr but returns every
+// usable address rather than one of each address family, for dialers
+// that fall back from one address to the next. IPv4 addresses come
+// before IPv6 ones, as in firstFavoriteAddr.
+func allFavoriteAddrs(filter func(IP) IP, ips []IP, inetaddr func(IP) netaddr) (netaddr, error) {
+	var list, ipv6 addrList
+	for _, ip := range ips {
+		if filter != nil {
+			if ip := filter(ip); ip != nil {
+				list = append(list, inetaddr(ip))
+			}
+		} else if ip4 := ipv4only(ip); ip4 != nil {
+			list = append(list, inetaddr(ip4))
+		} else if ip6 := ipv6only(ip); ip6 != nil {
+			ipv6 = append(ipv6, inetaddr(ip6))
+		}
+	}
+	list = append(list, ipv6...)
+	switch len(list) {
+	case 0:
+		return nil, errNoSuitableAddress
+	case 1:
+		return list[0], nil
+	default:
+		return list, nil
+	}
+}
+
 func firstSupportedAddr(filter func(IP) IP, ips []IP, inetaddr func(IP) netaddr) (netaddr, error) {
 	for _, ip := range ips {
 		if ip := filter(ip); ip != nil {
@@ -232,9 +282,10 @@ func JoinHostPort(host, port string) string {
 // address or a DNS name and returns an internet protocol family
 // address. It returns a list that contains a pair of different
 // address family addresses when addr is a DNS name and the name has
-// mutiple address family records. The result contains at least one
-// address when error is nil.
-func resolveInternetAddr(net, addr string, deadline time.Time) (netaddr, error) {
+// mutiple address family records. If op is "dial", the list holds
+// all the usable addresses of the name instead. The result contains
+// at least one address when error is nil.
+func resolveInternetAddr(op, net, addr string, deadline time.Time) (netaddr, error) {
 	var (
 		err              error
 		host, port, zone string
@@ -293,6 +344,9 @@ func resolveInternetAddr(net, addr string, deadline time.Time) (netaddr, error)
 	if net != "" && net[len(net)-1] == '6' || zone != "" {
 		filter = ipv6only
 	}
+	if op == "dial" {
+		return allFavoriteAddrs(filter, ips, inetaddr)
+	}
 	return firstFavoriteAddr(filter, ips, inetaddr)
 }
 
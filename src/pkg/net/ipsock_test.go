@@ -191,3 +191,86 @@ func TestFirstFavoriteAddr(t *testing.T) {
 		}
 	}
 }
+
+var allFavoriteAddrsTests = []struct {
+	filter   func(IP) IP
+	ips      []IP
+	inetaddr func(IP) netaddr
+	addr     netaddr
+	err      error
+}{
+	{
+		nil,
+		[]IP{
+			IPv6loopback,
+			IPv4(127, 0, 0, 1),
+			ParseIP("2001:db8::1"),
+			IPv4(192, 168, 0, 1),
+		},
+		testInetaddr,
+		addrList{
+			&TCPAddr{IP: IPv4(127, 0, 0, 1).To4(), Port: 5682},
+			&TCPAddr{IP: IPv4(192, 168, 0, 1).To4(), Port: 5682},
+			&TCPAddr{IP: IPv6loopback, Port: 5682},
+			&TCPAddr{IP: ParseIP("2001:db8::1"), Port: 5682},
+		},
+		nil,
+	},
+	{
+		ipv6only,
+		[]IP{
+			IPv6loopback,
+			IPv4(127, 0, 0, 1),
+			ParseIP("2001:db8::1"),
+		},
+		testInetaddr,
+		addrList{
+			&TCPAddr{IP: IPv6loopback, Port: 5682},
+			&TCPAddr{IP: ParseIP("2001:db8::1"), Port: 5682},
+		},
+		nil,
+	},
+	{
+		ipv4only,
+		[]IP{
+			IPv6loopback,
+			IPv4(127, 0, 0, 1),
+		},
+		testInetaddr,
+		&TCPAddr{IP: IPv4(127, 0, 0, 1).To4(), Port: 5682},
+		nil,
+	},
+	{ipv4only, []IP{IPv6loopback}, testInetaddr, nil, errNoSuitableAddress},
+}
+
+func TestAllFavoriteAddrs(t *testing.T) {
+	if !supportsIPv4 || !supportsIPv6 {
+		t.Skip("ipv4 or ipv6 is not supported")
+	}
+
+	for i, tt := range allFavoriteAddrsTests {
+		addr, err := allFavoriteAddrs(tt.filter, tt.ips, tt.inetaddr)
+		if err != tt.err {
+			t.Errorf("#%v: got %v; expected %v", i, err, tt.err)
+		}
+		if !reflect.DeepEqual(addr, tt.addr) {
+			t.Errorf("#%v: got %v; expected %v", i, addr, tt.addr)
+		}
+		if al, ok := addr.(addrList); ok {
+			ipv4, ipv6 := al.partition()
+			for _, a := range ipv4 {
+				if a.(*TCPAddr).IP.To4() == nil {
+					t.Errorf("#%v: partition put %v with IPv4", i, a)
+				}
+			}
+			for _, a := range ipv6 {
+				if a.(*TCPAddr).IP.To4() != nil {
+					t.Errorf("#%v: partition put %v with IPv6", i, a)
+				}
+			}
+			if len(ipv4)+len(ipv6) != len(al) {
+				t.Errorf("#%v: partition returned %d+%d addresses; want %d", i, len(ipv4), len(ipv6), len(al))
+			}
+		}
+	}
+}
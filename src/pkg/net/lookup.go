@@ -4,7 +4,11 @@
 
 package net
 
-import "time"
+import (
+	"net/dnsmessage"
+	"sync"
+	"time"
+)
 
 // protocols contains minimal mappings between internet protocol
 // names and numbers for platforms that don't have a complete list of
@@ -135,3 +139,177 @@ func LookupTXT(name string) (txt []string, err error) {
 func LookupAddr(addr string) (name []string, err error) {
 	return lookupAddr(addr)
 }
+
+// A Resolver looks up names and numbers. Its methods mirror the
+// package-level Lookup functions.
+//
+// The zero Resolver behaves like the package-level functions: it uses
+// the system's resolver where one is available and otherwise the
+// built-in Go DNS client configured from /etc/resolv.conf. The fields
+// below configure the built-in client and are ignored on systems that do
+// not use it (Windows and Plan 9).
+//
+// A Resolver is safe for concurrent use by multiple goroutines. It must
+// not be copied after first use.
+type Resolver struct {
+	// Servers lists the DNS servers to query, in order, overriding
+	// the nameservers in /etc/resolv.conf. Each entry is an IP address
+	// or an IP address and port in the form accepted by
+	// SplitHostPort; the port defaults to 53.
+	Servers []string
+
+	// Dial optionally specifies the function used to connect to DNS
+	// servers. The network is "udp" or "tcp" and address is one of
+	// the Servers, with port. If Dial is nil, the package-level Dial
+	// function is used.
+	Dial func(network, address string) (Conn, error)
+
+	// Timeout is the maximum time to wait for a response to a single
+	// query. If zero, the timeout from /etc/resolv.conf is used,
+	// which defaults to 5 seconds.
+	Timeout time.Duration
+
+	// Attempts is the number of times each server is queried before
+	// moving on to the next one. If zero, the value from
+	// /etc/resolv.conf is used, which defaults to 2.
+	Attempts int
+
+	// PreferGo causes the built-in Go DNS client to be used even when
+	// the system resolver is available. It is implied if Servers or
+	// Dial is set.
+	PreferGo bool
+
+	// Cache enables caching of the answers received from DNS
+	// servers. An answer is kept for the smallest TTL of the records
+	// in it; failed lookups are not cached.
+	Cache bool
+
+	cache dnsCache
+}
+
+// defaultResolver is the Resolver used by the package-level Lookup
+// functions.
+var defaultResolver = &Resolver{}
+
+// preferGo reports whether r should bypass the system resolver.
+func (r *Resolver) preferGo() bool {
+	return r.PreferGo || len(r.Servers) > 0 || r.Dial != nil
+}
+
+// LookupHost looks up the given host using r.
+// It returns an array of that host's addresses.
+func (r *Resolver) LookupHost(host string) (addrs []string, err error) {
+	return r.lookupHost(host)
+}
+
+// LookupIP looks up host using r.
+// It returns an array of that host's IPv4 and IPv6 addresses.
+func (r *Resolver) LookupIP(host string) (addrs []IP, err error) {
+	return r.lookupIP(host)
+}
+
+// LookupPort looks up the port for the given network and service.
+func (r *Resolver) LookupPort(network, service string) (port int, err error) {
+	return lookupPort(network, service)
+}
+
+// LookupCNAME returns the canonical DNS host for the given name.
+func (r *Resolver) LookupCNAME(name string) (cname string, err error) {
+	return r.lookupCNAME(name)
+}
+
+// LookupSRV tries to resolve an SRV query of the given service,
+// protocol, and domain name, as described for the package-level
+// LookupSRV function.
+func (r *Resolver) LookupSRV(service, proto, name string) (cname string, addrs []*SRV, err error) {
+	return r.lookupSRV(service, proto, name)
+}
+
+// LookupMX returns the DNS MX records for the given domain name sorted by preference.
+func (r *Resolver) LookupMX(name string) (mx []*MX, err error) {
+	return r.lookupMX(name)
+}
+
+// LookupNS returns the DNS NS records for the given domain name.
+func (r *Resolver) LookupNS(name string) (ns []*NS, err error) {
+	return r.lookupNS(name)
+}
+
+// LookupTXT returns the DNS TXT records for the given domain name.
+func (r *Resolver) LookupTXT(name string) (txt []string, err error) {
+	return r.lookupTXT(name)
+}
+
+// LookupAddr performs a reverse lookup for the given address, returning a list
+// of names mapping to that address.
+func (r *Resolver) LookupAddr(addr string) (name []string, err error) {
+	return r.lookupAddr(addr)
+}
+
+// dnsCache holds the answers cached by a Resolver.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[dnsCacheKey]*dnsCacheEntry
+}
+
+type dnsCacheKey struct {
+	name  string
+	qtype dnsmessage.Type
+}
+
+type dnsCacheEntry struct {
+	cname   string
+	rrs     []dnsmessage.Resource
+	expires time.Time
+}
+
+// maxDNSCacheEntries bounds the size of a Resolver's cache.
+const maxDNSCacheEntries = 1024
+
+// get returns the cached answer for the rooted name and query type, if
+// there is one that has not expired.
+func (c *dnsCache) get(name string, qtype dnsmessage.Type) (cname string, rrs []dnsmessage.Resource, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[dnsCacheKey{name, qtype}]
+	if e == nil {
+		return "", nil, false
+	}
+	if !time.Now().Before(e.expires) {
+		delete(c.entries, dnsCacheKey{name, qtype})
+		return "", nil, false
+	}
+	return e.cname, e.rrs, true
+}
+
+// put caches an answer for ttl seconds.
+func (c *dnsCache) put(name string, qtype dnsmessage.Type, cname string, rrs []dnsmessage.Resource, ttl uint32) {
+	if ttl == 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[dnsCacheKey]*dnsCacheEntry)
+	}
+	if len(c.entries) >= maxDNSCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: make room by dropping an arbitrary entry.
+		for k := range c.entries {
+			if len(c.entries) < maxDNSCacheEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[dnsCacheKey{name, qtype}] = &dnsCacheEntry{
+		cname:   cname,
+		rrs:     rrs,
+		expires: now.Add(time.Duration(ttl) * time.Second),
+	}
+}
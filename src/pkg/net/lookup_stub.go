@@ -0,0 +1,42 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build plan9 windows
+
+package net
+
+// On these systems there is no built-in Go DNS client, so a Resolver
+// always uses the system resolver and its fields have no effect.
+
+func (r *Resolver) lookupHost(host string) (addrs []string, err error) {
+	return lookupHost(host)
+}
+
+func (r *Resolver) lookupIP(host string) (addrs []IP, err error) {
+	return lookupIP(host)
+}
+
+func (r *Resolver) lookupCNAME(name string) (cname string, err error) {
+	return lookupCNAME(name)
+}
+
+func (r *Resolver) lookupSRV(service, proto, name string) (cname string, addrs []*SRV, err error) {
+	return lookupSRV(service, proto, name)
+}
+
+func (r *Resolver) lookupMX(name string) (mx []*MX, err error) {
+	return lookupMX(name)
+}
+
+func (r *Resolver) lookupNS(name string) (ns []*NS, err error) {
+	return lookupNS(name)
+}
+
+func (r *Resolver) lookupTXT(name string) (txt []string, err error) {
+	return lookupTXT(name)
+}
+
+func (r *Resolver) lookupAddr(addr string) (name []string, err error) {
+	return lookupAddr(addr)
+}
@@ -8,6 +8,7 @@ package net
 
 import (
 	"errors"
+	"net/dnsmessage"
 	"sync"
 )
 
@@ -53,19 +54,11 @@ func lookupProtocol(name string) (proto int, err error) {
 }
 
 func lookupHost(host string) (addrs []string, err error) {
-	addrs, err, ok := cgoLookupHost(host)
-	if !ok {
-		addrs, err = goLookupHost(host)
-	}
-	return
+	return defaultResolver.lookupHost(host)
 }
 
 func lookupIP(host string) (addrs []IP, err error) {
-	addrs, err, ok := cgoLookupIP(host)
-	if !ok {
-		addrs, err = goLookupIP(host)
-	}
-	return
+	return defaultResolver.lookupIP(host)
 }
 
 func lookupPort(network, service string) (port int, err error) {
@@ -77,74 +70,119 @@ func lookupPort(network, service string) (port int, err error) {
 }
 
 func lookupCNAME(name string) (cname string, err error) {
-	cname, err, ok := cgoLookupCNAME(name)
-	if !ok {
-		cname, err = goLookupCNAME(name)
-	}
-	return
+	return defaultResolver.lookupCNAME(name)
 }
 
 func lookupSRV(service, proto, name string) (cname string, addrs []*SRV, err error) {
+	return defaultResolver.lookupSRV(service, proto, name)
+}
+
+func lookupMX(name string) (mx []*MX, err error) {
+	return defaultResolver.lookupMX(name)
+}
+
+func lookupNS(name string) (ns []*NS, err error) {
+	return defaultResolver.lookupNS(name)
+}
+
+func lookupTXT(name string) (txt []string, err error) {
+	return defaultResolver.lookupTXT(name)
+}
+
+func lookupAddr(addr string) (name []string, err error) {
+	return defaultResolver.lookupAddr(addr)
+}
+
+func (r *Resolver) lookupHost(host string) (addrs []string, err error) {
+	if !r.preferGo() {
+		if addrs, err, ok := cgoLookupHost(host); ok {
+			return addrs, err
+		}
+	}
+	return r.goLookupHost(host)
+}
+
+func (r *Resolver) lookupIP(host string) (addrs []IP, err error) {
+	if !r.preferGo() {
+		if addrs, err, ok := cgoLookupIP(host); ok {
+			return addrs, err
+		}
+	}
+	return r.goLookupIP(host)
+}
+
+func (r *Resolver) lookupCNAME(name string) (cname string, err error) {
+	if !r.preferGo() {
+		if cname, err, ok := cgoLookupCNAME(name); ok {
+			return cname, err
+		}
+	}
+	return r.goLookupCNAME(name)
+}
+
+func (r *Resolver) lookupSRV(service, proto, name string) (cname string, addrs []*SRV, err error) {
 	var target string
 	if service == "" && proto == "" {
 		target = name
 	} else {
 		target = "_" + service + "._" + proto + "." + name
 	}
-	var records []dnsRR
-	cname, records, err = lookup(target, dnsTypeSRV)
+	var records []dnsmessage.Resource
+	cname, records, err = r.lookup(target, dnsmessage.TypeSRV)
 	if err != nil {
 		return
 	}
 	addrs = make([]*SRV, len(records))
 	for i, rr := range records {
-		r := rr.(*dnsRR_SRV)
-		addrs[i] = &SRV{r.Target, r.Port, r.Priority, r.Weight}
+		srv := rr.Body.(*dnsmessage.SRVResource)
+		addrs[i] = &SRV{srv.Target.String(), srv.Port, srv.Priority, srv.Weight}
 	}
 	byPriorityWeight(addrs).sort()
 	return
 }
 
-func lookupMX(name string) (mx []*MX, err error) {
-	_, records, err := lookup(name, dnsTypeMX)
+func (r *Resolver) lookupMX(name string) (mx []*MX, err error) {
+	_, records, err := r.lookup(name, dnsmessage.TypeMX)
 	if err != nil {
 		return
 	}
 	mx = make([]*MX, len(records))
 	for i, rr := range records {
-		r := rr.(*dnsRR_MX)
-		mx[i] = &MX{r.Mx, r.Pref}
+		m := rr.Body.(*dnsmessage.MXResource)
+		mx[i] = &MX{m.MX.String(), m.Pref}
 	}
 	byPref(mx).sort()
 	return
 }
 
-func lookupNS(name string) (ns []*NS, err error) {
-	_, records, err := lookup(name, dnsTypeNS)
+func (r *Resolver) lookupNS(name string) (ns []*NS, err error) {
+	_, records, err := r.lookup(name, dnsmessage.TypeNS)
 	if err != nil {
 		return
 	}
 	ns = make([]*NS, len(records))
-	for i, r := range records {
-		r := r.(*dnsRR_NS)
-		ns[i] = &NS{r.Ns}
+	for i, rr := range records {
+		ns[i] = &NS{rr.Body.(*dnsmessage.NSResource).NS.String()}
 	}
 	return
 }
 
-func lookupTXT(name string) (txt []string, err error) {
-	_, records, err := lookup(name, dnsTypeTXT)
+func (r *Resolver) lookupTXT(name string) (txt []string, err error) {
+	_, records, err := r.lookup(name, dnsmessage.TypeTXT)
 	if err != nil {
 		return
 	}
 	txt = make([]string, len(records))
-	for i, r := range records {
-		txt[i] = r.(*dnsRR_TXT).Txt
+	for i, rr := range records {
+		// A record's strings form a single text (RFC 7208 section 3.3).
+		for _, s := range rr.Body.(*dnsmessage.TXTResource).TXT {
+			txt[i] += s
+		}
 	}
 	return
 }
 
-func lookupAddr(addr string) (name []string, err error) {
+func (r *Resolver) lookupAddr(addr string) (name []string, err error) {
 	name = lookupStaticAddr(addr)
 	if len(name) > 0 {
 		return
@@ -154,15 +192,14 @@ func lookupAddr(addr string) (name []string, err error) {
 	if err != nil {
 		return
 	}
-	var records []dnsRR
-	_, records, err = lookup(arpa, dnsTypePTR)
+	var records []dnsmessage.Resource
+	_, records, err = r.lookup(arpa, dnsmessage.TypePTR)
 	if err != nil {
 		return
 	}
 	name = make([]string, len(records))
-	for i := range records {
-		r := records[i].(*dnsRR_PTR)
-		name[i] = r.Ptr
+	for i, rr := range records {
+		name[i] = rr.Body.(*dnsmessage.PTRResource).PTR.String()
 	}
 	return
 }
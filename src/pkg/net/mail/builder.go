@@ -0,0 +1,437 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Builder composes an RFC 5322 mail message.
+//
+// The message has a plain text body, an HTML body, or both as
+// alternatives, followed by any attachments. Bodies are encoded as
+// UTF-8 quoted-printable text and attachments as base64.
+type Builder struct {
+	From    *Address
+	To      []*Address
+	Cc      []*Address
+	Subject string
+
+	// Date is the date of the message. If zero, the time the message
+	// is written is used.
+	Date time.Time
+
+	// Header holds additional header fields, such as Reply-To or
+	// Message-Id. Address fields, such as Reply-To, must hold address
+	// lists as accepted by ParseAddressList, and are written like To.
+	// Other structured fields, such as Message-Id or References, are
+	// written as given. Values of unstructured fields that are not
+	// plain ASCII are written as RFC 2047 encoded-words. No key or
+	// value may contain CR or LF. The MIME-Version, Content-Type and
+	// Content-Transfer-Encoding fields are always set by the Builder.
+	Header Header
+
+	Text        string // plain text body
+	HTML        string // HTML body
+	Attachments []*Attachment
+}
+
+// An Attachment is a file attached to a message.
+type Attachment struct {
+	// Filename is the name of the file, without any directory.
+	Filename string
+
+	// ContentType is the media type of the file. If empty, it is
+	// derived from the extension of Filename, defaulting to
+	// "application/octet-stream".
+	ContentType string
+
+	Data []byte
+}
+
+var (
+	// errNoFrom is returned when writing a message without a From address.
+	errNoFrom = errors.New("mail: message has no From address")
+
+	// errHeaderNewline is returned when writing a message whose header
+	// fields hold a CR or LF, which would start new fields.
+	errHeaderNewline = errors.New("mail: header field contains CR or LF")
+)
+
+// WriteTo writes the message to w, with CRLF line endings, as required
+// for sending it with SMTP.
+func (b *Builder) WriteTo(w io.Writer) (n int64, err error) {
+	if b.From == nil {
+		return 0, errNoFrom
+	}
+	if !b.validHeader() {
+		return 0, errHeaderNewline
+	}
+	cw := &countWriter{w: w}
+	if err := b.write(cw); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// Bytes returns the message as written by WriteTo.
+func (b *Builder) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validHeader reports whether the header fields the Builder writes as
+// given are free of CR and LF.
+func (b *Builder) validHeader() bool {
+	addrs := append(append([]*Address{b.From}, b.To...), b.Cc...)
+	for _, a := range addrs {
+		if hasNewline(a.Address) {
+			return false
+		}
+	}
+	for k, vv := range b.Header {
+		if hasNewline(k) {
+			return false
+		}
+		for _, v := range vv {
+			if hasNewline(v) {
+				return false
+			}
+		}
+	}
+	for _, a := range b.Attachments {
+		if hasNewline(a.ContentType) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasNewline(s string) bool {
+	return strings.ContainsAny(s, "\r\n")
+}
+
+func (b *Builder) write(w io.Writer) error {
+	date := b.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	var hdr bytes.Buffer
+	writeHeader(&hdr, "From", b.From.String())
+	if len(b.To) > 0 {
+		writeHeader(&hdr, "To", formatAddressList(b.To))
+	}
+	if len(b.Cc) > 0 {
+		writeHeader(&hdr, "Cc", formatAddressList(b.Cc))
+	}
+	if b.Subject != "" {
+		writeHeader(&hdr, "Subject", mime.QEncoding.Encode("utf-8", b.Subject))
+	}
+	writeHeader(&hdr, "Date", date.Format(time.RFC1123Z))
+	for _, k := range sortedKeys(b.Header) {
+		ck := textproto.CanonicalMIMEHeaderKey(k)
+		switch ck {
+		case "From", "To", "Cc", "Subject", "Date",
+			"Mime-Version", "Content-Type", "Content-Transfer-Encoding":
+			continue
+		}
+		for _, v := range b.Header[k] {
+			switch {
+			case addressFields[ck]:
+				list, err := ParseAddressList(v)
+				if err != nil {
+					return fmt.Errorf("mail: %s header: %v", ck, err)
+				}
+				v = formatAddressList(list)
+			case !structuredFields[ck]:
+				v = mime.QEncoding.Encode("utf-8", v)
+			}
+			writeHeader(&hdr, ck, v)
+		}
+	}
+	writeHeader(&hdr, "MIME-Version", "1.0")
+
+	if len(b.Attachments) == 0 {
+		body := b.body()
+		for _, k := range sortedKeys(body.header) {
+			writeHeader(&hdr, k, body.header[k][0])
+		}
+		hdr.WriteString("\r\n")
+		if _, err := hdr.WriteTo(w); err != nil {
+			return err
+		}
+		return body.write(w)
+	}
+
+	mw := multipart.NewWriter(w)
+	writeHeader(&hdr, "Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	hdr.WriteString("\r\n")
+	if _, err := hdr.WriteTo(w); err != nil {
+		return err
+	}
+	if b.Text != "" || b.HTML != "" {
+		if err := b.body().writePart(mw); err != nil {
+			return err
+		}
+	}
+	for _, a := range b.Attachments {
+		if err := a.part().writePart(mw); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// addressFields are the header fields, other than From, To and Cc, that
+// hold address lists, as listed in RFC 5322 section 3.6.
+var addressFields = map[string]bool{
+	"Bcc":           true,
+	"Reply-To":      true,
+	"Resent-Bcc":    true,
+	"Resent-Cc":     true,
+	"Resent-From":   true,
+	"Resent-Sender": true,
+	"Resent-To":     true,
+	"Sender":        true,
+}
+
+// structuredFields are the other structured header fields of RFC 5322,
+// which may not hold encoded-words and are written as given.
+var structuredFields = map[string]bool{
+	"In-Reply-To":       true,
+	"Message-Id":        true,
+	"Received":          true,
+	"References":        true,
+	"Resent-Date":       true,
+	"Resent-Message-Id": true,
+	"Return-Path":       true,
+}
+
+// A bodyPart is a MIME entity: its header fields and a function that
+// writes its encoded body.
+type bodyPart struct {
+	header textproto.MIMEHeader
+	write  func(w io.Writer) error
+}
+
+func (p *bodyPart) writePart(mw *multipart.Writer) error {
+	w, err := mw.CreatePart(p.header)
+	if err != nil {
+		return err
+	}
+	return p.write(w)
+}
+
+// body returns the part holding the text and HTML bodies of the message.
+func (b *Builder) body() *bodyPart {
+	switch {
+	case b.Text != "" && b.HTML != "":
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		return &bodyPart{
+			header: textproto.MIMEHeader{
+				"Content-Type": {"multipart/alternative; boundary=" + mw.Boundary()},
+			},
+			write: func(w io.Writer) error {
+				if err := textPart("text/plain", b.Text).writePart(mw); err != nil {
+					return err
+				}
+				if err := textPart("text/html", b.HTML).writePart(mw); err != nil {
+					return err
+				}
+				if err := mw.Close(); err != nil {
+					return err
+				}
+				_, err := buf.WriteTo(w)
+				return err
+			},
+		}
+	case b.HTML != "":
+		return textPart("text/html", b.HTML)
+	}
+	return textPart("text/plain", b.Text)
+}
+
+// textPart returns a part holding s as UTF-8 text of media type typ.
+func textPart(typ, s string) *bodyPart {
+	return &bodyPart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {typ + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		write: func(w io.Writer) error {
+			qw := quotedprintable.NewWriter(w)
+			if _, err := io.WriteString(qw, s); err != nil {
+				return err
+			}
+			return qw.Close()
+		},
+	}
+}
+
+// part returns the part holding the attachment.
+func (a *Attachment) part() *bodyPart {
+	typ := a.ContentType
+	if typ == "" {
+		typ = mime.TypeByExtension(filepath.Ext(a.Filename))
+	}
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if a.Filename != "" {
+		disposition += "; " + filenameParam(a.Filename)
+	}
+	return &bodyPart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {typ},
+			"Content-Disposition":       {disposition},
+			"Content-Transfer-Encoding": {"base64"},
+		},
+		write: func(w io.Writer) error {
+			lw := &lineWrapper{w: w, max: 76}
+			bw := base64.NewEncoder(base64.StdEncoding, lw)
+			if _, err := bw.Write(a.Data); err != nil {
+				return err
+			}
+			return bw.Close()
+		},
+	}
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// filenameParam returns the filename parameter of a Content-Disposition
+// field for name. Encoded-words may not appear in quoted strings
+// (RFC 2047 section 5), so names that are not plain ASCII are written
+// in the extended form of RFC 2231: filename* with the charset utf-8
+// and the bytes of name percent-encoded.
+func filenameParam(name string) string {
+	ascii := true
+	for i := 0; i < len(name); i++ {
+		if !isVchar(name[i]) && !isWSP(name[i]) {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return `filename="` + quoteEscaper.Replace(name) + `"`
+	}
+	const hex = "0123456789ABCDEF"
+	buf := bytes.NewBufferString("filename*=utf-8''")
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if isAttrChar(c) {
+			buf.WriteByte(c)
+		} else {
+			buf.WriteByte('%')
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		}
+	}
+	return buf.String()
+}
+
+// isAttrChar reports whether c may appear unencoded in an RFC 2231
+// extended parameter value.
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+func sortedKeys(h map[string][]string) []string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatAddressList formats list for an address header field.
+func formatAddressList(list []*Address) string {
+	s := make([]string, len(list))
+	for i, a := range list {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// maxHeaderLine is the length at which header lines are folded, as
+// recommended by RFC 5322 section 2.1.1.
+const maxHeaderLine = 78
+
+// writeHeader writes a header field to buf, folding it at spaces so
+// that its lines are no longer than maxHeaderLine where possible.
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	n := len(key) + 1
+	buf.WriteString(key)
+	buf.WriteByte(':')
+	for _, word := range strings.Split(value, " ") {
+		if n+1+len(word) > maxHeaderLine {
+			buf.WriteString("\r\n")
+			n = 0
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(word)
+		n += 1 + len(word)
+	}
+	buf.WriteString("\r\n")
+}
+
+// A lineWrapper breaks the data written to it into lines of max bytes.
+type lineWrapper struct {
+	w   io.Writer
+	max int
+	n   int // bytes written to the current line
+}
+
+func (l *lineWrapper) Write(p []byte) (n int, err error) {
+	for len(p)+l.n > l.max {
+		m := l.max - l.n
+		if _, err := l.w.Write(p[:m]); err != nil {
+			return n, err
+		}
+		if _, err := io.WriteString(l.w, "\r\n"); err != nil {
+			return n + m, err
+		}
+		p = p[m:]
+		n += m
+		l.n = 0
+	}
+	m, err := l.w.Write(p)
+	l.n += m
+	return n + m, err
+}
+
+// A countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return
+}
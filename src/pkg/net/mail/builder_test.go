@@ -0,0 +1,233 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(2013, 6, 21, 14, 30, 0, 0, time.FixedZone("", -7*3600))
+
+func TestBuilderText(t *testing.T) {
+	b := &Builder{
+		From:    &Address{Name: "Jörg Doe", Address: "joerg@example.com"},
+		To:      []*Address{{Name: "Alice Smith", Address: "alice@example.com"}, {Address: "bob@example.com"}},
+		Subject: "Grüße",
+		Date:    testDate,
+		Header: Header{
+			"Reply-To":     {`=?utf-8?q?J=C3=B6rg?= <joerg@example.com>, "Bob" <bob@example.com>`},
+			"Message-Id":   {"<1234@example.com>"},
+			"X-Mailer":     {"Grüße 1.0"},
+			"content-type": {"text/html"},
+		},
+		Text: "Hallo Alice,\nwie geht's?\n",
+	}
+	got, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	want := "From: =?utf-8?q?J=C3=B6rg_Doe?= <joerg@example.com>\r\n" +
+		"To: \"Alice Smith\" <alice@example.com>, <bob@example.com>\r\n" +
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n" +
+		"Date: Fri, 21 Jun 2013 14:30:00 -0700\r\n" +
+		"Message-Id: <1234@example.com>\r\n" +
+		"Reply-To: =?utf-8?q?J=C3=B6rg?= <joerg@example.com>, \"Bob\" <bob@example.com>\r\n" +
+		"X-Mailer: =?utf-8?q?Gr=C3=BC=C3=9Fe_1.0?=\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"Hallo Alice,\r\n" +
+		"wie geht's?\r\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBuilderParse(t *testing.T) {
+	pdf := bytes.Repeat([]byte("%PDF-1.4 \x00\xff"), 20)
+	b := &Builder{
+		From:    &Address{Name: "Jörg Doe", Address: "joerg@example.com"},
+		To:      []*Address{{Name: "Алиса", Address: "alice@example.com"}},
+		Cc:      []*Address{{Address: "bob@example.com"}},
+		Subject: strings.Repeat("A long subject, with spaces ", 5) + "and ünïcödé",
+		Date:    testDate,
+		Text:    "Plain text.\n",
+		HTML:    "<p>HTML text with a long line: " + strings.Repeat("très ", 30) + "</p>\n",
+		Attachments: []*Attachment{
+			{Filename: "report.pdf", Data: pdf},
+			{Filename: "données.bin", ContentType: "application/x-test", Data: []byte("x")},
+		},
+	}
+	raw, err := b.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	header := string(raw[:bytes.Index(raw, []byte("\r\n\r\n"))])
+	for _, line := range strings.Split(header, "\r\n") {
+		if len(line) > maxHeaderLine {
+			t.Errorf("line longer than %d characters: %q", maxHeaderLine, line)
+		}
+	}
+
+	msg, err := ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	for _, key := range []string{"From", "To", "Cc"} {
+		list, err := msg.Header.AddressList(key)
+		if err != nil {
+			t.Errorf("AddressList(%q): %v", key, err)
+			continue
+		}
+		var want []*Address
+		switch key {
+		case "From":
+			want = []*Address{b.From}
+		case "To":
+			want = b.To
+		case "Cc":
+			want = b.Cc
+		}
+		if !reflect.DeepEqual(list, want) {
+			t.Errorf("%s = %v; want %v", key, list, want)
+		}
+	}
+	dec := new(mime.WordDecoder)
+	if subject, err := dec.DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != b.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, b.Subject)
+	}
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(testDate) {
+		t.Errorf("Date = %v, %v; want %v", date, err, testDate)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+
+	p, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart: %v", err)
+	}
+	mediaType, params, err = mime.ParseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("body Content-Type = %q, %v", p.Header.Get("Content-Type"), err)
+	}
+	ar := multipart.NewReader(p, params["boundary"])
+	for _, want := range []struct{ typ, body string }{
+		{"text/plain; charset=utf-8", "Plain text.\r\n"},
+		{"text/html; charset=utf-8", strings.Replace(b.HTML, "\n", "\r\n", -1)},
+	} {
+		ap, err := ar.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart of alternative: %v", err)
+		}
+		if typ := ap.Header.Get("Content-Type"); typ != want.typ {
+			t.Errorf("Content-Type = %q; want %q", typ, want.typ)
+		}
+		body, err := ioutil.ReadAll(ap)
+		if err != nil {
+			t.Fatalf("reading %s: %v", want.typ, err)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s body = %q; want %q", want.typ, body, want.body)
+		}
+	}
+	if _, err := ar.NextPart(); err == nil {
+		t.Error("alternative has more than two parts")
+	}
+
+	for _, want := range []struct {
+		typ, name string
+		data      []byte
+	}{
+		{"application/pdf", "report.pdf", pdf},
+		{"application/x-test", "données.bin", []byte("x")},
+	} {
+		p, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart: %v", err)
+		}
+		if typ := p.Header.Get("Content-Type"); typ != want.typ {
+			t.Errorf("Content-Type = %q; want %q", typ, want.typ)
+		}
+		_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+		if err != nil {
+			t.Errorf("Content-Disposition: %v", err)
+		}
+		if name := params["filename"]; name != want.name {
+			t.Errorf("file name = %q; want %q", name, want.name)
+		}
+		data, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+		if err != nil {
+			t.Fatalf("decoding %s: %v", want.name, err)
+		}
+		if !bytes.Equal(data, want.data) {
+			t.Errorf("%s = %q; want %q", want.name, data, want.data)
+		}
+	}
+	if _, err := mr.NextPart(); err == nil {
+		t.Error("message has more than three parts")
+	}
+}
+
+func TestBuilderNoFrom(t *testing.T) {
+	b := &Builder{Text: "Hi."}
+	if _, err := b.Bytes(); err == nil {
+		t.Error("Bytes succeeded without a From address")
+	}
+}
+
+func TestBuilderHeaderNewline(t *testing.T) {
+	tests := []*Builder{
+		{Header: Header{"Message-Id": {"<x@example.com>\r\nBcc: evil@example.com"}}},
+		{Header: Header{"References": {"<x@example.com>\nBcc: evil@example.com"}}},
+		{Header: Header{"X-Mailer": {"x\rBcc: evil@example.com"}}},
+		{Header: Header{"X-Mailer\r\nBcc": {"evil@example.com"}}},
+		{To: []*Address{{Address: "bob@example.com>\r\nBcc: <evil@example.com"}}},
+		{Attachments: []*Attachment{{Filename: "a.txt", ContentType: "text/plain\r\nX-Evil: 1"}}},
+	}
+	for _, b := range tests {
+		b.From = &Address{Address: "joerg@example.com"}
+		b.Text = "Hi."
+		if msg, err := b.Bytes(); err != errHeaderNewline {
+			t.Errorf("Bytes = %q, %v; want error %v", msg, err, errHeaderNewline)
+		}
+	}
+}
+
+func TestBuilderBadAddressHeader(t *testing.T) {
+	b := &Builder{
+		From:   &Address{Address: "joerg@example.com"},
+		Header: Header{"Reply-To": {"Jörg <joerg@example.com>"}},
+		Text:   "Hi.",
+	}
+	if _, err := b.Bytes(); err == nil {
+		t.Error("Bytes succeeded with an invalid Reply-To address")
+	}
+}
+
+func TestFilenameParam(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"report.pdf", `filename="report.pdf"`},
+		{`a "b".txt`, `filename="a \"b\".txt"`},
+		{"données 1.bin", "filename*=utf-8''donn%C3%A9es%201.bin"},
+	}
+	for _, tt := range tests {
+		if got := filenameParam(tt.name); got != tt.want {
+			t.Errorf("filenameParam(%q) = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -277,11 +278,22 @@ func TestAddressFormatting(t *testing.T) {
 			&Address{Name: "Böb", Address: "bob@example.com"},
 			`=?utf-8?q?B=C3=B6b?= <bob@example.com>`,
 		},
+		{
+			// a name long enough to be split into several encoded-words
+			&Address{Name: strings.Repeat("é", 40), Address: "bob@example.com"},
+			strings.Repeat("=?utf-8?q?"+strings.Repeat("=C3=A9", 10)+"?= ", 4) + "<bob@example.com>",
+		},
 	}
 	for _, test := range tests {
 		s := test.addr.String()
 		if s != test.exp {
 			t.Errorf("Address%+v.String() = %v, want %v", *test.addr, s, test.exp)
 		}
+		a, err := ParseAddress(s)
+		if err != nil {
+			t.Errorf("ParseAddress(%q): %v", s, err)
+		} else if *a != *test.addr {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", s, *a, *test.addr)
+		}
 	}
 }
@@ -0,0 +1,206 @@
+// Copyright 2013 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Batched datagram I/O using recvmmsg and sendmmsg.
+
+package net
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// An mmsghdr is the struct mmsghdr of the recvmmsg and sendmmsg
+// system calls: a message header followed by the number of bytes
+// transferred for the message.
+type mmsghdr struct {
+	hdr syscall.Msghdr
+	len uint32
+}
+
+// setBuffers points h at the payload b and out-of-band data oob,
+// using iov as the single I/O vector of the message.
+func (h *mmsghdr) setBuffers(iov *syscall.Iovec, b, oob []byte) {
+	if len(b) > 0 {
+		iov.Base = &b[0]
+		iov.SetLen(len(b))
+	}
+	h.hdr.Iov = iov
+	h.hdr.Iovlen = 1
+	if len(oob) > 0 {
+		h.hdr.Control = &oob[0]
+		h.hdr.SetControllen(len(oob))
+	}
+}
+
+func recvmmsg(s int, hs []mmsghdr, flags int) (int, error) {
+	n, _, e := syscall.Syscall6(sysRECVMMSG, uintptr(s), uintptr(unsafe.Pointer(&hs[0])), uintptr(len(hs)), uintptr(flags), 0, 0)
+	if e != 0 {
+		return 0, e
+	}
+	return int(n), nil
+}
+
+func sendmmsg(s int, hs []mmsghdr, flags int) (int, error) {
+	n, _, e := syscall.Syscall6(sysSENDMMSG, uintptr(s), uintptr(unsafe.Pointer(&hs[0])), uintptr(len(hs)), uintptr(flags), 0, 0)
+	if e != 0 {
+		return 0, e
+	}
+	return int(n), nil
+}
+
+// readMmsg receives as many of the messages described by hs as are
+// available, waiting for at least one. It returns syscall.ENOSYS as is
+// if the kernel lacks recvmmsg.
+func (fd *netFD) readMmsg(hs []mmsghdr) (n int, err error) {
+	if err := fd.readLock(); err != nil {
+		return 0, err
+	}
+	defer fd.readUnlock()
+	if err := fd.pd.PrepareRead(); err != nil {
+		return 0, &OpError{"read", fd.net, fd.laddr, err}
+	}
+	for {
+		n, err = recvmmsg(fd.sysfd, hs, 0)
+		if err == syscall.EAGAIN {
+			if err = fd.pd.WaitRead(); err == nil {
+				continue
+			}
+		}
+		break
+	}
+	if err != nil && err != syscall.ENOSYS {
+		err = &OpError{"read", fd.net, fd.laddr, err}
+	}
+	return
+}
+
+// writeMmsg sends the messages described by hs, returning the number
+// of messages sent. It returns syscall.ENOSYS as is if the kernel lacks
+// sendmmsg.
+func (fd *netFD) writeMmsg(hs []mmsghdr) (n int, err error) {
+	if err := fd.writeLock(); err != nil {
+		return 0, err
+	}
+	defer fd.writeUnlock()
+	if err := fd.pd.PrepareWrite(); err != nil {
+		return 0, &OpError{"write", fd.net, fd.raddr, err}
+	}
+	for n < len(hs) {
+		var m int
+		m, err = sendmmsg(fd.sysfd, hs[n:], 0)
+		if err == syscall.EAGAIN {
+			if err = fd.pd.WaitWrite(); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			break
+		}
+		n += m
+	}
+	if err != nil && err != syscall.ENOSYS {
+		err = &OpError{"write", fd.net, fd.raddr, err}
+	}
+	return
+}
+
+func (c *UDPConn) readBatch(ms []UDPMessage) (int, error) {
+	hs := make([]mmsghdr, len(ms))
+	iovs := make([]syscall.Iovec, len(ms))
+	rsas := make([]syscall.RawSockaddrAny, len(ms))
+	for i := range ms {
+		hs[i].setBuffers(&iovs[i], ms[i].Buf, ms[i].OOB)
+		hs[i].hdr.Name = (*byte)(unsafe.Pointer(&rsas[i]))
+		hs[i].hdr.Namelen = syscall.SizeofSockaddrAny
+	}
+	n, err := c.fd.readMmsg(hs)
+	if err == syscall.ENOSYS {
+		// recvmmsg was introduced in Linux 2.6.33.
+		return c.readBatchLoop(ms)
+	}
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < n; i++ {
+		m, h := &ms[i], &hs[i]
+		m.N = int(h.len)
+		m.OOBN = int(h.hdr.Controllen)
+		m.Flags = int(h.hdr.Flags)
+		m.Addr = rawToUDPAddr(&rsas[i])
+	}
+	return n, nil
+}
+
+func (c *UDPConn) writeBatch(ms []UDPMessage) (int, error) {
+	hs := make([]mmsghdr, len(ms))
+	iovs := make([]syscall.Iovec, len(ms))
+	rsas := make([]syscall.RawSockaddrAny, len(ms))
+	for i := range ms {
+		m := &ms[i]
+		hs[i].setBuffers(&iovs[i], m.Buf, m.OOB)
+		if m.Addr == nil {
+			continue
+		}
+		sa, err := m.Addr.sockaddr(c.fd.family)
+		if err != nil {
+			return 0, &OpError{Op: "write", Net: c.fd.net, Addr: m.Addr, Err: err}
+		}
+		hs[i].hdr.Name = (*byte)(unsafe.Pointer(&rsas[i]))
+		hs[i].hdr.Namelen = sockaddrToRaw(sa, &rsas[i])
+	}
+	n, err := c.fd.writeMmsg(hs)
+	if err == syscall.ENOSYS {
+		// sendmmsg was introduced in Linux 3.0.
+		return c.writeBatchLoop(ms)
+	}
+	for i := 0; i < n; i++ {
+		ms[i].N = int(hs[i].len)
+		ms[i].OOBN = len(ms[i].OOB)
+	}
+	return n, err
+}
+
+// rawToUDPAddr returns the UDP address held in rsa, or nil if it is
+// not an internet address.
+func rawToUDPAddr(rsa *syscall.RawSockaddrAny) *UDPAddr {
+	switch rsa.Addr.Family {
+	case syscall.AF_INET:
+		sa := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		p := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		ip := make(IP, IPv4len)
+		copy(ip, sa.Addr[:])
+		return &UDPAddr{IP: ip, Port: int(p[0])<<8 + int(p[1])}
+	case syscall.AF_INET6:
+		sa := (*syscall.RawSockaddrInet6)(unsafe.Pointer(rsa))
+		p := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		ip := make(IP, IPv6len)
+		copy(ip, sa.Addr[:])
+		return &UDPAddr{IP: ip, Port: int(p[0])<<8 + int(p[1]), Zone: zoneToString(int(sa.Scope_id))}
+	}
+	return nil
+}
+
+// sockaddrToRaw stores the internet address sa in rsa and returns
+// its length.
+func sockaddrToRaw(sa syscall.Sockaddr, rsa *syscall.RawSockaddrAny) uint32 {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		raw := (*syscall.RawSockaddrInet4)(unsafe.Pointer(rsa))
+		raw.Family = syscall.AF_INET
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0], p[1] = byte(sa.Port>>8), byte(sa.Port)
+		raw.Addr = sa.Addr
+		return syscall.SizeofSockaddrInet4
+	case *syscall.SockaddrInet6:
+		raw := (*syscall.RawSockaddrInet6)(unsafe.Pointer(rsa))
+		raw.Family = syscall.AF_INET6
+		p := (*[2]byte)(unsafe.Pointer(&raw.Port))
+		p[0], p[1] = byte(sa.Port>>8), byte(sa.Port)
+		raw.Addr = sa.Addr
+		raw.Scope_id = sa.ZoneId
+		return syscall.SizeofSockaddrInet6
+	}
+	return 0
+}
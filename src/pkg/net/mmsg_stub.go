@@ -0,0 +1,17 @@
+// Copyright 2013 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd netbsd openbsd windows
+
+// Batched datagram I/O for systems without recvmmsg and sendmmsg.
+
+package net
+
+func (c *UDPConn) readBatch(ms []UDPMessage) (int, error) {
+	return c.readBatchLoop(ms)
+}
+
+func (c *UDPConn) writeBatch(ms []UDPMessage) (int, error) {
+	return c.writeBatchLoop(ms)
+}
@@ -342,6 +342,8 @@ var errTimeout error = &timeoutError{}
 
 var errClosing = errors.New("use of closed network connection")
 
+var errCanceled = errors.New("operation was canceled")
+
 type AddrError struct {
 	Err  string
 	Addr string
@@ -0,0 +1,351 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import (
+	"errors"
+	"sort"
+)
+
+// An IPRange is an inclusive range of IP addresses of the same family,
+// such as 192.0.2.1-192.0.2.9.
+//
+// The zero IPRange is not valid. IPRange values are comparable.
+type IPRange struct {
+	from, to Addr
+}
+
+// IPRangeFrom returns the range of addresses from from to to,
+// inclusive. It does not check that the range is valid.
+func IPRangeFrom(from, to Addr) IPRange { return IPRange{from, to} }
+
+// ParseIPRange parses a range written as two addresses separated by a
+// hyphen, such as "192.0.2.1-192.0.2.9". The range must be valid.
+func ParseIPRange(s string) (IPRange, error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '-' {
+			continue
+		}
+		from, err := ParseAddr(s[:i])
+		if err != nil {
+			return IPRange{}, errors.New("netip.ParseIPRange: invalid From address: " + err.Error())
+		}
+		to, err := ParseAddr(s[i+1:])
+		if err != nil {
+			return IPRange{}, errors.New("netip.ParseIPRange: invalid To address: " + err.Error())
+		}
+		r := IPRange{from, to}
+		if !r.IsValid() {
+			return IPRange{}, errors.New("netip.ParseIPRange: invalid range " + s)
+		}
+		return r, nil
+	}
+	return IPRange{}, errors.New("netip.ParseIPRange: no '-' in " + s)
+}
+
+// From returns the first address of r.
+func (r IPRange) From() Addr { return r.from }
+
+// To returns the last address of r.
+func (r IPRange) To() Addr { return r.to }
+
+// IsValid reports whether r is a non-empty range: both ends are valid
+// addresses of the same family without zones, and From is not after
+// To.
+func (r IPRange) IsValid() bool {
+	return r.from.IsValid() && r.from.fam == r.to.fam &&
+		r.from.zone == "" && r.to.zone == "" &&
+		r.from.Compare(r.to) <= 0
+}
+
+// Contains reports whether r contains ip. As with Prefix.Contains,
+// addresses of the other family or with a zone never match.
+func (r IPRange) Contains(ip Addr) bool {
+	return r.IsValid() && ip.zone == "" &&
+		r.from.Compare(ip) <= 0 && ip.Compare(r.to) <= 0
+}
+
+// Overlaps reports whether r and o contain any addresses in common.
+func (r IPRange) Overlaps(o IPRange) bool {
+	return r.IsValid() && o.IsValid() &&
+		r.from.Compare(o.to) <= 0 && o.from.Compare(r.to) <= 0
+}
+
+// Prefix returns r as a Prefix, if it can be represented exactly as
+// one.
+func (r IPRange) Prefix() (p Prefix, ok bool) {
+	if !r.IsValid() {
+		return Prefix{}, false
+	}
+	p = r.largestPrefix()
+	return p, p.Range() == r
+}
+
+// Prefixes returns the smallest list of prefixes that covers exactly
+// the addresses in r. It returns nil if r is invalid.
+func (r IPRange) Prefixes() []Prefix {
+	if !r.IsValid() {
+		return nil
+	}
+	var ps []Prefix
+	for {
+		p := r.largestPrefix()
+		ps = append(ps, p)
+		last := p.Range().to
+		if last == r.to {
+			return ps
+		}
+		r.from = last.Next()
+	}
+}
+
+// largestPrefix returns the largest prefix that starts at r.From and
+// ends no later than r.To.
+func (r IPRange) largestPrefix() Prefix {
+	off := r.from.maskOffset()
+	bits := 0
+	for ; bits < r.from.BitLen(); bits++ {
+		n := off + bits
+		if r.from.addr.bitsClearedFrom(n) == r.from.addr && r.from.addr.bitsSetFrom(n).cmp(r.to.addr) <= 0 {
+			break
+		}
+	}
+	return PrefixFrom(r.from, bits)
+}
+
+// String returns r in the form accepted by ParseIPRange, or
+// "invalid IPRange" if r is invalid.
+func (r IPRange) String() string {
+	if !r.IsValid() {
+		return "invalid IPRange"
+	}
+	return r.from.String() + "-" + r.to.String()
+}
+
+// An IPSetBuilder builds an immutable IPSet.
+//
+// The zero IPSetBuilder is an empty set. Additions and removals take
+// effect in the order they are made: an address removed and then added
+// again is in the resulting set.
+type IPSetBuilder struct {
+	// in holds the ranges in the set; out holds the ranges to
+	// remove from it, which are applied before the next addition.
+	in, out []IPRange
+
+	// err is the first error encountered, reported by IPSet.
+	err error
+}
+
+// Add adds ip to the set. Invalid addresses are ignored.
+func (b *IPSetBuilder) Add(ip Addr) {
+	if ip.IsValid() {
+		b.AddRange(IPRange{ip.WithZone(""), ip.WithZone("")})
+	}
+}
+
+// AddPrefix adds all the addresses in p to the set.
+func (b *IPSetBuilder) AddPrefix(p Prefix) { b.addRange(p.Range(), "AddPrefix", p.String()) }
+
+// AddRange adds all the addresses in r to the set.
+func (b *IPSetBuilder) AddRange(r IPRange) { b.addRange(r, "AddRange", r.String()) }
+
+// AddSet adds all the addresses in s to the set.
+func (b *IPSetBuilder) AddSet(s *IPSet) {
+	for _, r := range s.rr {
+		b.AddRange(r)
+	}
+}
+
+func (b *IPSetBuilder) addRange(r IPRange, op, arg string) {
+	if !r.IsValid() {
+		b.setErr(op, arg)
+		return
+	}
+	if len(b.out) > 0 {
+		b.normalize()
+	}
+	b.in = append(b.in, r)
+}
+
+// Remove removes ip from the set. Invalid addresses are ignored.
+func (b *IPSetBuilder) Remove(ip Addr) {
+	if ip.IsValid() {
+		b.RemoveRange(IPRange{ip.WithZone(""), ip.WithZone("")})
+	}
+}
+
+// RemovePrefix removes all the addresses in p from the set.
+func (b *IPSetBuilder) RemovePrefix(p Prefix) { b.removeRange(p.Range(), "RemovePrefix", p.String()) }
+
+// RemoveRange removes all the addresses in r from the set.
+func (b *IPSetBuilder) RemoveRange(r IPRange) { b.removeRange(r, "RemoveRange", r.String()) }
+
+// RemoveSet removes all the addresses in s from the set.
+func (b *IPSetBuilder) RemoveSet(s *IPSet) {
+	for _, r := range s.rr {
+		b.RemoveRange(r)
+	}
+}
+
+func (b *IPSetBuilder) removeRange(r IPRange, op, arg string) {
+	if !r.IsValid() {
+		b.setErr(op, arg)
+		return
+	}
+	b.out = append(b.out, r)
+}
+
+func (b *IPSetBuilder) setErr(op, arg string) {
+	if b.err == nil {
+		b.err = errors.New("netip: IPSetBuilder." + op + " called with " + arg)
+	}
+}
+
+// normalize merges the ranges in b.in and subtracts b.out from them.
+func (b *IPSetBuilder) normalize() {
+	b.in = subtractRanges(mergeRanges(b.in), mergeRanges(b.out))
+	b.out = nil
+}
+
+// IPSet returns the set built so far. The builder remains usable. If
+// any invalid prefix or range was added or removed, IPSet returns the
+// set of the valid ones along with an error describing the first
+// invalid one.
+func (b *IPSetBuilder) IPSet() (*IPSet, error) {
+	b.normalize()
+	rr := make([]IPRange, len(b.in))
+	copy(rr, b.in)
+	return &IPSet{rr}, b.err
+}
+
+type rangesByFrom []IPRange
+
+func (rs rangesByFrom) Len() int           { return len(rs) }
+func (rs rangesByFrom) Less(i, j int) bool { return rs[i].from.Less(rs[j].from) }
+func (rs rangesByFrom) Swap(i, j int)      { rs[i], rs[j] = rs[j], rs[i] }
+
+// mergeRanges sorts rs and merges overlapping and adjacent ranges,
+// reusing the storage of rs.
+func mergeRanges(rs []IPRange) []IPRange {
+	if len(rs) == 0 {
+		return rs
+	}
+	sort.Sort(rangesByFrom(rs))
+	out := rs[:1]
+	for _, r := range rs[1:] {
+		last := &out[len(out)-1]
+		if r.from.Compare(last.to) <= 0 || r.from == last.to.Next() {
+			if last.to.Less(r.to) {
+				last.to = r.to
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// subtractRanges returns the ranges in in minus those in out. Both
+// must be sorted and merged.
+func subtractRanges(in, out []IPRange) []IPRange {
+	var rs []IPRange
+	j := 0
+	for _, r := range in {
+		for j < len(out) && out[j].to.Less(r.from) {
+			j++
+		}
+		valid := true
+		for k := j; k < len(out) && out[k].from.Compare(r.to) <= 0; k++ {
+			o := out[k]
+			if r.from.Less(o.from) {
+				rs = append(rs, IPRange{r.from, o.from.Prev()})
+			}
+			if r.to.Compare(o.to) <= 0 {
+				valid = false
+				break
+			}
+			r.from = o.to.Next()
+		}
+		if valid {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// An IPSet is an immutable set of IP addresses, made with an
+// IPSetBuilder. The zero IPSet is empty.
+type IPSet struct {
+	// rr holds the set's ranges, sorted and with no two ranges
+	// overlapping or adjacent.
+	rr []IPRange
+}
+
+// Ranges returns the minimum and sorted list of ranges that covers
+// exactly the addresses in s.
+func (s *IPSet) Ranges() []IPRange {
+	rr := make([]IPRange, len(s.rr))
+	copy(rr, s.rr)
+	return rr
+}
+
+// Prefixes returns the minimum and sorted list of prefixes that covers
+// exactly the addresses in s.
+func (s *IPSet) Prefixes() []Prefix {
+	var ps []Prefix
+	for _, r := range s.rr {
+		ps = append(ps, r.Prefixes()...)
+	}
+	return ps
+}
+
+// Equal reports whether s and o contain the same addresses.
+func (s *IPSet) Equal(o *IPSet) bool {
+	if len(s.rr) != len(o.rr) {
+		return false
+	}
+	for i := range s.rr {
+		if s.rr[i] != o.rr[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// find returns the index of the range of s that would contain ip.
+func (s *IPSet) find(ip Addr) int {
+	return sort.Search(len(s.rr), func(i int) bool { return ip.Compare(s.rr[i].to) <= 0 })
+}
+
+// Contains reports whether ip is in s. As with Prefix.Contains,
+// addresses with a zone never match.
+func (s *IPSet) Contains(ip Addr) bool {
+	i := s.find(ip)
+	return i < len(s.rr) && s.rr[i].Contains(ip)
+}
+
+// ContainsRange reports whether all the addresses in r are in s.
+func (s *IPSet) ContainsRange(r IPRange) bool {
+	if !r.IsValid() {
+		return false
+	}
+	i := s.find(r.from)
+	return i < len(s.rr) && s.rr[i].from.Compare(r.from) <= 0 && r.to.Compare(s.rr[i].to) <= 0
+}
+
+// ContainsPrefix reports whether all the addresses in p are in s.
+func (s *IPSet) ContainsPrefix(p Prefix) bool { return s.ContainsRange(p.Range()) }
+
+// OverlapsRange reports whether any address in r is in s.
+func (s *IPSet) OverlapsRange(r IPRange) bool {
+	if !r.IsValid() {
+		return false
+	}
+	i := s.find(r.from)
+	return i < len(s.rr) && s.rr[i].Overlaps(r)
+}
+
+// OverlapsPrefix reports whether any address in p is in s.
+func (s *IPSet) OverlapsPrefix(p Prefix) bool { return s.OverlapsRange(p.Range()) }
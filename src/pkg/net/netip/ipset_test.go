@@ -0,0 +1,267 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseIPRange(t *testing.T) {
+	r, err := ParseIPRange("192.0.2.1-192.0.2.9")
+	if err != nil {
+		t.Fatalf("ParseIPRange failed: %v", err)
+	}
+	if r.From() != MustParseAddr("192.0.2.1") || r.To() != MustParseAddr("192.0.2.9") || r.String() != "192.0.2.1-192.0.2.9" {
+		t.Errorf("ParseIPRange = %v", r)
+	}
+	for _, in := range []string{"", "192.0.2.1", "192.0.2.9-192.0.2.1", "192.0.2.1-2001:db8::1", "fe80::1%eth0-fe80::2", "x-192.0.2.1"} {
+		if r, err := ParseIPRange(in); err == nil {
+			t.Errorf("ParseIPRange(%q) = %v; want error", in, r)
+		}
+	}
+}
+
+func TestIPRangeContains(t *testing.T) {
+	r := IPRangeFrom(MustParseAddr("192.0.2.10"), MustParseAddr("192.0.2.20"))
+	for _, tt := range []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.9", false},
+		{"192.0.2.10", true},
+		{"192.0.2.15", true},
+		{"192.0.2.20", true},
+		{"192.0.2.21", false},
+		{"::ffff:192.0.2.15", false},
+	} {
+		if got := r.Contains(MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v; want %v", r, tt.ip, got, tt.want)
+		}
+	}
+	if IPRangeFrom(MustParseAddr("192.0.2.20"), MustParseAddr("192.0.2.10")).Contains(MustParseAddr("192.0.2.15")) {
+		t.Error("an invalid range contains an address")
+	}
+}
+
+func TestIPRangePrefixes(t *testing.T) {
+	tests := []struct {
+		r    string
+		want string
+	}{
+		{"192.0.2.0-192.0.2.255", "[192.0.2.0/24]"},
+		{"192.0.2.1-192.0.2.1", "[192.0.2.1/32]"},
+		{"192.0.2.1-192.0.2.6", "[192.0.2.1/32 192.0.2.2/31 192.0.2.4/31 192.0.2.6/32]"},
+		{"0.0.0.0-255.255.255.255", "[0.0.0.0/0]"},
+		{"255.255.255.254-255.255.255.255", "[255.255.255.254/31]"},
+		{"2001:db8::-2001:db8::1:ffff", "[2001:db8::/111]"},
+		{"::-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "[::/0]"},
+		{"::1-::2", "[::1/128 ::2/128]"},
+	}
+	for _, tt := range tests {
+		r, err := ParseIPRange(tt.r)
+		if err != nil {
+			t.Fatalf("ParseIPRange(%q) failed: %v", tt.r, err)
+		}
+		if got := fmt.Sprint(r.Prefixes()); got != tt.want {
+			t.Errorf("%v.Prefixes() = %v; want %v", r, got, tt.want)
+		}
+		p, ok := r.Prefix()
+		if want := len(r.Prefixes()) == 1; ok != want {
+			t.Errorf("%v.Prefix() = %v, %v; want ok %v", r, p, ok, want)
+		}
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	p := MustParsePrefix("192.0.2.77/28")
+	if got, want := p.Range().String(), "192.0.2.64-192.0.2.79"; got != want {
+		t.Errorf("%v.Range() = %v; want %v", p, got, want)
+	}
+	p = MustParsePrefix("2001:db8::/32")
+	if got, want := p.Range().String(), "2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"; got != want {
+		t.Errorf("%v.Range() = %v; want %v", p, got, want)
+	}
+}
+
+func TestIPSetBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *IPSetBuilder)
+		want  string // the set's ranges
+	}{
+		{
+			"empty",
+			func(b *IPSetBuilder) {},
+			"[]",
+		},
+		{
+			"merge adjacent and overlapping",
+			func(b *IPSetBuilder) {
+				b.AddPrefix(MustParsePrefix("192.0.2.0/25"))
+				b.AddPrefix(MustParsePrefix("192.0.2.128/25"))
+				b.AddRange(IPRangeFrom(MustParseAddr("192.0.2.200"), MustParseAddr("192.0.3.5")))
+				b.Add(MustParseAddr("192.0.3.6"))
+				b.Add(MustParseAddr("192.0.3.8"))
+			},
+			"[192.0.2.0-192.0.3.6 192.0.3.8-192.0.3.8]",
+		},
+		{
+			"families kept apart",
+			func(b *IPSetBuilder) {
+				b.Add(MustParseAddr("255.255.255.255"))
+				b.Add(MustParseAddr("::"))
+				b.Add(MustParseAddr("::ffff:255.255.255.255"))
+			},
+			"[255.255.255.255-255.255.255.255 ::-:: ::ffff:255.255.255.255-::ffff:255.255.255.255]",
+		},
+		{
+			"subtract",
+			func(b *IPSetBuilder) {
+				b.AddPrefix(MustParsePrefix("192.0.2.0/24"))
+				b.RemovePrefix(MustParsePrefix("192.0.2.64/26"))
+				b.Remove(MustParseAddr("192.0.2.0"))
+				b.Remove(MustParseAddr("192.0.2.255"))
+				b.Remove(MustParseAddr("198.51.100.1"))
+			},
+			"[192.0.2.1-192.0.2.63 192.0.2.128-192.0.2.254]",
+		},
+		{
+			"remove everything",
+			func(b *IPSetBuilder) {
+				b.AddPrefix(MustParsePrefix("192.0.2.0/24"))
+				b.AddPrefix(MustParsePrefix("2001:db8::/64"))
+				b.RemovePrefix(MustParsePrefix("0.0.0.0/0"))
+				b.RemovePrefix(MustParsePrefix("::/0"))
+			},
+			"[]",
+		},
+		{
+			"add after remove",
+			func(b *IPSetBuilder) {
+				b.AddPrefix(MustParsePrefix("192.0.2.0/30"))
+				b.Remove(MustParseAddr("192.0.2.1"))
+				b.Add(MustParseAddr("192.0.2.1"))
+				b.Remove(MustParseAddr("192.0.2.2"))
+			},
+			"[192.0.2.0-192.0.2.1 192.0.2.3-192.0.2.3]",
+		},
+		{
+			"one removal spanning ranges",
+			func(b *IPSetBuilder) {
+				b.Add(MustParseAddr("192.0.2.1"))
+				b.Add(MustParseAddr("192.0.2.3"))
+				b.Add(MustParseAddr("192.0.2.5"))
+				b.RemoveRange(IPRangeFrom(MustParseAddr("192.0.2.2"), MustParseAddr("192.0.2.4")))
+			},
+			"[192.0.2.1-192.0.2.1 192.0.2.5-192.0.2.5]",
+		},
+		{
+			"zones dropped",
+			func(b *IPSetBuilder) {
+				b.Add(MustParseAddr("fe80::1%eth0"))
+			},
+			"[fe80::1-fe80::1]",
+		},
+	}
+	for _, tt := range tests {
+		var b IPSetBuilder
+		tt.build(&b)
+		s, err := b.IPSet()
+		if err != nil {
+			t.Errorf("%s: IPSet failed: %v", tt.name, err)
+			continue
+		}
+		if got := fmt.Sprint(s.Ranges()); got != tt.want {
+			t.Errorf("%s: got ranges %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIPSetBuilderError(t *testing.T) {
+	var b IPSetBuilder
+	b.AddPrefix(MustParsePrefix("192.0.2.0/24"))
+	b.AddPrefix(Prefix{})
+	b.RemoveRange(IPRangeFrom(MustParseAddr("192.0.2.9"), MustParseAddr("192.0.2.1")))
+	s, err := b.IPSet()
+	if err == nil {
+		t.Fatal("IPSet succeeded after adding an invalid prefix")
+	}
+	if got, want := fmt.Sprint(s.Prefixes()), "[192.0.2.0/24]"; got != want {
+		t.Errorf("got prefixes %v; want %v", got, want)
+	}
+}
+
+func TestIPSet(t *testing.T) {
+	var b IPSetBuilder
+	b.AddPrefix(MustParsePrefix("10.0.0.0/8"))
+	b.AddPrefix(MustParsePrefix("192.0.2.0/24"))
+	b.AddPrefix(MustParsePrefix("2001:db8::/32"))
+	b.RemovePrefix(MustParsePrefix("10.1.0.0/16"))
+	s, err := b.IPSet()
+	if err != nil {
+		t.Fatalf("IPSet failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.1", true},
+		{"10.1.2.3", false},
+		{"10.2.0.0", true},
+		{"11.0.0.0", false},
+		{"192.0.2.255", true},
+		{"::ffff:192.0.2.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8::1%eth0", false},
+		{"2001:db9::1", false},
+	} {
+		if got := s.Contains(MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("Contains(%v) = %v; want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		p                  string
+		contains, overlaps bool
+	}{
+		{"10.0.0.0/16", true, true},
+		{"10.0.0.0/8", false, true},
+		{"10.1.0.0/16", false, false},
+		{"192.0.2.128/25", true, true},
+		{"192.0.0.0/16", false, true},
+		{"198.51.100.0/24", false, false},
+		{"2001:db8:1::/48", true, true},
+	} {
+		p := MustParsePrefix(tt.p)
+		if got := s.ContainsPrefix(p); got != tt.contains {
+			t.Errorf("ContainsPrefix(%v) = %v; want %v", p, got, tt.contains)
+		}
+		if got := s.OverlapsPrefix(p); got != tt.overlaps {
+			t.Errorf("OverlapsPrefix(%v) = %v; want %v", p, got, tt.overlaps)
+		}
+	}
+
+	want := "[10.0.0.0/16 10.2.0.0/15 10.4.0.0/14 10.8.0.0/13 10.16.0.0/12 10.32.0.0/11 10.64.0.0/10 10.128.0.0/9 192.0.2.0/24 2001:db8::/32]"
+	if got := fmt.Sprint(s.Prefixes()); got != want {
+		t.Errorf("Prefixes() = %v; want %v", got, want)
+	}
+
+	// Rebuilding from the set's own ranges gives an equal set.
+	var b2 IPSetBuilder
+	b2.AddSet(s)
+	s2, _ := b2.IPSet()
+	if !s.Equal(s2) {
+		t.Errorf("rebuilt set %v differs from %v", s2.Ranges(), s.Ranges())
+	}
+	b2.RemoveSet(s)
+	if s3, _ := b2.IPSet(); len(s3.Ranges()) != 0 {
+		t.Errorf("set minus itself = %v; want empty", s3.Ranges())
+	}
+	if s.Equal(&IPSet{}) {
+		t.Error("a set equals the empty set")
+	}
+}
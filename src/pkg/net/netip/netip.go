@@ -0,0 +1,610 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package netip defines IP address and prefix types that are small
+// immutable values.
+//
+// Unlike net.IP, which is a byte slice, an Addr can be compared with ==
+// and used as a map key, and parsing one does not allocate. Building on
+// Addr, the package defines Prefix (an IP network in CIDR notation),
+// IPRange (an inclusive range of addresses) and IPSet (an arbitrary set
+// of addresses, built with an IPSetBuilder).
+//
+// This package does not depend on package net. AddrFromSlice accepts a
+// net.IP and the result of Addr.AsSlice converts to one; the Prefix
+// method of net.IPNet and the net.IPNetFromPrefix function convert
+// between IPNet and Prefix.
+package netip
+
+import (
+	"errors"
+	"strconv"
+)
+
+// An Addr is an IPv4 or IPv6 address, possibly with an IPv6 zone.
+//
+// The zero Addr is not a valid IP address. Addr values are comparable;
+// two Addrs are equal if they are of the same address family and have
+// the same bits and zone. An IPv4 address is never equal to its
+// IPv4-mapped IPv6 form.
+type Addr struct {
+	// addr holds the address bits. IPv4 addresses are stored in
+	// their IPv4-mapped IPv6 form.
+	addr uint128
+
+	// fam is 4 or 6 for IPv4 and IPv6 addresses, or 0 for the
+	// zero Addr.
+	fam uint8
+
+	// zone is the IPv6 scoped addressing zone, if any.
+	zone string
+}
+
+// IPv4Unspecified returns the IPv4 unspecified address "0.0.0.0".
+func IPv4Unspecified() Addr { return AddrFrom4([4]byte{}) }
+
+// IPv6Unspecified returns the IPv6 unspecified address "::".
+func IPv6Unspecified() Addr { return Addr{fam: 6} }
+
+// AddrFrom4 returns the IPv4 address given by the bytes in b.
+func AddrFrom4(b [4]byte) Addr {
+	return Addr{
+		addr: uint128{0, 0xffff00000000 | uint64(b[0])<<24 | uint64(b[1])<<16 | uint64(b[2])<<8 | uint64(b[3])},
+		fam:  4,
+	}
+}
+
+// AddrFrom16 returns the IPv6 address given by the bytes in b. An
+// IPv4-mapped address is left as an IPv6 address; use Unmap to convert
+// it to an IPv4 address.
+func AddrFrom16(b [16]byte) Addr {
+	var a Addr
+	for i := 0; i < 8; i++ {
+		a.addr.hi = a.addr.hi<<8 | uint64(b[i])
+		a.addr.lo = a.addr.lo<<8 | uint64(b[i+8])
+	}
+	a.fam = 6
+	return a
+}
+
+// AddrFromSlice parses the 4- or 16-byte slice s, such as a net.IP, as
+// an IPv4 or IPv6 address. Note that package net returns most IPv4
+// addresses in their 16-byte form, which AddrFromSlice returns as an
+// IPv4-mapped IPv6 address; use Unmap to convert it to an IPv4 address.
+// It reports false if the length of s is neither 4 nor 16.
+func AddrFromSlice(s []byte) (ip Addr, ok bool) {
+	switch len(s) {
+	case 4:
+		var b [4]byte
+		copy(b[:], s)
+		return AddrFrom4(b), true
+	case 16:
+		var b [16]byte
+		copy(b[:], s)
+		return AddrFrom16(b), true
+	}
+	return Addr{}, false
+}
+
+// parseAddrError represents an error parsing an address.
+type parseAddrError struct {
+	in  string // the string given to ParseAddr
+	msg string // an explanation of the parse failure
+	at  string // optionally, the unparsed portion of in where the error occurred
+}
+
+func (err parseAddrError) Error() string {
+	q := strconv.Quote
+	if err.at != "" {
+		return "ParseAddr(" + q(err.in) + "): " + err.msg + " (at " + q(err.at) + ")"
+	}
+	return "ParseAddr(" + q(err.in) + "): " + err.msg
+}
+
+// ParseAddr parses s as an IP address. It accepts dotted decimal IPv4
+// addresses such as "192.0.2.1", IPv6 addresses such as "2001:db8::1"
+// or "::ffff:192.0.2.1", and IPv6 addresses with a zone such as
+// "fe80::1%eth0". IPv4 fields with leading zeros are rejected as
+// ambiguous.
+func ParseAddr(s string) (Addr, error) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '.':
+			return parseIPv4(s)
+		case ':':
+			return parseIPv6(s)
+		case '%':
+			return Addr{}, parseAddrError{in: s, msg: "missing IPv6 address"}
+		}
+	}
+	return Addr{}, parseAddrError{in: s, msg: "unable to parse IP"}
+}
+
+// MustParseAddr is like ParseAddr but panics if s cannot be parsed.
+// It simplifies safe initialization of global variables holding
+// addresses.
+func MustParseAddr(s string) Addr {
+	ip, err := ParseAddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return ip
+}
+
+func parseIPv4(s string) (Addr, error) {
+	var fields [4]byte
+	var val, pos, digits int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case '0' <= c && c <= '9':
+			if digits == 1 && val == 0 {
+				return Addr{}, parseAddrError{in: s, msg: "IPv4 field has octet with leading zero"}
+			}
+			val = val*10 + int(c-'0')
+			digits++
+			if val > 255 {
+				return Addr{}, parseAddrError{in: s, msg: "IPv4 field has value >255"}
+			}
+		case c == '.':
+			if digits == 0 || i == len(s)-1 {
+				return Addr{}, parseAddrError{in: s, msg: "IPv4 field must have at least one digit", at: s[i:]}
+			}
+			if pos == 3 {
+				return Addr{}, parseAddrError{in: s, msg: "IPv4 address too long"}
+			}
+			fields[pos] = byte(val)
+			pos++
+			val, digits = 0, 0
+		default:
+			return Addr{}, parseAddrError{in: s, msg: "unexpected character", at: s[i:]}
+		}
+	}
+	if pos < 3 {
+		return Addr{}, parseAddrError{in: s, msg: "IPv4 address too short"}
+	}
+	fields[3] = byte(val)
+	return AddrFrom4(fields), nil
+}
+
+// hexDigit returns the value of the hexadecimal digit c.
+func hexDigit(c byte) (uint32, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return uint32(c - '0'), true
+	case 'a' <= c && c <= 'f':
+		return uint32(c-'a') + 10, true
+	case 'A' <= c && c <= 'F':
+		return uint32(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+func parseIPv6(in string) (Addr, error) {
+	s := in
+
+	// Split off the zone, if any.
+	zone := ""
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' {
+			s, zone = s[:i], s[i+1:]
+			if zone == "" {
+				return Addr{}, parseAddrError{in: in, msg: "zone must be a non-empty string"}
+			}
+			break
+		}
+	}
+
+	var ip [16]byte
+	ellipsis := -1 // position of ellipsis in ip
+
+	// Might have leading ellipsis.
+	if len(s) >= 2 && s[0] == ':' && s[1] == ':' {
+		ellipsis = 0
+		s = s[2:]
+		if len(s) == 0 {
+			return IPv6Unspecified().WithZone(zone), nil
+		}
+	}
+
+	// Loop, parsing hex numbers followed by colon.
+	i := 0
+	for i < 16 {
+		off := 0
+		var acc uint32
+		for off < len(s) {
+			d, ok := hexDigit(s[off])
+			if !ok {
+				break
+			}
+			if off == 4 {
+				return Addr{}, parseAddrError{in: in, msg: "each colon-separated field must have at most 4 hex digits", at: s}
+			}
+			acc = acc<<4 | d
+			off++
+		}
+		if off == 0 {
+			return Addr{}, parseAddrError{in: in, msg: "each colon-separated field must have at least one digit", at: s}
+		}
+
+		// If followed by a dot, this is the trailing IPv4 address.
+		if off < len(s) && s[off] == '.' {
+			if ellipsis < 0 && i != 12 {
+				return Addr{}, parseAddrError{in: in, msg: "embedded IPv4 address must replace the final 2 fields of the address", at: s}
+			}
+			if i+4 > 16 {
+				return Addr{}, parseAddrError{in: in, msg: "too many hex fields to fit an embedded IPv4 at the end of the address", at: s}
+			}
+			ip4, err := parseIPv4(s)
+			if err != nil {
+				return Addr{}, parseAddrError{in: in, msg: err.(parseAddrError).msg, at: s}
+			}
+			b := ip4.As4()
+			copy(ip[i:], b[:])
+			i += 4
+			s = ""
+			break
+		}
+
+		ip[i] = byte(acc >> 8)
+		ip[i+1] = byte(acc)
+		i += 2
+
+		s = s[off:]
+		if len(s) == 0 {
+			break
+		}
+		if s[0] != ':' {
+			return Addr{}, parseAddrError{in: in, msg: "unexpected character, want colon", at: s}
+		}
+		if len(s) == 1 {
+			return Addr{}, parseAddrError{in: in, msg: "colon must be followed by more characters", at: s}
+		}
+		s = s[1:]
+
+		// Look for ellipsis.
+		if s[0] == ':' {
+			if ellipsis >= 0 {
+				return Addr{}, parseAddrError{in: in, msg: "multiple :: in address", at: s}
+			}
+			ellipsis = i
+			s = s[1:]
+			if len(s) == 0 {
+				break
+			}
+		}
+	}
+	if len(s) != 0 {
+		return Addr{}, parseAddrError{in: in, msg: "trailing garbage after address", at: s}
+	}
+
+	// If we didn't get 16 bytes, expand the ellipsis.
+	if i < 16 {
+		if ellipsis < 0 {
+			return Addr{}, parseAddrError{in: in, msg: "address string too short"}
+		}
+		n := 16 - i
+		for j := i - 1; j >= ellipsis; j-- {
+			ip[j+n] = ip[j]
+		}
+		for j := ellipsis + n - 1; j >= ellipsis; j-- {
+			ip[j] = 0
+		}
+	} else if ellipsis >= 0 {
+		return Addr{}, parseAddrError{in: in, msg: "the :: must expand to at least one field of zeros"}
+	}
+	return AddrFrom16(ip).WithZone(zone), nil
+}
+
+// IsValid reports whether ip is a valid address, that is, not the
+// zero Addr.
+func (ip Addr) IsValid() bool { return ip.fam != 0 }
+
+// BitLen returns the number of bits in ip: 32 for IPv4, 128 for IPv6
+// and 0 for the zero Addr.
+func (ip Addr) BitLen() int {
+	switch ip.fam {
+	case 4:
+		return 32
+	case 6:
+		return 128
+	}
+	return 0
+}
+
+// Is4 reports whether ip is an IPv4 address. It returns false for
+// IPv4-mapped IPv6 addresses; see Unmap.
+func (ip Addr) Is4() bool { return ip.fam == 4 }
+
+// Is6 reports whether ip is an IPv6 address, including IPv4-mapped
+// IPv6 addresses.
+func (ip Addr) Is6() bool { return ip.fam == 6 }
+
+// Is4In6 reports whether ip is an IPv4-mapped IPv6 address.
+func (ip Addr) Is4In6() bool {
+	return ip.fam == 6 && ip.addr.hi == 0 && ip.addr.lo>>32 == 0xffff
+}
+
+// Unmap returns ip with any IPv4-mapped IPv6 address prefix removed.
+// Other addresses are returned unchanged.
+func (ip Addr) Unmap() Addr {
+	if ip.Is4In6() {
+		ip.fam = 4
+		ip.zone = ""
+	}
+	return ip
+}
+
+// Zone returns the IPv6 scoped addressing zone of ip, if any.
+func (ip Addr) Zone() string { return ip.zone }
+
+// WithZone returns an address that is the same as ip but with the
+// provided zone. If zone is empty, the zone is removed. If ip is not
+// an IPv6 address, WithZone returns ip unchanged.
+func (ip Addr) WithZone(zone string) Addr {
+	if ip.Is6() {
+		ip.zone = zone
+	}
+	return ip
+}
+
+// v4 returns the i'th byte of an IPv4 address.
+func (ip Addr) v4(i int) byte { return byte(ip.addr.lo >> uint((3-i)*8)) }
+
+// v6u16 returns the i'th 16-bit field of an IPv6 address.
+func (ip Addr) v6u16(i int) uint16 {
+	if i < 4 {
+		return uint16(ip.addr.hi >> uint((3-i)*16))
+	}
+	return uint16(ip.addr.lo >> uint((7-i)*16))
+}
+
+// As4 returns an IPv4 or IPv4-mapped IPv6 address in its 4-byte
+// representation. It panics for other addresses.
+func (ip Addr) As4() [4]byte {
+	if !ip.Is4() && !ip.Is4In6() {
+		panic("netip: As4 called on " + ip.String())
+	}
+	return [4]byte{ip.v4(0), ip.v4(1), ip.v4(2), ip.v4(3)}
+}
+
+// As16 returns ip in its 16-byte representation. IPv4 addresses are
+// returned in their IPv4-mapped IPv6 form. The zone is dropped.
+func (ip Addr) As16() [16]byte {
+	var b [16]byte
+	for i := 0; i < 8; i++ {
+		b[i] = byte(ip.addr.hi >> uint((7-i)*8))
+		b[i+8] = byte(ip.addr.lo >> uint((7-i)*8))
+	}
+	return b
+}
+
+// AsSlice returns an IPv4 or IPv6 address in its 4- or 16-byte
+// representation, which can be converted to a net.IP. It returns nil
+// for the zero Addr.
+func (ip Addr) AsSlice() []byte {
+	switch ip.fam {
+	case 4:
+		b := ip.As4()
+		return b[:]
+	case 6:
+		b := ip.As16()
+		return b[:]
+	}
+	return nil
+}
+
+// IsLoopback reports whether ip is a loopback address.
+func (ip Addr) IsLoopback() bool {
+	switch ip.fam {
+	case 4:
+		return ip.v4(0) == 127
+	case 6:
+		return ip.addr == uint128{0, 1}
+	}
+	return false
+}
+
+// IsMulticast reports whether ip is a multicast address.
+func (ip Addr) IsMulticast() bool {
+	switch ip.fam {
+	case 4:
+		return ip.v4(0)&0xf0 == 0xe0
+	case 6:
+		return ip.addr.hi>>56 == 0xff
+	}
+	return false
+}
+
+// IsLinkLocalUnicast reports whether ip is a link-local unicast
+// address.
+func (ip Addr) IsLinkLocalUnicast() bool {
+	switch ip.fam {
+	case 4:
+		return ip.v4(0) == 169 && ip.v4(1) == 254
+	case 6:
+		return ip.v6u16(0)&0xffc0 == 0xfe80
+	}
+	return false
+}
+
+// IsUnspecified reports whether ip is an unspecified address, either
+// "0.0.0.0" or "::". The zero Addr is not an unspecified address.
+func (ip Addr) IsUnspecified() bool {
+	return ip == IPv4Unspecified() || ip == IPv6Unspecified()
+}
+
+// Compare returns an integer comparing two addresses. The result is 0
+// if ip == ip2, -1 if ip < ip2 and +1 if ip > ip2. Addresses sort
+// first by length (the zero Addr, then IPv4, then IPv6), then by
+// value, then by zone.
+func (ip Addr) Compare(ip2 Addr) int {
+	f1, f2 := ip.BitLen(), ip2.BitLen()
+	switch {
+	case f1 < f2:
+		return -1
+	case f1 > f2:
+		return 1
+	}
+	if c := ip.addr.cmp(ip2.addr); c != 0 {
+		return c
+	}
+	switch {
+	case ip.zone < ip2.zone:
+		return -1
+	case ip.zone > ip2.zone:
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether ip sorts before ip2.
+func (ip Addr) Less(ip2 Addr) bool { return ip.Compare(ip2) == -1 }
+
+// Next returns the address following ip, keeping its zone. If there
+// is none, it returns the zero Addr.
+func (ip Addr) Next() Addr {
+	if !ip.IsValid() {
+		return Addr{}
+	}
+	ip.addr = ip.addr.addOne()
+	if ip.Is4() && uint32(ip.addr.lo) == 0 || ip.Is6() && ip.addr.isZero() {
+		return Addr{}
+	}
+	return ip
+}
+
+// Prev returns the address preceding ip, keeping its zone. If there
+// is none, it returns the zero Addr.
+func (ip Addr) Prev() Addr {
+	if !ip.IsValid() {
+		return Addr{}
+	}
+	if ip.Is4() && uint32(ip.addr.lo) == 0 || ip.Is6() && ip.addr.isZero() {
+		return Addr{}
+	}
+	ip.addr = ip.addr.subOne()
+	return ip
+}
+
+// Prefix returns the prefix of ip with the given number of leading
+// bits kept and the rest cleared. The zone is dropped. It returns an
+// error if bits is negative or larger than ip.BitLen(), and the zero
+// Prefix if ip is the zero Addr.
+func (ip Addr) Prefix(bits int) (Prefix, error) {
+	if bits < 0 {
+		return Prefix{}, errors.New("netip: negative Prefix bits")
+	}
+	if !ip.IsValid() {
+		return Prefix{}, nil
+	}
+	if bits > ip.BitLen() {
+		return Prefix{}, errors.New("netip: prefix length " + strconv.Itoa(bits) + " too large for " + ip.family())
+	}
+	ip.addr = ip.addr.bitsClearedFrom(ip.maskOffset() + bits)
+	ip.zone = ""
+	return PrefixFrom(ip, bits), nil
+}
+
+// maskOffset returns the number of bits of ip.addr that precede the
+// address proper: 96 for IPv4 and 0 for IPv6.
+func (ip Addr) maskOffset() int {
+	if ip.Is4() {
+		return 96
+	}
+	return 0
+}
+
+func (ip Addr) family() string {
+	if ip.Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// String returns the text form of ip: "invalid IP" for the zero Addr,
+// dotted decimal for IPv4, and RFC 5952 form for IPv6, with
+// IPv4-mapped addresses written as "::ffff:192.0.2.1" and the zone, if
+// any, appended after a percent sign.
+func (ip Addr) String() string {
+	switch ip.fam {
+	case 0:
+		return "invalid IP"
+	case 4:
+		return string(ip.appendTo4(nil))
+	}
+	var b []byte
+	if ip.Is4In6() {
+		b = append(b, "::ffff:"...)
+		b = ip.appendTo4(b)
+	} else {
+		b = ip.appendTo6(b)
+	}
+	if ip.zone != "" {
+		b = append(b, '%')
+		b = append(b, ip.zone...)
+	}
+	return string(b)
+}
+
+func (ip Addr) appendTo4(b []byte) []byte {
+	for i := 0; i < 4; i++ {
+		if i > 0 {
+			b = append(b, '.')
+		}
+		b = strconv.AppendUint(b, uint64(ip.v4(i)), 10)
+	}
+	return b
+}
+
+func (ip Addr) appendTo6(b []byte) []byte {
+	// Find the longest run of two or more zero fields to write as
+	// "::"; the first one wins a tie.
+	zeroStart, zeroEnd := -1, -1
+	for i := 0; i < 8; i++ {
+		j := i
+		for j < 8 && ip.v6u16(j) == 0 {
+			j++
+		}
+		if l := j - i; l >= 2 && l > zeroEnd-zeroStart {
+			zeroStart, zeroEnd = i, j
+		}
+	}
+	for i := 0; i < 8; i++ {
+		if i == zeroStart {
+			b = append(b, "::"...)
+			i = zeroEnd
+			if i >= 8 {
+				break
+			}
+		} else if i > 0 {
+			b = append(b, ':')
+		}
+		b = strconv.AppendUint(b, uint64(ip.v6u16(i)), 16)
+	}
+	return b
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The
+// encoding is the same as returned by String, except that the zero
+// Addr is encoded as the empty string.
+func (ip Addr) MarshalText() ([]byte, error) {
+	if !ip.IsValid() {
+		return []byte{}, nil
+	}
+	return []byte(ip.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It
+// accepts the forms accepted by ParseAddr, and the empty string as the
+// zero Addr.
+func (ip *Addr) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*ip = Addr{}
+		return nil
+	}
+	var err error
+	*ip, err = ParseAddr(string(text))
+	return err
+}
@@ -0,0 +1,398 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import (
+	"sort"
+	"testing"
+)
+
+var parseAddrTests = []struct {
+	in   string
+	want Addr
+	str  string // if different from in
+}{
+	{"0.0.0.0", AddrFrom4([4]byte{}), ""},
+	{"192.0.2.1", AddrFrom4([4]byte{192, 0, 2, 1}), ""},
+	{"255.255.255.255", AddrFrom4([4]byte{255, 255, 255, 255}), ""},
+	{"::", IPv6Unspecified(), ""},
+	{"::1", AddrFrom16([16]byte{15: 1}), ""},
+	{"2001:db8::1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}), ""},
+	{"2001:DB8:0:0:0:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}), "2001:db8::1"},
+	{"2001:db8:0:1:0:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 1, 15: 1}), "2001:db8:0:1::1"},
+	{"2001:0:0:1:0:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 7: 1, 15: 1}), "2001:0:0:1::1"},
+	{"2001:db8:0:0:1:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 9: 1, 15: 1}), "2001:db8::1:0:0:1"},
+	{"1:2:3:4:5:6:7:8", AddrFrom16([16]byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 0, 7, 0, 8}), ""},
+	{"1::", AddrFrom16([16]byte{1: 1}), ""},
+	{"1:0:3:4:5:6:7:8", AddrFrom16([16]byte{1: 1, 5: 3, 7: 4, 9: 5, 11: 6, 13: 7, 15: 8}), ""},
+	{"::ffff:192.0.2.1", AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 192, 13: 0, 14: 2, 15: 1}), ""},
+	{"::ffff:c000:0201", AddrFrom16([16]byte{10: 0xff, 11: 0xff, 12: 192, 13: 0, 14: 2, 15: 1}), "::ffff:192.0.2.1"},
+	{"64:ff9b::192.0.2.1", AddrFrom16([16]byte{0, 0x64, 0xff, 0x9b, 12: 192, 14: 2, 15: 1}), "64:ff9b::c000:201"},
+	{"fe80::1%eth0", AddrFrom16([16]byte{0xfe, 0x80, 15: 1}).WithZone("eth0"), ""},
+	{"::%0", IPv6Unspecified().WithZone("0"), ""},
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range parseAddrTests {
+		got, err := ParseAddr(tt.in)
+		if err != nil {
+			t.Errorf("ParseAddr(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddr(%q) = %#v; want %#v", tt.in, got, tt.want)
+		}
+		str := tt.str
+		if str == "" {
+			str = tt.in
+		}
+		if s := got.String(); s != str {
+			t.Errorf("ParseAddr(%q).String() = %q; want %q", tt.in, s, str)
+		}
+		if again, err := ParseAddr(got.String()); err != nil || again != got {
+			t.Errorf("ParseAddr(%q) = %v, %v; want %v", got.String(), again, err, got)
+		}
+	}
+}
+
+var parseAddrErrorTests = []string{
+	"",
+	"localhost",
+	"192.0.2",
+	"192.0.2.1.5",
+	"192.0.2.256",
+	"192.0.2.01",
+	"192.0.2.",
+	".192.0.2",
+	"192..0.2",
+	"192.0.2.1%eth0",
+	"%eth0",
+	"2001:db8::1::",
+	"2001:db8:::1",
+	"2001:db8::12345",
+	"1:2:3:4:5:6:7",
+	"1:2:3:4:5:6:7:8:9",
+	"1:2:3:4:5:6:7::8",
+	"1:2:3:4:5:6:7:192.0.2.1",
+	"::ffff:192.0.2",
+	"fe80::1%",
+	"2001:db8::1:",
+	":2001:db8::1",
+	"2001:db8::g",
+}
+
+func TestParseAddrError(t *testing.T) {
+	for _, in := range parseAddrErrorTests {
+		if got, err := ParseAddr(in); err == nil {
+			t.Errorf("ParseAddr(%q) = %v; want error", in, got)
+		}
+	}
+}
+
+func TestParseAddrAllocs(t *testing.T) {
+	for _, s := range []string{"192.0.2.1", "2001:db8::1", "::ffff:192.0.2.1"} {
+		if n := testing.AllocsPerRun(100, func() { ParseAddr(s) }); n > 0 {
+			t.Errorf("ParseAddr(%q) allocates %v times; want 0", s, n)
+		}
+	}
+}
+
+func TestAddrFromSlice(t *testing.T) {
+	v4 := []byte{192, 0, 2, 1}
+	v4in6 := []byte{10: 0xff, 11: 0xff, 12: 192, 13: 0, 14: 2, 15: 1}
+	ip, ok := AddrFromSlice(v4)
+	if !ok || !ip.Is4() || ip.String() != "192.0.2.1" {
+		t.Errorf("AddrFromSlice(%v) = %v, %v", v4, ip, ok)
+	}
+	ip6, ok := AddrFromSlice(v4in6)
+	if !ok || !ip6.Is4In6() || ip6.Unmap() != ip || ip6 == ip {
+		t.Errorf("AddrFromSlice(%v) = %v, %v", v4in6, ip6, ok)
+	}
+	if _, ok := AddrFromSlice(v4[:3]); ok {
+		t.Error("AddrFromSlice accepted a 3-byte slice")
+	}
+	if s := ip.AsSlice(); string(s) != string(v4) {
+		t.Errorf("AsSlice() = %v; want %v", s, v4)
+	}
+	if s := ip6.AsSlice(); string(s) != string(v4in6) {
+		t.Errorf("AsSlice() = %v; want %v", s, v4in6)
+	}
+	if s := (Addr{}).AsSlice(); s != nil {
+		t.Errorf("Addr{}.AsSlice() = %v; want nil", s)
+	}
+}
+
+func TestAddrProperties(t *testing.T) {
+	tests := []struct {
+		ip                                                 string
+		loopback, multicast, linkLocalUnicast, unspecified bool
+	}{
+		{"127.0.0.1", true, false, false, false},
+		{"::1", true, false, false, false},
+		{"224.0.0.1", false, true, false, false},
+		{"ff02::1", false, true, false, false},
+		{"169.254.1.1", false, false, true, false},
+		{"fe80::1%eth0", false, false, true, false},
+		{"0.0.0.0", false, false, false, true},
+		{"::", false, false, false, true},
+		{"192.0.2.1", false, false, false, false},
+		{"2001:db8::1", false, false, false, false},
+	}
+	for _, tt := range tests {
+		ip := MustParseAddr(tt.ip)
+		if got := ip.IsLoopback(); got != tt.loopback {
+			t.Errorf("%v.IsLoopback() = %v", ip, got)
+		}
+		if got := ip.IsMulticast(); got != tt.multicast {
+			t.Errorf("%v.IsMulticast() = %v", ip, got)
+		}
+		if got := ip.IsLinkLocalUnicast(); got != tt.linkLocalUnicast {
+			t.Errorf("%v.IsLinkLocalUnicast() = %v", ip, got)
+		}
+		if got := ip.IsUnspecified(); got != tt.unspecified {
+			t.Errorf("%v.IsUnspecified() = %v", ip, got)
+		}
+	}
+	var zero Addr
+	if zero.IsValid() || zero.BitLen() != 0 || zero.IsUnspecified() || zero.String() != "invalid IP" {
+		t.Errorf("unexpected properties of the zero Addr: %v", zero)
+	}
+}
+
+func TestAddrNextPrev(t *testing.T) {
+	tests := []struct {
+		ip, next string // "" for the zero Addr
+	}{
+		{"192.0.2.1", "192.0.2.2"},
+		{"192.0.2.255", "192.0.3.0"},
+		{"255.255.255.255", ""},
+		{"2001:db8::ffff", "2001:db8::1:0"},
+		{"::ffff:ffff:ffff", "::1:0:0:0"},
+		{"0:0:0:0:ffff:ffff:ffff:ffff", "0:0:0:1::"},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", ""},
+		{"fe80::1%eth0", "fe80::2%eth0"},
+	}
+	for _, tt := range tests {
+		ip := MustParseAddr(tt.ip)
+		var want Addr
+		if tt.next != "" {
+			want = MustParseAddr(tt.next)
+		}
+		if got := ip.Next(); got != want {
+			t.Errorf("%v.Next() = %v; want %v", ip, got, want)
+		}
+		if tt.next != "" {
+			if got := want.Prev(); got != ip {
+				t.Errorf("%v.Prev() = %v; want %v", want, got, ip)
+			}
+		}
+	}
+	for _, s := range []string{"0.0.0.0", "::"} {
+		if got := MustParseAddr(s).Prev(); got.IsValid() {
+			t.Errorf("%v.Prev() = %v; want the zero Addr", s, got)
+		}
+	}
+	if got := (Addr{}).Next(); got != (Addr{}) {
+		t.Errorf("Addr{}.Next() = %#v; want the zero Addr", got)
+	}
+}
+
+type addrs []Addr
+
+func (a addrs) Len() int           { return len(a) }
+func (a addrs) Less(i, j int) bool { return a[i].Less(a[j]) }
+func (a addrs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+func TestAddrCompare(t *testing.T) {
+	want := []string{
+		"0.0.0.0",
+		"192.0.2.1",
+		"192.0.2.2",
+		"::",
+		"::ffff:192.0.2.1",
+		"2001:db8::1",
+		"fe80::1",
+		"fe80::1%eth0",
+		"fe80::1%eth1",
+	}
+	var got addrs
+	for i := len(want) - 1; i >= 0; i-- {
+		got = append(got, MustParseAddr(want[i]))
+	}
+	got = append(got, Addr{})
+	sort.Sort(got)
+	if got[0].IsValid() {
+		t.Errorf("the zero Addr sorted to %v", got[0])
+	}
+	for i, ip := range got[1:] {
+		if ip.String() != want[i] {
+			t.Errorf("sorted[%d] = %v; want %v", i, ip, want[i])
+		}
+	}
+	a := MustParseAddr("192.0.2.1")
+	if a.Compare(a) != 0 || a.Compare(a.Next()) != -1 || a.Next().Compare(a) != 1 {
+		t.Error("Compare is inconsistent")
+	}
+}
+
+func TestAddrMapKey(t *testing.T) {
+	m := map[Addr]int{
+		MustParseAddr("192.0.2.1"):        4,
+		MustParseAddr("::ffff:192.0.2.1"): 6,
+	}
+	if got := m[MustParseAddr("192.0.2.1")]; got != 4 {
+		t.Errorf("m[192.0.2.1] = %v; want 4", got)
+	}
+	if got := m[MustParseAddr("::ffff:c000:201")]; got != 6 {
+		t.Errorf("m[::ffff:c000:201] = %v; want 6", got)
+	}
+}
+
+func TestAddrMarshalText(t *testing.T) {
+	for _, s := range []string{"192.0.2.1", "2001:db8::1", "fe80::1%eth0", ""} {
+		var ip Addr
+		if err := ip.UnmarshalText([]byte(s)); err != nil {
+			t.Errorf("UnmarshalText(%q) error: %v", s, err)
+			continue
+		}
+		b, err := ip.MarshalText()
+		if err != nil || string(b) != s {
+			t.Errorf("MarshalText() = %q, %v; want %q", b, err, s)
+		}
+	}
+}
+
+var parsePrefixTests = []struct {
+	in     string
+	masked string
+	bits   int
+}{
+	{"192.0.2.0/24", "192.0.2.0/24", 24},
+	{"192.0.2.1/24", "192.0.2.0/24", 24},
+	{"192.0.2.1/32", "192.0.2.1/32", 32},
+	{"0.0.0.0/0", "0.0.0.0/0", 0},
+	{"2001:db8::1/32", "2001:db8::/32", 32},
+	{"2001:db8:1234::/47", "2001:db8:1234::/47", 47},
+	{"::ffff:192.0.2.1/120", "::ffff:192.0.2.0/120", 120},
+	{"::/0", "::/0", 0},
+}
+
+func TestParsePrefix(t *testing.T) {
+	for _, tt := range parsePrefixTests {
+		p, err := ParsePrefix(tt.in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q) error: %v", tt.in, err)
+			continue
+		}
+		if p.String() != tt.in || p.Bits() != tt.bits {
+			t.Errorf("ParsePrefix(%q) = %v, bits %d", tt.in, p, p.Bits())
+		}
+		if m := p.Masked().String(); m != tt.masked {
+			t.Errorf("ParsePrefix(%q).Masked() = %v; want %v", tt.in, m, tt.masked)
+		}
+	}
+	for _, in := range []string{"", "192.0.2.0", "192.0.2.0/", "192.0.2.0/33", "192.0.2.0/-1", "192.0.2.0/024",
+		"2001:db8::/129", "fe80::%eth0/64", "192.0.2/24", "192.0.2.0/2a"} {
+		if p, err := ParsePrefix(in); err == nil {
+			t.Errorf("ParsePrefix(%q) = %v; want error", in, p)
+		}
+	}
+}
+
+func TestPrefixFrom(t *testing.T) {
+	ip := MustParseAddr("192.0.2.1")
+	if p := PrefixFrom(ip, 33); p.IsValid() || p.Bits() != -1 {
+		t.Errorf("PrefixFrom(%v, 33) = %v", ip, p)
+	}
+	if p := PrefixFrom(Addr{}, 0); p.IsValid() {
+		t.Errorf("PrefixFrom(Addr{}, 0) = %v", p)
+	}
+	if p := (Prefix{}); p.IsValid() || p.Bits() != -1 || p.String() != "invalid Prefix" {
+		t.Errorf("unexpected properties of the zero Prefix: %v", p)
+	}
+	zoned := MustParseAddr("fe80::1%eth0")
+	if p := PrefixFrom(zoned, 64); p.Addr().Zone() != "" {
+		t.Errorf("PrefixFrom(%v, 64) kept the zone: %v", zoned, p)
+	}
+	if p, err := ip.Prefix(24); err != nil || p != MustParsePrefix("192.0.2.0/24") {
+		t.Errorf("%v.Prefix(24) = %v, %v", ip, p, err)
+	}
+	if _, err := ip.Prefix(33); err == nil {
+		t.Errorf("%v.Prefix(33) succeeded", ip)
+	}
+	if !PrefixFrom(ip, 32).IsSingleIP() || PrefixFrom(ip, 31).IsSingleIP() {
+		t.Error("IsSingleIP is wrong")
+	}
+}
+
+func TestPrefixContains(t *testing.T) {
+	tests := []struct {
+		p    string
+		ip   string
+		want bool
+	}{
+		{"192.0.2.0/24", "192.0.2.1", true},
+		{"192.0.2.0/24", "192.0.3.1", false},
+		{"192.0.2.128/25", "192.0.2.127", false},
+		{"192.0.2.1/24", "192.0.2.200", true},
+		{"0.0.0.0/0", "203.0.113.9", true},
+		{"192.0.2.0/24", "::ffff:192.0.2.1", false},
+		{"::ffff:0:0/96", "192.0.2.1", false},
+		{"2001:db8::/32", "2001:db8:ffff::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"fe80::/10", "fe80::1%eth0", false},
+	}
+	for _, tt := range tests {
+		p, ip := MustParsePrefix(tt.p), MustParseAddr(tt.ip)
+		if got := p.Contains(ip); got != tt.want {
+			t.Errorf("%v.Contains(%v) = %v; want %v", p, ip, got, tt.want)
+		}
+	}
+	if (Prefix{}).Contains(Addr{}) {
+		t.Error("the zero Prefix contains the zero Addr")
+	}
+}
+
+func TestPrefixOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"192.0.2.0/24", "192.0.2.128/25", true},
+		{"192.0.2.0/25", "192.0.2.128/25", false},
+		{"0.0.0.0/0", "192.0.2.1/32", true},
+		{"192.0.2.0/24", "::ffff:192.0.2.0/120", false},
+		{"2001:db8::/32", "2001:db8:1::/48", true},
+		{"2001:db8::/48", "2001:db8:1::/48", false},
+	}
+	for _, tt := range tests {
+		a, b := MustParsePrefix(tt.a), MustParsePrefix(tt.b)
+		if got := a.Overlaps(b); got != tt.want {
+			t.Errorf("%v.Overlaps(%v) = %v; want %v", a, b, got, tt.want)
+		}
+		if got := b.Overlaps(a); got != tt.want {
+			t.Errorf("%v.Overlaps(%v) = %v; want %v", b, a, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkParseAddr4(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParseAddr("192.0.2.1")
+	}
+}
+
+func BenchmarkParseAddr6(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParseAddr("2001:db8::1")
+	}
+}
+
+func BenchmarkAddrString6(b *testing.B) {
+	ip := MustParseAddr("2001:db8::1")
+	for i := 0; i < b.N; i++ {
+		_ = ip.String()
+	}
+}
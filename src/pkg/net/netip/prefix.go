@@ -0,0 +1,179 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import (
+	"errors"
+	"strconv"
+)
+
+// A Prefix is an IP network: an address and a number of leading bits
+// of it, as in the CIDR notation "192.0.2.0/24" or "2001:db8::/32".
+//
+// The zero Prefix is not valid. Prefix values are comparable. A Prefix
+// need not be masked: "192.0.2.1/24" is a valid Prefix whose Masked
+// form is "192.0.2.0/24". A Prefix never has a zone.
+type Prefix struct {
+	ip Addr
+
+	// bitsPlusOne is the prefix length plus one, so that the zero
+	// Prefix is invalid.
+	bitsPlusOne uint8
+}
+
+// PrefixFrom returns the Prefix of ip with the given number of bits,
+// without masking ip and dropping its zone. The result is invalid if
+// ip is the zero Addr or bits is not in the range 0 to ip.BitLen().
+func PrefixFrom(ip Addr, bits int) Prefix {
+	var b uint8
+	if ip.IsValid() && bits >= 0 && bits <= ip.BitLen() {
+		b = uint8(bits) + 1
+	}
+	return Prefix{ip: ip.WithZone(""), bitsPlusOne: b}
+}
+
+// ParsePrefix parses s as an IP network in CIDR notation, such as
+// "192.0.2.0/24" or "2001:db8::/32". The address is not masked; use
+// Masked for that. IPv6 zones are not permitted.
+func ParsePrefix(s string) (Prefix, error) {
+	i := len(s) - 1
+	for i >= 0 && s[i] != '/' {
+		i--
+	}
+	if i < 0 {
+		return Prefix{}, prefixError(s, "no '/'")
+	}
+	ip, err := ParseAddr(s[:i])
+	if err != nil {
+		return Prefix{}, prefixError(s, err.Error())
+	}
+	if ip.Zone() != "" {
+		return Prefix{}, prefixError(s, "IPv6 zones cannot be present in a prefix")
+	}
+	bitsStr := s[i+1:]
+	if len(bitsStr) == 0 || len(bitsStr) > 1 && bitsStr[0] == '0' {
+		return Prefix{}, prefixError(s, "bad bits after slash: "+strconv.Quote(bitsStr))
+	}
+	bits := 0
+	for j := 0; j < len(bitsStr); j++ {
+		c := bitsStr[j]
+		if c < '0' || c > '9' || bits > ip.BitLen() {
+			return Prefix{}, prefixError(s, "bad bits after slash: "+strconv.Quote(bitsStr))
+		}
+		bits = bits*10 + int(c-'0')
+	}
+	if bits > ip.BitLen() {
+		return Prefix{}, prefixError(s, "prefix length out of range")
+	}
+	return PrefixFrom(ip, bits), nil
+}
+
+func prefixError(in, msg string) error {
+	return errors.New("netip.ParsePrefix(" + strconv.Quote(in) + "): " + msg)
+}
+
+// MustParsePrefix is like ParsePrefix but panics if s cannot be
+// parsed.
+func MustParsePrefix(s string) Prefix {
+	p, err := ParsePrefix(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Addr returns the address of p, which is not necessarily masked.
+func (p Prefix) Addr() Addr { return p.ip }
+
+// Bits returns the prefix length of p, or -1 if p is invalid.
+func (p Prefix) Bits() int { return int(p.bitsPlusOne) - 1 }
+
+// IsValid reports whether p has a valid address and prefix length.
+func (p Prefix) IsValid() bool { return p.bitsPlusOne > 0 }
+
+// IsSingleIP reports whether p contains exactly one address.
+func (p Prefix) IsSingleIP() bool { return p.IsValid() && p.Bits() == p.ip.BitLen() }
+
+// Masked returns p with all the bits of its address after the prefix
+// length cleared. It returns the zero Prefix if p is invalid.
+func (p Prefix) Masked() Prefix {
+	if !p.IsValid() {
+		return Prefix{}
+	}
+	m, _ := p.ip.Prefix(p.Bits())
+	return m
+}
+
+// maskBits returns the number of leading bits of the address bits of
+// p that are fixed by the prefix.
+func (p Prefix) maskBits() int { return p.ip.maskOffset() + p.Bits() }
+
+// Contains reports whether p contains ip. An IPv4 address does not
+// match an IPv6 prefix and an IPv4-mapped IPv6 address does not match
+// an IPv4 prefix. Addresses with a zone never match.
+func (p Prefix) Contains(ip Addr) bool {
+	if !p.IsValid() || ip.fam != p.ip.fam || ip.zone != "" {
+		return false
+	}
+	n := p.maskBits()
+	return ip.addr.bitsClearedFrom(n) == p.ip.addr.bitsClearedFrom(n)
+}
+
+// Overlaps reports whether p and o contain any addresses in common.
+func (p Prefix) Overlaps(o Prefix) bool {
+	if !p.IsValid() || !o.IsValid() || p.ip.fam != o.ip.fam {
+		return false
+	}
+	n := p.maskBits()
+	if m := o.maskBits(); m < n {
+		n = m
+	}
+	return p.ip.addr.bitsClearedFrom(n) == o.ip.addr.bitsClearedFrom(n)
+}
+
+// Range returns the range of addresses contained in p. It returns the
+// zero IPRange if p is invalid.
+func (p Prefix) Range() IPRange {
+	if !p.IsValid() {
+		return IPRange{}
+	}
+	n := p.maskBits()
+	from, to := p.ip, p.ip
+	from.addr = from.addr.bitsClearedFrom(n)
+	to.addr = to.addr.bitsSetFrom(n)
+	return IPRange{from, to}
+}
+
+// String returns the CIDR notation of p, or "invalid Prefix" if p is
+// invalid.
+func (p Prefix) String() string {
+	if !p.IsValid() {
+		return "invalid Prefix"
+	}
+	return p.ip.String() + "/" + strconv.Itoa(p.Bits())
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The
+// encoding is the same as returned by String, except that an invalid
+// Prefix is encoded as the empty string.
+func (p Prefix) MarshalText() ([]byte, error) {
+	if !p.IsValid() {
+		return []byte{}, nil
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. It
+// accepts the forms accepted by ParsePrefix, and the empty string as
+// the zero Prefix.
+func (p *Prefix) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = Prefix{}
+		return nil
+	}
+	var err error
+	*p, err = ParsePrefix(string(text))
+	return err
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+// A uint128 is a 128-bit unsigned integer, the representation of an
+// IPv6 address. The most significant bit is the first bit of the
+// address.
+type uint128 struct {
+	hi, lo uint64
+}
+
+// mask6 returns a uint128 with the first n bits set.
+func mask6(n int) uint128 {
+	switch {
+	case n <= 0:
+		return uint128{}
+	case n < 64:
+		return uint128{^(^uint64(0) >> uint(n)), 0}
+	case n < 128:
+		return uint128{^uint64(0), ^(^uint64(0) >> uint(n-64))}
+	}
+	return uint128{^uint64(0), ^uint64(0)}
+}
+
+func (u uint128) isZero() bool { return u.hi|u.lo == 0 }
+
+func (u uint128) and(m uint128) uint128 { return uint128{u.hi & m.hi, u.lo & m.lo} }
+
+func (u uint128) or(m uint128) uint128 { return uint128{u.hi | m.hi, u.lo | m.lo} }
+
+func (u uint128) not() uint128 { return uint128{^u.hi, ^u.lo} }
+
+// addOne returns u+1, wrapping around to zero.
+func (u uint128) addOne() uint128 {
+	lo := u.lo + 1
+	hi := u.hi
+	if lo == 0 {
+		hi++
+	}
+	return uint128{hi, lo}
+}
+
+// subOne returns u-1, wrapping around to all ones.
+func (u uint128) subOne() uint128 {
+	lo := u.lo - 1
+	hi := u.hi
+	if u.lo == 0 {
+		hi--
+	}
+	return uint128{hi, lo}
+}
+
+// bitsClearedFrom returns u with all the bits after the first n
+// cleared.
+func (u uint128) bitsClearedFrom(n int) uint128 { return u.and(mask6(n)) }
+
+// bitsSetFrom returns u with all the bits after the first n set.
+func (u uint128) bitsSetFrom(n int) uint128 { return u.or(mask6(n).not()) }
+
+// cmp returns -1, 0 or +1 as u is less than, equal to or greater
+// than v.
+func (u uint128) cmp(v uint128) int {
+	switch {
+	case u.hi < v.hi, u.hi == v.hi && u.lo < v.lo:
+		return -1
+	case u == v:
+		return 0
+	}
+	return 1
+}
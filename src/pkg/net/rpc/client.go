@@ -13,6 +13,7 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"time"
 )
 
 // ServerError represents an error that has been returned from
@@ -25,6 +26,17 @@ func (e ServerError) Error() string {
 
 var ErrShutdown = errors.New("connection is shut down")
 
+var (
+	// ErrCanceled is the error of a call abandoned by the client.  It is
+	// also the error reported by the Context of a call the client has
+	// canceled.
+	ErrCanceled = errors.New("call canceled")
+
+	// ErrTimeout is the error of a call that did not complete by its
+	// deadline.
+	ErrTimeout = errors.New("call timed out")
+)
+
 // Call represents an active RPC.
 type Call struct {
 	ServiceMethod string      // The name of the service and method to call.
@@ -32,6 +44,11 @@ type Call struct {
 	Reply         interface{} // The reply from the function (*struct).
 	Error         error       // After completion, the error status.
 	Done          chan *Call  // Strobes when call is complete.
+
+	seq      uint64
+	deadline time.Time
+	timer    *time.Timer   // abandons the call at its deadline
+	stream   *ClientStream // for streaming calls
 }
 
 // Client represents an RPC Client.
@@ -65,10 +82,33 @@ type ClientCodec interface {
 	Close() error
 }
 
+// A ClientCanceler is a ClientCodec that can ask the server to cancel a
+// call in progress.  The client uses it when a call is canceled or passes
+// its deadline; with other codecs the call is abandoned by the client
+// alone, and its response is discarded when it arrives.
+type ClientCanceler interface {
+	ClientCodec
+
+	// WriteCancel writes a request to cancel the call with sequence
+	// number seq.  The request has a sequence number of its own, r.Seq,
+	// for servers that answer it with an error.
+	WriteCancel(r *Request, seq uint64) error
+}
+
 func (client *Client) send(call *Call) {
 	client.sending.Lock()
 	defer client.sending.Unlock()
 
+	var timeout time.Duration
+	if !call.deadline.IsZero() {
+		timeout = call.deadline.Sub(time.Now())
+		if timeout <= 0 {
+			call.Error = ErrTimeout
+			call.done()
+			return
+		}
+	}
+
 	// Register this call.
 	client.mutex.Lock()
 	if client.shutdown || client.closing {
@@ -79,12 +119,17 @@ func (client *Client) send(call *Call) {
 	}
 	seq := client.seq
 	client.seq++
+	call.seq = seq
 	client.pending[seq] = call
+	if timeout > 0 {
+		call.timer = time.AfterFunc(timeout, func() { client.abandon(call, ErrTimeout) })
+	}
 	client.mutex.Unlock()
 
 	// Encode and send the request.
 	client.request.Seq = seq
 	client.request.ServiceMethod = call.ServiceMethod
+	client.request.Timeout = timeout
 	err := client.codec.WriteRequest(&client.request, call.Args)
 	if err != nil {
 		client.mutex.Lock()
@@ -110,7 +155,10 @@ func (client *Client) input() {
 		seq := response.Seq
 		client.mutex.Lock()
 		call := client.pending[seq]
-		delete(client.pending, seq)
+		streamed := response.More && call != nil && call.stream != nil
+		if !streamed {
+			delete(client.pending, seq)
+		}
 		client.mutex.Unlock()
 
 		switch {
@@ -118,12 +166,25 @@ func (client *Client) input() {
 			// We've got no pending call. That usually means that
 			// WriteRequest partially failed, and call was already
 			// removed; response is a server telling us about an
-			// error reading request body. We should still attempt
-			// to read error body, but there's no one to give it to.
+			// error reading request body. It may also be a late
+			// reply to a call that was abandoned. We should still
+			// attempt to read the body, but there's no one to give
+			// it to.
 			err = client.codec.ReadResponseBody(nil)
 			if err != nil {
 				err = errors.New("reading error body: " + err.Error())
 			}
+		case streamed:
+			// One of the replies of a streaming call, which
+			// remains pending until its final response.
+			err = call.stream.readReply(client.codec)
+		case response.More:
+			call.Error = errors.New("rpc: streamed reply to " + call.ServiceMethod + " expected a single reply")
+			err = client.codec.ReadResponseBody(nil)
+			if err != nil {
+				err = errors.New("reading error body: " + err.Error())
+			}
+			call.done()
 		case response.Error != "":
 			// We've got an error response. Give this to the request;
 			// any subsequent requests will get the ReadResponseBody
@@ -166,6 +227,12 @@ func (client *Client) input() {
 }
 
 func (call *Call) done() {
+	if call.timer != nil {
+		call.timer.Stop()
+	}
+	if call.stream != nil {
+		call.stream.finish()
+	}
 	select {
 	case call.Done <- call:
 		// ok
@@ -214,6 +281,11 @@ func (c *gobClientCodec) WriteRequest(r *Request, body interface{}) (err error)
 	return c.encBuf.Flush()
 }
 
+func (c *gobClientCodec) WriteCancel(r *Request, seq uint64) error {
+	r.ServiceMethod = cancelServiceMethod
+	return c.WriteRequest(r, seq)
+}
+
 func (c *gobClientCodec) ReadResponseHeader(r *Response) error {
 	return c.dec.Decode(r)
 }
@@ -285,10 +357,18 @@ func (client *Client) Close() error {
 // the same Call object.  If done is nil, Go will allocate a new channel.
 // If non-nil, done must be buffered or Go will deliberately crash.
 func (client *Client) Go(serviceMethod string, args interface{}, reply interface{}, done chan *Call) *Call {
+	return client.GoDeadline(serviceMethod, args, reply, time.Time{}, done)
+}
+
+// GoDeadline is like Go but the call fails with ErrTimeout if it has not
+// completed by deadline.  The time remaining is sent to the server along
+// with the request.  A zero deadline means the call has no deadline.
+func (client *Client) GoDeadline(serviceMethod string, args interface{}, reply interface{}, deadline time.Time, done chan *Call) *Call {
 	call := new(Call)
 	call.ServiceMethod = serviceMethod
 	call.Args = args
 	call.Reply = reply
+	call.deadline = deadline
 	if done == nil {
 		done = make(chan *Call, 10) // buffered.
 	} else {
@@ -310,3 +390,54 @@ func (client *Client) Call(serviceMethod string, args interface{}, reply interfa
 	call := <-client.Go(serviceMethod, args, reply, make(chan *Call, 1)).Done
 	return call.Error
 }
+
+// CallDeadline is like Call but fails with ErrTimeout if the call has not
+// completed by deadline.
+func (client *Client) CallDeadline(serviceMethod string, args interface{}, reply interface{}, deadline time.Time) error {
+	call := <-client.GoDeadline(serviceMethod, args, reply, deadline, make(chan *Call, 1)).Done
+	return call.Error
+}
+
+// Cancel abandons call, which must have been started by client.  If the
+// call is still pending, it completes at once with ErrCanceled, the server
+// is asked to cancel it if the codec is a ClientCanceler, and any reply
+// that arrives later is discarded.
+// Cancel does nothing if the call has already completed.
+func (client *Client) Cancel(call *Call) {
+	client.abandon(call, ErrCanceled)
+}
+
+// abandon completes a pending call with err and, if the codec can, tells
+// the server to cancel it.
+func (client *Client) abandon(call *Call, err error) {
+	client.mutex.Lock()
+	if client.pending[call.seq] != call {
+		client.mutex.Unlock()
+		return
+	}
+	delete(client.pending, call.seq)
+	client.mutex.Unlock()
+	call.Error = err
+	call.done()
+
+	canceler, ok := client.codec.(ClientCanceler)
+	if !ok {
+		return
+	}
+	client.sending.Lock()
+	defer client.sending.Unlock()
+	client.mutex.Lock()
+	if client.shutdown || client.closing {
+		client.mutex.Unlock()
+		return
+	}
+	seq := client.seq
+	client.seq++
+	client.mutex.Unlock()
+	client.request.Seq = seq
+	client.request.ServiceMethod = ""
+	client.request.Timeout = 0
+	// An error here is reported to the calls that follow, if it
+	// has left the connection unusable.
+	canceler.WriteCancel(&client.request, call.seq)
+}
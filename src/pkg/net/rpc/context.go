@@ -0,0 +1,129 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"sync"
+	"time"
+)
+
+// cancelServiceMethod is the service method of the request the gob codecs
+// use to cancel a call.  The body of the request holds the sequence number
+// of the call.  Servers that predate cancellation answer it with an error,
+// which the client discards, as the request has a sequence number of its
+// own.
+const cancelServiceMethod = "_goRPC_.Cancel"
+
+// A Context carries the deadline of a call and reports its cancellation
+// to the method serving it.  A method receives the Context of its call if
+// it takes a *Context as its first argument; a streaming method may also
+// obtain it from its ServerStream.
+//
+// A call is canceled when its deadline passes, when the client cancels
+// it, or when the connection to the client is lost.
+type Context struct {
+	deadline time.Time
+	done     chan struct{}
+	timer    *time.Timer
+	calls    *callSet
+	seq      uint64
+
+	mu  sync.Mutex // protects err
+	err error
+}
+
+// Deadline returns the time by which the client expects the call to
+// complete.  The result ok is false if the call has no deadline.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.deadline, !c.deadline.IsZero()
+}
+
+// Done returns a channel that is closed when the call is canceled.
+func (c *Context) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns nil while the call is in progress.  Once the call has been
+// canceled it returns ErrTimeout if its deadline passed, and ErrCanceled
+// otherwise.
+func (c *Context) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Context) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+}
+
+// release is called when the method serving the call has returned.
+func (c *Context) release() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.calls.remove(c)
+}
+
+// A callSet holds the Contexts of the calls in progress on a connection,
+// by sequence number, so that they can be canceled.
+type callSet struct {
+	mu sync.Mutex
+	m  map[uint64]*Context
+}
+
+func newCallSet() *callSet {
+	return &callSet{m: make(map[uint64]*Context)}
+}
+
+// add returns the Context for the call requested by req, or nil if the
+// method takes no Context.
+func (s *callSet) add(mtype *methodType, req *Request) *Context {
+	if !mtype.hasContext && !mtype.isStreaming {
+		return nil
+	}
+	c := &Context{done: make(chan struct{}), calls: s, seq: req.Seq}
+	if req.Timeout > 0 {
+		c.deadline = time.Now().Add(req.Timeout)
+		c.timer = time.AfterFunc(req.Timeout, func() { c.cancel(ErrTimeout) })
+	}
+	s.mu.Lock()
+	s.m[c.seq] = c
+	s.mu.Unlock()
+	return c
+}
+
+func (s *callSet) remove(c *Context) {
+	s.mu.Lock()
+	if s.m[c.seq] == c {
+		delete(s.m, c.seq)
+	}
+	s.mu.Unlock()
+}
+
+// cancel cancels the call with sequence number seq, if it is in progress.
+func (s *callSet) cancel(seq uint64) {
+	s.mu.Lock()
+	c := s.m[seq]
+	s.mu.Unlock()
+	if c != nil {
+		c.cancel(ErrCanceled)
+	}
+}
+
+func (s *callSet) cancelAll() {
+	s.mu.Lock()
+	m := s.m
+	s.m = make(map[uint64]*Context)
+	s.mu.Unlock()
+	for _, c := range m {
+		c.cancel(ErrCanceled)
+	}
+}
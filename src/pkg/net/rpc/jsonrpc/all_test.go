@@ -5,6 +5,7 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +13,10 @@ import (
 	"io/ioutil"
 	"net"
 	"net/rpc"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type Args struct {
@@ -53,8 +57,24 @@ func (t *Arith) Error(args *Args, reply *Reply) error {
 	panic("ERROR")
 }
 
+// Blocker blocks calls until they are released.
+type Blocker struct {
+	started chan bool
+	release chan bool
+}
+
+func (b *Blocker) Block(args int, reply *int) error {
+	b.started <- true
+	<-b.release
+	*reply = args
+	return nil
+}
+
+var blocker = &Blocker{make(chan bool), make(chan bool)}
+
 func init() {
 	rpc.Register(new(Arith))
+	rpc.Register(blocker)
 }
 
 func TestServerNoParams(t *testing.T) {
@@ -209,6 +229,91 @@ func TestUnexpectedError(t *testing.T) {
 }
 
 // Copied from package net.
+// A recorder records what is written to a connection.
+type recorder struct {
+	io.ReadWriteCloser
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	r.buf.Write(p)
+	r.mu.Unlock()
+	return r.ReadWriteCloser.Write(p)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.String()
+}
+
+// numPending returns the number of calls codec has to respond to.
+func numPending(codec rpc.ServerCodec) int {
+	switch c := codec.(type) {
+	case *serverCodec:
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(c.pending)
+	case *serverCodec2:
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(c.pending)
+	}
+	panic("unknown codec")
+}
+
+// The JSON-RPC codecs cannot carry cancellations, so abandoned calls run
+// to completion without the client writing anything to the server.
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		version string
+		client  func(io.ReadWriteCloser) rpc.ClientCodec
+		server  func(io.ReadWriteCloser) rpc.ServerCodec
+	}{
+		{"1.0", NewClientCodec, NewServerCodec},
+		{"2.0", NewClientCodec2, NewServerCodec2},
+	}
+	for _, tt := range tests {
+		cli, srv := net.Pipe()
+		server := tt.server(srv)
+		go rpc.ServeCodec(server)
+		rec := &recorder{ReadWriteCloser: cli}
+		client := rpc.NewClientWithCodec(tt.client(rec))
+
+		call := client.Go("Blocker.Block", 1, new(int), nil)
+		<-blocker.started
+		client.Cancel(call)
+		<-call.Done
+		if call.Error != rpc.ErrCanceled {
+			t.Errorf("%s: Block: got %v; want ErrCanceled", tt.version, call.Error)
+		}
+		call = client.GoDeadline("Blocker.Block", 2, new(int), time.Now().Add(10*time.Millisecond), nil)
+		<-blocker.started
+		<-call.Done
+		if call.Error != rpc.ErrTimeout {
+			t.Errorf("%s: Block: got %v; want ErrTimeout", tt.version, call.Error)
+		}
+
+		if n := numPending(server); n != 2 {
+			t.Errorf("%s: server has %d pending calls; want 2", tt.version, n)
+		}
+		if s := rec.String(); strings.Contains(s, "_goRPC_") {
+			t.Errorf("%s: client wrote a cancel request: %s", tt.version, s)
+		}
+
+		// The late replies are discarded.
+		blocker.release <- true
+		blocker.release <- true
+		reply := new(Reply)
+		if err := client.Call("Arith.Add", &Args{7, 8}, reply); err != nil || reply.C != 15 {
+			t.Errorf("%s: Add = %d, %v; want 15, nil", tt.version, reply.C, err)
+		}
+		client.Close()
+	}
+}
+
 func myPipe() (*pipe, *pipe) {
 	r1, w1 := io.Pipe()
 	r2, w2 := io.Pipe()
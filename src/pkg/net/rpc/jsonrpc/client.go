@@ -2,8 +2,10 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package jsonrpc implements a JSON-RPC ClientCodec and ServerCodec
-// for the rpc package.
+// Package jsonrpc implements JSON-RPC ClientCodecs and ServerCodecs
+// for the rpc package.  NewClientCodec and NewServerCodec speak
+// JSON-RPC 1.0; NewClientCodec2 and NewServerCodec2 speak JSON-RPC 2.0,
+// which HTTPHandler also serves over HTTP.
 package jsonrpc
 
 import (
@@ -0,0 +1,129 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+)
+
+type clientCodec2 struct {
+	dec *json.Decoder // for reading JSON values
+	enc *json.Encoder // for writing JSON values
+	c   io.Closer
+
+	// temporary work space
+	req  clientRequest2
+	resp clientResponse2
+
+	// As with clientCodec, pending maps request ids to method names.
+	mutex   sync.Mutex // protects pending
+	pending map[uint64]string
+}
+
+// NewClientCodec2 returns a new rpc.ClientCodec using JSON-RPC 2.0 on conn.
+// Arguments that encode as JSON objects are sent as named parameters;
+// any other argument is sent as the only positional parameter.  Errors
+// returned by the server carry error objects, which CallError recovers.
+func NewClientCodec2(conn io.ReadWriteCloser) rpc.ClientCodec {
+	return &clientCodec2{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		pending: make(map[uint64]string),
+	}
+}
+
+type clientRequest2 struct {
+	Version string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  *json.RawMessage `json:"params"`
+	Id      uint64           `json:"id"`
+}
+
+func (c *clientCodec2) WriteRequest(r *rpc.Request, param interface{}) error {
+	b, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+	if b[0] != '{' {
+		b = append(append([]byte{'['}, b...), ']')
+	}
+	params := json.RawMessage(b)
+	c.mutex.Lock()
+	c.pending[r.Seq] = r.ServiceMethod
+	c.mutex.Unlock()
+	c.req.Version = "2.0"
+	c.req.Method = r.ServiceMethod
+	c.req.Params = &params
+	c.req.Id = r.Seq
+	return c.enc.Encode(&c.req)
+}
+
+type clientResponse2 struct {
+	Version string          `json:"jsonrpc"`
+	Id      *uint64         `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+func (c *clientCodec2) ReadResponseHeader(r *rpc.Response) error {
+	c.resp = clientResponse2{}
+	if err := c.dec.Decode(&c.resp); err != nil {
+		return err
+	}
+	if c.resp.Id == nil {
+		// The server could not tell which request failed.
+		if c.resp.Error != nil {
+			return fmt.Errorf("jsonrpc: server error %v", c.resp.Error)
+		}
+		return errors.New("jsonrpc: response has no id")
+	}
+
+	c.mutex.Lock()
+	r.ServiceMethod = c.pending[*c.resp.Id]
+	delete(c.pending, *c.resp.Id)
+	c.mutex.Unlock()
+
+	r.Error = ""
+	r.Seq = *c.resp.Id
+	if c.resp.Error != nil {
+		r.Error = c.resp.Error.Error()
+	} else if c.resp.Result == nil {
+		return errors.New("jsonrpc: response has neither result nor error")
+	}
+	return nil
+}
+
+func (c *clientCodec2) ReadResponseBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	return json.Unmarshal(c.resp.Result, x)
+}
+
+func (c *clientCodec2) Close() error {
+	return c.c.Close()
+}
+
+// NewClient2 returns a new rpc.Client to handle requests to the
+// set of services at the other end of the connection, using JSON-RPC 2.0.
+func NewClient2(conn io.ReadWriteCloser) *rpc.Client {
+	return rpc.NewClientWithCodec(NewClientCodec2(conn))
+}
+
+// Dial2 connects to a JSON-RPC 2.0 server at the specified network address.
+func Dial2(network, address string) (*rpc.Client, error) {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient2(conn), err
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/rpc"
+	"strings"
+)
+
+// Error codes defined by JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700 // invalid JSON was received
+	CodeInvalidRequest = -32600 // the JSON sent is not a valid request
+	CodeMethodNotFound = -32601 // the method does not exist
+	CodeInvalidParams  = -32602 // invalid method parameters
+	CodeInternalError  = -32603 // internal JSON-RPC error
+	CodeServerError    = -32000 // a method returned an error that is not an *Error
+)
+
+// An Error is a JSON-RPC 2.0 error object.
+//
+// A method served with a JSON-RPC 2.0 codec may return an *Error to
+// choose the code and data of the error sent to the client.  Package rpc
+// passes errors on as strings, so Error returns the JSON encoding of the
+// error object, from which the codecs recover it.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	b, err := json.Marshal(e)
+	if err != nil {
+		// Data cannot be encoded.
+		b, _ = json.Marshal(&Error{Code: e.Code, Message: e.Message})
+	}
+	return string(b)
+}
+
+// parseError returns the error object encoded in s by Error, or nil if
+// s is not such an encoding.
+func parseError(s string) *Error {
+	if !strings.HasPrefix(s, "{") {
+		return nil
+	}
+	e := new(Error)
+	if err := json.Unmarshal([]byte(s), e); err != nil || e.Code == 0 {
+		return nil
+	}
+	return e
+}
+
+// CallError returns the error object of a call that failed with err, made
+// by a client using a JSON-RPC 2.0 codec.  It returns nil if err is not an
+// error returned by the server.
+func CallError(err error) *Error {
+	if e, ok := err.(rpc.ServerError); ok {
+		return parseError(string(e))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/rpc"
+)
+
+// maxHTTPBody is the size limit of the body of an HTTP request.
+const maxHTTPBody = 1 << 20
+
+// HTTPHandler returns an http.Handler that serves JSON-RPC 2.0 requests,
+// single or batch, sent in the body of POST requests, using server.  If
+// server is nil, rpc.DefaultServer is used.  A request holding only
+// notifications is answered with status 204 No Content, and a body
+// larger than 1 MB with status 413 Request Entity Too Large.
+func HTTPHandler(server *rpc.Server) http.Handler {
+	if server == nil {
+		server = rpc.DefaultServer
+	}
+	return httpHandler{server}
+}
+
+type httpHandler struct {
+	server *rpc.Server
+}
+
+func (h httpHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		io.WriteString(w, "405 must POST\n")
+		return
+	}
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(http.MaxBytesReader(w, req.Body, maxHTTPBody)); err != nil {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if body.Len() < maxHTTPBody {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "400 cannot read request\n")
+			return
+		}
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		io.WriteString(w, "413 request too large\n")
+		return
+	}
+	var buf bytes.Buffer
+	codec := NewServerCodec2(&httpConn{&body, &buf}).(*serverCodec2)
+	if len(bytes.TrimSpace(body.Bytes())) == 0 {
+		// An empty body is invalid JSON, but the codec sees only
+		// the end of the request.
+		codec.writeError(CodeParseError, "Parse error")
+	} else {
+		// The body holds a single request or a batch, which is
+		// served one request at a time.
+		for {
+			h.server.ServeRequest(codec)
+			if len(codec.queue) == 0 {
+				break
+			}
+		}
+	}
+	if buf.Len() == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	buf.WriteTo(w)
+}
+
+// An httpConn joins the body of an HTTP request to the buffer holding
+// the response.
+type httpConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (c *httpConn) Close() error { return nil }
@@ -0,0 +1,259 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/rpc"
+	"strings"
+	"sync"
+)
+
+type serverCodec2 struct {
+	dec *json.Decoder // for reading JSON values
+	enc *json.Encoder // for writing JSON values
+	c   io.Closer
+
+	// temporary work space
+	req serverRequest2
+	cur *serverCall2 // the call whose request is being read
+
+	// A batch request is read one request at a time.
+	queue []json.RawMessage // requests of the batch not read yet
+	batch *batch
+
+	// As in serverCodec, incoming requests are assigned uint64
+	// sequence numbers, and pending holds what is needed to
+	// respond to each.
+	mutex   sync.Mutex // protects seq, pending, batch responses and writing to enc
+	seq     uint64
+	pending map[uint64]*serverCall2
+}
+
+// A serverCall2 describes how to respond to a request.
+type serverCall2 struct {
+	id           *json.RawMessage
+	notification bool   // the request has no id and gets no response
+	code         int    // error code of the response, if known when reading the request
+	batch        *batch // the batch holding the request, if any
+}
+
+// A batch collects the responses to the requests of a batch request,
+// which are sent together once all have been served.
+type batch struct {
+	n         int // requests not yet responded to
+	responses []*serverResponse2
+}
+
+// NewServerCodec2 returns a new rpc.ServerCodec using JSON-RPC 2.0 on conn.
+// It serves batch requests and notifications, and accepts parameters given
+// by name, as a JSON object, or by position, as a JSON array of one value.
+// The errors it sends to clients are error objects as described by Error.
+func NewServerCodec2(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return &serverCodec2{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		pending: make(map[uint64]*serverCall2),
+	}
+}
+
+type serverRequest2 struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Id      json.RawMessage `json:"id"`
+}
+
+type serverResponse2 struct {
+	Version string           `json:"jsonrpc"`
+	Id      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+var errInvalidParams = errors.New("jsonrpc: params must be an object or an array")
+
+func (c *serverCodec2) ReadRequestHeader(r *rpc.Request) error {
+	raw, err := c.next()
+	if err != nil {
+		return err
+	}
+
+	call := &serverCall2{batch: c.batch}
+	c.req = serverRequest2{}
+	if err := json.Unmarshal(raw, &c.req); err != nil || c.req.Version != "2.0" || c.req.Method == "" || !validId(c.req.Id) {
+		// Leave the method empty, so that package rpc rejects
+		// the request and responds with an error.
+		call.code = CodeInvalidRequest
+		call.id = &null
+		c.req = serverRequest2{}
+	} else if c.req.Id == nil {
+		call.notification = true
+	} else {
+		id := c.req.Id
+		call.id = &id
+	}
+	c.cur = call
+	r.ServiceMethod = c.req.Method
+
+	c.mutex.Lock()
+	c.seq++
+	c.pending[c.seq] = call
+	r.Seq = c.seq
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// next returns the next request to serve, taking it from the batch being
+// read if there is one.
+func (c *serverCodec2) next() (json.RawMessage, error) {
+	for len(c.queue) == 0 {
+		var raw json.RawMessage
+		if err := c.dec.Decode(&raw); err != nil {
+			// A request cut short is invalid JSON too.
+			if _, ok := err.(*json.SyntaxError); ok || err == io.ErrUnexpectedEOF {
+				c.writeError(CodeParseError, "Parse error")
+			}
+			return nil, err
+		}
+		raw = bytes.TrimLeft(raw, " \t\r\n")
+		if raw[0] != '[' {
+			c.batch = nil
+			return raw, nil
+		}
+		// A batch request.
+		json.Unmarshal(raw, &c.queue)
+		if len(c.queue) == 0 {
+			c.writeError(CodeInvalidRequest, "Invalid Request")
+			continue
+		}
+		c.batch = &batch{n: len(c.queue)}
+	}
+	raw := c.queue[0]
+	c.queue = c.queue[1:]
+	return raw, nil
+}
+
+// validId reports whether id is absent, or is a string, a number or null,
+// as a request id must be.
+func validId(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	switch id[0] {
+	case '{', '[', 't', 'f':
+		return false
+	}
+	return true
+}
+
+// writeError writes an error response that is not tied to a request.
+func (c *serverCodec2) writeError(code int, message string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.enc.Encode(&serverResponse2{
+		Version: "2.0",
+		Id:      &null,
+		Error:   &Error{Code: code, Message: message},
+	})
+}
+
+func (c *serverCodec2) ReadRequestBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	params := bytes.TrimLeft(c.req.Params, " \t\r\n")
+	if len(params) == 0 || string(params) == "null" {
+		// The method gets the zero value.
+		return nil
+	}
+	var err error
+	switch params[0] {
+	case '{':
+		err = json.Unmarshal(params, x)
+	case '[':
+		var a []json.RawMessage
+		if err = json.Unmarshal(params, &a); err == nil {
+			if len(a) == 1 {
+				err = json.Unmarshal(a[0], x)
+			} else {
+				// Hand the whole array to the method, whose
+				// argument may be a slice.
+				err = json.Unmarshal(params, x)
+			}
+		}
+	default:
+		err = errInvalidParams
+	}
+	if err != nil {
+		c.cur.code = CodeInvalidParams
+	}
+	return err
+}
+
+func (c *serverCodec2) WriteResponse(r *rpc.Response, x interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	call, ok := c.pending[r.Seq]
+	if !ok {
+		return errors.New("invalid sequence number in response")
+	}
+	delete(c.pending, r.Seq)
+
+	resp := &serverResponse2{Version: "2.0", Id: call.id}
+	if r.Error == "" {
+		resp.Result = x
+	} else {
+		resp.Error = serverError(r.Error, call.code)
+	}
+	b := call.batch
+	if b == nil {
+		if call.notification {
+			return nil
+		}
+		return c.enc.Encode(resp)
+	}
+	if !call.notification {
+		b.responses = append(b.responses, resp)
+	}
+	if b.n--; b.n > 0 || len(b.responses) == 0 {
+		return nil
+	}
+	return c.enc.Encode(b.responses)
+}
+
+// serverError returns the error object for errmsg, the error of a call,
+// given the error code known from reading the request, if any.
+func serverError(errmsg string, code int) *Error {
+	if e := parseError(errmsg); e != nil {
+		return e
+	}
+	switch code {
+	case CodeInvalidRequest:
+		return &Error{Code: code, Message: "Invalid Request"}
+	case 0:
+		code = CodeServerError
+		if strings.HasPrefix(errmsg, "rpc: can't find ") || strings.HasPrefix(errmsg, "rpc: service/method request ill-formed") {
+			code = CodeMethodNotFound
+		}
+	}
+	return &Error{Code: code, Message: errmsg}
+}
+
+func (c *serverCodec2) Close() error {
+	return c.c.Close()
+}
+
+// ServeConn2 runs the JSON-RPC 2.0 server on a single connection.
+// ServeConn2 blocks, serving the connection until the client hangs up.
+// The caller typically invokes ServeConn2 in a go statement.
+func ServeConn2(conn io.ReadWriteCloser) {
+	rpc.ServeCodec(NewServerCodec2(conn))
+}
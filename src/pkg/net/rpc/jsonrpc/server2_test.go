@@ -0,0 +1,264 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Coded has methods that exercise JSON-RPC 2.0 features.
+type Coded int
+
+func (t *Coded) Fail(args *Args, reply *Reply) error {
+	return &Error{Code: 42, Message: "coded failure", Data: args.A}
+}
+
+func (t *Coded) Sum(args []int, reply *int) error {
+	for _, n := range args {
+		*reply += n
+	}
+	return nil
+}
+
+func init() {
+	rpc.Register(new(Coded))
+}
+
+type response2 struct {
+	Version string          `json:"jsonrpc"`
+	Id      interface{}     `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *Error          `json:"error"`
+}
+
+func TestServer2(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer cli.Close()
+	go ServeConn2(srv)
+	dec := json.NewDecoder(cli)
+
+	tests := []struct {
+		req    string
+		id     interface{}
+		result string
+		code   int
+	}{
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 1}`, 1.0, `{"C":3}`, 0},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": [{"A": 3, "B": 4}], "id": "a"}`, "a", `{"C":7}`, 0},
+		{`{"jsonrpc": "2.0", "method": "Coded.Sum", "params": [1, 2, 3], "id": 2}`, 2.0, `6`, 0},
+		{`{"jsonrpc": "2.0", "method": "Coded.Sum", "id": 3}`, 3.0, `0`, 0},
+		{`{"jsonrpc": "2.0", "method": "Arith.Div", "params": {"A": 1, "B": 0}, "id": 4}`, 4.0, "", CodeServerError},
+		{`{"jsonrpc": "2.0", "method": "Coded.Fail", "params": {"A": 5}, "id": 5}`, 5.0, "", 42},
+		{`{"jsonrpc": "2.0", "method": "Arith.Nope", "id": 6}`, 6.0, "", CodeMethodNotFound},
+		{`{"jsonrpc": "2.0", "method": "nope", "id": 7}`, 7.0, "", CodeMethodNotFound},
+		{`{"jsonrpc": "2.0", "method": "_goRPC_.Cancel", "params": [1], "id": 7.5}`, 7.5, "", CodeMethodNotFound},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": "x", "id": 8}`, 8.0, "", CodeInvalidParams},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": "x"}, "id": 9}`, 9.0, "", CodeInvalidParams},
+		{`{"method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 10}`, nil, "", CodeInvalidRequest},
+		{`{"jsonrpc": "2.0", "method": "Arith.Add", "id": {}}`, nil, "", CodeInvalidRequest},
+		{`{"jsonrpc": "2.0", "method": 1, "id": 11}`, nil, "", CodeInvalidRequest},
+		{`[]`, nil, "", CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		fmt.Fprint(cli, tt.req)
+		var resp response2
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatalf("%s: Decode: %v", tt.req, err)
+		}
+		if resp.Version != "2.0" {
+			t.Errorf("%s: jsonrpc = %q; want 2.0", tt.req, resp.Version)
+		}
+		if resp.Id != tt.id {
+			t.Errorf("%s: id = %v; want %v", tt.req, resp.Id, tt.id)
+		}
+		if string(resp.Result) != tt.result {
+			t.Errorf("%s: result = %s; want %s", tt.req, resp.Result, tt.result)
+		}
+		switch {
+		case tt.code == 0 && resp.Error != nil:
+			t.Errorf("%s: error = %v; want none", tt.req, resp.Error)
+		case tt.code != 0 && (resp.Error == nil || resp.Error.Code != tt.code):
+			t.Errorf("%s: error = %v; want code %d", tt.req, resp.Error, tt.code)
+		}
+	}
+
+	// A notification has no response, even if it fails.
+	fmt.Fprint(cli, `{"jsonrpc": "2.0", "method": "Arith.Div", "params": {"A": 1, "B": 0}}`)
+	fmt.Fprint(cli, `{"jsonrpc": "2.0", "method": "Arith.Mul", "params": {"A": 2, "B": 3}, "id": 12}`)
+	var resp response2
+	if err := dec.Decode(&resp); err != nil {
+		t.Fatalf("Decode after notification: %v", err)
+	}
+	if resp.Id != 12.0 || string(resp.Result) != `{"C":6}` {
+		t.Errorf("after notification: got id %v, result %s; want 12, {\"C\":6}", resp.Id, resp.Result)
+	}
+
+	// The responses to a batch are sent together.
+	fmt.Fprint(cli, `[
+		{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 1}, "id": 1},
+		{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 1}},
+		1,
+		{"jsonrpc": "2.0", "method": "Arith.Mul", "params": {"A": 2, "B": 5}, "id": 2}
+	]`)
+	var batch []response2
+	if err := dec.Decode(&batch); err != nil {
+		t.Fatalf("Decode batch: %v", err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("batch has %d responses; want 3", len(batch))
+	}
+	got := make(map[interface{}]string)
+	for _, r := range batch {
+		if r.Error != nil {
+			got[r.Id] = fmt.Sprint(r.Error.Code)
+		} else {
+			got[r.Id] = string(r.Result)
+		}
+	}
+	want := map[interface{}]string{1.0: `{"C":2}`, 2.0: `{"C":10}`, nil: fmt.Sprint(CodeInvalidRequest)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("batch responses = %v; want %v", got, want)
+	}
+
+	// A batch of notifications has no response.
+	fmt.Fprint(cli, `[{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 1}}]`)
+	fmt.Fprint(cli, `{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 1}, "id": 13}`)
+	resp = response2{}
+	if err := dec.Decode(&resp); err != nil || resp.Id != 13.0 {
+		t.Errorf("after batch of notifications: got id %v, %v; want 13", resp.Id, err)
+	}
+
+	// Invalid JSON ends the connection after a parse error.
+	fmt.Fprint(cli, `{"jsonrpc": "2.0", "method"`+"\x00}")
+	resp = response2{}
+	if err := dec.Decode(&resp); err != nil {
+		t.Fatalf("Decode parse error: %v", err)
+	}
+	if resp.Id != nil || resp.Error == nil || resp.Error.Code != CodeParseError {
+		t.Errorf("parse error: got id %v, error %v", resp.Id, resp.Error)
+	}
+}
+
+func TestClient2(t *testing.T) {
+	cli, srv := net.Pipe()
+	go ServeConn2(srv)
+
+	client := NewClient2(cli)
+	defer client.Close()
+
+	args := &Args{7, 8}
+	reply := new(Reply)
+	if err := client.Call("Arith.Add", args, reply); err != nil || reply.C != 15 {
+		t.Errorf("Add = %d, %v; want 15, nil", reply.C, err)
+	}
+	var sum int
+	if err := client.Call("Coded.Sum", []int{1, 2, 3, 4}, &sum); err != nil || sum != 10 {
+		t.Errorf("Sum = %d, %v; want 10, nil", sum, err)
+	}
+
+	err := client.Call("Arith.Div", &Args{7, 0}, reply)
+	if e := CallError(err); e == nil || e.Code != CodeServerError || e.Message != "divide by zero" {
+		t.Errorf("Div: got error %v; want divide by zero", err)
+	}
+	err = client.Call("Coded.Fail", &Args{5, 0}, reply)
+	if e := CallError(err); e == nil || e.Code != 42 || e.Message != "coded failure" || e.Data != 5.0 {
+		t.Errorf("Fail: got error %v; want coded failure with data 5", err)
+	}
+	err = client.Call("Arith.Nope", args, reply)
+	if e := CallError(err); e == nil || e.Code != CodeMethodNotFound {
+		t.Errorf("Nope: got error %v; want method not found", err)
+	}
+	if e := CallError(errors.New("divide by zero")); e != nil {
+		t.Errorf("CallError of a local error = %v; want nil", e)
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	ts := httptest.NewServer(HTTPHandler(nil))
+	defer ts.Close()
+
+	tests := []struct {
+		body   string
+		status int
+		want   string
+	}{
+		{
+			`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 1}`,
+			http.StatusOK,
+			`{"jsonrpc":"2.0","id":1,"result":{"C":3}}`,
+		},
+		{
+			`[{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 1}, {"jsonrpc": "2.0", "method": "Arith.Mul", "params": {"A": 3, "B": 2}, "id": 2}]`,
+			http.StatusOK,
+			`[{"jsonrpc":"2.0","id":1,"result":{"C":3}},{"jsonrpc":"2.0","id":2,"result":{"C":6}}]`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}}`,
+			http.StatusNoContent,
+			``,
+		},
+		{
+			`[{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}, "id": 1}, {"jsonrpc": "2.0", "method": "_goRPC_.Cancel", "id": 2}]`,
+			http.StatusOK,
+			`[{"jsonrpc":"2.0","id":1,"result":{"C":3}},{"jsonrpc":"2.0","id":2,"error":{"code":-32601,"message":"rpc: can't find service _goRPC_.Cancel"}}]`,
+		},
+		{
+			`{"jsonrpc": "2.0"`,
+			http.StatusOK,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+		{
+			``,
+			http.StatusOK,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+		{
+			" \r\n",
+			http.StatusOK,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+		},
+		{
+			`[` + strings.Repeat(`{"jsonrpc": "2.0", "method": "Arith.Add", "params": {"A": 1, "B": 2}},`, maxHTTPBody/64) + `1]`,
+			http.StatusRequestEntityTooLarge,
+			`413 request too large`,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(ts.URL, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("Post: %v", err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("reading body: %v", err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%.100s: status = %d; want %d", tt.body, resp.StatusCode, tt.status)
+		}
+		if got := strings.TrimSpace(string(body)); got != tt.want {
+			t.Errorf("%.100s: body = %s; want %s", tt.body, got, tt.want)
+		}
+	}
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Get: status = %d; want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
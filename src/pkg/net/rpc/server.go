@@ -26,6 +26,12 @@
 	These requirements apply even if a different codec is used.
 	(In the future, these requirements may soften for custom codecs.)
 
+	A method may also take a *Context ahead of its two arguments,
+
+		func (t *T) MethodName(ctx *rpc.Context, argType T1, replyType *T2) error
+
+	to learn the deadline of the call and whether it has been canceled.
+
 	The method's first argument represents the arguments provided by the caller; the
 	second argument represents the result parameters to be returned to the caller.
 	The method's return value, if non-nil, is passed back as a string that the client
@@ -45,7 +51,25 @@
 
 	The Call method waits for the remote call to complete while the Go method
 	launches the call asynchronously and signals completion using the Call
-	structure's Done channel.
+	structure's Done channel.  CallDeadline and GoDeadline additionally bound
+	the time the call may take; the deadline is passed on to the server, where
+	it is visible to methods that take a *Context.  The client's Cancel method
+	abandons a pending call and asks the server to cancel it.
+
+	A streaming method sends any number of replies before it returns.  Its
+	second argument is a *ServerStream instead of a reply pointer:
+
+		func (t *T) MethodName(argType T1, stream *rpc.ServerStream) error
+
+	The method calls stream.Send once for every reply.  A client invokes a
+	streaming method with Stream and receives the replies in order by calling
+	Recv on the resulting ClientStream.
+
+	Deadlines and streaming need a codec that carries the Timeout field of
+	Request and the More field of Response, and cancellation needs codecs
+	that implement ClientCanceler and ServerCanceler, as the default codec
+	does.  With other codecs, a call the client cancels runs to completion
+	on the server.
 
 	Unless an explicit codec is set up, package encoding/gob is used to
 	transport the data.
@@ -132,6 +156,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 	"unicode/utf8"
 )
@@ -146,12 +171,19 @@ const (
 // because Typeof takes an empty interface value.  This is annoying.
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
+var (
+	typeOfContext      = reflect.TypeOf((*Context)(nil))
+	typeOfServerStream = reflect.TypeOf((*ServerStream)(nil))
+)
+
 type methodType struct {
-	sync.Mutex // protects counters
-	method     reflect.Method
-	ArgType    reflect.Type
-	ReplyType  reflect.Type
-	numCalls   uint
+	sync.Mutex  // protects counters
+	method      reflect.Method
+	ArgType     reflect.Type
+	ReplyType   reflect.Type
+	numCalls    uint
+	hasContext  bool // method takes a *Context as its first argument
+	isStreaming bool // method sends its replies on a *ServerStream
 }
 
 type service struct {
@@ -165,9 +197,10 @@ type service struct {
 // but documented here as an aid to debugging, such as when analyzing
 // network traffic.
 type Request struct {
-	ServiceMethod string   // format: "Service.Method"
-	Seq           uint64   // sequence number chosen by client
-	next          *Request // for free list in Server
+	ServiceMethod string        // format: "Service.Method"
+	Seq           uint64        // sequence number chosen by client
+	Timeout       time.Duration // time left before the call's deadline; zero means none
+	next          *Request      // for free list in Server
 }
 
 // Response is a header written before every RPC return.  It is used internally
@@ -177,6 +210,7 @@ type Response struct {
 	ServiceMethod string    // echoes that of the Request
 	Seq           uint64    // echoes that of the request
 	Error         string    // error, if any.
+	More          bool      // a streamed reply; more responses follow
 	next          *Response // for free list in Server
 }
 
@@ -217,8 +251,11 @@ func isExportedOrBuiltinType(t reflect.Type) bool {
 // Register publishes in the server the set of methods of the
 // receiver value that satisfy the following conditions:
 //	- exported method
-//	- two arguments, both pointers to exported structs
+//	- two arguments, both pointers to exported structs,
+//	  optionally preceded by a *Context
 //	- one return value, of type error
+// A method whose second argument is a *ServerStream is a streaming
+// method.
 // It returns an error if the receiver is not an exported type or has
 // no methods or unsuitable methods. It also logs the error using package log.
 // The client accesses each method using a string of the form "Type.Method",
@@ -292,15 +329,21 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 		if method.PkgPath != "" {
 			continue
 		}
-		// Method needs three ins: receiver, *args, *reply.
-		if mtype.NumIn() != 3 {
+		// Method needs three ins: receiver, *args, *reply; or four,
+		// with a leading *Context.
+		hasContext := mtype.NumIn() == 4 && mtype.In(1) == typeOfContext
+		if mtype.NumIn() != 3 && !hasContext {
 			if reportErr {
 				log.Println("method", mname, "has wrong number of ins:", mtype.NumIn())
 			}
 			continue
 		}
+		in := 1
+		if hasContext {
+			in++
+		}
 		// First arg need not be a pointer.
-		argType := mtype.In(1)
+		argType := mtype.In(in)
 		if !isExportedOrBuiltinType(argType) {
 			if reportErr {
 				log.Println(mname, "argument type not exported:", argType)
@@ -308,7 +351,7 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 			continue
 		}
 		// Second arg must be a pointer.
-		replyType := mtype.In(2)
+		replyType := mtype.In(in + 1)
 		if replyType.Kind() != reflect.Ptr {
 			if reportErr {
 				log.Println("method", mname, "reply type not a pointer:", replyType)
@@ -336,7 +379,13 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 			}
 			continue
 		}
-		methods[mname] = &methodType{method: method, ArgType: argType, ReplyType: replyType}
+		methods[mname] = &methodType{
+			method:      method,
+			ArgType:     argType,
+			ReplyType:   replyType,
+			hasContext:  hasContext,
+			isStreaming: replyType == typeOfServerStream,
+		}
 	}
 	return methods
 }
@@ -371,20 +420,41 @@ func (m *methodType) NumCalls() (n uint) {
 	return n
 }
 
-func (s *service) call(server *Server, sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, codec ServerCodec) {
+func (s *service) call(server *Server, sending *sync.Mutex, mtype *methodType, req *Request, argv, replyv reflect.Value, codec ServerCodec, ctx *Context) {
 	mtype.Lock()
 	mtype.numCalls++
 	mtype.Unlock()
 	function := mtype.method.Func
+	in := []reflect.Value{s.rcvr}
+	if mtype.hasContext {
+		in = append(in, reflect.ValueOf(ctx))
+	}
+	if mtype.isStreaming {
+		replyv = reflect.ValueOf(&ServerStream{
+			server:  server,
+			sending: sending,
+			codec:   codec,
+			req:     req,
+			ctx:     ctx,
+		})
+	}
 	// Invoke the method, providing a new value for the reply.
-	returnValues := function.Call([]reflect.Value{s.rcvr, argv, replyv})
+	returnValues := function.Call(append(in, argv, replyv))
+	if ctx != nil {
+		ctx.release()
+	}
 	// The return value for the method is an error.
 	errInter := returnValues[0].Interface()
 	errmsg := ""
 	if errInter != nil {
 		errmsg = errInter.(error).Error()
 	}
-	server.sendResponse(sending, req, replyv.Interface(), codec, errmsg)
+	// A streaming method has sent its replies already.
+	var reply interface{} = invalidRequest
+	if !mtype.isStreaming {
+		reply = replyv.Interface()
+	}
+	server.sendResponse(sending, req, reply, codec, errmsg)
 	server.freeRequest(req)
 }
 
@@ -403,6 +473,14 @@ func (c *gobServerCodec) ReadRequestBody(body interface{}) error {
 	return c.dec.Decode(body)
 }
 
+func (c *gobServerCodec) ReadCancel(r *Request) (seq uint64, ok bool, err error) {
+	if r.ServiceMethod != cancelServiceMethod {
+		return 0, false, nil
+	}
+	err = c.dec.Decode(&seq)
+	return seq, true, err
+}
+
 func (c *gobServerCodec) WriteResponse(r *Response, body interface{}) (err error) {
 	if err = c.enc.Encode(r); err != nil {
 		return
@@ -432,6 +510,7 @@ func (server *Server) ServeConn(conn io.ReadWriteCloser) {
 // decode requests and encode responses.
 func (server *Server) ServeCodec(codec ServerCodec) {
 	sending := new(sync.Mutex)
+	calls := newCallSet()
 	for {
 		service, mtype, req, argv, replyv, keepReading, err := server.readRequest(codec)
 		if err != nil {
@@ -448,8 +527,16 @@ func (server *Server) ServeCodec(codec ServerCodec) {
 			}
 			continue
 		}
-		go service.call(server, sending, mtype, req, argv, replyv, codec)
+		if service == nil {
+			// A request to cancel the call with sequence number req.Seq.
+			calls.cancel(req.Seq)
+			server.freeRequest(req)
+			continue
+		}
+		go service.call(server, sending, mtype, req, argv, replyv, codec, calls.add(mtype, req))
 	}
+	// Calls still running have lost their client.
+	calls.cancelAll()
 	codec.Close()
 }
 
@@ -469,7 +556,12 @@ func (server *Server) ServeRequest(codec ServerCodec) error {
 		}
 		return err
 	}
-	service.call(server, sending, mtype, req, argv, replyv, codec)
+	if service == nil {
+		// There is no call in progress to cancel.
+		server.freeRequest(req)
+		return nil
+	}
+	service.call(server, sending, mtype, req, argv, replyv, codec, newCallSet().add(mtype, req))
 	return nil
 }
 
@@ -523,6 +615,10 @@ func (server *Server) readRequest(codec ServerCodec) (service *service, mtype *m
 		codec.ReadRequestBody(nil)
 		return
 	}
+	if service == nil {
+		// A cancellation, whose body has been read.
+		return
+	}
 
 	// Decode the argument value.
 	argIsValue := false // if true, need to indirect before calling.
@@ -540,7 +636,9 @@ func (server *Server) readRequest(codec ServerCodec) (service *service, mtype *m
 		argv = argv.Elem()
 	}
 
-	replyv = reflect.New(mtype.ReplyType.Elem())
+	if !mtype.isStreaming {
+		replyv = reflect.New(mtype.ReplyType.Elem())
+	}
 	return
 }
 
@@ -561,6 +659,22 @@ func (server *Server) readRequestHeader(codec ServerCodec) (service *service, mt
 	// we can still recover and move on to the next request.
 	keepReading = true
 
+	if canceler, ok := codec.(ServerCanceler); ok {
+		var seq uint64
+		var cancel bool
+		if seq, cancel, err = canceler.ReadCancel(req); err != nil {
+			keepReading = false
+			err = errors.New("rpc: server cannot decode cancel request: " + err.Error())
+			return
+		}
+		if cancel {
+			// A cancellation names no service; the caller
+			// cancels the call with sequence number req.Seq.
+			req.Seq = seq
+			return
+		}
+	}
+
 	dot := strings.LastIndex(req.ServiceMethod, ".")
 	if dot < 0 {
 		err = errors.New("rpc: service/method request ill-formed: " + req.ServiceMethod)
@@ -621,6 +735,19 @@ type ServerCodec interface {
 	Close() error
 }
 
+// A ServerCanceler is a ServerCodec that reads the requests to cancel
+// calls written by a ClientCanceler.  The server writes no response to
+// them, so the codec must not keep any state for them.
+type ServerCanceler interface {
+	ServerCodec
+
+	// ReadCancel is called after ReadRequestHeader.  If r asks to
+	// cancel a call, ReadCancel reads the body of the request and
+	// returns the sequence number of the call, as set by
+	// ReadRequestHeader for the call's own request, with ok true.
+	ReadCancel(r *Request) (seq uint64, ok bool, err error)
+}
+
 // ServeConn runs the DefaultServer on a single connection.
 // ServeConn blocks, serving the connection until the client hangs up.
 // The caller typically invokes ServeConn in a go statement.
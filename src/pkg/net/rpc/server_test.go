@@ -74,6 +74,60 @@ func (t *Arith) Error(args *Args, reply *Reply) error {
 	panic("ERROR")
 }
 
+// Waiter has methods that take a Context, and streaming methods.
+// It reports on canceled the error of every canceled call.
+type Waiter struct {
+	canceled chan error
+}
+
+// Wait waits for the given duration or until the call is canceled.
+func (t *Waiter) Wait(ctx *Context, d time.Duration, reply *bool) error {
+	select {
+	case <-time.After(d):
+		*reply = true
+		return nil
+	case <-ctx.Done():
+		t.canceled <- ctx.Err()
+		return ctx.Err()
+	}
+}
+
+// Deadline replies with the time left before the deadline of the call.
+func (t *Waiter) Deadline(ctx *Context, args int, reply *time.Duration) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return errors.New("no deadline")
+	}
+	*reply = deadline.Sub(time.Now())
+	return nil
+}
+
+// Count sends the numbers from 0 to n-1.
+func (t *Waiter) Count(n int, stream *ServerStream) error {
+	if n < 0 {
+		return errors.New("negative count")
+	}
+	for i := 0; i < n; i++ {
+		if err := stream.Send(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forever sends numbers until the call is canceled.
+func (t *Waiter) Forever(ctx *Context, args int, stream *ServerStream) error {
+	for i := 0; ; i++ {
+		if err := stream.Send(i); err != nil {
+			t.canceled <- err
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var waiter = &Waiter{canceled: make(chan error, 10)}
+
 func listenTCP() (net.Listener, string) {
 	l, e := net.Listen("tcp", "127.0.0.1:0") // any available address
 	if e != nil {
@@ -85,6 +139,7 @@ func listenTCP() (net.Listener, string) {
 func startServer() {
 	Register(new(Arith))
 	RegisterName("net.rpc.Arith", new(Arith))
+	Register(waiter)
 
 	var l net.Listener
 	l, serverAddr = listenTCP()
@@ -473,6 +528,164 @@ func testSendDeadlock(client *Client) {
 	client.Call("Arith.Add", args, reply)
 }
 
+// waitCanceled waits for a canceled call to be reported by the server.
+func waitCanceled(t *testing.T, want ...error) {
+	select {
+	case err := <-waiter.canceled:
+		for _, w := range want {
+			if err == w {
+				return
+			}
+		}
+		t.Errorf("server saw call canceled with %v; want one of %v", err, want)
+	case <-time.After(5 * time.Second):
+		t.Error("server did not see the call canceled")
+	}
+}
+
+func TestCallDeadline(t *testing.T) {
+	once.Do(startServer)
+	client, err := dialDirect()
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	var ok bool
+	err = client.CallDeadline("Waiter.Wait", time.Hour, &ok, time.Now().Add(50*time.Millisecond))
+	if err != ErrTimeout {
+		t.Errorf("Wait: got %v; want ErrTimeout", err)
+	}
+	// The server's timer and the client's cancellation race.
+	waitCanceled(t, ErrTimeout, ErrCanceled)
+
+	err = client.CallDeadline("Waiter.Wait", time.Hour, &ok, time.Now().Add(-time.Second))
+	if err != ErrTimeout {
+		t.Errorf("Wait past deadline: got %v; want ErrTimeout", err)
+	}
+
+	err = client.CallDeadline("Waiter.Wait", time.Millisecond, &ok, time.Now().Add(time.Minute))
+	if err != nil || !ok {
+		t.Errorf("Wait = %v, %v; want true, nil", ok, err)
+	}
+
+	var left time.Duration
+	err = client.CallDeadline("Waiter.Deadline", 0, &left, time.Now().Add(time.Minute))
+	if err != nil || left <= 0 || left > time.Minute {
+		t.Errorf("Deadline = %v, %v; want a duration of at most a minute", left, err)
+	}
+	err = client.Call("Waiter.Deadline", 0, &left)
+	if err == nil || err.Error() != "no deadline" {
+		t.Errorf("Deadline without deadline: got %v; want no deadline", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	once.Do(startServer)
+	client, err := dialDirect()
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	call := client.Go("Waiter.Wait", time.Hour, new(bool), nil)
+	client.Cancel(call)
+	<-call.Done
+	if call.Error != ErrCanceled {
+		t.Errorf("Wait: got %v; want ErrCanceled", call.Error)
+	}
+	waitCanceled(t, ErrCanceled)
+
+	// Canceling a completed call has no effect.
+	var ok bool
+	call = <-client.Go("Waiter.Wait", time.Millisecond, &ok, nil).Done
+	client.Cancel(call)
+	if call.Error != nil || !ok {
+		t.Errorf("Wait = %v, %v; want true, nil", ok, call.Error)
+	}
+
+	// The client is still usable.
+	reply := new(Reply)
+	if err := client.Call("Arith.Add", Args{7, 8}, reply); err != nil || reply.C != 15 {
+		t.Errorf("Add = %d, %v; want 15, nil", reply.C, err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	once.Do(startServer)
+	client, err := dialDirect()
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+	defer client.Close()
+
+	s := client.Stream("Waiter.Count", 5, new(int))
+	for i := 0; i < 5; i++ {
+		var n int
+		if err := s.Recv(&n); err != nil || n != i {
+			t.Fatalf("Recv = %d, %v; want %d, nil", n, err, i)
+		}
+	}
+	var n int
+	if err := s.Recv(&n); err != io.EOF {
+		t.Errorf("Recv after last reply: got %v; want io.EOF", err)
+	}
+	if err := s.Recv(new(string)); err == nil {
+		t.Error("Recv of wrong type succeeded")
+	}
+
+	s = client.Stream("Waiter.Count", -1, new(int))
+	if err := s.Recv(&n); err == nil || err.Error() != "negative count" {
+		t.Errorf("Recv: got %v; want negative count", err)
+	}
+
+	s = client.Stream("Waiter.Forever", 0, new(int))
+	for i := 0; i < 3; i++ {
+		if err := s.Recv(&n); err != nil || n != i {
+			t.Fatalf("Recv = %d, %v; want %d, nil", n, err, i)
+		}
+	}
+	s.Close()
+	if err := s.Recv(&n); err != ErrCanceled {
+		t.Errorf("Recv after Close: got %v; want ErrCanceled", err)
+	}
+	waitCanceled(t, ErrCanceled)
+
+	// A streaming method answers a call with more than one reply.
+	if err := client.Call("Waiter.Count", 2, &n); err == nil {
+		t.Error("Call of streaming method succeeded")
+	}
+	reply := new(Reply)
+	if err := client.Call("Arith.Add", Args{7, 8}, reply); err != nil || reply.C != 15 {
+		t.Errorf("Add = %d, %v; want 15, nil", reply.C, err)
+	}
+}
+
+func TestStreamShutdown(t *testing.T) {
+	once.Do(startServer)
+	client, err := dialDirect()
+	if err != nil {
+		t.Fatal("dialing", err)
+	}
+
+	s := client.Stream("Waiter.Forever", 0, new(int))
+	var n int
+	if err := s.Recv(&n); err != nil {
+		t.Fatal("Recv:", err)
+	}
+	client.Close()
+	for {
+		if err = s.Recv(&n); err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		t.Error("Recv after Close: got io.EOF; want an error")
+	}
+	// The server cancels the calls of a closed connection.
+	waitCanceled(t, ErrCanceled)
+}
+
 func dialDirect() (*Client, error) {
 	return Dial("tcp", serverAddr)
 }
@@ -0,0 +1,137 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"reflect"
+	"sync"
+)
+
+// A ServerStream sends the replies of a call to a streaming method.
+type ServerStream struct {
+	server  *Server
+	sending *sync.Mutex
+	codec   ServerCodec
+	req     *Request
+	ctx     *Context
+}
+
+// Send sends reply to the client as the next reply of the call.  It
+// returns the error of the Context once the call has been canceled, and
+// any error writing the reply.  Send must not be called after the method
+// has returned.
+func (s *ServerStream) Send(reply interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	resp := s.server.getResponse()
+	resp.ServiceMethod = s.req.ServiceMethod
+	resp.Seq = s.req.Seq
+	resp.More = true
+	s.sending.Lock()
+	err := s.codec.WriteResponse(resp, reply)
+	s.sending.Unlock()
+	s.server.freeResponse(resp)
+	return err
+}
+
+// Context returns the Context of the call.
+func (s *ServerStream) Context() *Context {
+	return s.ctx
+}
+
+// A ClientStream receives the replies of a call to a streaming method.
+// Replies that arrive before they are received are held by the
+// ClientStream, so a stream does not hold up other calls on the client.
+type ClientStream struct {
+	client *Client
+	call   *Call
+	typ    reflect.Type // type of the replies, a pointer
+
+	mu       sync.Mutex // protects replies and finished
+	cond     *sync.Cond
+	replies  []reflect.Value
+	finished bool
+}
+
+// Stream invokes a streaming method.  Its replies are received by calling
+// Recv on the returned ClientStream.  The argument reply is a pointer to a
+// value of the type of the replies; it is used only for its type.
+func (client *Client) Stream(serviceMethod string, args interface{}, reply interface{}) *ClientStream {
+	typ := reflect.TypeOf(reply)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		log.Panic("rpc: Stream reply is not a pointer")
+	}
+	s := &ClientStream{client: client, typ: typ}
+	s.cond = sync.NewCond(&s.mu)
+	s.call = &Call{
+		ServiceMethod: serviceMethod,
+		Args:          args,
+		Done:          make(chan *Call, 1),
+		stream:        s,
+	}
+	client.send(s.call)
+	return s
+}
+
+// Recv stores the next reply of the call in reply, which must have the
+// type given to Stream, waiting for it to arrive if necessary.  After the
+// last reply, Recv returns io.EOF if the call succeeded and the error of
+// the call otherwise.
+func (s *ClientStream) Recv(reply interface{}) error {
+	v := reflect.ValueOf(reply)
+	if !v.IsValid() || v.Type() != s.typ {
+		return fmt.Errorf("rpc: Recv reply has type %T, want %v", reply, s.typ)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.replies) == 0 && !s.finished {
+		s.cond.Wait()
+	}
+	if len(s.replies) > 0 {
+		v.Elem().Set(s.replies[0].Elem())
+		s.replies[0] = reflect.Value{}
+		s.replies = s.replies[1:]
+		return nil
+	}
+	if s.call.Error != nil {
+		return s.call.Error
+	}
+	return io.EOF
+}
+
+// Close abandons the call if it is still in progress, as Client.Cancel
+// does, and discards any replies not yet received.
+func (s *ClientStream) Close() error {
+	s.client.Cancel(s.call)
+	s.mu.Lock()
+	s.replies = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// readReply reads the body of a streamed reply from codec.
+func (s *ClientStream) readReply(codec ClientCodec) error {
+	reply := reflect.New(s.typ.Elem())
+	if err := codec.ReadResponseBody(reply.Interface()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.replies = append(s.replies, reply)
+	s.mu.Unlock()
+	s.cond.Signal()
+	return nil
+}
+
+// finish is called when the call has completed.
+func (s *ClientStream) finish() {
+	s.mu.Lock()
+	s.finished = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
@@ -0,0 +1,747 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smtp
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Backend handles the mail received by a Server.
+type Backend interface {
+	// NewSession is called for each new connection, before the
+	// greeting is sent. A non-nil error rejects the connection.
+	// The server keeps c up to date for the life of the connection.
+	NewSession(c *ConnState) (Session, error)
+}
+
+// A Session handles the mail transactions of a single connection.
+// Its methods are called sequentially, from the goroutine serving the
+// connection.
+//
+// An error returned by a Session method is reported to the client. If
+// it is a *textproto.Error, its code and message are used as the reply;
+// otherwise the server replies with a generic permanent failure.
+type Session interface {
+	// Mail starts a mail transaction with the given reverse path, which
+	// is empty for the null sender "<>".
+	Mail(from string) error
+
+	// Rcpt adds a recipient to the current mail transaction.
+	Rcpt(to string) error
+
+	// Data is called with the message body once all recipients have
+	// been accepted. The body has its dot-stuffing removed and its
+	// line endings converted to "\n". Reading from r fails with
+	// ErrMessageTooLarge if the body exceeds the server's
+	// MaxMessageBytes. Whatever Data does not read is discarded.
+	Data(r io.Reader) error
+
+	// Reset aborts the current mail transaction. It is called once
+	// Mail has succeeded, when the transaction ends: after every
+	// completed or failed DATA command, and for the RSET, HELO, EHLO
+	// and STARTTLS commands. A transaction still open when the
+	// connection is closed is not reset.
+	Reset()
+
+	// Logout is called when the connection is closed.
+	Logout() error
+}
+
+// An AuthSession is a Session that supports the AUTH extension. If the
+// Session returned by a Backend implements AuthSession, the Server
+// advertises and handles the PLAIN and CRAM-MD5 mechanisms, mirroring
+// PlainAuth and CRAMMD5Auth on the client side. PLAIN is only offered
+// over TLS unless the Server allows insecure authentication.
+type AuthSession interface {
+	Session
+
+	// Secret returns the secret (password) shared with username, and
+	// whether username is known. Both mechanisms verify the client's
+	// credentials against it.
+	Secret(username string) (secret string, ok bool)
+
+	// Authenticated is called once the client has proven that it knows
+	// the secret of username. The identity is the authorization
+	// identity requested with PLAIN, and empty otherwise. A non-nil
+	// error fails the authentication.
+	Authenticated(identity, username string) error
+}
+
+// ConnState describes a connection being served by a Server.
+type ConnState struct {
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+
+	// Hostname is the host name given by the client in its most
+	// recent HELO or EHLO command.
+	Hostname string
+
+	// TLS is the state of the connection's TLS layer, or nil if
+	// STARTTLS has not been used.
+	TLS *tls.ConnectionState
+}
+
+// ErrMessageTooLarge is returned when reading a message body that
+// exceeds the Server's MaxMessageBytes.
+var ErrMessageTooLarge = errors.New("smtp: message exceeds maximum size")
+
+// A Server defines parameters for running an SMTP server.
+// It implements the following extensions:
+//	8BITMIME    RFC 6152
+//	AUTH        RFC 4954
+//	PIPELINING  RFC 2920
+//	SIZE        RFC 1870
+//	STARTTLS    RFC 3207
+type Server struct {
+	Addr     string  // TCP address to listen on, ":smtp" if empty
+	Hostname string  // name used in the greeting, "localhost" if empty
+	Backend  Backend // handles the received mail
+
+	// TLSConfig, if not nil, enables the STARTTLS extension. It must
+	// contain at least one certificate.
+	TLSConfig *tls.Config
+
+	// MaxMessageBytes limits the size of a message body.
+	// If zero, there is no limit.
+	MaxMessageBytes int64
+
+	// MaxRecipients limits the number of recipients of a message.
+	// If zero, there is no limit.
+	MaxRecipients int
+
+	// AllowInsecureAuth permits PLAIN authentication on connections
+	// that do not use TLS.
+	AllowInsecureAuth bool
+
+	ReadTimeout  time.Duration // maximum duration before timing out read of a command
+	WriteTimeout time.Duration // maximum duration before timing out write of a reply
+
+	// ErrorLog specifies an optional logger for errors accepting
+	// connections. If nil, logging goes to os.Stderr via the log
+	// package's standard logger.
+	ErrorLog *log.Logger
+}
+
+// ListenAndServe listens on the TCP network address srv.Addr and then
+// calls Serve to handle connections on incoming connections.
+// If srv.Addr is blank, ":smtp" is used.
+func (srv *Server) ListenAndServe() error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":smtp"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(l)
+}
+
+// Serve accepts incoming connections on the Listener l, creating a
+// new service goroutine for each. The service goroutines speak SMTP
+// with the client and hand the mail to srv.Backend.
+func (srv *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	var tempDelay time.Duration // how long to sleep on accept failure
+	for {
+		rw, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := 1 * time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				srv.logf("smtp: Accept error: %v; retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		c := &serverConn{srv: srv, conn: rw, text: textproto.NewConn(rw)}
+		go c.serve()
+	}
+}
+
+func (srv *Server) logf(format string, args ...interface{}) {
+	if srv.ErrorLog != nil {
+		srv.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+func (srv *Server) hostname() string {
+	if srv.Hostname == "" {
+		return "localhost"
+	}
+	return srv.Hostname
+}
+
+// A serverConn is the server side of an SMTP connection.
+type serverConn struct {
+	srv     *Server
+	conn    net.Conn
+	text    *textproto.Conn
+	state   ConnState
+	session Session
+
+	didHello bool // whether the client has said HELO or EHLO
+	authed   bool // whether the client has authenticated
+	fromSet  bool // whether MAIL was accepted
+	nrcpt    int  // number of recipients accepted
+
+	authFailures int // number of failed AUTH commands
+}
+
+// errQuit is returned by a command handler to end the connection
+// after its reply has been sent.
+var errQuit = errors.New("smtp: quit")
+
+func (c *serverConn) serve() {
+	defer func() { c.conn.Close() }()
+	c.state.RemoteAddr = c.conn.RemoteAddr()
+	session, err := c.srv.Backend.NewSession(&c.state)
+	if err != nil {
+		c.replyErr(err, 554, "Transaction failed")
+		c.flush()
+		return
+	}
+	c.session = session
+	defer session.Logout()
+
+	c.reply(220, "%s ESMTP Service Ready", c.srv.hostname())
+	for {
+		// Replies to pipelined commands are held back until all the
+		// commands already received have been handled.
+		if c.text.R.Buffered() == 0 {
+			if err := c.flush(); err != nil {
+				return
+			}
+		}
+		if d := c.srv.ReadTimeout; d != 0 {
+			c.conn.SetReadDeadline(time.Now().Add(d))
+		}
+		line, err := c.readLine()
+		if err == errLineTooLong {
+			c.reply(500, "Line too long")
+			continue
+		}
+		if err != nil {
+			return
+		}
+		verb, args := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			verb, args = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if err := c.handle(strings.ToUpper(verb), args); err != nil {
+			c.flush()
+			return
+		}
+	}
+}
+
+// maxLineLength is the length limit of command lines and of the lines
+// of an AUTH exchange, including the CRLF. See RFC 5321, section
+// 4.5.3.1.4.
+const maxLineLength = 512
+
+var errLineTooLong = errors.New("smtp: line too long")
+
+// readLine reads a line from the client, without its line ending. A line
+// longer than maxLineLength is discarded and reported as errLineTooLong.
+func (c *serverConn) readLine() (string, error) {
+	var line []byte
+	tooLong := false
+	for {
+		l, more, err := c.text.R.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if !tooLong {
+			line = append(line, l...)
+			if len(line) > maxLineLength-2 {
+				tooLong, line = true, nil
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if tooLong {
+		return "", errLineTooLong
+	}
+	return string(line), nil
+}
+
+// reply queues a reply to the client, to be sent when the connection
+// is flushed. Each line of the message becomes a line of the reply.
+func (c *serverConn) reply(code int, format string, args ...interface{}) {
+	lines := strings.Split(fmt.Sprintf(format, args...), "\n")
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(c.text.W, "%d%s%s\r\n", code, sep, line)
+	}
+}
+
+// replyErr replies with err, using code and msg unless err is a
+// *textproto.Error.
+func (c *serverConn) replyErr(err error, code int, msg string) {
+	if e, ok := err.(*textproto.Error); ok {
+		c.reply(e.Code, "%s", e.Msg)
+		return
+	}
+	c.reply(code, "%s", msg)
+}
+
+// flush sends the queued replies.
+func (c *serverConn) flush() error {
+	if d := c.srv.WriteTimeout; d != 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(d))
+	}
+	return c.text.W.Flush()
+}
+
+// handle runs a single command. A non-nil error ends the connection.
+func (c *serverConn) handle(verb, args string) error {
+	switch verb {
+	case "HELO", "EHLO":
+		c.hello(verb, args)
+	case "MAIL":
+		c.mail(args)
+	case "RCPT":
+		c.rcpt(args)
+	case "DATA":
+		return c.data()
+	case "RSET":
+		c.reset()
+		c.reply(250, "OK")
+	case "NOOP":
+		c.reply(250, "OK")
+	case "VRFY":
+		c.reply(252, "Cannot VRFY user, but will accept message and attempt delivery")
+	case "QUIT":
+		c.reply(221, "%s Service closing transmission channel", c.srv.hostname())
+		return errQuit
+	case "STARTTLS":
+		return c.startTLS()
+	case "AUTH":
+		return c.auth(args)
+	default:
+		c.reply(500, "Syntax error, command unrecognized")
+	}
+	return nil
+}
+
+func (c *serverConn) reset() {
+	if c.fromSet {
+		c.session.Reset()
+	}
+	c.fromSet = false
+	c.nrcpt = 0
+}
+
+func (c *serverConn) hello(verb, args string) {
+	if args == "" {
+		c.reply(501, "Domain or address required")
+		return
+	}
+	c.reset()
+	c.didHello = true
+	c.state.Hostname = args
+	if verb == "HELO" {
+		c.reply(250, "%s Hello %s", c.srv.hostname(), args)
+		return
+	}
+	ext := []string{fmt.Sprintf("%s Hello %s", c.srv.hostname(), args), "PIPELINING", "8BITMIME"}
+	if c.srv.MaxMessageBytes > 0 {
+		ext = append(ext, fmt.Sprintf("SIZE %d", c.srv.MaxMessageBytes))
+	} else {
+		ext = append(ext, "SIZE")
+	}
+	if c.srv.TLSConfig != nil && c.state.TLS == nil {
+		ext = append(ext, "STARTTLS")
+	}
+	if mechs := c.authMechanisms(); len(mechs) > 0 && !c.authed {
+		ext = append(ext, "AUTH "+strings.Join(mechs, " "))
+	}
+	c.reply(250, "%s", strings.Join(ext, "\n"))
+}
+
+// authMechanisms returns the authentication mechanisms available on
+// the connection.
+func (c *serverConn) authMechanisms() []string {
+	if _, ok := c.session.(AuthSession); !ok {
+		return nil
+	}
+	if c.state.TLS != nil || c.srv.AllowInsecureAuth {
+		return []string{"PLAIN", "CRAM-MD5"}
+	}
+	return []string{"CRAM-MD5"}
+}
+
+// parsePath parses the argument of a MAIL or RCPT command, which must
+// start with prefix, returning the address between angle brackets and
+// any parameters after it.
+func parsePath(prefix, args string) (addr string, params []string, ok bool) {
+	if len(args) < len(prefix) || !strings.EqualFold(args[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	args = strings.TrimSpace(args[len(prefix):])
+	if !strings.HasPrefix(args, "<") {
+		return "", nil, false
+	}
+	i := strings.Index(args, ">")
+	if i < 0 {
+		return "", nil, false
+	}
+	return args[1:i], strings.Fields(args[i+1:]), true
+}
+
+func (c *serverConn) mail(args string) {
+	if !c.didHello {
+		c.reply(503, "Send HELO or EHLO first")
+		return
+	}
+	if c.fromSet {
+		c.reply(503, "Nested MAIL command")
+		return
+	}
+	from, params, ok := parsePath("FROM:", args)
+	if !ok {
+		c.reply(501, "Syntax error in parameters or arguments")
+		return
+	}
+	for _, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		switch strings.ToUpper(kv[0]) {
+		case "SIZE":
+			if len(kv) != 2 {
+				c.reply(501, "Syntax error in SIZE parameter")
+				return
+			}
+			size, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				c.reply(501, "Syntax error in SIZE parameter")
+				return
+			}
+			if max := c.srv.MaxMessageBytes; max > 0 && size > max {
+				c.reply(552, "Message size exceeds fixed maximum message size")
+				return
+			}
+		case "BODY":
+			// Both 7BIT and 8BITMIME bodies are passed through unchanged.
+		default:
+			c.reply(555, "MAIL FROM parameters not recognized or not implemented")
+			return
+		}
+	}
+	if err := c.session.Mail(from); err != nil {
+		c.replyErr(err, 550, "Sender rejected")
+		return
+	}
+	c.fromSet = true
+	c.reply(250, "OK")
+}
+
+func (c *serverConn) rcpt(args string) {
+	if !c.fromSet {
+		c.reply(503, "Send MAIL first")
+		return
+	}
+	if max := c.srv.MaxRecipients; max > 0 && c.nrcpt >= max {
+		c.reply(452, "Too many recipients")
+		return
+	}
+	to, _, ok := parsePath("TO:", args)
+	if !ok || to == "" {
+		c.reply(501, "Syntax error in parameters or arguments")
+		return
+	}
+	if err := c.session.Rcpt(to); err != nil {
+		c.replyErr(err, 550, "Recipient rejected")
+		return
+	}
+	c.nrcpt++
+	c.reply(250, "OK")
+}
+
+// A limitReader reads from r but fails with ErrMessageTooLarge once
+// more than n bytes have been read.
+type limitReader struct {
+	r        io.Reader
+	n        int64
+	exceeded bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrMessageTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		l.exceeded = true
+		return int(l.n), ErrMessageTooLarge
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
+func (c *serverConn) data() error {
+	if !c.fromSet || c.nrcpt == 0 {
+		c.reply(503, "Send RCPT first")
+		return nil
+	}
+	c.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+	if err := c.flush(); err != nil {
+		return err
+	}
+
+	dr := c.text.DotReader()
+	r := dr
+	lr := &limitReader{r: dr, n: c.srv.MaxMessageBytes}
+	if lr.n > 0 {
+		r = lr
+	}
+	err := c.session.Data(r)
+	// Consume the rest of the body so that the connection is back in
+	// sync with the client's commands.
+	if _, cerr := io.Copy(ioutil.Discard, dr); cerr != nil {
+		return cerr
+	}
+	c.reset()
+	switch {
+	case lr.exceeded:
+		c.reply(552, "Message size exceeds fixed maximum message size")
+	case err != nil:
+		c.replyErr(err, 554, "Transaction failed")
+	default:
+		c.reply(250, "OK")
+	}
+	return nil
+}
+
+func (c *serverConn) startTLS() error {
+	if c.srv.TLSConfig == nil || c.state.TLS != nil {
+		c.reply(502, "Command not implemented")
+		return nil
+	}
+	// Commands pipelined after STARTTLS were sent in the clear and
+	// must not be honoured once the connection is secure.
+	if c.text.R.Buffered() > 0 {
+		c.reply(501, "Unexpected data after STARTTLS")
+		return errQuit
+	}
+	c.reply(220, "Ready to start TLS")
+	if err := c.flush(); err != nil {
+		return err
+	}
+	tlsConn := tls.Server(c.conn, c.srv.TLSConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	c.text = textproto.NewConn(tlsConn)
+	cs := tlsConn.ConnectionState()
+	c.state.TLS = &cs
+	// The client must start over with a new EHLO.
+	c.reset()
+	c.didHello = false
+	c.state.Hostname = ""
+	return nil
+}
+
+// maxAuthFailures is the number of failed AUTH commands after which
+// the connection is closed.
+const maxAuthFailures = 3
+
+func (c *serverConn) auth(args string) error {
+	if !c.didHello {
+		c.reply(503, "Send EHLO first")
+		return nil
+	}
+	if c.authed {
+		c.reply(503, "Already authenticated")
+		return nil
+	}
+	if c.fromSet {
+		c.reply(503, "AUTH not permitted during a mail transaction")
+		return nil
+	}
+	as, ok := c.session.(AuthSession)
+	if !ok {
+		c.reply(502, "Command not implemented")
+		return nil
+	}
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		c.reply(501, "Syntax error in parameters or arguments")
+		return nil
+	}
+	mech := strings.ToUpper(fields[0])
+	supported := false
+	for _, m := range c.authMechanisms() {
+		supported = supported || m == mech
+	}
+	if !supported {
+		c.reply(504, "Unrecognized authentication type")
+		return nil
+	}
+
+	var identity, username string
+	var err error
+	switch mech {
+	case "PLAIN":
+		var resp []byte
+		if len(fields) == 2 {
+			resp, err = decodeAuthResponse(fields[1])
+		} else {
+			resp, err = c.authChallenge(nil)
+		}
+		if err != nil {
+			if !isAuthError(err) {
+				return err
+			}
+			break
+		}
+		identity, username, err = c.verifyPlain(as, resp)
+	case "CRAM-MD5":
+		challenge := make([]byte, 8)
+		if _, err := io.ReadFull(rand.Reader, challenge); err != nil {
+			return err
+		}
+		challenge = []byte(fmt.Sprintf("<%x.%d@%s>", challenge, time.Now().Unix(), c.srv.hostname()))
+		var resp []byte
+		if resp, err = c.authChallenge(challenge); err != nil {
+			if !isAuthError(err) {
+				return err
+			}
+			break
+		}
+		username, err = verifyCRAMMD5(as, challenge, resp)
+	}
+	if err == nil {
+		err = as.Authenticated(identity, username)
+	}
+	switch {
+	case err == errAuthCanceled:
+		c.reply(501, "Authentication canceled")
+	case err == errAuthSyntax:
+		c.reply(501, "Syntax error in authentication response")
+	case err == errLineTooLong:
+		c.reply(500, "Line too long")
+	case err != nil:
+		c.replyErr(err, 535, "Authentication credentials invalid")
+		c.authFailures++
+		if c.authFailures >= maxAuthFailures {
+			return errQuit
+		}
+	default:
+		c.authed = true
+		c.reply(235, "Authentication successful")
+	}
+	return nil
+}
+
+var (
+	errAuthCanceled = errors.New("smtp: authentication canceled")
+	errAuthSyntax   = errors.New("smtp: malformed authentication response")
+	errAuthFailed   = errors.New("smtp: authentication failed")
+)
+
+// isAuthError reports whether err is an error in the client's
+// authentication response rather than a failure of the connection.
+func isAuthError(err error) bool {
+	return err == errAuthCanceled || err == errAuthSyntax || err == errLineTooLong
+}
+
+func decodeAuthResponse(s string) ([]byte, error) {
+	if s == "*" {
+		return nil, errAuthCanceled
+	}
+	if s == "=" {
+		return []byte{}, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errAuthSyntax
+	}
+	return b, nil
+}
+
+// authChallenge sends challenge to the client and returns its
+// decoded response.
+func (c *serverConn) authChallenge(challenge []byte) ([]byte, error) {
+	c.reply(334, "%s", base64.StdEncoding.EncodeToString(challenge))
+	if err := c.flush(); err != nil {
+		return nil, err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return decodeAuthResponse(strings.TrimSpace(line))
+}
+
+// verifyPlain checks a PLAIN response of the form
+// identity\x00username\x00password, as sent by PlainAuth.
+func (c *serverConn) verifyPlain(as AuthSession, resp []byte) (identity, username string, err error) {
+	parts := strings.Split(string(resp), "\x00")
+	if len(parts) != 3 {
+		return "", "", errAuthSyntax
+	}
+	identity, username = parts[0], parts[1]
+	secret, ok := as.Secret(username)
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(parts[2])) != 1 {
+		return "", "", errAuthFailed
+	}
+	return identity, username, nil
+}
+
+// verifyCRAMMD5 checks a CRAM-MD5 response of the form
+// "username digest", as sent by CRAMMD5Auth.
+func verifyCRAMMD5(as AuthSession, challenge, resp []byte) (username string, err error) {
+	i := strings.LastIndex(string(resp), " ")
+	if i < 0 {
+		return "", errAuthSyntax
+	}
+	username = string(resp[:i])
+	secret, ok := as.Secret(username)
+	if !ok {
+		return "", errAuthFailed
+	}
+	d := hmac.New(md5.New, []byte(secret))
+	d.Write(challenge)
+	want := fmt.Sprintf("%x", d.Sum(nil))
+	if subtle.ConstantTimeCompare(resp[i+1:], []byte(want)) != 1 {
+		return "", errAuthFailed
+	}
+	return username, nil
+}
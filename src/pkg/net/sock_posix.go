@@ -35,8 +35,10 @@ type sockaddr interface {
 }
 
 // socket returns a network file descriptor that is ready for
-// asynchronous I/O using the network poller.
-func socket(net string, family, sotype, proto int, ipv6only bool, laddr, raddr sockaddr, deadline time.Time, toAddr func(syscall.Sockaddr) Addr) (fd *netFD, err error) {
+// asynchronous I/O using the network poller. If ctrlFn is not nil, it
+// is called with the raw socket after the default socket options are
+// set and before the socket is bound or connected.
+func socket(net string, family, sotype, proto int, ipv6only bool, laddr, raddr sockaddr, deadline time.Time, toAddr func(syscall.Sockaddr) Addr, ctrlFn func(string, string, uintptr) error) (fd *netFD, err error) {
 	s, err := sysSocket(family, sotype, proto)
 	if err != nil {
 		return nil, err
@@ -45,6 +47,18 @@ func socket(net string, family, sotype, proto int, ipv6only bool, laddr, raddr s
 		closesocket(s)
 		return nil, err
 	}
+	if ctrlFn != nil {
+		var addr string
+		if raddr != nil {
+			addr = raddr.String()
+		} else if laddr != nil {
+			addr = laddr.String()
+		}
+		if err = ctrlFn(controlNetwork(net, family), addr, uintptr(s)); err != nil {
+			closesocket(s)
+			return nil, err
+		}
+	}
 	if fd, err = newFD(s, family, sotype, net); err != nil {
 		closesocket(s)
 		return nil, err
@@ -196,3 +210,19 @@ func (fd *netFD) listenDatagram(laddr sockaddr, toAddr func(syscall.Sockaddr) Ad
 	fd.setAddr(toAddr(lsa), nil)
 	return nil
 }
+
+// controlNetwork returns the network name passed to a Control
+// function. The generic "tcp", "udp" and "ip" networks are qualified
+// with the address family of the socket, as in "tcp4" or "tcp6".
+func controlNetwork(net string, family int) string {
+	switch net {
+	case "tcp", "udp", "ip":
+		switch family {
+		case syscall.AF_INET:
+			return net + "4"
+		case syscall.AF_INET6:
+			return net + "6"
+		}
+	}
+	return net
+}
@@ -82,10 +82,10 @@ func (c *TCPConn) SetNoDelay(noDelay bool) error {
 // which must be "tcp", "tcp4", or "tcp6".  If laddr is not nil, it is
 // used as the local address for the connection.
 func DialTCP(net string, laddr, raddr *TCPAddr) (*TCPConn, error) {
-	return dialTCP(net, laddr, raddr, noDeadline)
+	return dialTCP(net, laddr, raddr, noDeadline, nil)
 }
 
-func dialTCP(net string, laddr, raddr *TCPAddr, deadline time.Time) (*TCPConn, error) {
+func dialTCP(net string, laddr, raddr *TCPAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*TCPConn, error) {
 	if !deadline.IsZero() {
 		panic("net.dialTCP: deadline not implemented on Plan 9")
 	}
@@ -175,6 +175,10 @@ func (l *TCPListener) File() (f *os.File, err error) { return l.dup() }
 // port of 0, ListenTCP will choose an available port.  The caller can
 // use the Addr method of TCPListener to retrieve the chosen address.
 func ListenTCP(net string, laddr *TCPAddr) (*TCPListener, error) {
+	return listenTCP(net, laddr, nil)
+}
+
+func listenTCP(net string, laddr *TCPAddr, ctrlFn func(string, string, uintptr) error) (*TCPListener, error) {
 	switch net {
 	case "tcp", "tcp4", "tcp6":
 	default:
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"runtime"
 	"testing"
+	"time"
 )
 
 type resolveUDPAddrTest struct {
@@ -141,6 +142,88 @@ func testWriteToPacketConn(t *testing.T, raddr string) {
 	}
 }
 
+func TestUDPBatch(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("skipping test on %q", runtime.GOOS)
+	}
+
+	rc, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer rc.Close()
+	rc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	ra := rc.LocalAddr().(*UDPAddr)
+
+	wc, err := ListenUDP("udp4", &UDPAddr{IP: IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP failed: %v", err)
+	}
+	defer wc.Close()
+
+	payloads := []string{"first", "second", "", "fourth"}
+	wms := make([]UDPMessage, len(payloads))
+	for i, p := range payloads {
+		wms[i] = UDPMessage{Buf: []byte(p), Addr: ra}
+	}
+	n, err := wc.WriteBatch(wms)
+	if err != nil || n != len(wms) {
+		t.Fatalf("WriteBatch = %v, %v; want %v, <nil>", n, err, len(wms))
+	}
+	for i, m := range wms {
+		if m.N != len(payloads[i]) {
+			t.Errorf("message %d: N = %v; want %v", i, m.N, len(payloads[i]))
+		}
+	}
+
+	var got []string
+	for len(got) < len(payloads) {
+		rms := make([]UDPMessage, len(payloads)-len(got))
+		for i := range rms {
+			rms[i].Buf = make([]byte, 64)
+		}
+		n, err := rc.ReadBatch(rms)
+		if err != nil {
+			t.Fatalf("ReadBatch failed: %v", err)
+		}
+		if n == 0 {
+			t.Fatal("ReadBatch read no messages")
+		}
+		for _, m := range rms[:n] {
+			got = append(got, string(m.Buf[:m.N]))
+			if m.Addr == nil || m.Addr.String() != wc.LocalAddr().String() {
+				t.Errorf("got source address %v; want %v", m.Addr, wc.LocalAddr())
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, payloads) {
+		t.Errorf("got %q; want %q", got, payloads)
+	}
+
+	// A connected socket needs no address, and refuses one.
+	cc, err := DialUDP("udp4", nil, ra)
+	if err != nil {
+		t.Fatalf("DialUDP failed: %v", err)
+	}
+	defer cc.Close()
+	if _, err := cc.WriteBatch([]UDPMessage{{Buf: []byte("x"), Addr: ra}}); err == nil || err.(*OpError).Err != ErrWriteToConnected {
+		t.Errorf("WriteBatch with address on connected socket: %v; want %v", err, ErrWriteToConnected)
+	}
+	if n, err := cc.WriteBatch([]UDPMessage{{Buf: []byte("connected")}}); err != nil || n != 1 {
+		t.Fatalf("WriteBatch = %v, %v; want 1, <nil>", n, err)
+	}
+	rms := []UDPMessage{{Buf: make([]byte, 64)}}
+	if n, err := rc.ReadBatch(rms); err != nil || n != 1 || string(rms[0].Buf[:rms[0].N]) != "connected" {
+		t.Errorf("ReadBatch = %v, %v, %q; want 1, <nil>, %q", n, err, rms[0].Buf[:rms[0].N], "connected")
+	}
+
+	// An unconnected socket needs an address.
+	if _, err := wc.WriteBatch([]UDPMessage{{Buf: []byte("x")}}); err == nil {
+		t.Error("WriteBatch without address on unconnected socket succeeded")
+	}
+}
+
 var udpConnLocalNameTests = []struct {
 	net   string
 	laddr *UDPAddr
@@ -8,6 +8,18 @@ import "errors"
 
 var ErrWriteToConnected = errors.New("use of WriteTo with pre-connected UDP")
 
+// A UDPMessage is a single datagram read or written by the ReadBatch
+// and WriteBatch methods of UDPConn.
+type UDPMessage struct {
+	Buf  []byte   // payload
+	OOB  []byte   // out-of-band data
+	Addr *UDPAddr // source address on read, destination on write
+
+	N     int // number of bytes of Buf read or written
+	OOBN  int // number of bytes of OOB read or written
+	Flags int // flags set on the message read
+}
+
 // UDPAddr represents the address of a UDP end point.
 type UDPAddr struct {
 	IP   IP
@@ -49,7 +61,7 @@ func ResolveUDPAddr(net, addr string) (*UDPAddr, error) {
 	default:
 		return nil, UnknownNetworkError(net)
 	}
-	a, err := resolveInternetAddr(net, addr, noDeadline)
+	a, err := resolveInternetAddr("resolve", net, addr, noDeadline)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,48 @@
+// Copyright 2013 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd linux netbsd openbsd windows
+
+package net
+
+// readBatchLoop implements readBatch without batched system calls,
+// reading a single message.
+func (c *UDPConn) readBatchLoop(ms []UDPMessage) (int, error) {
+	m := &ms[0]
+	var err error
+	if len(m.OOB) == 0 {
+		m.N, m.Addr, err = c.ReadFromUDP(m.Buf)
+		m.OOBN, m.Flags = 0, 0
+	} else {
+		m.N, m.OOBN, m.Flags, m.Addr, err = c.ReadMsgUDP(m.Buf, m.OOB)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// writeBatchLoop implements writeBatch without batched system calls,
+// writing one message at a time.
+func (c *UDPConn) writeBatchLoop(ms []UDPMessage) (int, error) {
+	for i := range ms {
+		m := &ms[i]
+		var err error
+		m.OOBN = 0
+		switch {
+		case len(m.OOB) != 0 && m.Addr == nil:
+			m.N, m.OOBN, err = c.fd.WriteMsg(m.Buf, m.OOB, nil)
+		case len(m.OOB) != 0:
+			m.N, m.OOBN, err = c.WriteMsgUDP(m.Buf, m.OOB, m.Addr)
+		case m.Addr == nil:
+			m.N, err = c.Write(m.Buf)
+		default:
+			m.N, err = c.WriteToUDP(m.Buf, m.Addr)
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(ms), nil
+}
@@ -108,14 +108,27 @@ func (c *UDPConn) WriteMsgUDP(b, oob []byte, addr *UDPAddr) (n, oobn int, err er
 	return 0, 0, syscall.EPLAN9
 }
 
+// ReadBatch reads one or more packets from c into ms, filling in the
+// payload, out-of-band data, flags and source address of each.  It
+// returns the number of messages read.
+func (c *UDPConn) ReadBatch(ms []UDPMessage) (int, error) {
+	return 0, syscall.EPLAN9
+}
+
+// WriteBatch writes the packets in ms via c and returns the number of
+// messages written.
+func (c *UDPConn) WriteBatch(ms []UDPMessage) (int, error) {
+	return 0, syscall.EPLAN9
+}
+
 // DialUDP connects to the remote address raddr on the network net,
 // which must be "udp", "udp4", or "udp6".  If laddr is not nil, it is
 // used as the local address for the connection.
 func DialUDP(net string, laddr, raddr *UDPAddr) (*UDPConn, error) {
-	return dialUDP(net, laddr, raddr, noDeadline)
+	return dialUDP(net, laddr, raddr, noDeadline, nil)
 }
 
-func dialUDP(net string, laddr, raddr *UDPAddr, deadline time.Time) (*UDPConn, error) {
+func dialUDP(net string, laddr, raddr *UDPAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*UDPConn, error) {
 	if !deadline.IsZero() {
 		panic("net.dialUDP: deadline not implemented on Plan 9")
 	}
@@ -170,6 +183,10 @@ func unmarshalUDPHeader(b []byte) (*udpHeader, []byte) {
 // methods can be used to receive and send UDP packets with per-packet
 // addressing.
 func ListenUDP(net string, laddr *UDPAddr) (*UDPConn, error) {
+	return listenUDP(net, laddr, nil)
+}
+
+func listenUDP(net string, laddr *UDPAddr, ctrlFn func(string, string, uintptr) error) (*UDPConn, error) {
 	switch net {
 	case "udp", "udp4", "udp6":
 	default:
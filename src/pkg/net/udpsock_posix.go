@@ -159,6 +159,51 @@ func (c *UDPConn) WriteMsgUDP(b, oob []byte, addr *UDPAddr) (n, oobn int, err er
 	return c.fd.WriteMsg(b, oob, sa)
 }
 
+// ReadBatch reads one or more packets from c into ms, filling in the
+// payload, out-of-band data, flags and source address of each.  It
+// blocks until at least one packet is available and returns the
+// number of messages read, which may be less than len(ms).
+//
+// On Linux, ReadBatch reads all the available packets with a single
+// system call.  On other systems it reads one packet per call.
+func (c *UDPConn) ReadBatch(ms []UDPMessage) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	return c.readBatch(ms)
+}
+
+// WriteBatch writes the packets in ms via c, setting the N and OOBN
+// fields of each message written.  If c is connected, the Addr of
+// every message must be nil; otherwise it must be the destination
+// address.  It returns the number of messages written, which is less
+// than len(ms) only if err is not nil.
+//
+// On Linux, WriteBatch writes as many packets as the system accepts
+// with a single system call.  On other systems it writes one packet
+// at a time.
+func (c *UDPConn) WriteBatch(ms []UDPMessage) (int, error) {
+	if !c.ok() {
+		return 0, syscall.EINVAL
+	}
+	for i := range ms {
+		addr := ms[i].Addr
+		if c.fd.isConnected && addr != nil {
+			return 0, &OpError{Op: "write", Net: c.fd.net, Addr: addr, Err: ErrWriteToConnected}
+		}
+		if !c.fd.isConnected && addr == nil {
+			return 0, &OpError{Op: "write", Net: c.fd.net, Addr: nil, Err: errMissingAddress}
+		}
+	}
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	return c.writeBatch(ms)
+}
+
 // DialUDP connects to the remote address raddr on the network net,
 // which must be "udp", "udp4", or "udp6".  If laddr is not nil, it is
 // used as the local address for the connection.
@@ -171,11 +216,11 @@ func DialUDP(net string, laddr, raddr *UDPAddr) (*UDPConn, error) {
 	if raddr == nil {
 		return nil, &OpError{Op: "dial", Net: net, Addr: nil, Err: errMissingAddress}
 	}
-	return dialUDP(net, laddr, raddr, noDeadline)
+	return dialUDP(net, laddr, raddr, noDeadline, nil)
 }
 
-func dialUDP(net string, laddr, raddr *UDPAddr, deadline time.Time) (*UDPConn, error) {
-	fd, err := internetSocket(net, laddr, raddr, deadline, syscall.SOCK_DGRAM, 0, "dial", sockaddrToUDP)
+func dialUDP(net string, laddr, raddr *UDPAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*UDPConn, error) {
+	fd, err := internetSocket(net, laddr, raddr, deadline, syscall.SOCK_DGRAM, 0, "dial", sockaddrToUDP, ctrlFn)
 	if err != nil {
 		return nil, &OpError{Op: "dial", Net: net, Addr: raddr, Err: err}
 	}
@@ -190,6 +235,10 @@ func dialUDP(net string, laddr, raddr *UDPAddr, deadline time.Time) (*UDPConn, e
 // methods can be used to receive and send UDP packets with per-packet
 // addressing.
 func ListenUDP(net string, laddr *UDPAddr) (*UDPConn, error) {
+	return listenUDP(net, laddr, nil)
+}
+
+func listenUDP(net string, laddr *UDPAddr, ctrlFn func(string, string, uintptr) error) (*UDPConn, error) {
 	switch net {
 	case "udp", "udp4", "udp6":
 	default:
@@ -198,7 +247,7 @@ func ListenUDP(net string, laddr *UDPAddr) (*UDPConn, error) {
 	if laddr == nil {
 		laddr = &UDPAddr{}
 	}
-	fd, err := internetSocket(net, laddr, nil, noDeadline, syscall.SOCK_DGRAM, 0, "listen", sockaddrToUDP)
+	fd, err := internetSocket(net, laddr, nil, noDeadline, syscall.SOCK_DGRAM, 0, "listen", sockaddrToUDP, ctrlFn)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Addr: laddr, Err: err}
 	}
@@ -218,7 +267,7 @@ func ListenMulticastUDP(net string, ifi *Interface, gaddr *UDPAddr) (*UDPConn, e
 	if gaddr == nil || gaddr.IP == nil {
 		return nil, &OpError{Op: "listen", Net: net, Addr: nil, Err: errMissingAddress}
 	}
-	fd, err := internetSocket(net, gaddr, nil, noDeadline, syscall.SOCK_DGRAM, 0, "listen", sockaddrToUDP)
+	fd, err := internetSocket(net, gaddr, nil, noDeadline, syscall.SOCK_DGRAM, 0, "listen", sockaddrToUDP, nil)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Addr: gaddr, Err: err}
 	}
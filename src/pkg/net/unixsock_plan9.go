@@ -78,10 +78,10 @@ func (c *UnixConn) CloseWrite() error {
 // which must be "unix", "unixgram" or "unixpacket".  If laddr is not
 // nil, it is used as the local address for the connection.
 func DialUnix(net string, laddr, raddr *UnixAddr) (*UnixConn, error) {
-	return dialUnix(net, laddr, raddr, noDeadline)
+	return dialUnix(net, laddr, raddr, noDeadline, nil)
 }
 
-func dialUnix(net string, laddr, raddr *UnixAddr, deadline time.Time) (*UnixConn, error) {
+func dialUnix(net string, laddr, raddr *UnixAddr, deadline time.Time, ctrlFn func(string, string, uintptr) error) (*UnixConn, error) {
 	return nil, syscall.EPLAN9
 }
 
@@ -93,6 +93,10 @@ type UnixListener struct{}
 // ListenUnix announces on the Unix domain socket laddr and returns a
 // Unix listener.  The network net must be "unix" or "unixpacket".
 func ListenUnix(net string, laddr *UnixAddr) (*UnixListener, error) {
+	return listenUnix(net, laddr, nil)
+}
+
+func listenUnix(net string, laddr *UnixAddr, ctrlFn func(string, string, uintptr) error) (*UnixListener, error) {
 	return nil, syscall.EPLAN9
 }
 
@@ -139,5 +143,9 @@ func (l *UnixListener) File() (*os.File, error) {
 // The returned connection's ReadFrom and WriteTo methods can be used
 // to receive and send packets with per-packet addressing.
 func ListenUnixgram(net string, laddr *UnixAddr) (*UnixConn, error) {
+	return listenUnixgram(net, laddr, nil)
+}
+
+func listenUnixgram(net string, laddr *UnixAddr, ctrlFn func(string, string, uintptr) error) (*UnixConn, error) {
 	return nil, syscall.EPLAN9
 }